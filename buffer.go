@@ -18,7 +18,8 @@ type Buffer interface {
 	// time.
 	DataSize(size int, usage BufferUsage)
 
-	// Data* prepares this buffer with the given data.
+	// Data* prepares this buffer with the given data, which must not be empty
+	// (use DataSize to allocate an empty buffer).
 	//
 	// The usage hint is only a performance hint, it has no effect on the
 	// actual usage of the buffer.
@@ -35,7 +36,8 @@ type Buffer interface {
 	DataFloat64(data []float64, usage BufferUsage)
 
 	// SubData updates a subarea of the data buffer with the given data,
-	// starting at the offset in elements (not bytes).
+	// starting at the offset in elements (not bytes). The data must not be
+	// empty.
 	//
 	// This function will generate an InvalidValue panic at Context.Check time
 	// if the new data would write past the end of the buffer.
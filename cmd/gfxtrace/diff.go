@@ -0,0 +1,109 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+
+	"github.com/slimsag/gfx/trace"
+)
+
+// traceFile is a trace being read from a file.
+type traceFile struct {
+	*trace.Reader
+	f    *os.File
+	path string
+}
+
+// next returns the next call of the trace, or nil at its end.
+func (t *traceFile) next(i int) (*trace.Call, error) {
+	c, err := t.Next()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: call %d: %v", t.path, i, err)
+	}
+	return c, nil
+}
+
+func openTrace(path string) (*traceFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := trace.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return &traceFile{Reader: r, f: f, path: path}, nil
+}
+
+// diff compares two traces call-by-call and prints the first call where they
+// diverge, preceded by at most context common calls. It tells if the traces
+// are identical.
+func diff(w io.Writer, pathA, pathB string, context int) (bool, error) {
+	a, err := openTrace(pathA)
+	if err != nil {
+		return false, err
+	}
+	defer a.f.Close()
+	b, err := openTrace(pathB)
+	if err != nil {
+		return false, err
+	}
+	defer b.f.Close()
+
+	var common []string
+	frame := 0
+	for i := 0; ; i++ {
+		ca, err := a.next(i)
+		if err != nil {
+			return false, err
+		}
+		cb, err := b.next(i)
+		if err != nil {
+			return false, err
+		}
+		if ca == nil && cb == nil {
+			fmt.Fprintf(w, "traces are identical (%d calls, %d frames)\n", i, frame)
+			return true, nil
+		}
+		if ca != nil && cb != nil && reflect.DeepEqual(ca, cb) {
+			common = append(common, fmt.Sprintf("  %d\t%s", i, formatCall(ca)))
+			if len(common) > context {
+				common = common[1:]
+			}
+			if ca.Op == trace.Frame {
+				frame++
+			}
+			continue
+		}
+
+		fmt.Fprintf(w, "traces diverge at call %d (frame %d):\n", i, frame)
+		for _, line := range common {
+			fmt.Fprintln(w, line)
+		}
+		fa, fb := describe(ca), describe(cb)
+		if fa == fb {
+			fa += " (payload differs)"
+			fb += " (payload differs)"
+		}
+		fmt.Fprintf(w, "- %d\t%s\n+ %d\t%s\n", i, fa, i, fb)
+		return false, nil
+	}
+}
+
+// describe formats a call, or the end of a trace if c is nil.
+func describe(c *trace.Call) string {
+	if c == nil {
+		return "<end of trace>"
+	}
+	return formatCall(c)
+}
@@ -0,0 +1,22 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io"
+	"os"
+
+	"github.com/slimsag/gfx/trace"
+)
+
+// export prints the source of a program issuing the calls of a trace.
+func export(w io.Writer, path string, o *trace.ProgramOptions) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return trace.WriteProgram(w, f, o)
+}
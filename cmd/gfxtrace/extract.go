@@ -0,0 +1,51 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/slimsag/gfx/trace"
+)
+
+// extract writes the payloads of a range of calls of a trace to files in the
+// given directory, and prints their names.
+func extract(w io.Writer, path string, r callRange, dir string) error {
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return err
+	}
+	write := func(i int, c *trace.Call, arg string, data []byte) error {
+		name := filepath.Join(dir, fmt.Sprintf("%06d.%s.%s.bin", i, c.Op, arg))
+		if err := ioutil.WriteFile(name, data, 0666); err != nil {
+			return err
+		}
+		_, err := fmt.Fprintf(w, "%s\t%d bytes\n", name, len(data))
+		return err
+	}
+	return forEach(path, func(i, frame int, c *trace.Call) error {
+		if r.contains(i) {
+			for k, a := range c.Args {
+				if a.Kind == trace.Bytes {
+					if err := write(i, c, fmt.Sprint(k), a.Bytes); err != nil {
+						return err
+					}
+				}
+			}
+			if c.Result.Kind == trace.Bytes {
+				if err := write(i, c, "r", c.Result.Bytes); err != nil {
+					return err
+				}
+			}
+		}
+		if r.done(i) {
+			return errStop
+		}
+		return nil
+	})
+}
@@ -0,0 +1,49 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/slimsag/gfx/trace"
+)
+
+// formatCall formats a call like trace.Call.String does, except that
+// enumerations are given by name, e.g. "Buffer(6).Draw(Triangles, 0, 3)".
+func formatCall(c *trace.Call) string {
+	recv := c.Op.Receiver()
+	if recv == "" {
+		return c.Op.String() + formatArgs(c)
+	}
+	s := fmt.Sprintf("%s(%d)%s%s", recv, c.Object, c.Op.String()[len(recv):], formatArgs(c))
+	if c.Result.Kind != trace.Nil {
+		result := c.Result.Format()
+		if name, ok := trace.EnumName(c.Op, -1, c.Result.Int); ok && c.Result.Kind == trace.Int {
+			result = name
+		}
+		s += " = " + result
+	}
+	return s
+}
+
+func formatArgs(c *trace.Call) string {
+	parts := make([]string, len(c.Args))
+	for i, a := range c.Args {
+		parts[i] = a.Format()
+		switch a.Kind {
+		case trace.StateValue:
+			if a.Call != nil {
+				parts[i] = formatCall(a.Call)
+				parts[i] = parts[i][strings.IndexByte(parts[i], '.')+1:]
+			}
+		case trace.Int:
+			if name, ok := trace.EnumName(c.Op, i, a.Int); ok {
+				parts[i] = name
+			}
+		}
+	}
+	return "(" + strings.Join(parts, ", ") + ")"
+}
@@ -0,0 +1,182 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/slimsag/gfx"
+	"github.com/slimsag/gfx/driver/null"
+	"github.com/slimsag/gfx/trace"
+)
+
+// record writes a trace of two frames to a file in dir, drawing the given
+// number of vertices in the second one.
+func record(t *testing.T, dir, name string, count int) string {
+	var w bytes.Buffer
+	r := trace.Record(null.New(4, 4), &w)
+	buf := r.NewBuffer(gfx.ArrayBuffer)
+	buf.DataFloat32(make([]float32, 6), gfx.StaticDraw)
+	fb := r.Framebuffer()
+	fb.Load(fb.NewState(fb.ClearColor(1, 0, 0, 1)))
+	fb.Clear(gfx.ColorBuffer | gfx.DepthBuffer)
+	r.EndFrame()
+	buf.SubDataFloat32(0, []float32{1, 2})
+	buf.Draw(gfx.Triangles, 0, count)
+	r.EndFrame()
+
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, w.Bytes(), 0666); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "gfxtrace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func runOK(t *testing.T, wantStatus int, args ...string) string {
+	var w bytes.Buffer
+	status, err := run(args, &w)
+	if err != nil || status != wantStatus {
+		t.Fatalf("%q: status %d, err %v; want status %d", args, status, err, wantStatus)
+	}
+	return w.String()
+}
+
+func TestCommands(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	a := record(t, dir, "a.trace", 3)
+
+	tests := []struct {
+		args []string
+		want []string // Substrings of the output.
+	}{
+		{[]string{"frames", a}, []string{"0      0-5    0      1              24", "1      6-8    1      0              8"}},
+		{[]string{"stats", a}, []string{"draw calls      1 (3 vertices)", "bytes uploaded  32 (32 to buffers, 0 to uniforms)", "Frame                  2"}},
+		{[]string{"stats", "-from", "6", a}, []string{"calls           3\n", "state changes   0"}},
+		{[]string{"dump", "-frame", "1", a}, []string{"6\tBuffer(1).SubDataFloat32(0, [8 bytes])\n7\tBuffer(1).Draw(Triangles, 0, 3)\n8\tFrame()\n"}},
+		{[]string{"dump", "-from", "2", "-to", "4", a}, []string{"2\tFramebuffer(0).NewState(ClearColor(1, 0, 0, 1)) = #2\n3\tFramebuffer(0).Load(#2)\n"}},
+		{[]string{"dump", "-from", "4", "-to", "5", a}, []string{"4\tFramebuffer(0).Clear(ColorBuffer|DepthBuffer)\n"}},
+		{[]string{"export", "-driver", "null", "-size", "4x4", a}, []string{"null.New(4, 4)", "\tfb.Clear(gfx.ColorBuffer | gfx.DepthBuffer)\n", "\tbuffer1.Draw(gfx.Triangles, 0, 3)\n"}},
+	}
+	for _, tst := range tests {
+		out := runOK(t, 0, tst.args...)
+		for _, want := range tst.want {
+			if !strings.Contains(out, want) {
+				t.Errorf("%q: output\n%s\ndoes not contain %q", tst.args, out, want)
+			}
+		}
+	}
+	if out := runOK(t, 0, "dump", "-from", "2", "-to", "4", a); strings.Count(out, "\n") != 2 {
+		t.Errorf("dump range: got\n%s", out)
+	}
+}
+
+func TestExtract(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	a := record(t, dir, "a.trace", 3)
+
+	out := filepath.Join(dir, "out")
+	runOK(t, 0, "extract", "-from", "6", "-o", out, a)
+	data, err := ioutil.ReadFile(filepath.Join(out, "000006.Buffer.SubDataFloat32.1.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []byte{0, 0, 0x80, 0x3f, 0, 0, 0, 0x40}; !bytes.Equal(data, want) {
+		t.Errorf("payload = %v, want %v", data, want)
+	}
+	if files, _ := ioutil.ReadDir(out); len(files) != 1 {
+		t.Errorf("extracted %d files, want 1", len(files))
+	}
+}
+
+func TestDiff(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	a := record(t, dir, "a.trace", 3)
+	b := record(t, dir, "b.trace", 3)
+	c := record(t, dir, "c.trace", 6)
+
+	if out := runOK(t, 0, "diff", a, b); !strings.Contains(out, "identical (9 calls, 2 frames)") {
+		t.Errorf("identical traces: got %s", out)
+	}
+	out := runOK(t, 1, "diff", "-context", "1", a, c)
+	want := "traces diverge at call 7 (frame 1):\n  6\tBuffer(1).SubDataFloat32(0, [8 bytes])\n- 7\tBuffer(1).Draw(Triangles, 0, 3)\n+ 7\tBuffer(1).Draw(Triangles, 0, 6)\n"
+	if out != want {
+		t.Errorf("got\n%s\nwant\n%s", out, want)
+	}
+}
+
+func TestUsage(t *testing.T) {
+	for _, args := range [][]string{nil, {"foo", "a"}, {"dump"}, {"diff", "a"}, {"dump", "-bad", "a"}, {"export", "-size", "4", "a"}} {
+		status, err := run(args, ioutil.Discard)
+		if _, ok := err.(usageError); !ok || status != 2 {
+			t.Errorf("%q: status %d, err %v", args, status, err)
+		}
+	}
+	if status, err := run([]string{"dump", "missing.trace"}, ioutil.Discard); status != 2 || err == nil {
+		t.Errorf("missing file: status %d, err %v", status, err)
+	}
+}
+
+func TestMinimize(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	a := record(t, dir, "a.trace", 3)
+
+	// The draw of record fails, as no program is used.
+	var w bytes.Buffer
+	r := trace.Record(null.New(4, 4), &w)
+	if err := trace.Replay(bytes.NewReader(readFile(t, a)), r, nil); err != nil {
+		t.Fatal(err)
+	}
+	func() {
+		defer func() { recover() }()
+		r.Check()
+	}()
+	b := filepath.Join(dir, "b.trace")
+	if err := ioutil.WriteFile(b, w.Bytes(), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	out := filepath.Join(dir, "min.trace")
+	got := runOK(t, 0, "minimize", "-driver", "null", "-panic", "invalid operation", "-o", out, b)
+	want := "minimized 10 calls to 3:\n0\tContext(0).NewBuffer(ArrayBuffer) = #1\n1\tBuffer(1).Draw(Triangles, 0, 3)\n2\tContext(0).Check() = \"invalid operation\"\n"
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	if got := runOK(t, 0, "dump", out); strings.Count(got, "\n") != 3 {
+		t.Errorf("minimized trace:\n%s", got)
+	}
+
+	got = runOK(t, 0, "minimize", "-size", "4x4", "-pixel", "1,1", a)
+	if !strings.Contains(got, "to 3:\n") || !strings.Contains(got, "Framebuffer(0).Clear(ColorBuffer|DepthBuffer)") {
+		t.Errorf("pixel: got\n%s", got)
+	}
+	if status, err := run([]string{"minimize", "-driver", "null", "-panic", "stack overflow", b}, ioutil.Discard); status != 2 || err == nil {
+		t.Errorf("no reproduction: status %d, err %v", status, err)
+	}
+}
+
+func readFile(t *testing.T, path string) []byte {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
@@ -0,0 +1,216 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Command gfxtrace inspects trace files recorded by the trace package. It
+// works entirely offline: only the minimize command uses a graphics context,
+// of a headless driver.
+//
+// Usage:
+//
+//	gfxtrace frames <trace>
+//	gfxtrace stats [-from i] [-to j] <trace>
+//	gfxtrace dump [-from i] [-to j] [-frame n] <trace>
+//	gfxtrace extract [-from i] [-to j] [-o dir] <trace>
+//	gfxtrace diff [-context n] <trace> <trace>
+//	gfxtrace export [-driver d] [-size wxh] <trace>
+//	gfxtrace minimize [-driver d] [-size wxh] [-panic msg | -pixel x,y [-color rrggbbaa]] [-o file] <trace>
+//
+// The frames command lists the frames of the trace, with the range of their
+// calls and their statistics. The stats command shows statistics of a range
+// of calls: the number of calls of each operation, draw calls, state changes
+// and bytes uploaded. The dump command prints a range of calls as text.
+//
+// The extract command writes the payloads of a range of calls (e.g. buffer
+// data, uniform values and pixels read) to files named after the index of
+// the call, its operation and the index of the argument, e.g.
+// "000012.Buffer.DataFloat32.0.bin" (the result has index "r").
+//
+// The diff command compares two traces call-by-call, including their
+// payloads, and prints the first call where they diverge, preceded by the
+// given number of common calls. It exits with status 1 if the traces differ.
+//
+// The export command prints the source of a standalone Go program issuing the
+// calls of the trace using the given driver (soft, null, gl2, gles2 or webgl)
+// with a default framebuffer of the given size, see trace.WriteProgram:
+//
+//	gfxtrace export -driver gl2 app.trace > repro/main.go
+//
+// The minimize command removes as many calls of the trace as possible while
+// the problem it reproduces persists when replayed onto a context of the
+// given headless driver (soft or null) with a default framebuffer of the given
+// size, see trace.Minimize. The problem is either a panic whose message
+// contains the -panic string (e.g. "invalid operation" for a Context.Check
+// call panicking with gfx.InvalidOperation; any panic by default), or the
+// pixel at the -pixel coordinates having the -color (by default, the color
+// it has after replaying the whole trace). The remaining calls are printed,
+// and written as a new trace to the -o file if given:
+//
+//	gfxtrace minimize -panic "invalid operation" -o min.trace app.trace
+//
+// Calls are indexed from zero, excluding the header of the trace; ranges
+// include the call at index i and exclude the one at index j.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/slimsag/gfx/trace"
+)
+
+const usage = `usage:
+	gfxtrace frames <trace>
+	gfxtrace stats [-from i] [-to j] <trace>
+	gfxtrace dump [-from i] [-to j] [-frame n] <trace>
+	gfxtrace extract [-from i] [-to j] [-o dir] <trace>
+	gfxtrace diff [-context n] <trace> <trace>
+	gfxtrace export [-driver d] [-size wxh] <trace>
+	gfxtrace minimize [-driver d] [-size wxh] [-panic msg | -pixel x,y [-color rrggbbaa]] [-o file] <trace>
+`
+
+// callRange is a range of call indices, given by the -from and -to flags.
+type callRange struct {
+	from, to int
+}
+
+func (r *callRange) flags(fs *flag.FlagSet) {
+	fs.IntVar(&r.from, "from", 0, "index of the first call")
+	fs.IntVar(&r.to, "to", -1, "index after the last call (-1 for the end of the trace)")
+}
+
+func (r *callRange) contains(i int) bool {
+	return i >= r.from && (r.to < 0 || i < r.to)
+}
+
+// done tells if the calls following the one at index i are out of range.
+func (r *callRange) done(i int) bool {
+	return r.to >= 0 && i+1 >= r.to
+}
+
+// errStop stops the iteration of forEach.
+var errStop = errors.New("stop")
+
+// usageError is an error in the command-line arguments.
+type usageError struct {
+	error
+}
+
+// forEach calls fn for each call of the trace read from the named file, with
+// its index and the index of its frame, until fn returns an error.
+func forEach(path string, fn func(i, frame int, c *trace.Call) error) error {
+	t, err := openTrace(path)
+	if err != nil {
+		return err
+	}
+	defer t.f.Close()
+	frame := 0
+	for i := 0; ; i++ {
+		c, err := t.next(i)
+		if c == nil || err != nil {
+			return err
+		}
+		if err := fn(i, frame, c); err == errStop {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if c.Op == trace.Frame {
+			frame++
+		}
+	}
+}
+
+// run runs the command given by the arguments, writing its output to w, and
+// returns its exit status.
+func run(args []string, w io.Writer) (status int, err error) {
+	if len(args) == 0 {
+		return 2, usageError{errors.New("no command given")}
+	}
+	fs := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	var (
+		r       callRange
+		frame   = -1
+		dir     = "."
+		context = 3
+		driver  = "soft"
+		size    = "640x480"
+		m       minimizeOptions
+	)
+	switch args[0] {
+	case "frames", "diff", "export", "minimize":
+	case "stats", "dump", "extract":
+		r.flags(fs)
+	default:
+		return 2, usageError{fmt.Errorf("unknown command %q", args[0])}
+	}
+	switch args[0] {
+	case "dump":
+		fs.IntVar(&frame, "frame", -1, "index of the frame to dump")
+	case "extract":
+		fs.StringVar(&dir, "o", ".", "output directory")
+	case "diff":
+		fs.IntVar(&context, "context", 3, "number of common calls to print before the divergence")
+	case "export":
+		fs.StringVar(&driver, "driver", "soft", "driver used by the program")
+		fs.StringVar(&size, "size", "640x480", "size of the default framebuffer")
+	case "minimize":
+		fs.StringVar(&driver, "driver", "soft", "headless driver used to replay the trace (soft or null)")
+		fs.StringVar(&size, "size", "640x480", "size of the default framebuffer")
+		m.flags(fs)
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2, usageError{err}
+	}
+	var width, height int
+	if _, err := fmt.Sscanf(size, "%dx%d", &width, &height); err != nil || width <= 0 || height <= 0 {
+		return 2, usageError{fmt.Errorf("invalid size %q", size)}
+	}
+	nargs := 1
+	if args[0] == "diff" {
+		nargs = 2
+	}
+	if fs.NArg() != nargs {
+		return 2, usageError{fmt.Errorf("%s: expected %d trace file(s)", args[0], nargs)}
+	}
+
+	switch args[0] {
+	case "frames":
+		err = frames(w, fs.Arg(0))
+	case "stats":
+		err = printStats(w, fs.Arg(0), r)
+	case "dump":
+		err = dump(w, fs.Arg(0), r, frame)
+	case "extract":
+		err = extract(w, fs.Arg(0), r, dir)
+	case "diff":
+		var same bool
+		same, err = diff(w, fs.Arg(0), fs.Arg(1), context)
+		if err == nil && !same {
+			return 1, nil
+		}
+	case "export":
+		err = export(w, fs.Arg(0), &trace.ProgramOptions{Driver: driver, Width: width, Height: height})
+	case "minimize":
+		err = minimize(w, fs.Arg(0), driver, width, height, &m)
+	}
+	if err != nil {
+		return 2, err
+	}
+	return 0, nil
+}
+
+func main() {
+	status, err := run(os.Args[1:], os.Stdout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gfxtrace: %v\n", err)
+		if _, ok := err.(usageError); ok {
+			fmt.Fprint(os.Stderr, usage)
+		}
+	}
+	os.Exit(status)
+}
@@ -0,0 +1,120 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/slimsag/gfx"
+	"github.com/slimsag/gfx/driver/null"
+	"github.com/slimsag/gfx/driver/soft"
+	"github.com/slimsag/gfx/trace"
+)
+
+// minimizeOptions are the options of the minimize command.
+type minimizeOptions struct {
+	panic string // Substring of the message of the panic.
+	pixel string // Coordinates of the pixel, "x,y".
+	color string // Color of the pixel, "rrggbbaa".
+	out   string // Path of the minimized trace.
+}
+
+func (o *minimizeOptions) flags(fs *flag.FlagSet) {
+	fs.StringVar(&o.panic, "panic", "", "substring of the message of the panic to reproduce")
+	fs.StringVar(&o.pixel, "pixel", "", "coordinates x,y of the pixel whose color to reproduce")
+	fs.StringVar(&o.color, "color", "", "color rrggbbaa of the pixel (default the color after replaying the trace)")
+	fs.StringVar(&o.out, "o", "", "path of the minimized trace to write")
+}
+
+// minimize prints the calls of a minimized trace reproducing the problem given
+// by the options, and writes it to a file if requested.
+func minimize(w io.Writer, path, driver string, width, height int, o *minimizeOptions) error {
+	var newContext func() gfx.Context
+	switch driver {
+	case "soft":
+		newContext = func() gfx.Context { return soft.New(width, height) }
+	case "null":
+		newContext = func() gfx.Context { return null.New(width, height) }
+	default:
+		return usageError{fmt.Errorf("unknown headless driver %q", driver)}
+	}
+	var calls []*trace.Call
+	err := forEach(path, func(i, frame int, c *trace.Call) error {
+		calls = append(calls, c)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	holds := trace.Panics(newContext, func(v interface{}) bool {
+		return strings.Contains(fmt.Sprint(v), o.panic)
+	})
+	if o.pixel != "" {
+		if driver == "null" {
+			return usageError{errors.New("the null driver does not render pixels")}
+		}
+		var x, y int
+		if _, err := fmt.Sscanf(o.pixel, "%d,%d", &x, &y); err != nil {
+			return usageError{fmt.Errorf("invalid pixel %q", o.pixel)}
+		}
+		var color [4]uint8
+		if o.color != "" {
+			b, err := hex.DecodeString(o.color)
+			if err != nil || len(b) != len(color) {
+				return usageError{fmt.Errorf("invalid color %q", o.color)}
+			}
+			copy(color[:], b)
+		} else if color, err = pixel(newContext(), calls, x, y); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		holds = trace.PixelIs(newContext, x, y, color)
+	}
+	if !holds(calls) {
+		return fmt.Errorf("%s: the trace does not reproduce the problem", path)
+	}
+
+	small := trace.Minimize(calls, holds)
+	fmt.Fprintf(w, "minimized %d calls to %d:\n", len(calls), len(small))
+	for i, c := range small {
+		fmt.Fprintf(w, "%d\t%s\n", i, formatCall(c))
+	}
+	if o.out == "" {
+		return nil
+	}
+	f, err := os.Create(o.out)
+	if err != nil {
+		return err
+	}
+	tw := trace.NewWriter(f)
+	for _, c := range small {
+		tw.Write(c)
+	}
+	if err := tw.Err(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// pixel replays calls onto ctx, and returns the color of the pixel of its
+// default framebuffer at x, y.
+func pixel(ctx gfx.Context, calls []*trace.Call, x, y int) ([4]uint8, error) {
+	p := trace.NewPlayer(ctx)
+	for i, c := range calls {
+		if err := p.Play(c); err != nil {
+			return [4]uint8{}, fmt.Errorf("call %d (%s): %v", i, c, err)
+		}
+	}
+	var color [4]uint8
+	ctx.Framebuffer().ReadPixelsUint8(x, y, 1, 1, color[:])
+	return color, nil
+}
@@ -0,0 +1,159 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/slimsag/gfx/trace"
+)
+
+// stats are the statistics of a range of calls.
+type stats struct {
+	calls, frames, draws, vertices int
+
+	// stateChanges is the number of context and framebuffer states loaded,
+	// by Load or PopState.
+	stateChanges int
+
+	// bufferBytes and uniformBytes are the number of bytes uploaded to
+	// buffers and uniforms.
+	bufferBytes, uniformBytes int
+
+	// ops is the number of calls of each operation.
+	ops map[trace.Op]int
+}
+
+func (s *stats) add(c *trace.Call) {
+	if s.ops == nil {
+		s.ops = make(map[trace.Op]int)
+	}
+	s.calls++
+	s.ops[c.Op]++
+	switch {
+	case c.Op == trace.Frame:
+		s.frames++
+	case c.Op == trace.BufferDraw:
+		s.draws++
+		if len(c.Args) == 3 {
+			s.vertices += int(c.Args[2].Int)
+		}
+	case c.Op == trace.ContextLoad || c.Op == trace.ContextPopState ||
+		c.Op == trace.FramebufferLoad || c.Op == trace.FramebufferPopState:
+		s.stateChanges++
+	case c.Op >= trace.BufferDataInt8 && c.Op <= trace.BufferSubDataFloat64:
+		s.bufferBytes += payload(c)
+	case c.Op >= trace.ProgramUniform1fv && c.Op <= trace.ProgramUniformMatrix4fv:
+		s.uniformBytes += payload(c)
+	}
+}
+
+// payload returns the number of bytes of the Bytes arguments of a call.
+func payload(c *trace.Call) int {
+	n := 0
+	for _, a := range c.Args {
+		if a.Kind == trace.Bytes {
+			n += len(a.Bytes)
+		}
+	}
+	return n
+}
+
+// frames prints the frames of a trace, with the range of their calls and
+// their statistics.
+func frames(w io.Writer, path string) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "FRAME\tCALLS\tDRAWS\tSTATE CHANGES\tBYTES UPLOADED")
+	var (
+		s     stats
+		first int
+		last  = -1
+	)
+	flush := func(frame int, unfinished bool) {
+		note := ""
+		if unfinished {
+			note = " (unfinished)"
+		}
+		fmt.Fprintf(tw, "%d%s\t%d-%d\t%d\t%d\t%d\n", frame, note, first, last, s.draws, s.stateChanges, s.bufferBytes+s.uniformBytes)
+		s, first = stats{}, last+1
+	}
+	frame := 0
+	err := forEach(path, func(i, f int, c *trace.Call) error {
+		s.add(c)
+		last, frame = i, f
+		if c.Op == trace.Frame {
+			flush(f, false)
+			frame++
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if last >= first {
+		flush(frame, true)
+	}
+	return tw.Flush()
+}
+
+// printStats prints the statistics of a range of calls of a trace.
+func printStats(w io.Writer, path string, r callRange) error {
+	var s stats
+	err := forEach(path, func(i, frame int, c *trace.Call) error {
+		if r.contains(i) {
+			s.add(c)
+		}
+		if r.done(i) {
+			return errStop
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "calls\t%d\n", s.calls)
+	fmt.Fprintf(tw, "frames\t%d\n", s.frames)
+	fmt.Fprintf(tw, "draw calls\t%d (%d vertices)\n", s.draws, s.vertices)
+	fmt.Fprintf(tw, "state changes\t%d\n", s.stateChanges)
+	fmt.Fprintf(tw, "bytes uploaded\t%d (%d to buffers, %d to uniforms)\n", s.bufferBytes+s.uniformBytes, s.bufferBytes, s.uniformBytes)
+	fmt.Fprintln(tw)
+
+	// The number of calls of each operation, most frequent first.
+	ops := make([]trace.Op, 0, len(s.ops))
+	for op := range s.ops {
+		ops = append(ops, op)
+	}
+	sort.Slice(ops, func(i, j int) bool {
+		if s.ops[ops[i]] != s.ops[ops[j]] {
+			return s.ops[ops[i]] > s.ops[ops[j]]
+		}
+		return ops[i] < ops[j]
+	})
+	for _, op := range ops {
+		fmt.Fprintf(tw, "%s\t%d\n", op, s.ops[op])
+	}
+	return tw.Flush()
+}
+
+// dump prints a range of calls of a trace, or those of the given frame if it
+// is not negative.
+func dump(w io.Writer, path string, r callRange, frame int) error {
+	return forEach(path, func(i, f int, c *trace.Call) error {
+		if r.contains(i) && (frame < 0 || f == frame) {
+			if _, err := fmt.Fprintf(w, "%d\t%s\n", i, formatCall(c)); err != nil {
+				return err
+			}
+		}
+		if r.done(i) || (frame >= 0 && f > frame) {
+			return errStop
+		}
+		return nil
+	})
+}
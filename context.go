@@ -73,4 +73,32 @@ type Context interface {
 	//
 	// In most cases, you shouldn't ever use Finish, but rather Flush.
 	Finish()
+
+	// Invalidate discards the context's caches of OpenGL state, such as the
+	// currently bound objects and the loaded context and framebuffer state.
+	// Subsequent operations make the OpenGL calls needed to reach the state
+	// they require, regardless of what the context believed was current.
+	//
+	// It must be called after foreign code (e.g. another OpenGL wrapper used
+	// alongside this one) has made OpenGL calls, before the context is used
+	// again.
+	Invalidate()
+
+	// Resync is like Invalidate, except that it queries the actual OpenGL
+	// state and updates the context's caches to match it, such that redundant
+	// OpenGL calls can still be avoided. The queries are slow, so Resync is
+	// only beneficial if the foreign code leaves most state unchanged.
+	Resync()
+
+	// SetDebugHandler sets the function invoked with the debug messages
+	// generated by the OpenGL implementation, e.g. performance or portability
+	// warnings, or disables debug output if h is nil. Use a DebugFilter to
+	// select the messages of interest.
+	//
+	// Messages are only generated by OpenGL implementations supporting debug
+	// output, which may require a debug OpenGL context. Unless debug output is
+	// synchronous (which contexts of the debug package request), h may be
+	// invoked from another thread, after the call that caused the message
+	// returned.
+	SetDebugHandler(h func(m DebugMessage))
 }
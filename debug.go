@@ -0,0 +1,196 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gfx
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// DebugSource is the source of a debug message, see Context.SetDebugHandler.
+type DebugSource int
+
+const (
+	// DebugSourceAPI is the OpenGL API, e.g. a call made with invalid
+	// arguments.
+	DebugSourceAPI DebugSource = iota
+
+	// DebugSourceWindowSystem is the window system, e.g. WGL or GLX.
+	DebugSourceWindowSystem
+
+	// DebugSourceShaderCompiler is the compiler of the shading language.
+	DebugSourceShaderCompiler
+
+	// DebugSourceThirdParty is an application associated with OpenGL, e.g. a
+	// debugger or profiler.
+	DebugSourceThirdParty
+
+	// DebugSourceApplication is the user of the OpenGL implementation.
+	DebugSourceApplication
+
+	// DebugSourceOther is any other source.
+	DebugSourceOther
+)
+
+var debugSourceNames = [...]string{"API", "WindowSystem", "ShaderCompiler", "ThirdParty", "Application", "Other"}
+
+// String returns the name of the source, e.g. "ShaderCompiler".
+func (s DebugSource) String() string {
+	if s >= 0 && int(s) < len(debugSourceNames) {
+		return debugSourceNames[s]
+	}
+	return "DebugSource(" + strconv.Itoa(int(s)) + ")"
+}
+
+// DebugType is the type of a debug message, see Context.SetDebugHandler.
+type DebugType int
+
+const (
+	// DebugTypeError is an error, which is also reported by Context.Check.
+	DebugTypeError DebugType = iota
+
+	// DebugTypeDeprecatedBehavior is the use of deprecated behavior.
+	DebugTypeDeprecatedBehavior
+
+	// DebugTypeUndefinedBehavior is the use of undefined behavior.
+	DebugTypeUndefinedBehavior
+
+	// DebugTypePortability is the use of functionality that is not portable,
+	// e.g. which only works with some OpenGL implementations.
+	DebugTypePortability
+
+	// DebugTypePerformance is the use of functionality that may harm
+	// performance, e.g. causing a buffer to be copied or a shader to be
+	// recompiled.
+	DebugTypePerformance
+
+	// DebugTypeOther is any other type.
+	DebugTypeOther
+)
+
+var debugTypeNames = [...]string{"Error", "DeprecatedBehavior", "UndefinedBehavior", "Portability", "Performance", "Other"}
+
+// String returns the name of the type, e.g. "Performance".
+func (t DebugType) String() string {
+	if t >= 0 && int(t) < len(debugTypeNames) {
+		return debugTypeNames[t]
+	}
+	return "DebugType(" + strconv.Itoa(int(t)) + ")"
+}
+
+// DebugSeverity is the severity of a debug message, see
+// Context.SetDebugHandler. Severities are ordered, from the least to the most
+// severe.
+type DebugSeverity int
+
+const (
+	// DebugSeverityNotification is anything that is not an error or a
+	// performance issue, e.g. an informational message.
+	DebugSeverityNotification DebugSeverity = iota
+
+	// DebugSeverityLow is e.g. a redundant state change or a minor
+	// performance issue.
+	DebugSeverityLow
+
+	// DebugSeverityMedium is e.g. a major performance issue, or the use of
+	// deprecated functionality.
+	DebugSeverityMedium
+
+	// DebugSeverityHigh is e.g. an error or undefined behavior.
+	DebugSeverityHigh
+)
+
+var debugSeverityNames = [...]string{"Notification", "Low", "Medium", "High"}
+
+// String returns the name of the severity, e.g. "High".
+func (s DebugSeverity) String() string {
+	if s >= 0 && int(s) < len(debugSeverityNames) {
+		return debugSeverityNames[s]
+	}
+	return "DebugSeverity(" + strconv.Itoa(int(s)) + ")"
+}
+
+// DebugMessage is a message generated by the OpenGL implementation, e.g. a
+// warning about the performance or the portability of a call.
+type DebugMessage struct {
+	Source   DebugSource
+	Type     DebugType
+	Severity DebugSeverity
+
+	// ID identifies the message among those of the same source and type. IDs
+	// are specific to the OpenGL implementation.
+	ID uint32
+
+	// Message is the text of the message, as generated by the OpenGL
+	// implementation.
+	Message string
+
+	// Call is the name of the gfx call during which the message was
+	// generated, e.g. "Buffer.DataFloat32". It is only known to contexts of
+	// the debug package, and is empty otherwise.
+	Call string
+}
+
+// String returns e.g. "Buffer.Draw: API Performance (Medium, 131218): ...", or
+// the same without the call name if it is unknown.
+func (m DebugMessage) String() string {
+	s := fmt.Sprintf("%v %v (%v, %d): %s", m.Source, m.Type, m.Severity, m.ID, m.Message)
+	if m.Call != "" {
+		s = m.Call + ": " + s
+	}
+	return s
+}
+
+// DebugFilter selects debug messages, see its Handler method. The zero filter
+// selects all messages.
+type DebugFilter struct {
+	// MinSeverity is the severity under which messages are discarded.
+	MinSeverity DebugSeverity
+
+	// Sources and Types, if not empty, are the only sources and types of the
+	// messages selected.
+	Sources []DebugSource
+	Types   []DebugType
+
+	// IgnoreIDs are the IDs of messages which are discarded regardless of
+	// their source and type, e.g. known harmless messages of an OpenGL
+	// implementation.
+	IgnoreIDs []uint32
+}
+
+// Handler returns a debug handler which invokes h with the messages selected
+// by the filter. Later changes to the filter do not affect the handler:
+//
+//	ctx.SetDebugHandler((&gfx.DebugFilter{
+//		MinSeverity: gfx.DebugSeverityLow,
+//		Types:       []gfx.DebugType{gfx.DebugTypePerformance, gfx.DebugTypePortability},
+//	}).Handler(func(m gfx.DebugMessage) {
+//		log.Println(m)
+//	}))
+func (f *DebugFilter) Handler(h func(m DebugMessage)) func(m DebugMessage) {
+	sources := make(map[DebugSource]bool, len(f.Sources))
+	for _, s := range f.Sources {
+		sources[s] = true
+	}
+	types := make(map[DebugType]bool, len(f.Types))
+	for _, t := range f.Types {
+		types[t] = true
+	}
+	ignored := make(map[uint32]bool, len(f.IgnoreIDs))
+	for _, id := range f.IgnoreIDs {
+		ignored[id] = true
+	}
+	min := f.MinSeverity
+	return func(m DebugMessage) {
+		switch {
+		case m.Severity < min:
+		case len(sources) > 0 && !sources[m.Source]:
+		case len(types) > 0 && !types[m.Type]:
+		case ignored[m.ID]:
+		default:
+			h(m)
+		}
+	}
+}
@@ -0,0 +1,343 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/slimsag/gfx"
+	"github.com/slimsag/gfx/intercept"
+)
+
+// checker holds the state of a context wrapped by Checker, whose intercepted
+// calls are followed by an implicit call to the Check method of the
+// underlying context. Thus, if any error should occur you will receive a nice
+// stack trace where that error occured.
+type checker struct {
+	ctx gfx.Context
+
+	// pushed holds the calls to PushState not yet matched by a call to
+	// PopState, by intercepted context or framebuffer, and pushes is the
+	// number of calls to PushState made.
+	pushed map[interface{}][]push
+	pushes uint64
+
+	// verify is whether the context's caches are verified after each call.
+	verify bool
+
+	// group is the share group of the context, guarded by the objects lock.
+	group *shareGroup
+
+	// owner is the ownership token of the context, or nil if concurrent
+	// calls are not detected.
+	owner *owner
+
+	// current holds the *intercept.Call being made, or nil, to annotate the
+	// debug messages generated by the underlying context.
+	current atomic.Value
+}
+
+// push is a call to PushState.
+type push struct {
+	seq   uint64
+	name  string
+	stack []uintptr
+}
+
+// unchecked is the set of methods which cannot generate errors, and after
+// which the context is thus not checked.
+var unchecked = map[string]bool{
+	"Context.Framebuffer":             true,
+	"Context.NewState":                true,
+	"Context.Bake":                    true,
+	"Context.Current":                 true,
+	"Context.Describe":                true,
+	"Context.PushState":               true,
+	"Context.BlendColor":              true,
+	"Context.BlendEquation":           true,
+	"Context.DepthMask":               true,
+	"Context.Enable":                  true,
+	"Context.Disable":                 true,
+	"Context.UseProgram":              true,
+	"Context.Viewport":                true,
+	"Context.Scissor":                 true,
+	"Context.LineWidth":               true,
+	"Context.ColorMask":               true,
+	"Context.CullFace":                true,
+	"Context.FrontFace":               true,
+	"Context.EnableVertexAttribArray": true,
+	"Context.Invalidate":              true,
+	"Context.Check":                   true,
+	"Context.SetDebugHandler":         true,
+	"Framebuffer.NewState":            true,
+	"Framebuffer.Current":             true,
+	"Framebuffer.Describe":            true,
+	"Framebuffer.PushState":           true,
+	"Framebuffer.ClearColor":          true,
+	"Framebuffer.ClearDepth":          true,
+	"Framebuffer.ClearStencil":        true,
+}
+
+// debugSynchronizer is implemented by contexts whose debug output can be made
+// synchronous.
+type debugSynchronizer interface {
+	SetDebugSynchronous(sync bool)
+}
+
+// setDebugHandler sets the debug handler of the underlying context to one
+// passing h the messages annotated with the call being made, and makes debug
+// output synchronous, if possible, such that messages are generated during
+// the call which caused them.
+func (c *checker) setDebugHandler(h func(m gfx.DebugMessage)) {
+	c.ctx.SetDebugHandler(func(m gfx.DebugMessage) {
+		if call, _ := c.current.Load().(*intercept.Call); call != nil && m.Call == "" {
+			m.Call = call.Interface + "." + call.Method
+		}
+		h(m)
+	})
+	if s, ok := c.ctx.(debugSynchronizer); ok {
+		s.SetDebugSynchronous(true)
+	}
+}
+
+// cacheVerifier is implemented by contexts that can verify their caches of
+// OpenGL state against the actual OpenGL state.
+type cacheVerifier interface {
+	VerifyCache() error
+}
+
+// check invokes the Check method of the underlying context and, if enabled,
+// verifies its caches.
+func (c *checker) check() {
+	c.ctx.Check()
+	if !c.verify {
+		return
+	}
+	if v, ok := c.ctx.(cacheVerifier); ok {
+		if err := v.VerifyCache(); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// before acquires the ownership token of the context, if any, and validates
+// the call before it is made.
+func (c *checker) before(call *intercept.Call) {
+	if c.owner == nil {
+		c.validate(call)
+		c.current.Store(call)
+		return
+	}
+	c.owner.acquire(call)
+	valid := false
+	defer func() {
+		if !valid {
+			c.owner.release(call)
+		}
+	}()
+	c.validate(call)
+	valid = true
+	c.current.Store(call)
+}
+
+// validate validates the call and its arguments before it is made.
+func (c *checker) validate(call *intercept.Call) {
+	c.checkObjects(call)
+	c.validateArgs(call)
+	switch call.Interface + "." + call.Method {
+	case "Context.PopState", "Framebuffer.PopState":
+		pushed := c.pushed[call.Object]
+		n := len(pushed)
+		if n == 0 {
+			panic(call.Interface + ".PopState: no matching call to PushState")
+		}
+		if n == 1 {
+			// Forget the object, which may then be reported as leaked.
+			delete(c.pushed, call.Object)
+			break
+		}
+		pushed[n-1] = push{}
+		c.pushed[call.Object] = pushed[:n-1]
+
+	case "Framebuffer.Delete":
+		if pushed := c.pushed[call.Object]; len(pushed) > 0 {
+			panic(fmt.Sprintf("Framebuffer.Delete: deleted with a state pushed by PushState but not popped, pushed at:\n%sdeleted at:\n%s", formatStack(pushed[0].stack), formatStack(callers())))
+		}
+	}
+}
+
+// after checks the context after a call was made, and releases its
+// ownership token, if any.
+func (c *checker) after(call *intercept.Call) {
+	if c.owner != nil {
+		defer c.owner.release(call)
+	}
+	defer c.current.Store((*intercept.Call)(nil))
+	name := call.Interface + "." + call.Method
+	switch {
+	case call.Interface == "Context" && strings.HasPrefix(call.Method, "New") && call.Method != "NewState":
+		track(c, strings.TrimPrefix(call.Method, "New"), call.Result)
+	case call.Method == "Delete":
+		markDeleted(call.Object)
+	case call.Interface == "Buffer" && strings.HasPrefix(call.Method, "Data"):
+		setBufferSize(call.Object, dataSize(call))
+	case name == "Program.AttribLocation", name == "Program.UniformLocation":
+		c.addLocation(call)
+	case name == "Program.Link":
+		forgetUniforms(call.Object)
+	case name == "Context.SetDebugHandler":
+		if h := call.Args[0].(func(m gfx.DebugMessage)); h != nil {
+			c.setDebugHandler(h)
+		}
+	}
+
+	switch {
+	case unchecked[name]:
+		if call.Method == "PushState" {
+			c.pushes++
+			c.pushed[call.Object] = append(c.pushed[call.Object], push{seq: c.pushes, name: name, stack: callers()})
+		}
+		return
+
+	case call.Interface == "Framebuffer" && call.Method != "Status" && call.Method != "Delete":
+		// Operations using the framebuffer also panic if it is incomplete.
+		status := intercept.Unwrap(call.Object).(gfx.Framebuffer).Status()
+		c.check()
+		if status != nil {
+			panic(status)
+		}
+		return
+
+	case name == "Framebuffer.Delete":
+		delete(c.pushed, call.Object)
+	}
+	c.check()
+}
+
+// panicked releases the ownership token of the context, if any, after a call
+// panicked.
+func (c *checker) panicked(call *intercept.Call) {
+	c.current.Store((*intercept.Call)(nil))
+	if c.owner != nil {
+		c.owner.release(call)
+	}
+}
+
+// Options are the options of a debug context, see New.
+type Options struct {
+	// VerifyCache is whether the context's caches of OpenGL state are
+	// verified after each call, see CacheChecker.
+	VerifyCache bool
+
+	// Goroutines is whether calls made concurrently from several goroutines
+	// on the context, or on any object gotten from it, are detected.
+	//
+	// Like stated by the gfx.Context documentation, a context must only be
+	// accessed from one goroutine at a time. When a goroutine makes a call
+	// while another one is making one, the context panics with the stack
+	// traces of both goroutines. The detection is cheap (two atomic
+	// operations per call, one on entry and one on exit) but only catches
+	// calls which actually overlap, so it is best used with tests making many
+	// calls from their goroutines.
+	Goroutines bool
+}
+
+// New wraps the given graphics context like Checker does, with the given
+// options. Checker and CacheChecker are short-handed for:
+//
+//	debug.New(c, nil)
+//	debug.New(c, &debug.Options{VerifyCache: true})
+func New(c gfx.Context, o *Options) gfx.Context {
+	if o == nil {
+		o = &Options{}
+	}
+	ch := &checker{ctx: c, pushed: make(map[interface{}][]push), verify: o.VerifyCache}
+	ch.group = &shareGroup{members: []*checker{ch}}
+	if o.Goroutines {
+		ch.owner = newOwner()
+	}
+	ctx := intercept.Context(c, intercept.Hooks{
+		Before:   ch.before,
+		After:    ch.after,
+		Panicked: ch.panicked,
+	})
+	register(ctx, ch)
+	return ctx
+}
+
+// CheckPushed returns an error if states were pushed by PushState on the
+// given debug context, or on any of its framebuffers, and not yet popped by
+// PopState, e.g. at the end of a frame or of a test. The error holds the stack
+// trace of the oldest such call to PushState. It panics if ctx is not a debug
+// context.
+//
+// Deleting a framebuffer with a pushed state also panics.
+func CheckPushed(ctx gfx.Context) error {
+	objects.Lock()
+	c := lookupContext(ctx)
+	objects.Unlock()
+	if c == nil {
+		panic("debug.CheckPushed: not a debug context")
+	}
+	var (
+		oldest *push
+		n      int
+	)
+	for _, pushed := range c.pushed {
+		for i := range pushed {
+			if oldest == nil || pushed[i].seq < oldest.seq {
+				oldest = &pushed[i]
+			}
+		}
+		n += len(pushed)
+	}
+	if oldest == nil {
+		return nil
+	}
+	return fmt.Errorf("%s: %d state(s) pushed but not popped, the first one at:\n%s", oldest.name, n, formatStack(oldest.stack))
+}
+
+// Checker wraps the given graphics context such that each function call to the
+// context (or any object gotten from it, e.g. a Framebuffer) has an implicit
+// Check() call after it.
+//
+// This ensures that, should any error occur in the context, you will receive
+// a nice Go stack trace with the exact function where the error was made.
+//
+// Additionally, it will generate panics for any Framebuffer operations whose
+// Status is != nil, and for any call made on (or given) an object which was
+// deleted, with the stack traces of both the deletion and the call, since
+// drivers would otherwise silently use the default OpenGL object instead.
+//
+// The arguments of each call are also validated before the call is made, and
+// any violation of the documented preconditions of the method (e.g. an enum
+// out of range, a SubData call writing past the end of the buffer or uniform
+// data whose length is not a multiple of the number of components) panics
+// with the call and its arguments, since OpenGL would otherwise report an
+// undescriptive error, if any, and some drivers panic with an index out of
+// range given empty slices.
+//
+// Debug output is made synchronous when the driver supports it, and the debug
+// messages passed to handlers set with SetDebugHandler hold the name of the
+// call which caused them (see gfx.DebugMessage.Call), such that e.g.
+// performance warnings can be logged next to the offending call.
+func Checker(c gfx.Context) gfx.Context {
+	return New(c, nil)
+}
+
+// CacheChecker is like Checker, except that after each function call it also
+// verifies that the context's caches of OpenGL state (e.g. the currently bound
+// objects and the loaded state) match the actual OpenGL state, by querying
+// it. If they do not, it panics.
+//
+// This detects foreign OpenGL calls that were not followed by a call to the
+// context's Invalidate or Resync method. The queries are very slow, so it
+// should only be used for debugging. Contexts that cannot verify their caches
+// are only checked like Checker does.
+func CacheChecker(c gfx.Context) gfx.Context {
+	return New(c, &Options{VerifyCache: true})
+}
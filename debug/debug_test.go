@@ -0,0 +1,453 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/slimsag/gfx"
+	"github.com/slimsag/gfx/driver/null"
+	"github.com/slimsag/gfx/intercept"
+)
+
+// panics calls f and returns the value it panicked with.
+func panics(f func()) (v interface{}) {
+	defer func() { v = recover() }()
+	f()
+	return nil
+}
+
+func TestChecker(t *testing.T) {
+	ctx := Checker(null.New(4, 4))
+	b := ctx.NewBuffer(gfx.ArrayBuffer)
+	b.DataSize(12, gfx.StaticDraw)
+	fb := ctx.Framebuffer()
+	fb.PushState()
+	fb.PopState()
+
+	tests := []struct {
+		name string
+		f    func()
+		want interface{}
+	}{
+		{"Draw", func() { b.Draw(gfx.Triangles, 0, 3) }, gfx.InvalidOperation},
+		{"PopState", ctx.PopState, "Context.PopState: no matching call to PushState"},
+		{"Framebuffer.PopState", fb.PopState, "Framebuffer.PopState: no matching call to PushState"},
+		{"Clear", func() { fb.Clear(0) }, "Framebuffer.Clear(0): invalid clear mask"},
+		{"ReadPixelsUint8", func() {
+			fb.ReadPixelsUint8(0, 0, 2, 2, make([]uint8, 4))
+		}, "Framebuffer.ReadPixelsUint8(0, 0, 2, 2, []uint8 of length 4): dst is not large enough, need a length of at least 16"},
+	}
+	for _, tst := range tests {
+		if v := panics(tst.f); v != tst.want {
+			t.Errorf("%s: panicked with %v, want %v", tst.name, v, tst.want)
+		}
+	}
+}
+
+// cacheContext is a context able to verify its caches, whose VerifyCache
+// method counts its calls and returns err.
+type cacheContext struct {
+	gfx.Context
+	verified int
+	err      error
+}
+
+func (c *cacheContext) VerifyCache() error {
+	c.verified++
+	return c.err
+}
+
+func TestCacheChecker(t *testing.T) {
+	c := &cacheContext{Context: null.New(4, 4)}
+	ctx := CacheChecker(c)
+	b := ctx.NewBuffer(gfx.ArrayBuffer)
+	b.DataSize(12, gfx.StaticDraw)
+	if c.verified != 2 {
+		t.Errorf("caches verified %d times, want 2", c.verified)
+	}
+
+	// Checker does not verify caches.
+	Checker(c).Flush()
+	if c.verified != 2 {
+		t.Errorf("caches verified %d times by Checker, want none", c.verified-2)
+	}
+
+	c.err = errors.New("null: cached array buffer binding 1 but actually 0")
+	if v := panics(ctx.Flush); v != c.err {
+		t.Errorf("Flush panicked with %v, want %v", v, c.err)
+	}
+}
+
+// isLive tells if the given object of a debug context is live, and returns
+// its description.
+func isLive(o gfx.Object) (LiveObject, bool) {
+	for _, l := range LiveObjects() {
+		if l.Object == intercept.Unwrap(o) {
+			return l, true
+		}
+	}
+	return LiveObject{}, false
+}
+
+func TestLiveObjects(t *testing.T) {
+	ctx := Checker(null.New(4, 4))
+	b := ctx.NewBuffer(gfx.ArrayBuffer)
+	tex := ctx.NewTexture(gfx.TextureType2D)
+	l, ok := isLive(b)
+	if !ok {
+		t.Fatal("buffer is not live")
+	}
+	if l.Type != "Buffer" || !strings.HasPrefix(l.Stack, "github.com/slimsag/gfx/debug.TestLiveObjects\n") {
+		t.Errorf("live object %v", l)
+	}
+	b.Delete()
+	if _, ok := isLive(b); ok {
+		t.Error("deleted buffer is live")
+	}
+	if _, ok := isLive(tex); !ok {
+		t.Error("texture is not live")
+	}
+	tex.Delete()
+}
+
+func TestLeaked(t *testing.T) {
+	// Objects leaked by other tests may be reported as well.
+	leaked := make(chan LiveObject, 2)
+	defer SetLeakHandler(SetLeakHandler(func(o LiveObject) {
+		if strings.Contains(o.Stack, "debug.TestLeaked") {
+			leaked <- o
+		}
+	}))
+
+	ctx := Checker(null.New(4, 4))
+	func() {
+		ctx.NewProgram()
+
+		// A framebuffer whose pushed state was popped is not referenced
+		// anymore. It is incomplete, so PopState panics with its status.
+		fb := ctx.NewFramebuffer()
+		fb.PushState()
+		panics(fb.PopState)
+	}()
+	want := map[string]bool{"Program": true, "Framebuffer": true}
+	for i := 0; i < 100 && len(want) > 0; i++ {
+		runtime.GC()
+		select {
+		case o := <-leaked:
+			if !want[o.Type] {
+				t.Errorf("leaked %v", o)
+			}
+			delete(want, o.Type)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	for typ := range want {
+		t.Errorf("leaked %s not reported", typ)
+	}
+}
+
+func TestUseAfterDelete(t *testing.T) {
+	ctx := Checker(null.New(4, 4))
+	b := ctx.NewBuffer(gfx.ArrayBuffer)
+	tex := ctx.NewTexture(gfx.TextureType2D)
+	p := ctx.NewProgram()
+	fb := ctx.NewFramebuffer()
+	b.Delete()
+	tex.Delete()
+	p.Delete()
+
+	const test = "github.com/slimsag/gfx/debug.TestUseAfterDelete"
+	tests := []struct {
+		name string
+		f    func()
+		want string
+	}{
+		{"Draw", func() { b.Draw(gfx.Triangles, 0, 3) }, "Buffer.Draw: use of a Buffer deleted at:\n" + test},
+		{"Delete", func() { b.Delete() }, "Buffer.Delete: Buffer deleted twice, first at:\n" + test},
+		{"Texture2D", func() {
+			fb.Texture2D(gfx.ColorAttachment0, gfx.Texture2D, tex)
+		}, "Framebuffer.Texture2D: use of a Texture deleted at:\n" + test},
+		{"UseProgram", func() { ctx.UseProgram(p) }, "Context.UseProgram: use of a Program deleted at:\n" + test},
+	}
+	for _, tst := range tests {
+		v, _ := panics(tst.f).(string)
+		if !strings.HasPrefix(v, tst.want) || !strings.Contains(v, "at:\n"+test+".func") {
+			t.Errorf("%s: panicked with %q, want deletion and use stacks", tst.name, v)
+		}
+	}
+	fb.Delete()
+}
+
+func TestCrossContext(t *testing.T) {
+	a, b, c := Checker(null.New(4, 4)), Checker(null.New(4, 4)), Checker(null.New(4, 4))
+	p := a.NewProgram()
+	rb := a.NewRenderbuffer()
+	fb := b.NewFramebuffer()
+
+	const want = "use of a %s of another context, created at:\ngithub.com/slimsag/gfx/debug.TestCrossContext\n"
+	if v, _ := panics(func() { b.UseProgram(p) }).(string); !strings.HasPrefix(v, "Context.UseProgram: "+fmt.Sprintf(want, "Program")) {
+		t.Errorf("UseProgram panicked with %q", v)
+	}
+	if v, _ := panics(func() {
+		fb.Renderbuffer(gfx.ColorAttachment0, rb)
+	}).(string); !strings.HasPrefix(v, "Framebuffer.Renderbuffer: "+fmt.Sprintf(want, "Renderbuffer")) {
+		t.Errorf("Renderbuffer panicked with %q", v)
+	}
+
+	// Share groups are transitive.
+	Share(c, b)
+	Share(a, c)
+	if v := panics(func() { b.UseProgram(p) }); v != nil {
+		t.Errorf("UseProgram with a shared program panicked with %v", v)
+	}
+	if v := panics(func() { Share(a, null.New(4, 4)) }); v != "debug.Share: not a debug context" {
+		t.Errorf("Share panicked with %v", v)
+	}
+	p.Delete()
+	rb.Delete()
+	fb.Delete()
+}
+
+func TestGoroutines(t *testing.T) {
+	// The underlying context blocks on Flush until released.
+	entered, release := make(chan bool), make(chan bool)
+	inner := intercept.Context(null.New(4, 4), intercept.Hooks{Before: func(c *intercept.Call) {
+		if c.Method == "Flush" {
+			entered <- true
+			<-release
+		}
+	}})
+	ctx := New(inner, &Options{Goroutines: true})
+	objects.Lock()
+	ch := lookupContext(ctx)
+	objects.Unlock()
+
+	// Calls made one at a time, from any goroutine, are fine.
+	b := ctx.NewBuffer(gfx.ArrayBuffer)
+	done := make(chan interface{})
+	go func() {
+		done <- panics(func() { b.DataSize(4, gfx.StaticDraw) })
+	}()
+	if v := <-done; v != nil {
+		t.Fatalf("DataSize panicked with %v", v)
+	}
+
+	// Overlapping calls panic in the goroutine making the second one, once
+	// the first one returns.
+	go func() {
+		done <- panics(ctx.Flush)
+	}()
+	<-entered
+	go func() {
+		done <- panics(func() { b.DataSize(8, gfx.StaticDraw) })
+	}()
+	for atomic.LoadInt32(&ch.owner.state) != contended {
+		runtime.Gosched()
+	}
+	close(release)
+	if v := <-done; v != nil {
+		t.Fatalf("Flush panicked with %v", v)
+	}
+	v, _ := (<-done).(string)
+	if !strings.HasPrefix(v, "Buffer.DataSize: called concurrently with Context.Flush, by another goroutine at:\n") ||
+		!strings.Contains(v, "and by this one at:\ngithub.com/slimsag/gfx/debug.TestGoroutines.func") {
+		t.Errorf("DataSize panicked with %q", v)
+	}
+
+	// The token is released by calls which panic.
+	if v := panics(func() { b.Draw(gfx.Triangles, 0, 3) }); v != gfx.InvalidOperation {
+		t.Errorf("Draw panicked with %v", v)
+	}
+	go func() {
+		done <- panics(b.Delete)
+	}()
+	if v := <-done; v != nil {
+		t.Errorf("Delete panicked with %v", v)
+	}
+}
+
+func TestDebugHandler(t *testing.T) {
+	ctx := Checker(null.New(4, 4))
+	var msgs []gfx.DebugMessage
+	ctx.SetDebugHandler(func(m gfx.DebugMessage) {
+		msgs = append(msgs, m)
+	})
+	b := ctx.NewBuffer(gfx.ArrayBuffer)
+	if v := panics(func() { b.Draw(gfx.Triangles, 0, 3) }); v != gfx.InvalidOperation {
+		t.Errorf("Draw panicked with %v", v)
+	}
+	want := []gfx.DebugMessage{{
+		Source:   gfx.DebugSourceAPI,
+		Type:     gfx.DebugTypeError,
+		Severity: gfx.DebugSeverityHigh,
+		ID:       0x0502,
+		Message:  "invalid operation",
+		Call:     "Buffer.Draw",
+	}}
+	if len(msgs) != 1 || msgs[0] != want[0] {
+		t.Errorf("messages %v, want %v", msgs, want)
+	}
+	b.Delete()
+}
+
+func TestValidate(t *testing.T) {
+	ctx := Checker(null.New(4, 4))
+	b := ctx.NewBuffer(gfx.ArrayBuffer)
+	b.DataFloat32(make([]float32, 6), gfx.StaticDraw)
+	b.SubDataFloat32(2, make([]float32, 4))
+	p := ctx.NewProgram()
+
+	tests := []struct {
+		name string
+		f    func()
+		want string
+	}{
+		{"SubData", func() {
+			b.SubDataFloat32(4, make([]float32, 4))
+		}, "Buffer.SubDataFloat32(4, []float32 of length 4): writes bytes 16 to 32, past the end of the 24-byte buffer"},
+		{"Data", func() {
+			b.DataUint8(nil, gfx.StaticDraw)
+		}, "Buffer.DataUint8([]uint8 of length 0, StaticDraw): data is empty, use DataSize to allocate an empty buffer"},
+		{"Draw", func() {
+			b.Draw(gfx.Primitive(100), 0, 3)
+		}, "Buffer.Draw(100, 0, 3): invalid gfx.Primitive 100"},
+		{"NewTexture", func() {
+			ctx.NewTexture(gfx.TextureType(0))
+		}, "Context.NewTexture(0): invalid gfx.TextureType 0"},
+		{"VertexAttribPointer size", func() {
+			b.VertexAttribPointer(0, 5, false, 0, 0)
+		}, "Buffer.VertexAttribPointer(0, 5, false, 0, 0): size must be 1, 2, 3 or 4"},
+		{"VertexAttribPointer stride", func() {
+			b.VertexAttribPointer(0, 3, false, 6, 0)
+		}, "Buffer.VertexAttribPointer(0, 3, false, 6, 0): stride must be a multiple of the size of float32"},
+		{"Uniform3fv", func() {
+			p.Uniform3fv(nil, make([]float32, 4))
+		}, "Program.Uniform3fv(<nil>, []float32 of length 4): length of data is not a multiple of 3"},
+		{"UniformMatrix4fv", func() {
+			p.UniformMatrix4fv(nil, false, make([]float32, 8))
+		}, "Program.UniformMatrix4fv(<nil>, false, []float32 of length 8): length of data is not a multiple of 16"},
+		{"Viewport", func() {
+			ctx.Viewport(0, 0, -1, 4)
+		}, "Context.Viewport(0, 0, -1, 4): negative width or height"},
+	}
+	for _, tst := range tests {
+		if v := panics(tst.f); v != tst.want {
+			t.Errorf("%s: panicked with %v, want %v", tst.name, v, tst.want)
+		}
+	}
+
+	// The size of the buffer is tracked.
+	b.DataSize(32, gfx.StaticDraw)
+	b.SubDataFloat32(4, make([]float32, 4))
+	b.Delete()
+	p.Delete()
+}
+
+// link links p with shaders compiled by the null driver.
+func link(ctx gfx.Context, p gfx.Program) {
+	vert, frag := ctx.NewShader(gfx.VertexShader), ctx.NewShader(gfx.FragmentShader)
+	vert.Compile("void main() {}")
+	frag.Compile("void main() {}")
+	p.Link(vert, frag)
+	vert.Delete()
+	frag.Delete()
+}
+
+func TestValidateLocations(t *testing.T) {
+	ctx := Checker(null.New(4, 4))
+	b := ctx.NewBuffer(gfx.ArrayBuffer)
+	p, q := ctx.NewProgram(), ctx.NewProgram()
+	link(ctx, p)
+	link(ctx, q)
+	a, u := p.AttribLocation("a"), p.UniformLocation("u")
+	other := q.UniformLocation("v")
+	q.UniformLocation("w")
+
+	tests := []struct {
+		name string
+		f    func()
+		want string
+	}{
+		{"VertexAttribPointer nil", func() {
+			b.VertexAttribPointer(nil, 3, false, 0, 0)
+		}, "Buffer.VertexAttribPointer(<nil>, 3, false, 0, 0): nil location, as returned by AttribLocation for unknown attributes"},
+		{"VertexAttribPointer foreign", func() {
+			b.VertexAttribPointer(int32(0), 3, false, 0, 0)
+		}, "Buffer.VertexAttribPointer(0, 3, false, 0, 0): location not returned by the AttribLocation method of a program of this context"},
+		{"EnableVertexAttribArray nil", func() {
+			ctx.EnableVertexAttribArray(nil)
+		}, "Context.EnableVertexAttribArray(<nil>): nil location, as returned by AttribLocation for unknown attributes"},
+		{"EnableVertexAttribArray foreign", func() {
+			ctx.EnableVertexAttribArray(1)
+		}, "Context.EnableVertexAttribArray(1): location not returned by the AttribLocation method of a program of this context"},
+		{"Uniform1fv nil", func() {
+			p.Uniform1fv(nil, []float32{1})
+		}, "Program.Uniform1fv(<nil>, []float32 of length 1): nil location, as returned by UniformLocation for unknown uniforms"},
+		{"Uniform1iv another program's location", func() {
+			p.Uniform1iv(q.UniformLocation("w"), []int32{1})
+		}, "Program.Uniform1iv(1, []int32 of length 1): location not returned by the UniformLocation method of this program since it was linked"},
+	}
+	for _, tst := range tests {
+		if v := panics(tst.f); v != tst.want {
+			t.Errorf("%s: panicked with %v, want %v", tst.name, v, tst.want)
+		}
+	}
+
+	// Locations returned by the context are valid, those of attributes for
+	// any program.
+	b.DataFloat32(make([]float32, 9), gfx.StaticDraw)
+	b.VertexAttribPointer(a, 3, false, 0, 0)
+	ctx.NewState(ctx.EnableVertexAttribArray(a))
+	p.Uniform1fv(u, []float32{1})
+	q.Uniform1fv(other, []float32{1})
+
+	// Linking a program again invalidates its uniform locations.
+	link(ctx, q)
+	want := "Program.Uniform1fv(0, []float32 of length 1): location not returned by the UniformLocation method of this program since it was linked"
+	if v := panics(func() { q.Uniform1fv(other, []float32{1}) }); v != want {
+		t.Errorf("Uniform1fv after Link: panicked with %v, want %v", v, want)
+	}
+	b.Delete()
+	p.Delete()
+	q.Delete()
+}
+
+func TestCheckPushed(t *testing.T) {
+	ctx := Checker(null.New(4, 4))
+	def, fb := ctx.Framebuffer(), ctx.NewFramebuffer()
+	if err := CheckPushed(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	const test = "github.com/slimsag/gfx/debug.TestCheckPushed"
+	ctx.PushState()
+	def.PushState()
+	err := CheckPushed(ctx)
+	if err == nil || !strings.HasPrefix(err.Error(), "Context.PushState: 2 state(s) pushed but not popped, the first one at:\n"+test+"\n") {
+		t.Errorf("CheckPushed = %v", err)
+	}
+	ctx.PopState()
+	def.PopState()
+	if err := CheckPushed(ctx); err != nil {
+		t.Error(err)
+	}
+
+	// The framebuffer is incomplete, so PopState panics with its status
+	// after popping the state.
+	fb.PushState()
+	v, _ := panics(fb.Delete).(string)
+	if !strings.HasPrefix(v, "Framebuffer.Delete: deleted with a state pushed by PushState but not popped, pushed at:\n"+test+"\n") {
+		t.Errorf("Delete panicked with %q", v)
+	}
+	panics(fb.PopState)
+	fb.Delete()
+}
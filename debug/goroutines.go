@@ -0,0 +1,79 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/slimsag/gfx/intercept"
+)
+
+// States of an ownership token.
+const (
+	free int32 = iota
+	held
+	contended
+)
+
+// owner is the ownership token of a debug context, held by the goroutine
+// making a call on the context or on one of its objects.
+type owner struct {
+	state int32
+
+	// holder receives the call and stack of the goroutine holding a
+	// contended token, when it releases it.
+	holder chan holder
+}
+
+// holder is the goroutine which held an ownership token.
+type holder struct {
+	name  string
+	stack []uintptr
+}
+
+func newOwner() *owner {
+	return &owner{holder: make(chan holder, 1)}
+}
+
+// acquire acquires the token for the given call, using a single atomic
+// compare-and-swap if it is free. If another goroutine holds it, it panics
+// with the stacks of both goroutines.
+func (o *owner) acquire(call *intercept.Call) {
+	if atomic.CompareAndSwapInt32(&o.state, free, held) {
+		return
+	}
+	name := call.Interface + "." + call.Method
+	stack := callers()
+
+	// Forget the holder of a previous contention, which we did not wait for,
+	// and ask the current holder for its stack.
+	select {
+	case <-o.holder:
+	default:
+	}
+	if atomic.CompareAndSwapInt32(&o.state, held, contended) {
+		select {
+		case h := <-o.holder:
+			panic(fmt.Sprintf("%s: called concurrently with %s, by another goroutine at:\n%sand by this one at:\n%s", name, h.name, formatStack(h.stack), formatStack(stack)))
+		case <-time.After(time.Second):
+		}
+	}
+	panic(fmt.Sprintf("%s: called concurrently with a call made by another goroutine, whose stack is unavailable; called at:\n%s", name, formatStack(stack)))
+}
+
+// release releases the token after the given call, using a single atomic
+// swap. If another goroutine is waiting for it, it is sent the call and stack
+// of this one.
+func (o *owner) release(call *intercept.Call) {
+	if atomic.SwapInt32(&o.state, free) != contended {
+		return
+	}
+	select {
+	case o.holder <- holder{name: call.Interface + "." + call.Method, stack: callers()}:
+	default:
+	}
+}
@@ -0,0 +1,338 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/slimsag/gfx"
+	"github.com/slimsag/gfx/intercept"
+)
+
+// LiveObject describes an object created by a debug context (e.g. using
+// NewBuffer) which was not yet deleted.
+type LiveObject struct {
+	// Type is the type of the object: "Framebuffer", "Renderbuffer",
+	// "Shader", "Texture", "Buffer" or "Program".
+	Type string
+
+	// Object is the object of the underlying context.
+	Object gfx.Object
+
+	// Stack is the stack trace of the goroutine which created the object, as
+	// formatted by Go in panics.
+	Stack string
+}
+
+// String returns e.g. "Buffer created at:\n" followed by the stack trace.
+func (o LiveObject) String() string {
+	return o.Type + " created at:\n" + o.Stack
+}
+
+// SetLeakHandler sets the function invoked for each object created by a debug
+// context which became unreachable without having been deleted, leaking its
+// OpenGL resources, and returns the previous one. The function is invoked from
+// the goroutine running finalizers. The default one logs the object along
+// with the stack trace where it was created.
+func SetLeakHandler(f func(o LiveObject)) func(o LiveObject) {
+	objects.Lock()
+	defer objects.Unlock()
+	prev := objects.leaked
+	objects.leaked = f
+	return prev
+}
+
+// object is an object created by a debug context, as recorded by track.
+type object struct {
+	seq     uint64
+	typ     string
+	obj     gfx.Object
+	owner   *checker
+	created []uintptr
+
+	// size is the size in bytes of the data store of a buffer.
+	size int
+
+	// uniforms is the set of uniform locations returned by a program since
+	// it was last linked.
+	uniforms map[interface{}]bool
+
+	// deleted is the stack of the call to Delete, or nil if the object is
+	// live.
+	deleted []uintptr
+}
+
+// live returns the description of the live object.
+func (o *object) live() LiveObject {
+	return LiveObject{Type: o.typ, Object: o.obj, Stack: formatStack(o.created)}
+}
+
+// objects holds the objects of all debug contexts, by address of their
+// intercepted object. The addresses are used as keys, and not the objects,
+// such that the objects can become unreachable and be finalized; deleted
+// objects are thus remembered until they are finalized.
+var objects = struct {
+	sync.Mutex
+	seq    uint64
+	byAddr map[uintptr]*object
+	leaked func(o LiveObject)
+}{
+	byAddr: make(map[uintptr]*object),
+	leaked: func(o LiveObject) {
+		log.Printf("debug: leaked %v", o)
+	},
+}
+
+// LiveObjects returns the objects created by any debug context which were
+// not yet deleted, in the order they were created. It is useful to find the
+// objects leaked, e.g. when a level is unloaded.
+func LiveObjects() []LiveObject {
+	objects.Lock()
+	live := make([]*object, 0, len(objects.byAddr))
+	for _, o := range objects.byAddr {
+		if o.deleted == nil {
+			live = append(live, o)
+		}
+	}
+	objects.Unlock()
+
+	sort.Slice(live, func(i, j int) bool {
+		return live[i].seq < live[j].seq
+	})
+	descs := make([]LiveObject, len(live))
+	for i, o := range live {
+		descs[i] = o.live()
+	}
+	return descs
+}
+
+// callers returns the stack of the calling goroutine.
+func callers() []uintptr {
+	pcs := make([]uintptr, 64)
+	return pcs[:runtime.Callers(1, pcs)]
+}
+
+// track records the given intercepted object, just created by the owner
+// context, as live.
+func track(owner *checker, typ string, o interface{}) {
+	created := callers()
+	objects.Lock()
+	objects.seq++
+	objects.byAddr[reflect.ValueOf(o).Pointer()] = &object{
+		seq:     objects.seq,
+		typ:     typ,
+		obj:     intercept.Unwrap(o).(gfx.Object),
+		owner:   owner,
+		created: created,
+	}
+	objects.Unlock()
+	runtime.SetFinalizer(o, finalize)
+}
+
+// lookup returns the recorded object of the given intercepted one, or nil.
+// Objects intercepted again, e.g. by a stats context wrapping a debug one,
+// are unwrapped. The objects lock must be held.
+func lookup(o interface{}) *object {
+	for o != nil {
+		v := reflect.ValueOf(o)
+		if v.Kind() != reflect.Ptr {
+			return nil
+		}
+		if t, ok := objects.byAddr[v.Pointer()]; ok {
+			return t
+		}
+		u := intercept.Unwrap(o)
+		if u == o {
+			return nil
+		}
+		o = u
+	}
+	return nil
+}
+
+// checkObjects panics if the given call is made on, or with, a deleted
+// object, including if it deletes an object twice, or with an object of a
+// context which does not share objects with c. The panic value holds the
+// stacks of both the call and of the deletion or creation of the object.
+func (c *checker) checkObjects(call *intercept.Call) {
+	if msg := c.misuse(call); msg != "" {
+		panic(msg)
+	}
+}
+
+// misuse returns the description of the misuse of objects made by the given
+// call, if any, see checkObjects.
+func (c *checker) misuse(call *intercept.Call) string {
+	objects.Lock()
+	defer objects.Unlock()
+	name := call.Interface + "." + call.Method
+	if o := lookup(call.Object); o != nil && o.deleted != nil {
+		if call.Method == "Delete" {
+			return fmt.Sprintf("%s: %s deleted twice, first at:\n%sthen at:\n%s", name, o.typ, formatStack(o.deleted), formatStack(callers()))
+		}
+		return fmt.Sprintf("%s: use of a %s deleted at:\n%sused at:\n%s", name, o.typ, formatStack(o.deleted), formatStack(callers()))
+	}
+	for _, arg := range call.Args {
+		if _, ok := arg.(gfx.Object); !ok {
+			continue
+		}
+		switch a := lookup(arg); {
+		case a == nil:
+		case a.deleted != nil:
+			return fmt.Sprintf("%s: use of a %s deleted at:\n%sused at:\n%s", name, a.typ, formatStack(a.deleted), formatStack(callers()))
+		case a.owner.group != c.group:
+			return fmt.Sprintf("%s: use of a %s of another context, created at:\n%sused at:\n%s", name, a.typ, formatStack(a.created), formatStack(callers()))
+		}
+	}
+	return ""
+}
+
+// markDeleted records the given intercepted object, just deleted, as no
+// longer live.
+func markDeleted(o interface{}) {
+	deleted := callers()
+	objects.Lock()
+	if t := lookup(o); t != nil {
+		t.deleted = deleted
+	}
+	objects.Unlock()
+}
+
+// bufferSize returns the size in bytes of the data store of the given
+// intercepted buffer, if known.
+func bufferSize(o interface{}) (int, bool) {
+	objects.Lock()
+	defer objects.Unlock()
+	if t := lookup(o); t != nil {
+		return t.size, true
+	}
+	return 0, false
+}
+
+// setBufferSize records the size in bytes of the data store of the given
+// intercepted buffer.
+func setBufferSize(o interface{}, size int) {
+	objects.Lock()
+	if t := lookup(o); t != nil {
+		t.size = size
+	}
+	objects.Unlock()
+}
+
+// recordable tells if the given location can be recorded, i.e. used as a map
+// key. Locations are opaque, and drivers may use any type for them.
+func recordable(l interface{}) bool {
+	return reflect.TypeOf(l).Comparable()
+}
+
+// addLocation records the location returned by the given call to the
+// AttribLocation or UniformLocation method of an intercepted program of c.
+func (c *checker) addLocation(call *intercept.Call) {
+	l := call.Result
+	if l == nil || !recordable(l) {
+		return
+	}
+	objects.Lock()
+	defer objects.Unlock()
+	if call.Method == "AttribLocation" {
+		c.group.addAttrib(l)
+		return
+	}
+	if t := lookup(call.Object); t != nil {
+		if t.uniforms == nil {
+			t.uniforms = make(map[interface{}]bool)
+		}
+		t.uniforms[l] = true
+	}
+}
+
+// forgetUniforms forgets the uniform locations returned by the given
+// intercepted program, which was just linked again.
+func forgetUniforms(o interface{}) {
+	objects.Lock()
+	if t := lookup(o); t != nil {
+		t.uniforms = nil
+	}
+	objects.Unlock()
+}
+
+// unknownAttrib tells if the given vertex attribute location was not
+// returned by a program of the share group of c.
+func (c *checker) unknownAttrib(l gfx.AttribLocation) bool {
+	if !recordable(l) {
+		return false
+	}
+	objects.Lock()
+	defer objects.Unlock()
+	return !c.group.attribs[l]
+}
+
+// unknownUniform tells if the given uniform location was not returned by the
+// given intercepted program since it was last linked.
+func unknownUniform(o interface{}, l gfx.UniformLocation) bool {
+	if !recordable(l) {
+		return false
+	}
+	objects.Lock()
+	defer objects.Unlock()
+	t := lookup(o)
+	return t != nil && !t.uniforms[l]
+}
+
+// finalize forgets an intercepted object which became unreachable, and
+// reports it if it was still live.
+func finalize(o interface{}) {
+	key := reflect.ValueOf(o).Pointer()
+	objects.Lock()
+	t, ok := objects.byAddr[key]
+	delete(objects.byAddr, key)
+	leaked := objects.leaked
+	objects.Unlock()
+	if ok && t.deleted == nil {
+		leaked(t.live())
+	}
+}
+
+// internalFrames are the prefixes of the functions of the leading frames
+// omitted by formatStack.
+var internalFrames = []string{
+	"runtime.Callers",
+	"github.com/slimsag/gfx/debug.callers",
+	"github.com/slimsag/gfx/debug.track",
+	"github.com/slimsag/gfx/debug.markDeleted",
+	"github.com/slimsag/gfx/debug.(*checker)",
+	"github.com/slimsag/gfx/debug.(*owner)",
+	"github.com/slimsag/gfx/intercept.",
+}
+
+// formatStack formats the stack trace of the given program counters, as
+// returned by runtime.Callers, omitting the leading frames of the debug
+// checker and of the intercept package.
+func formatStack(pcs []uintptr) string {
+	var b strings.Builder
+	frames := runtime.CallersFrames(pcs)
+	leading := true
+	for {
+		f, more := frames.Next()
+		if leading {
+			leading = false
+			for _, prefix := range internalFrames {
+				leading = leading || strings.HasPrefix(f.Function, prefix)
+			}
+		}
+		if !leading {
+			fmt.Fprintf(&b, "%s\n\t%s:%d\n", f.Function, f.File, f.Line)
+		}
+		if !more {
+			return b.String()
+		}
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug
+
+import (
+	"reflect"
+	"runtime"
+
+	"github.com/slimsag/gfx"
+)
+
+// shareGroup is a group of debug contexts which share their objects.
+type shareGroup struct {
+	members []*checker
+
+	// attribs is the set of vertex attribute locations returned by the
+	// programs of the group.
+	attribs map[interface{}]bool
+}
+
+// contexts holds the debug contexts, by address of their intercepted
+// context, until they are finalized. It is guarded by the objects lock.
+var contexts = make(map[uintptr]*checker)
+
+// register records the intercepted context of the given checker.
+func register(ctx gfx.Context, c *checker) {
+	objects.Lock()
+	contexts[reflect.ValueOf(ctx).Pointer()] = c
+	objects.Unlock()
+	runtime.SetFinalizer(ctx, func(ctx interface{}) {
+		objects.Lock()
+		delete(contexts, reflect.ValueOf(ctx).Pointer())
+		objects.Unlock()
+	})
+}
+
+// lookupContext returns the checker of the given intercepted context, or nil.
+// The objects lock must be held.
+func lookupContext(ctx gfx.Context) *checker {
+	v := reflect.ValueOf(ctx)
+	if v.Kind() != reflect.Ptr {
+		return nil
+	}
+	return contexts[v.Pointer()]
+}
+
+// Share declares that the given debug contexts are in the same share group,
+// i.e. that the OpenGL contexts they wrap share their objects, such that the
+// objects created by either of them can be used with the other one. Share
+// groups are transitive: sharing a with b and b with c also shares a with c.
+//
+// Debug contexts otherwise panic when given an object created by another
+// context, e.g. a program to UseProgram, as drivers would then use a foreign
+// OpenGL object. Share panics if either context is not a debug context.
+func Share(a, b gfx.Context) {
+	objects.Lock()
+	defer objects.Unlock()
+	ca, cb := lookupContext(a), lookupContext(b)
+	if ca == nil || cb == nil {
+		panic("debug.Share: not a debug context")
+	}
+	if ca.group == cb.group {
+		return
+	}
+	g := cb.group
+	for _, m := range g.members {
+		m.group = ca.group
+	}
+	ca.group.members = append(ca.group.members, g.members...)
+	for l := range g.attribs {
+		ca.group.addAttrib(l)
+	}
+}
+
+// addAttrib records the given vertex attribute location, returned by a
+// program of the group. The objects lock must be held.
+func (g *shareGroup) addAttrib(l gfx.AttribLocation) {
+	if g.attribs == nil {
+		g.attribs = make(map[interface{}]bool)
+	}
+	g.attribs[l] = true
+}
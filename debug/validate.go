@@ -0,0 +1,243 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/slimsag/gfx"
+	"github.com/slimsag/gfx/intercept"
+)
+
+// validateArgs panics if the arguments of the given call violate the
+// documented preconditions of its method, before the call reaches the
+// underlying context, where it would otherwise generate a less descriptive
+// OpenGL error or panic with an index out of range (or with a failed type
+// assertion, given a location it did not return). The panic value holds the
+// call and its arguments, e.g.:
+//
+//	Buffer.VertexAttribPointer(0, 5, false, 0, 0): size must be 1, 2, 3 or 4
+func (c *checker) validateArgs(call *intercept.Call) {
+	if msg := c.invalidArgs(call); msg != "" {
+		panic(formatCall(call) + ": " + msg)
+	}
+}
+
+// invalidArgs returns the description of the precondition violated by the
+// arguments of the given call, if any, see validateArgs.
+func (c *checker) invalidArgs(call *intercept.Call) string {
+	for _, arg := range call.Args {
+		if msg := invalidEnum(arg); msg != "" {
+			return msg
+		}
+	}
+	args := call.Args
+	switch name := call.Interface + "." + call.Method; {
+	case name == "Buffer.DataSize":
+		if args[0].(int) < 0 {
+			return "negative size"
+		}
+
+	case call.Interface == "Buffer" && strings.HasPrefix(call.Method, "Data"):
+		if reflect.ValueOf(args[0]).Len() == 0 {
+			return "data is empty, use DataSize to allocate an empty buffer"
+		}
+
+	case call.Interface == "Buffer" && strings.HasPrefix(call.Method, "SubData"):
+		offset, data := args[0].(int), reflect.ValueOf(args[1])
+		elem := int(data.Type().Elem().Size())
+		switch size, ok := bufferSize(call.Object); {
+		case offset < 0:
+			return "negative offset"
+		case data.Len() == 0:
+			return "data is empty"
+		case ok && (offset+data.Len())*elem > size:
+			return fmt.Sprintf("writes bytes %d to %d, past the end of the %d-byte buffer", offset*elem, (offset+data.Len())*elem, size)
+		}
+
+	case name == "Buffer.Draw":
+		if args[1].(int) < 0 || args[2].(int) < 0 {
+			return "negative first or count"
+		}
+
+	case name == "Buffer.VertexAttribPointer":
+		// The type of the attributes is always float32.
+		const typeSize = 4
+		size, stride, offset := args[1].(int), args[3].(int), args[4].(int)
+		switch {
+		case size < 1 || size > 4:
+			return "size must be 1, 2, 3 or 4"
+		case stride < 0 || stride > 255:
+			return "stride must be in the range 0-255"
+		case stride%typeSize != 0:
+			return "stride must be a multiple of the size of float32"
+		case offset < 0 || offset%typeSize != 0:
+			return "offset must be a non-negative multiple of the size of float32"
+		default:
+			return c.invalidAttrib(args[0])
+		}
+
+	case name == "Context.EnableVertexAttribArray":
+		return c.invalidAttrib(args[0])
+
+	case call.Interface == "Program" && strings.HasPrefix(call.Method, "Uniform") && strings.HasSuffix(call.Method, "v"):
+		n := uniformComponents(call.Method)
+		switch l := reflect.ValueOf(args[len(args)-1]).Len(); {
+		case l == 0:
+			return "data is empty"
+		case l%n != 0:
+			return fmt.Sprintf("length of data is not a multiple of %d", n)
+		default:
+			return invalidUniform(call.Object, args[0])
+		}
+
+	case name == "Framebuffer.Clear":
+		// Clearing all possible bits should yield zero.
+		m := args[0].(gfx.ClearMask)
+		if m == 0 || m&^(gfx.ColorBuffer|gfx.DepthBuffer|gfx.StencilBuffer) != 0 {
+			return "invalid clear mask"
+		}
+
+	case name == "Framebuffer.ReadPixelsUint8":
+		width, height, dst := args[2].(int), args[3].(int), args[4].([]uint8)
+		switch {
+		case width < 0 || height < 0:
+			return "negative width or height"
+		case len(dst) == 0:
+			return "dst is empty"
+		case len(dst) < width*height*4:
+			return fmt.Sprintf("dst is not large enough, need a length of at least %d", width*height*4)
+		}
+
+	case name == "Context.Viewport", name == "Context.Scissor":
+		if args[2].(int) < 0 || args[3].(int) < 0 {
+			return "negative width or height"
+		}
+
+	case name == "Renderbuffer.Storage":
+		if args[1].(int) < 0 || args[2].(int) < 0 {
+			return "negative width or height"
+		}
+
+	case name == "Context.LineWidth":
+		if args[0].(float32) <= 0 {
+			return "width must be positive"
+		}
+	}
+	return ""
+}
+
+// invalidAttrib returns a description of the given vertex attribute location
+// if it is nil, or was not returned by a program of the share group of c.
+func (c *checker) invalidAttrib(l gfx.AttribLocation) string {
+	switch {
+	case l == nil:
+		return "nil location, as returned by AttribLocation for unknown attributes"
+	case c.unknownAttrib(l):
+		return "location not returned by the AttribLocation method of a program of this context"
+	}
+	return ""
+}
+
+// invalidUniform returns a description of the given uniform location if it is
+// nil, or was not returned by the given intercepted program since it was last
+// linked.
+func invalidUniform(program interface{}, l gfx.UniformLocation) string {
+	switch {
+	case l == nil:
+		return "nil location, as returned by UniformLocation for unknown uniforms"
+	case unknownUniform(program, l):
+		return "location not returned by the UniformLocation method of this program since it was linked"
+	}
+	return ""
+}
+
+// invalidEnum returns a description of the given argument if it is an
+// enumeration whose value is not one of the predefined constants of its type.
+func invalidEnum(arg interface{}) string {
+	var v, first, last int
+	switch a := arg.(type) {
+	case gfx.TextureTarget:
+		v, first, last = int(a), int(gfx.Texture2D), int(gfx.TextureCubeMapNegativeZ)
+	case gfx.TextureType:
+		v, first, last = int(a), int(gfx.TextureType2D), int(gfx.TextureTypeCubeMap)
+	case gfx.RenderbufferFormat:
+		v, first, last = int(a), int(gfx.RGBA4), int(gfx.DepthComponent16)
+	case gfx.FramebufferAttachment:
+		v, first, last = int(a), int(gfx.ColorAttachment0), int(gfx.DepthStencilAttachment)
+	case gfx.BufferUsage:
+		v, first, last = int(a), int(gfx.StaticDraw), int(gfx.StreamDraw)
+	case gfx.BufferType:
+		v, first, last = int(a), int(gfx.ArrayBuffer), int(gfx.ElementArrayBuffer)
+	case gfx.Feature:
+		v, first, last = int(a), int(gfx.Blend), int(gfx.Dither)
+	case gfx.Orientation:
+		v, first, last = int(a), int(gfx.CCW), int(gfx.CW)
+	case gfx.Facet:
+		v, first, last = int(a), int(gfx.Front), int(gfx.FrontAndBack)
+	case gfx.ShaderType:
+		v, first, last = int(a), int(gfx.VertexShader), int(gfx.FragmentShader)
+	case gfx.BlendEquation:
+		v, first, last = int(a), int(gfx.FuncAdd), int(gfx.FuncReverseSubtract)
+	case gfx.Primitive:
+		v, first, last = int(a), int(gfx.Points), int(gfx.TriangleFan)
+	default:
+		return ""
+	}
+	if v < first || v > last || v >= gfx.EnumMax {
+		return fmt.Sprintf("invalid %T %d", arg, v)
+	}
+	return ""
+}
+
+// uniformComponents returns the number of components of the values set by
+// the given Uniform* method, e.g. 3 for Uniform3fv and 16 for
+// UniformMatrix4fv.
+func uniformComponents(method string) int {
+	if n := strings.TrimPrefix(method, "UniformMatrix"); n != method {
+		return int(n[0]-'0') * int(n[0]-'0')
+	}
+	return int(method[len("Uniform")] - '0')
+}
+
+// dataSize returns the size in bytes of the data store of a buffer after the
+// given call to DataSize or Data*.
+func dataSize(call *intercept.Call) int {
+	if size, ok := call.Args[0].(int); ok {
+		return size
+	}
+	data := reflect.ValueOf(call.Args[0])
+	return data.Len() * int(data.Type().Elem().Size())
+}
+
+// formatCall formats the given call along with its arguments, e.g.
+// "Buffer.SubDataFloat32(4, []float32 of length 6)".
+func formatCall(call *intercept.Call) string {
+	args := make([]string, len(call.Args))
+	for i, arg := range call.Args {
+		args[i] = formatArg(arg)
+	}
+	return call.Interface + "." + call.Method + "(" + strings.Join(args, ", ") + ")"
+}
+
+// formatArg formats an argument of a call: objects by their type, slices by
+// their type and length, and other values like fmt.Sprint does.
+func formatArg(arg interface{}) string {
+	if o, ok := arg.(gfx.Object); ok {
+		objects.Lock()
+		t := lookup(o)
+		objects.Unlock()
+		if t != nil {
+			return t.typ
+		}
+		return "gfx.Object"
+	}
+	if v := reflect.ValueOf(arg); v.Kind() == reflect.Slice {
+		return fmt.Sprintf("%T of length %d", arg, v.Len())
+	}
+	return fmt.Sprint(arg)
+}
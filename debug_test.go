@@ -0,0 +1,52 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gfx
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDebugFilter(t *testing.T) {
+	f := &DebugFilter{
+		MinSeverity: DebugSeverityLow,
+		Types:       []DebugType{DebugTypePerformance, DebugTypePortability},
+		IgnoreIDs:   []uint32{7},
+	}
+	var ids []uint32
+	h := f.Handler(func(m DebugMessage) {
+		ids = append(ids, m.ID)
+	})
+	f.MinSeverity = DebugSeverityHigh
+	for _, m := range []DebugMessage{
+		{ID: 1, Type: DebugTypePerformance, Severity: DebugSeverityLow},
+		{ID: 2, Type: DebugTypePerformance, Severity: DebugSeverityNotification},
+		{ID: 3, Type: DebugTypeError, Severity: DebugSeverityHigh},
+		{ID: 7, Type: DebugTypePortability, Severity: DebugSeverityMedium},
+		{ID: 8, Type: DebugTypePortability, Severity: DebugSeverityMedium},
+	} {
+		h(m)
+	}
+	if want := []uint32{1, 8}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("handled %v, want %v", ids, want)
+	}
+}
+
+func TestDebugMessageString(t *testing.T) {
+	m := DebugMessage{
+		Source:   DebugSourceAPI,
+		Type:     DebugTypePerformance,
+		Severity: DebugSeverityMedium,
+		ID:       131218,
+		Message:  "shader recompiled",
+		Call:     "Buffer.Draw",
+	}
+	if s, want := m.String(), "Buffer.Draw: API Performance (Medium, 131218): shader recompiled"; s != want {
+		t.Errorf("String() = %q, want %q", s, want)
+	}
+	if s := DebugType(42).String(); s != "DebugType(42)" {
+		t.Errorf("String() = %q", s)
+	}
+}
@@ -25,13 +25,13 @@ type Buffer struct {
 // DataSize implements the gfx.Buffer interface.
 func (b *Buffer) DataSize(size int, usage gfx.BufferUsage) {
 	typ := b.ctx.Enums[int(b.typ)]
-	b.ctx.fastBindBuffer(typ, b.o)
+	b.ctx.fastBindBuffer(b.typ, b.o)
 	gl.BufferData(typ, size, nil, b.ctx.Enums[int(usage)])
 }
 
 func (b *Buffer) data(size int, ptr unsafe.Pointer, usage gfx.BufferUsage) {
 	typ := b.ctx.Enums[int(b.typ)]
-	b.ctx.fastBindBuffer(typ, b.o)
+	b.ctx.fastBindBuffer(b.typ, b.o)
 	gl.BufferData(typ, size, ptr, b.ctx.Enums[int(usage)])
 }
 
@@ -77,7 +77,7 @@ func (b *Buffer) DataFloat64(data []float64, usage gfx.BufferUsage) {
 
 func (b *Buffer) subData(offset, size int, ptr unsafe.Pointer) {
 	typ := b.ctx.Enums[int(b.typ)]
-	b.ctx.fastBindBuffer(typ, b.o)
+	b.ctx.fastBindBuffer(b.typ, b.o)
 	gl.BufferSubData(typ, offset, size, ptr)
 }
 
@@ -123,7 +123,7 @@ func (b *Buffer) SubDataFloat64(offset int, data []float64) {
 
 // Draw implements the gfx.Buffer interface.
 func (b *Buffer) Draw(p gfx.Primitive, first, count int) {
-	b.ctx.fastBindBuffer(b.ctx.Enums[int(b.typ)], b.o)
+	b.ctx.fastBindBuffer(b.typ, b.o)
 	if b.typ == gfx.ArrayBuffer {
 		gl.DrawArrays(b.ctx.Enums[int(p)], int32(first), int32(count))
 	} else {
@@ -134,7 +134,7 @@ func (b *Buffer) Draw(p gfx.Primitive, first, count int) {
 
 // VertexAttribPointer implements the gfx.Buffer interface.
 func (b *Buffer) VertexAttribPointer(l gfx.AttribLocation, size int, normalized bool, stride, offset int) {
-	b.ctx.fastBindBuffer(b.ctx.Enums[int(b.typ)], b.o)
+	b.ctx.fastBindBuffer(b.typ, b.o)
 	gl.VertexAttribPointer(uint32(l.(int32)), int32(size), gl.FLOAT, normalized, int32(stride), unsafe.Pointer(uintptr(offset)))
 }
 
@@ -144,6 +144,7 @@ func (b *Buffer) Delete() {
 		return
 	}
 	gl.DeleteBuffers(1, &b.o)
+	b.ctx.forgetBuffer(b.o)
 	b.o = 0
 }
 
@@ -11,215 +11,291 @@ import (
 	s "github.com/slimsag/gfx/internal/state"
 )
 
-const (
-	csBlendColor = iota
-	csBlendEquation
-	csDepthMask
-	csUseProgram
-	csViewport
-	csScissor
-	csLineWidth
-	csColorMask
-	csCullFace
-	csFrontFace
-	csEnable
-	csDisable
-	csEnableVertexAttribArray
-)
+// defaultState returns the default value of each piece of context state, as
+// specified by OpenGL.
+func (c *Context) defaultState() []gfx.ContextStateValue {
+	// The viewport and scissor box default to the size of the window the
+	// OpenGL context was first attached to.
+	var viewport, scissor [4]int32
+	gl.GetIntegerv(gl.VIEWPORT, &viewport[0])
+	gl.GetIntegerv(gl.SCISSOR_BOX, &scissor[0])
+
+	d := []gfx.ContextStateValue{
+		c.BlendColor(0, 0, 0, 0),
+		c.BlendEquation(gfx.FuncAdd),
+		c.DepthMask(true),
+		c.UseProgram(nil),
+		c.Viewport(int(viewport[0]), int(viewport[1]), int(viewport[2]), int(viewport[3])),
+		c.Scissor(int(scissor[0]), int(scissor[1]), int(scissor[2]), int(scissor[3])),
+		c.LineWidth(1),
+		c.ColorMask(true, true, true, true),
+		c.CullFace(gfx.Back),
+		c.FrontFace(gfx.CCW),
+	}
+	for f := gfx.Blend; f <= gfx.Dither; f++ {
+		d = append(d, c.feature(f, f == gfx.Dither))
+	}
+	for l := 0; l < s.MaxVertexAttribs; l++ {
+		d = append(d, c.vertexAttribArray(uint32(l), false))
+	}
+	return d
+}
+
+// queryState queries the actual value of the given key from OpenGL, see the
+// state.Query type.
+func (c *Context) queryState(k s.Key, v *s.Value) bool {
+	switch k {
+	case s.BlendColor:
+		var x [4]float32
+		gl.GetFloatv(gl.BLEND_COLOR, &x[0])
+		v.SetColor(x)
+	case s.BlendEquation:
+		return c.queryEnum(gl.BLEND_EQUATION_RGB, v, int(gfx.FuncAdd), int(gfx.FuncReverseSubtract))
+	case s.DepthMask:
+		gl.GetBooleanv(gl.DEPTH_WRITEMASK, &v.B[0])
+	case s.UseProgram:
+		// The program object can only be known if it is the cached one, or no
+		// program at all.
+		o := getBinding(gl.CURRENT_PROGRAM)
+		if o != v.U {
+			if o != 0 {
+				return false
+			}
+			v.U, v.P = 0, nil
+		}
+	case s.Viewport:
+		gl.GetIntegerv(gl.VIEWPORT, &v.I[0])
+	case s.Scissor:
+		gl.GetIntegerv(gl.SCISSOR_BOX, &v.I[0])
+	case s.LineWidth:
+		gl.GetFloatv(gl.LINE_WIDTH, &v.F[0])
+	case s.ColorMask:
+		gl.GetBooleanv(gl.COLOR_WRITEMASK, &v.B[0])
+	case s.CullFace:
+		return c.queryEnum(gl.CULL_FACE_MODE, v, int(gfx.Front), int(gfx.FrontAndBack))
+	case s.FrontFace:
+		return c.queryEnum(gl.FRONT_FACE, v, int(gfx.CCW), int(gfx.CW))
+	default:
+		if k >= s.VertexAttribArray {
+			// Vertex attribute arrays hold their location in v.U. Locations
+			// past the supported ones generate InvalidValue, like enabling
+			// them did.
+			var max, enabled int32
+			gl.GetIntegerv(gl.MAX_VERTEX_ATTRIBS, &max)
+			if int64(v.U) >= int64(max) {
+				return false
+			}
+			gl.GetVertexAttribiv(v.U, gl.VERTEX_ATTRIB_ARRAY_ENABLED, &enabled)
+			v.B[0] = enabled != 0
+			return true
+		}
+		// Features hold their OpenGL capability in v.U.
+		gl.GetBooleanv(v.U, &v.B[0])
+	}
+	return true
+}
 
-func glBlendColor(v interface{}) {
-	x := v.([4]float32)
-	gl.BlendColor(x[0], x[1], x[2], x[3])
+// queryEnum queries an OpenGL enumeration, storing it and its gfx enumeration
+// (in the range of [first, last]) in v.
+func (c *Context) queryEnum(pname uint32, v *s.Value, first, last int) bool {
+	var x int32
+	gl.GetIntegerv(pname, &x)
+	for e := first; e <= last; e++ {
+		if c.Enums[e] == uint32(x) {
+			v.U, v.E = uint32(x), e
+			return true
+		}
+	}
+	return false
+}
+
+func glBlendColor(v *s.Value) {
+	gl.BlendColor(v.F[0], v.F[1], v.F[2], v.F[3])
 }
 
 // BlendColor implements the gfx.ContextStateProvider interface.
 func (c *Context) BlendColor(r, g, b, a float32) gfx.ContextStateValue {
 	return s.CSV{
-		Value:        [4]float32{r, g, b, a},
-		DefaultValue: [4]float32{0, 0, 0, 0}, // TODO(slimsag): verify
-		Key:          csBlendColor,
-		GLCall:       glBlendColor,
+		Key:    s.BlendColor,
+		Value:  s.Value{F: [4]float32{r, g, b, a}},
+		GLCall: glBlendColor,
 	}
 }
 
-func glBlendEquation(v interface{}) {
-	gl.BlendEquation(v.(uint32))
+func glBlendEquation(v *s.Value) {
+	gl.BlendEquation(v.U)
 }
 
 // BlendEquation implements the gfx.ContextStateProvider interface.
 func (c *Context) BlendEquation(eq gfx.BlendEquation) gfx.ContextStateValue {
 	return s.CSV{
-		Value:        c.Enums[int(eq)],
-		DefaultValue: uint32(gl.FUNC_ADD), // TODO(slimsag): verify
-		Key:          csBlendEquation,
-		GLCall:       glBlendEquation,
+		Key:    s.BlendEquation,
+		Value:  s.Value{U: c.Enums[int(eq)], E: int(eq)},
+		GLCall: glBlendEquation,
 	}
 }
 
-func glDepthMask(v interface{}) {
-	gl.DepthMask(v.(bool))
+func glDepthMask(v *s.Value) {
+	gl.DepthMask(v.B[0])
 }
 
 // DepthMask implements the gfx.ContextStateProvider interface.
 func (c *Context) DepthMask(m bool) gfx.ContextStateValue {
 	return s.CSV{
-		Value:        m,
-		DefaultValue: true, // TODO(slimsag): verify
-		Key:          csDepthMask,
-		GLCall:       glDepthMask,
+		Key:    s.DepthMask,
+		Value:  s.Value{B: [4]bool{m}},
+		GLCall: glDepthMask,
 	}
 }
 
-func glUseProgram(v interface{}) {
-	gl.UseProgram(v.(gfx.Program).Object().(uint32))
+func (c *Context) glUseProgram(v *s.Value) {
+	c.fastUseProgram(v.U)
 }
 
 // UseProgram implements the gfx.ContextStateProvider interface.
 func (c *Context) UseProgram(p gfx.Program) gfx.ContextStateValue {
+	// The program object ID is stored alongside the program itself, such that
+	// it contributes to pipeline hashes.
+	var o uint32
+	if p != nil {
+		o = p.Object().(uint32)
+	}
 	return s.CSV{
-		Value:        p,
-		DefaultValue: nil, // TODO(slimsag): verify
-		Key:          csUseProgram,
-		GLCall:       glUseProgram,
+		Key:    s.UseProgram,
+		Value:  s.Value{U: o, P: p},
+		GLCall: c.glUseProgram,
 	}
 }
 
-func glViewport(v interface{}) {
-	x := v.([4]int32)
-	gl.Viewport(x[0], x[1], x[2], x[3])
+func glViewport(v *s.Value) {
+	gl.Viewport(v.I[0], v.I[1], v.I[2], v.I[3])
 }
 
 // Viewport implements the gfx.ContextStateProvider interface.
 func (c *Context) Viewport(x, y, width, height int) gfx.ContextStateValue {
 	return s.CSV{
-		Value:        [4]int32{int32(x), int32(y), int32(width), int32(height)},
-		DefaultValue: [4]int32{0, 0, 0, 0}, // TODO(slimsag): track real default viewport values
-		Key:          csViewport,
-		GLCall:       glViewport,
+		Key:    s.Viewport,
+		Value:  s.Value{I: [4]int32{int32(x), int32(y), int32(width), int32(height)}},
+		GLCall: glViewport,
 	}
 }
 
-func glScissor(v interface{}) {
-	x := v.([4]int32)
-	gl.Scissor(x[0], x[1], x[2], x[3])
+func glScissor(v *s.Value) {
+	gl.Scissor(v.I[0], v.I[1], v.I[2], v.I[3])
 }
 
 // Scissor implements the gfx.ContextStateProvider interface.
 func (c *Context) Scissor(x, y, width, height int) gfx.ContextStateValue {
 	return s.CSV{
-		Value:        [4]int32{int32(x), int32(y), int32(width), int32(height)},
-		DefaultValue: [4]int32{0, 0, 0, 0}, // TODO(slimsag): track real default scissor values
-		Key:          csScissor,
-		GLCall:       glScissor,
+		Key:    s.Scissor,
+		Value:  s.Value{I: [4]int32{int32(x), int32(y), int32(width), int32(height)}},
+		GLCall: glScissor,
 	}
 }
 
-func glLineWidth(v interface{}) {
-	gl.LineWidth(v.(float32))
+func glLineWidth(v *s.Value) {
+	gl.LineWidth(v.F[0])
 }
 
 // LineWidth implements the gfx.ContextStateProvider interface.
 func (c *Context) LineWidth(w float32) gfx.ContextStateValue {
 	return s.CSV{
-		Value:        w,
-		DefaultValue: 1.0,
-		Key:          csLineWidth,
-		GLCall:       glLineWidth,
+		Key:    s.LineWidth,
+		Value:  s.Value{F: [4]float32{w}},
+		GLCall: glLineWidth,
 	}
 }
 
-func glColorMask(v interface{}) {
-	x := v.([4]bool)
-	gl.ColorMask(x[0], x[1], x[2], x[3])
+func glColorMask(v *s.Value) {
+	gl.ColorMask(v.B[0], v.B[1], v.B[2], v.B[3])
 }
 
 // ColorMask implements the gfx.ContextStateProvider interface.
 func (c *Context) ColorMask(r, g, b, a bool) gfx.ContextStateValue {
 	return s.CSV{
-		Value:        [4]bool{r, g, b, a},
-		DefaultValue: [4]bool{true, true, true, true},
-		Key:          csColorMask,
-		GLCall:       glColorMask,
+		Key:    s.ColorMask,
+		Value:  s.Value{B: [4]bool{r, g, b, a}},
+		GLCall: glColorMask,
 	}
 }
 
-func glCullFace(v interface{}) {
-	gl.CullFace(v.(uint32))
+func glCullFace(v *s.Value) {
+	gl.CullFace(v.U)
 }
 
 // CullFace implements the gfx.ContextStateProvider interface.
 func (c *Context) CullFace(f gfx.Facet) gfx.ContextStateValue {
 	return s.CSV{
-		Value:        c.Enums[int(f)],
-		DefaultValue: uint32(gl.FRONT), // TODO(slimsag): verify
-		Key:          csCullFace,
-		GLCall:       glCullFace,
+		Key:    s.CullFace,
+		Value:  s.Value{U: c.Enums[int(f)], E: int(f)},
+		GLCall: glCullFace,
 	}
 }
 
-func glFrontFace(v interface{}) {
-	gl.FrontFace(v.(uint32))
+func glFrontFace(v *s.Value) {
+	gl.FrontFace(v.U)
 }
 
 // FrontFace implements the gfx.ContextStateProvider interface.
 func (c *Context) FrontFace(o gfx.Orientation) gfx.ContextStateValue {
 	return s.CSV{
-		Value:        c.Enums[int(o)],
-		DefaultValue: uint32(gl.CCW), // TODO(slimsag): verify
-		Key:          csFrontFace,
-		GLCall:       glFrontFace,
+		Key:    s.FrontFace,
+		Value:  s.Value{U: c.Enums[int(o)], E: int(o)},
+		GLCall: glFrontFace,
 	}
 }
 
-type featureKey struct {
-	csKey int
-	f     gfx.Feature
-}
-
-func glEnable(v interface{}) {
-	gl.Enable(v.(uint32))
+// glFeature enables or disables the OpenGL capability v.U, depending on
+// v.B[0].
+func glFeature(v *s.Value) {
+	if v.B[0] {
+		gl.Enable(v.U)
+		return
+	}
+	gl.Disable(v.U)
 }
 
-// Enable implements the gfx.ContextStateProvider interface.
-func (c *Context) Enable(f gfx.Feature) gfx.ContextStateValue {
+// feature returns a state value for the given feature. All features are
+// disabled by default, except for dithering.
+func (c *Context) feature(f gfx.Feature, enabled bool) gfx.ContextStateValue {
 	return s.CSV{
-		Value:        c.Enums[int(f)],
-		DefaultValue: 0, // TODO(slimsag): default feature values!
-		Key: featureKey{
-			csKey: csEnable,
-			f:     f,
-		},
-		GLCall: glEnable,
+		Key:    s.FeatureKey(f),
+		Value:  s.Value{U: c.Enums[int(f)], E: int(f), B: [4]bool{enabled}},
+		GLCall: glFeature,
 	}
 }
 
-func glDisable(v interface{}) {
-	gl.Disable(v.(uint32))
+// Enable implements the gfx.ContextStateProvider interface.
+func (c *Context) Enable(f gfx.Feature) gfx.ContextStateValue {
+	return c.feature(f, true)
 }
 
 // Disable implements the gfx.ContextStateProvider interface.
 func (c *Context) Disable(f gfx.Feature) gfx.ContextStateValue {
-	return s.CSV{
-		Value:        c.Enums[int(f)],
-		DefaultValue: 0, // TODO(slimsag): default feature values!
-		Key: featureKey{
-			csKey: csDisable,
-			f:     f,
-		},
-		GLCall: glDisable,
+	return c.feature(f, false)
+}
+
+// glVertexAttribArray enables or disables the vertex attribute array at
+// location v.U, depending on v.B[0].
+func glVertexAttribArray(v *s.Value) {
+	if v.B[0] {
+		gl.EnableVertexAttribArray(v.U)
+		return
 	}
+	gl.DisableVertexAttribArray(v.U)
 }
 
-func glEnableVertexAttribArray(v interface{}) {
-	gl.EnableVertexAttribArray(uint32(v.(int32)))
+// vertexAttribArray returns a state value for the vertex attribute array at
+// the given location. All arrays are disabled by default.
+func (c *Context) vertexAttribArray(l uint32, enabled bool) gfx.ContextStateValue {
+	return s.CSV{
+		Key:    s.VertexAttribKey(int(l)),
+		Value:  s.Value{U: l, B: [4]bool{enabled}},
+		GLCall: glVertexAttribArray,
+	}
 }
 
 // EnableVertexAttribArray implements the gfx.ContextStateProvider interface.
 func (c *Context) EnableVertexAttribArray(l gfx.AttribLocation) gfx.ContextStateValue {
-	return s.CSV{
-		Value:        l.(int32),
-		DefaultValue: 0, // TODO(slimsag): default enable VAA values!
-		Key:          csEnableVertexAttribArray,
-		GLCall:       glEnableVertexAttribArray,
-	}
+	return c.vertexAttribArray(uint32(l.(int32)), true)
 }
@@ -0,0 +1,98 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+// +build amd64,!gles2 386,!gles2
+
+package gl2
+
+import (
+	"strings"
+	"unsafe"
+
+	"github.com/slimsag/gfx"
+	"github.com/slimsag/gfx/internal/gl/2.0/gl"
+)
+
+// Names of the OpenGL extensions providing debug output.
+const (
+	arbDebugOutput = "GL_ARB_debug_output"
+	khrDebug       = "GL_KHR_debug"
+)
+
+// hasExtension tells if the OpenGL implementation supports the named
+// extension.
+func hasExtension(name string) bool {
+	for _, ext := range strings.Fields(gl.GoStr(gl.GetString(gl.EXTENSIONS))) {
+		if ext == name {
+			return true
+		}
+	}
+	return false
+}
+
+// debugMessage converts a message passed to the debug callback of OpenGL.
+func debugMessage(source, gltype, id, severity uint32, message string) gfx.DebugMessage {
+	m := gfx.DebugMessage{
+		Source:  gfx.DebugSourceOther,
+		Type:    gfx.DebugTypeOther,
+		ID:      id,
+		Message: message,
+	}
+	if source >= gl.DEBUG_SOURCE_API && source <= gl.DEBUG_SOURCE_OTHER {
+		m.Source = gfx.DebugSource(source - gl.DEBUG_SOURCE_API)
+	}
+	if gltype >= gl.DEBUG_TYPE_ERROR && gltype <= gl.DEBUG_TYPE_OTHER {
+		m.Type = gfx.DebugType(gltype - gl.DEBUG_TYPE_ERROR)
+	}
+	switch severity {
+	case gl.DEBUG_SEVERITY_HIGH:
+		m.Severity = gfx.DebugSeverityHigh
+	case gl.DEBUG_SEVERITY_MEDIUM:
+		m.Severity = gfx.DebugSeverityMedium
+	case gl.DEBUG_SEVERITY_LOW:
+		m.Severity = gfx.DebugSeverityLow
+	default:
+		m.Severity = gfx.DebugSeverityNotification
+	}
+	return m
+}
+
+// SetDebugHandler implements the gfx.Context interface. Debug output
+// requires the GL_ARB_debug_output extension, which is usually only exposed
+// by debug OpenGL contexts; without it the handler is never invoked.
+//
+// The bindings hold a single debug callback for the whole process, so only
+// the handler of the context which set it last is invoked.
+func (c *Context) SetDebugHandler(h func(m gfx.DebugMessage)) {
+	if !hasExtension(arbDebugOutput) {
+		return
+	}
+	if h == nil {
+		gl.DebugMessageCallbackARB(nil, nil)
+		if hasExtension(khrDebug) {
+			gl.Disable(gl.DEBUG_OUTPUT)
+		}
+		return
+	}
+	gl.DebugMessageCallbackARB(func(source, gltype, id, severity uint32, length int32, message string, userParam unsafe.Pointer) {
+		h(debugMessage(source, gltype, id, severity, message))
+	}, nil)
+	if hasExtension(khrDebug) {
+		gl.Enable(gl.DEBUG_OUTPUT)
+	}
+}
+
+// SetDebugSynchronous sets whether debug output is synchronous, i.e. whether
+// debug handlers are invoked before the OpenGL call causing the message
+// returns, from the same thread. Synchronous output may be slower; it is
+// enabled by the contexts of the debug package.
+func (c *Context) SetDebugSynchronous(sync bool) {
+	if !hasExtension(arbDebugOutput) {
+		return
+	}
+	if sync {
+		gl.Enable(gl.DEBUG_OUTPUT_SYNCHRONOUS_ARB)
+	} else {
+		gl.Disable(gl.DEBUG_OUTPUT_SYNCHRONOUS_ARB)
+	}
+}
@@ -129,6 +129,7 @@ func (p *Program) Delete() {
 		return
 	}
 	gl.DeleteProgram(p.o)
+	p.ctx.forgetProgram(p.o)
 	p.o = 0
 }
 
@@ -22,12 +22,21 @@ type Context struct {
 
 	LastBindFramebuffer  uint32
 	LastBindRenderbuffer uint32
-	LastBindBuffer       uint32
 	LastUseProgram       uint32
 
+	// LastBindBuffer is the last buffer bound to each buffer type.
+	LastBindBuffer [numBufferTypes]uint32
+
 	// The default framebuffer implementation for the context.
 	fb Framebuffer
 
+	// fbState is the framebuffer state table, shared by all framebuffers.
+	fbState state.Table
+
+	// counters counts the work done, and avoided, by the caches of the
+	// context.
+	counters state.Counters
+
 	puts int
 }
 
@@ -82,6 +91,7 @@ func (c *Context) loadEnums() {
 	c.putEnum(int(gfx.CullFace), gl.CULL_FACE)
 	c.putEnum(int(gfx.PolygonOffsetFill), gl.POLYGON_OFFSET_FILL)
 	c.putEnum(int(gfx.ScissorTest), gl.SCISSOR_TEST)
+	c.putEnum(int(gfx.Dither), gl.DITHER)
 
 	// Orientations.
 	c.putEnum(int(gfx.CCW), gl.CCW)
@@ -121,8 +131,65 @@ func (c *Context) loadEnums() {
 	}
 }
 
+// invalidBinding is a bind cache value that never matches an actual object,
+// such that the next bind is always made.
+const invalidBinding = ^uint32(0)
+
+// getBinding returns the object currently bound to the given binding point,
+// e.g. gl.FRAMEBUFFER_BINDING.
+func getBinding(pname uint32) uint32 {
+	var o int32
+	gl.GetIntegerv(pname, &o)
+	return uint32(o)
+}
+
+// numBufferTypes is the number of gfx.BufferType values.
+const numBufferTypes = int(gfx.ElementArrayBuffer) + 1
+
+// bufferBindings maps each buffer type to the OpenGL binding point that
+// queries its binding.
+var bufferBindings = [numBufferTypes]uint32{
+	gfx.ArrayBuffer:        gl.ARRAY_BUFFER_BINDING,
+	gfx.ElementArrayBuffer: gl.ELEMENT_ARRAY_BUFFER_BINDING,
+}
+
+// forgetFramebuffer clears the bind cache of the given framebuffer, which is
+// being deleted. Deleting a bound object reverts its binding to zero.
+func (c *Context) forgetFramebuffer(framebuffer uint32) {
+	if c.LastBindFramebuffer == framebuffer {
+		c.LastBindFramebuffer = 0
+	}
+}
+
+// forgetRenderbuffer is like forgetFramebuffer, but for a renderbuffer.
+func (c *Context) forgetRenderbuffer(renderbuffer uint32) {
+	if c.LastBindRenderbuffer == renderbuffer {
+		c.LastBindRenderbuffer = 0
+	}
+}
+
+// forgetBuffer is like forgetFramebuffer, but for a buffer bound to any
+// buffer type.
+func (c *Context) forgetBuffer(buffer uint32) {
+	for t, b := range c.LastBindBuffer {
+		if b == buffer {
+			c.LastBindBuffer[t] = 0
+		}
+	}
+}
+
+// forgetProgram clears the cache of the given program, which is being
+// deleted. A program in use is only deleted once it is no longer used, so
+// it is unknown which program will be in use by then.
+func (c *Context) forgetProgram(program uint32) {
+	if c.LastUseProgram == program {
+		c.LastUseProgram = invalidBinding
+	}
+}
+
 func (c *Context) fastBindFramebuffer(framebuffer uint32) bool {
 	if c.LastBindFramebuffer == framebuffer {
+		c.counters.BindsElided++
 		return false
 	}
 	c.LastBindFramebuffer = framebuffer
@@ -132,6 +199,7 @@ func (c *Context) fastBindFramebuffer(framebuffer uint32) bool {
 
 func (c *Context) fastBindRenderbuffer(renderbuffer uint32) bool {
 	if c.LastBindRenderbuffer == renderbuffer {
+		c.counters.BindsElided++
 		return false
 	}
 	c.LastBindRenderbuffer = renderbuffer
@@ -139,17 +207,19 @@ func (c *Context) fastBindRenderbuffer(renderbuffer uint32) bool {
 	return true
 }
 
-func (c *Context) fastBindBuffer(typ, buffer uint32) bool {
-	if c.LastBindBuffer == buffer {
+func (c *Context) fastBindBuffer(t gfx.BufferType, buffer uint32) bool {
+	if c.LastBindBuffer[t] == buffer {
+		c.counters.BindsElided++
 		return false
 	}
-	c.LastBindBuffer = buffer
-	gl.BindBuffer(typ, buffer)
+	c.LastBindBuffer[t] = buffer
+	gl.BindBuffer(c.Enums[int(t)], buffer)
 	return true
 }
 
 func (c *Context) fastUseProgram(program uint32) bool {
 	if c.LastUseProgram == program {
+		c.counters.BindsElided++
 		return false
 	}
 	c.LastUseProgram = program
@@ -165,7 +235,8 @@ func (c *Context) Framebuffer() gfx.Framebuffer {
 // NewFramebuffer implements the gfx.Context interface.
 func (c *Context) NewFramebuffer() gfx.Framebuffer {
 	fb := &Framebuffer{
-		ctx: c,
+		Framebuffer: state.Framebuffer{Table: &c.fbState},
+		ctx:         c,
 	}
 	gl.GenFramebuffers(1, &fb.o)
 	return fb
@@ -258,6 +329,73 @@ func (c *Context) Finish() {
 	gl.Finish()
 }
 
+// Invalidate implements the gfx.Context interface.
+func (c *Context) Invalidate() {
+	c.LastBindFramebuffer = invalidBinding
+	c.LastBindRenderbuffer = invalidBinding
+	c.LastUseProgram = invalidBinding
+	for t := range c.LastBindBuffer {
+		c.LastBindBuffer[t] = invalidBinding
+	}
+	c.Context.Invalidate()
+	c.fbState.Invalidate()
+}
+
+// Resync implements the gfx.Context interface.
+func (c *Context) Resync() {
+	c.LastBindFramebuffer = getBinding(gl.FRAMEBUFFER_BINDING)
+	c.LastBindRenderbuffer = getBinding(gl.RENDERBUFFER_BINDING)
+	c.LastUseProgram = getBinding(gl.CURRENT_PROGRAM)
+	for t, pname := range bufferBindings {
+		c.LastBindBuffer[t] = getBinding(pname)
+	}
+
+	c.Context.Resync(c.queryState)
+	c.fbState.Resync(queryFramebufferState)
+}
+
+// VerifyCache verifies that the context's caches of OpenGL state match the
+// actual OpenGL state, and returns an error describing the first mismatch
+// found. It is used by the debug package, and is very slow.
+func (c *Context) VerifyCache() error {
+	type binding struct {
+		name   string
+		cached uint32
+		pname  uint32
+	}
+	bindings := []binding{
+		{"framebuffer", c.LastBindFramebuffer, gl.FRAMEBUFFER_BINDING},
+		{"renderbuffer", c.LastBindRenderbuffer, gl.RENDERBUFFER_BINDING},
+		{"program", c.LastUseProgram, gl.CURRENT_PROGRAM},
+	}
+	bufferNames := [numBufferTypes]string{
+		gfx.ArrayBuffer:        "array buffer",
+		gfx.ElementArrayBuffer: "element array buffer",
+	}
+	for t, pname := range bufferBindings {
+		bindings = append(bindings, binding{bufferNames[t], c.LastBindBuffer[t], pname})
+	}
+	for _, b := range bindings {
+		if b.cached == invalidBinding {
+			continue
+		}
+		if actual := getBinding(b.pname); b.cached != actual {
+			return fmt.Errorf("gles2: cached %s binding %d but actually %d", b.name, b.cached, actual)
+		}
+	}
+	if err := c.Context.Verify(c.queryState); err != nil {
+		return err
+	}
+	return c.fbState.Verify(queryFramebufferState)
+}
+
+// Counters returns the number of state values applied and elided when
+// loading states, and of bind calls elided, since the context was created. It
+// is used by the stats package.
+func (c *Context) Counters() (applied, elided, bindsElided int) {
+	return c.counters.Applied, c.counters.Elided, c.counters.BindsElided
+}
+
 // New returns a new OpenGL ES 2 graphics context. It must only be called under
 // the presence of an active OpenGL context in the OS thread.
 func New() (gfx.Context, error) {
@@ -268,6 +406,11 @@ func New() (gfx.Context, error) {
 	ctx := &Context{}
 	ctx.fb.o = 0 // Default framebuffer object.
 	ctx.fb.ctx = ctx
+	ctx.fb.Table = &ctx.fbState
 	ctx.loadEnums()
+	ctx.Context.Init(ctx.defaultState()...)
+	ctx.fbState.Init(ctx.fb.defaultState()...)
+	ctx.Context.SetCounters(&ctx.counters)
+	ctx.fbState.Counters = &ctx.counters
 	return ctx, nil
 }
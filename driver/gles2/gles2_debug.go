@@ -0,0 +1,120 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+// +build arm gles2
+
+package gles2
+
+import (
+	"strings"
+	"unsafe"
+
+	"github.com/slimsag/gfx"
+	gl "github.com/slimsag/gfx/internal/gles2/2.0/gles2"
+)
+
+// Names of the OpenGL extensions providing debug output.
+const (
+	arbDebugOutput = "GL_ARB_debug_output"
+	khrDebug       = "GL_KHR_debug"
+)
+
+// hasExtension tells if the OpenGL implementation supports the named
+// extension.
+func hasExtension(name string) bool {
+	for _, ext := range strings.Fields(gl.GoStr(gl.GetString(gl.EXTENSIONS))) {
+		if ext == name {
+			return true
+		}
+	}
+	return false
+}
+
+// debugMessageCallback returns the function setting the debug callback of
+// OpenGL, or nil if debug output is not supported. OpenGL ES implementations
+// provide debug output with GL_KHR_debug, whose entry points have the KHR
+// suffix, and some with GL_ARB_debug_output.
+func debugMessageCallback() func(callback gl.DebugProc, userParam unsafe.Pointer) {
+	switch {
+	case hasExtension(khrDebug):
+		return gl.DebugMessageCallbackKHR
+	case hasExtension(arbDebugOutput):
+		return gl.DebugMessageCallbackARB
+	}
+	return nil
+}
+
+// debugMessage converts a message passed to the debug callback of OpenGL.
+func debugMessage(source, gltype, id, severity uint32, message string) gfx.DebugMessage {
+	m := gfx.DebugMessage{
+		Source:  gfx.DebugSourceOther,
+		Type:    gfx.DebugTypeOther,
+		ID:      id,
+		Message: message,
+	}
+	if source >= gl.DEBUG_SOURCE_API && source <= gl.DEBUG_SOURCE_OTHER {
+		m.Source = gfx.DebugSource(source - gl.DEBUG_SOURCE_API)
+	}
+	if gltype >= gl.DEBUG_TYPE_ERROR && gltype <= gl.DEBUG_TYPE_OTHER {
+		m.Type = gfx.DebugType(gltype - gl.DEBUG_TYPE_ERROR)
+	}
+	switch severity {
+	case gl.DEBUG_SEVERITY_HIGH:
+		m.Severity = gfx.DebugSeverityHigh
+	case gl.DEBUG_SEVERITY_MEDIUM:
+		m.Severity = gfx.DebugSeverityMedium
+	case gl.DEBUG_SEVERITY_LOW:
+		m.Severity = gfx.DebugSeverityLow
+	default:
+		m.Severity = gfx.DebugSeverityNotification
+	}
+	return m
+}
+
+// SetDebugHandler implements the gfx.Context interface. Debug output
+// requires the GL_KHR_debug or GL_ARB_debug_output extension, which may only
+// be exposed by debug OpenGL ES contexts; without either the handler is never
+// invoked.
+//
+// The bindings hold a single debug callback for the whole process, so only
+// the handler of the context which set it last is invoked.
+func (c *Context) SetDebugHandler(h func(m gfx.DebugMessage)) {
+	setCallback := debugMessageCallback()
+	if setCallback == nil {
+		return
+	}
+	if h == nil {
+		setCallback(nil, nil)
+		if hasExtension(khrDebug) {
+			gl.Disable(gl.DEBUG_OUTPUT_KHR)
+		}
+		return
+	}
+	setCallback(func(source, gltype, id, severity uint32, length int32, message string, userParam unsafe.Pointer) {
+		h(debugMessage(source, gltype, id, severity, message))
+	}, nil)
+	if hasExtension(khrDebug) {
+		gl.Enable(gl.DEBUG_OUTPUT_KHR)
+	}
+}
+
+// SetDebugSynchronous sets whether debug output is synchronous, i.e. whether
+// debug handlers are invoked before the OpenGL call causing the message
+// returns, from the same thread. Synchronous output may be slower; it is
+// enabled by the contexts of the debug package.
+func (c *Context) SetDebugSynchronous(sync bool) {
+	var capability uint32
+	switch {
+	case hasExtension(khrDebug):
+		capability = gl.DEBUG_OUTPUT_SYNCHRONOUS_KHR
+	case hasExtension(arbDebugOutput):
+		capability = gl.DEBUG_OUTPUT_SYNCHRONOUS_ARB
+	default:
+		return
+	}
+	if sync {
+		gl.Enable(capability)
+	} else {
+		gl.Disable(capability)
+	}
+}
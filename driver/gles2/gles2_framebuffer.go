@@ -29,10 +29,11 @@ type Framebuffer struct {
 // useState binds the global OpenGL state for this local Framebuffer object.
 func (f *Framebuffer) useState() {
 	// Bind the framebuffer now.
-	if f.ctx.fastBindFramebuffer(f.o) {
-		f.GLCall(nil)
-	}
-	f.GLCall(f.Loaded)
+	f.ctx.fastBindFramebuffer(f.o)
+
+	// Framebuffer state is global to the OpenGL context, so apply the state
+	// loaded by this framebuffer.
+	f.ctx.fbState.Apply(f.Loaded)
 }
 
 // Clear implements the gfx.Framebuffer interface.
@@ -105,7 +106,7 @@ func (f *Framebuffer) Status() error {
 	case gl.FRAMEBUFFER_UNSUPPORTED:
 		return gfx.ErrFramebufferIncompleteDimensions
 	default:
-		panic(fmt.Sprintf("gl2: unhandled framebuffer status 0x%X\n", e))
+		panic(fmt.Sprintf("gles2: unhandled framebuffer status 0x%X\n", e))
 	}
 }
 
@@ -115,6 +116,7 @@ func (f *Framebuffer) Delete() {
 		return
 	}
 	gl.DeleteFramebuffers(1, &f.o)
+	f.ctx.forgetFramebuffer(f.o)
 	f.o = 0
 }
 
@@ -123,51 +125,69 @@ func (f *Framebuffer) Object() interface{} {
 	return f.o
 }
 
-const (
-	csClearColor = iota
-	csClearDepth
-	csClearStencil
-)
+// defaultState returns the default value of each piece of framebuffer state,
+// as specified by OpenGL.
+func (f *Framebuffer) defaultState() []s.CSV {
+	return []s.CSV{
+		f.ClearColor(0, 0, 0, 0).(s.CSV),
+		f.ClearDepth(1).(s.CSV),
+		f.ClearStencil(0).(s.CSV),
+	}
+}
+
+// queryFramebufferState queries the actual value of the given framebuffer
+// state key from OpenGL, see the state.Query type.
+func queryFramebufferState(k s.Key, v *s.Value) bool {
+	switch k {
+	case s.ClearColor:
+		var x [4]float32
+		gl.GetFloatv(gl.COLOR_CLEAR_VALUE, &x[0])
+		v.SetColor(x)
+	case s.ClearDepth:
+		var x float32
+		gl.GetFloatv(gl.DEPTH_CLEAR_VALUE, &x)
+		v.SetDepth(float64(x))
+	case s.ClearStencil:
+		gl.GetIntegerv(gl.STENCIL_CLEAR_VALUE, &v.I[0])
+	}
+	return true
+}
 
-func glClearColor(v interface{}) {
-	x := v.([4]float32)
-	gl.ClearColor(x[0], x[1], x[2], x[3])
+func glClearColor(v *s.Value) {
+	gl.ClearColor(v.F[0], v.F[1], v.F[2], v.F[3])
 }
 
-// ClearColor implements the gfx.ContextStateProvider interface.
+// ClearColor implements the gfx.FramebufferStateProvider interface.
 func (f *Framebuffer) ClearColor(r, g, b, a float32) gfx.FramebufferStateValue {
 	return s.CSV{
-		Value:        [4]float32{r, g, b, a},
-		DefaultValue: [4]float32{0, 0, 0, 0}, // TODO(slimsag): verify
-		Key:          csClearColor,
-		GLCall:       glClearColor,
+		Key:    s.ClearColor,
+		Value:  s.Value{F: [4]float32{r, g, b, a}},
+		GLCall: glClearColor,
 	}
 }
 
-func glClearDepth(v interface{}) {
-	gl.ClearDepthf(v.(float32))
+func glClearDepth(v *s.Value) {
+	gl.ClearDepthf(float32(v.D))
 }
 
-// ClearDepth implements the gfx.ContextStateProvider interface.
+// ClearDepth implements the gfx.FramebufferStateProvider interface.
 func (f *Framebuffer) ClearDepth(depth float64) gfx.FramebufferStateValue {
 	return s.CSV{
-		Value:        float32(depth),
-		DefaultValue: float32(0), // TODO(slimsag): verify
-		Key:          csClearDepth,
-		GLCall:       glClearDepth,
+		Key:    s.ClearDepth,
+		Value:  s.Value{D: depth},
+		GLCall: glClearDepth,
 	}
 }
 
-func glClearStencil(v interface{}) {
-	gl.ClearStencil(v.(int32))
+func glClearStencil(v *s.Value) {
+	gl.ClearStencil(v.I[0])
 }
 
-// ClearStencil implements the gfx.ContextStateProvider interface.
+// ClearStencil implements the gfx.FramebufferStateProvider interface.
 func (f *Framebuffer) ClearStencil(stencil int) gfx.FramebufferStateValue {
 	return s.CSV{
-		Value:        int32(stencil),
-		DefaultValue: int32(0), // TODO(slimsag): verify
-		Key:          csClearStencil,
-		GLCall:       glClearStencil,
+		Key:    s.ClearStencil,
+		Value:  s.Value{I: [4]int32{int32(stencil)}},
+		GLCall: glClearStencil,
 	}
 }
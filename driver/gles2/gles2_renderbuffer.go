@@ -37,6 +37,7 @@ func (r *Renderbuffer) Delete() {
 		return
 	}
 	gl.DeleteRenderbuffers(1, &r.o)
+	r.ctx.forgetRenderbuffer(r.o)
 	r.o = 0
 }
 
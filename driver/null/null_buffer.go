@@ -0,0 +1,44 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package null
+
+import (
+	"github.com/slimsag/gfx"
+	"github.com/slimsag/gfx/internal/emul"
+)
+
+// Buffer implements the gfx.Buffer interface.
+type Buffer struct {
+	emul.Buffer
+}
+
+// Draw implements the gfx.Buffer interface. Nothing is drawn, but the draw
+// call is validated. Element array buffers hold 16-bit indices, and first is
+// the index of the first one to draw.
+func (b *Buffer) Draw(p gfx.Primitive, first, count int) {
+	c := b.Ctx
+	c.Call("Buffer.Draw", b.O)
+	c.BindBuffer(&b.Buffer)
+	c.Bound.UseState()
+	switch {
+	case p < gfx.Points || p > gfx.TriangleFan:
+		c.SetError(gfx.InvalidEnum)
+	case first < 0 || count < 0:
+		c.SetError(gfx.InvalidValue)
+	case c.GL.Program == nil:
+		c.SetError(gfx.InvalidOperation)
+	case b.Type() == gfx.ElementArrayBuffer && (first+count)*2 > len(b.Bytes()):
+		c.SetError(gfx.InvalidOperation)
+	}
+	for l, enabled := range c.GL.AttribArrays {
+		if enabled && c.Attribs[l].Buffer == nil {
+			// The enabled array has no buffer.
+			c.SetError(gfx.InvalidOperation)
+		}
+	}
+	if err := c.Bound.Err(); err != nil {
+		c.SetError(gfx.InvalidFramebufferOperation)
+	}
+}
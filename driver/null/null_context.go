@@ -0,0 +1,169 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package null implements a graphics context that renders nothing.
+//
+// Its objects track their state in memory instead: the contents of buffers,
+// the attachments of framebuffers, the sources of shaders, the uniform values
+// of programs and the state loaded into the context. It is meant for tests
+// that only need object lifecycles and call sequences, and requires no GPU,
+// no cgo and no window:
+//
+//	ctx := null.New(640, 480)
+//	...
+//	for _, call := range ctx.(*null.Context).Calls() {
+//		fmt.Println(call)
+//	}
+//
+// OpenGL errors are detected for invalid arguments and misuse of objects, and
+// reported by Check like other drivers. Errors may also be simulated using
+// SetError and FailOn.
+//
+// Shaders compile successfully unless their source is empty, and programs
+// link successfully if given compiled vertex and fragment shaders. Attribute
+// and uniform locations are assigned on demand, in order of first request.
+package null
+
+import (
+	"fmt"
+
+	"github.com/slimsag/gfx"
+	"github.com/slimsag/gfx/internal/emul"
+)
+
+// Call is a method call made on the context or one of its objects.
+type Call struct {
+	// Method is the name of the method, qualified by the name of its type,
+	// e.g. "Buffer.DataFloat32" or "Context.Load".
+	Method string
+
+	// Object is the name of the object whose method was called, or zero for
+	// the context and its default framebuffer.
+	Object uint32
+}
+
+// String returns e.g. "Buffer(3).DataFloat32".
+func (c Call) String() string {
+	i := 0
+	for i < len(c.Method) && c.Method[i] != '.' {
+		i++
+	}
+	return fmt.Sprintf("%s(%d)%s", c.Method[:i], c.Object, c.Method[i:])
+}
+
+// Context implements the gfx.Context interface.
+type Context struct {
+	emul.Context
+
+	// The default framebuffer implementation for the context.
+	fb Framebuffer
+
+	// calls are the calls recorded since the last call to ClearCalls, and
+	// failures the errors to simulate on the next call of each method.
+	calls    []Call
+	failures map[string]error
+}
+
+// GLState is the state of a context, the equivalent of the global OpenGL
+// state.
+type GLState = emul.GL
+
+// GL returns the current state of the context.
+func (c *Context) GL() GLState {
+	return c.Context.GL
+}
+
+// Calls returns the calls made on the context and its objects since the last
+// call to ClearCalls, in order.
+func (c *Context) Calls() []Call {
+	return append([]Call(nil), c.calls...)
+}
+
+// ClearCalls forgets the recorded calls.
+func (c *Context) ClearCalls() {
+	c.calls = c.calls[:0]
+}
+
+// FailOn makes the next call of the given method (e.g. "Buffer.DataFloat32",
+// see Call) record err as if it occurred in the context. The call still has
+// its usual effects. A nil error cancels the failure.
+func (c *Context) FailOn(method string, err error) {
+	if err == nil {
+		delete(c.failures, method)
+		return
+	}
+	c.failures[method] = err
+}
+
+// call records a call of the given method on the object with the given name,
+// and simulates its failure if requested.
+func (c *Context) call(method string, o uint32) {
+	c.calls = append(c.calls, Call{Method: method, Object: o})
+	if err, ok := c.failures[method]; ok {
+		delete(c.failures, method)
+		c.SetError(err)
+	}
+}
+
+// Framebuffer implements the gfx.Context interface.
+func (c *Context) Framebuffer() gfx.Framebuffer {
+	return &c.fb
+}
+
+// NewFramebuffer implements the gfx.Context interface.
+func (c *Context) NewFramebuffer() gfx.Framebuffer {
+	fb := &Framebuffer{}
+	fb.Table = &c.FBState
+	c.NewObject("Context.NewFramebuffer", &fb.Handle, fb)
+	return fb
+}
+
+// NewRenderbuffer implements the gfx.Context interface.
+func (c *Context) NewRenderbuffer() gfx.Renderbuffer {
+	rb := &Renderbuffer{}
+	c.NewObject("Context.NewRenderbuffer", &rb.Handle, rb)
+	return rb
+}
+
+// NewShader implements the gfx.Context interface.
+func (c *Context) NewShader(t gfx.ShaderType) gfx.Shader {
+	s := &Shader{Shader: emul.NewShader(t)}
+	c.NewObject("Context.NewShader", &s.Handle, s)
+	return s
+}
+
+// NewTexture implements the gfx.Context interface.
+func (c *Context) NewTexture(t gfx.TextureType) gfx.Texture {
+	tex := &Texture{Texture: emul.NewTexture(t)}
+	c.NewObject("Context.NewTexture", &tex.Handle, tex)
+	return tex
+}
+
+// NewBuffer implements the gfx.Context interface.
+func (c *Context) NewBuffer(t gfx.BufferType) gfx.Buffer {
+	b := &Buffer{Buffer: emul.NewBuffer(t)}
+	c.NewObject("Context.NewBuffer", &b.Handle, b)
+	return b
+}
+
+// NewProgram implements the gfx.Context interface.
+func (c *Context) NewProgram() gfx.Program {
+	p := &Program{}
+	c.NewObject("Context.NewProgram", &p.Handle, p)
+	return p
+}
+
+// New returns a new null graphics context, whose default framebuffer has the
+// given size in pixels.
+func New(width, height int) gfx.Context {
+	if width < 0 || height < 0 {
+		panic(fmt.Sprintf("null: invalid framebuffer size %dx%d", width, height))
+	}
+	ctx := &Context{
+		failures: make(map[string]error),
+	}
+	ctx.OnCall = ctx.call
+	ctx.Init(width, height, &ctx.fb.Framebuffer)
+	return ctx
+}
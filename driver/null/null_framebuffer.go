@@ -0,0 +1,41 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package null
+
+import (
+	"github.com/slimsag/gfx"
+	"github.com/slimsag/gfx/internal/emul"
+)
+
+// Framebuffer implements the gfx.Framebuffer interface.
+type Framebuffer struct {
+	emul.Framebuffer
+}
+
+// Clear implements the gfx.Framebuffer interface. Nothing is cleared, but the
+// framebuffer must be complete.
+func (f *Framebuffer) Clear(m gfx.ClearMask) {
+	f.Ctx.Call("Framebuffer.Clear", f.O)
+	f.UseState()
+	if f.Err() != nil {
+		f.Ctx.SetError(gfx.InvalidFramebufferOperation)
+	}
+}
+
+// ReadPixelsUint8 implements the gfx.Framebuffer interface. Nothing is read,
+// such that dst is left unmodified.
+func (f *Framebuffer) ReadPixelsUint8(x, y, width, height int, dst []uint8) {
+	f.Ctx.Call("Framebuffer.ReadPixelsUint8", f.O)
+	f.UseState()
+	color, _ := f.Attachment(gfx.ColorAttachment0)
+	switch {
+	case f.Err() != nil:
+		f.Ctx.SetError(gfx.InvalidFramebufferOperation)
+	case &f.Framebuffer != f.Ctx.Default && color == nil:
+		f.Ctx.SetError(gfx.InvalidOperation)
+	case width < 0 || height < 0 || len(dst) < width*height*4:
+		f.Ctx.SetError(gfx.InvalidValue)
+	}
+}
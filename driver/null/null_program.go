@@ -0,0 +1,93 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package null
+
+import (
+	"github.com/slimsag/gfx"
+	"github.com/slimsag/gfx/internal/emul"
+	"github.com/slimsag/gfx/internal/state"
+)
+
+// Program implements the gfx.Program interface.
+type Program struct {
+	emul.Program
+
+	// The shaders of the program, once it is linked.
+	vert, frag *Shader
+
+	// attribs maps the names of the attributes whose location was requested
+	// to their locations, which are assigned on demand, like those of the
+	// uniforms.
+	attribs map[string]int
+}
+
+// Link implements the gfx.Program interface. It succeeds if given compiled
+// vertex and fragment shaders.
+func (p *Program) Link(vert, frag gfx.Shader) bool {
+	p.Ctx.Call("Program.Link", p.O)
+	vs, _ := p.Ctx.Lookup(vert).(*Shader)
+	fs, _ := p.Ctx.Lookup(frag).(*Shader)
+	switch {
+	case vs == nil || vs.Type() != gfx.VertexShader || !vs.Compiled():
+		return p.SetLinked("null: no compiled vertex shader")
+	case fs == nil || fs.Type() != gfx.FragmentShader || !fs.Compiled():
+		return p.SetLinked("null: no compiled fragment shader")
+	}
+	p.vert, p.frag = vs, fs
+	p.attribs = make(map[string]int)
+	return p.SetLinked("")
+}
+
+// Shaders returns the shaders the program was linked with, or nils if it is
+// not linked.
+func (p *Program) Shaders() (vert, frag gfx.Shader) {
+	if !p.Linked() {
+		return nil, nil
+	}
+	return p.vert, p.frag
+}
+
+// Float returns the value of the named uniform variable, as set by one of
+// the Uniform*fv or UniformMatrix*fv methods (matrices being stored in
+// column-major order), or nil if it was not set.
+func (p *Program) Float(name string) []float32 {
+	return p.Uniforms.Float(name)
+}
+
+// Int returns the value of the named uniform variable, as set by one of the
+// Uniform*iv methods, or nil if it was not set.
+func (p *Program) Int(name string) []int32 {
+	return p.Uniforms.Int(name)
+}
+
+// AttribLocation implements the gfx.Program interface. Each new name gets
+// the next location, and nil is returned once all locations are in use.
+func (p *Program) AttribLocation(name string) gfx.AttribLocation {
+	p.Ctx.Call("Program.AttribLocation", p.O)
+	if !p.Linked() {
+		p.Ctx.SetError(gfx.InvalidOperation)
+		return nil
+	}
+	l, ok := p.attribs[name]
+	if !ok {
+		if len(p.attribs) == state.MaxVertexAttribs {
+			return nil
+		}
+		l = len(p.attribs)
+		p.attribs[name] = l
+	}
+	return gfx.AttribLocation(l)
+}
+
+// UniformLocation implements the gfx.Program interface. Each new name gets
+// the next location.
+func (p *Program) UniformLocation(name string) gfx.UniformLocation {
+	p.Ctx.Call("Program.UniformLocation", p.O)
+	if !p.Linked() {
+		p.Ctx.SetError(gfx.InvalidOperation)
+		return nil
+	}
+	return gfx.UniformLocation(p.AddUniform(name))
+}
@@ -0,0 +1,12 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package null
+
+import "github.com/slimsag/gfx/internal/emul"
+
+// Renderbuffer implements the gfx.Renderbuffer interface.
+type Renderbuffer struct {
+	emul.Renderbuffer
+}
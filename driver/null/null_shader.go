@@ -0,0 +1,33 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package null
+
+import (
+	"strings"
+
+	"github.com/slimsag/gfx/internal/emul"
+)
+
+// Shader implements the gfx.Shader interface.
+type Shader struct {
+	emul.Shader
+	src string
+}
+
+// Compile implements the gfx.Shader interface. It succeeds unless the source
+// is empty.
+func (s *Shader) Compile(src string) bool {
+	s.Ctx.Call("Shader.Compile", s.O)
+	s.src = src
+	if strings.TrimSpace(src) == "" {
+		return s.SetCompiled("null: empty shader source")
+	}
+	return s.SetCompiled("")
+}
+
+// Source returns the source given to Compile.
+func (s *Shader) Source() string {
+	return s.src
+}
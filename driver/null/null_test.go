@@ -0,0 +1,310 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package null
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/slimsag/gfx"
+	"github.com/slimsag/gfx/internal/state"
+)
+
+// Just for ensuring we meet the interface requirements.
+func init() {
+	_ = gfx.Buffer(&Buffer{})
+	_ = gfx.Context(&Context{})
+	_ = gfx.Framebuffer(&Framebuffer{})
+	_ = gfx.Program(&Program{})
+	_ = gfx.Renderbuffer(&Renderbuffer{})
+	_ = gfx.Shader(&Shader{})
+	_ = gfx.Texture(&Texture{})
+}
+
+// check calls ctx.Check, and returns the error it panics with.
+func check(ctx gfx.Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = r.(error)
+		}
+	}()
+	ctx.Check()
+	return nil
+}
+
+func newProgram(t *testing.T, ctx gfx.Context) gfx.Program {
+	vert := ctx.NewShader(gfx.VertexShader)
+	frag := ctx.NewShader(gfx.FragmentShader)
+	if !vert.Compile("void main() {}") || !frag.Compile("void main() {}") {
+		t.Fatal("Compile failed")
+	}
+	p := ctx.NewProgram()
+	if !p.Link(vert, frag) {
+		t.Fatal(p.InfoLog())
+	}
+	return p
+}
+
+func TestBuffer(t *testing.T) {
+	ctx := New(4, 4)
+	buf := ctx.NewBuffer(gfx.ArrayBuffer)
+	buf.DataUint16([]uint16{1, 2, 3}, gfx.DynamicDraw)
+	buf.SubDataUint16(1, []uint16{0x0a0b})
+	b := buf.(*Buffer)
+	if want := []byte{1, 0, 0x0b, 0x0a, 3, 0}; !reflect.DeepEqual(b.Bytes(), want) {
+		t.Errorf("Bytes = %v, want %v", b.Bytes(), want)
+	}
+	if b.Usage() != gfx.DynamicDraw {
+		t.Errorf("Usage = %v, want %v", b.Usage(), gfx.DynamicDraw)
+	}
+	if err := check(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	buf.SubDataUint16(2, []uint16{1, 2})
+	if err := check(ctx); err != gfx.InvalidValue {
+		t.Errorf("SubData out of range: Check = %v, want %v", err, gfx.InvalidValue)
+	}
+}
+
+// bindsElided returns the number of bind calls elided by the context.
+func bindsElided(c *Context) int {
+	_, _, n := c.Counters()
+	return n
+}
+
+func TestBufferBindings(t *testing.T) {
+	ctx := New(4, 4)
+	c := ctx.(*Context)
+	vertices := ctx.NewBuffer(gfx.ArrayBuffer)
+	indices := ctx.NewBuffer(gfx.ElementArrayBuffer)
+	vertices.DataSize(12, gfx.StaticDraw)
+	indices.DataSize(6, gfx.StaticDraw)
+
+	// Binding the element array buffer leaves the array buffer bound.
+	vertices.SubDataFloat32(0, []float32{1})
+	if n := bindsElided(c); n != 1 {
+		t.Errorf("%d binds elided, want 1", n)
+	}
+	gl := c.GL()
+	if got := gl.BufferBinding(gfx.ArrayBuffer); got != vertices.Object() {
+		t.Errorf("array buffer binding %d, want %v", got, vertices.Object())
+	}
+	if got := gl.BufferBinding(gfx.ElementArrayBuffer); got != indices.Object() {
+		t.Errorf("element array buffer binding %d, want %v", got, indices.Object())
+	}
+
+	// Deleting the bound buffer unbinds it, so a new buffer reusing its name
+	// must be bound again.
+	name := vertices.Object()
+	vertices.Delete()
+	gl = c.GL()
+	if got := gl.BufferBinding(gfx.ArrayBuffer); got != 0 {
+		t.Errorf("array buffer binding %d after Delete, want 0", got)
+	}
+	recycled := ctx.NewBuffer(gfx.ArrayBuffer)
+	if recycled.Object() != name {
+		t.Fatalf("new buffer named %v, want the recycled name %v", recycled.Object(), name)
+	}
+	recycled.DataSize(4, gfx.StaticDraw)
+	gl = c.GL()
+	if got := gl.BufferBinding(gfx.ArrayBuffer); got != name {
+		t.Errorf("array buffer binding %d, want %v", got, name)
+	}
+	if n := bindsElided(c); n != 1 {
+		t.Errorf("%d binds elided, want 1", n)
+	}
+	if err := check(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCalls(t *testing.T) {
+	ctx := New(4, 4)
+	c := ctx.(*Context)
+	p := newProgram(t, ctx)
+	c.ClearCalls()
+
+	buf := ctx.NewBuffer(gfx.ArrayBuffer)
+	buf.DataFloat32(make([]float32, 12), gfx.StaticDraw)
+	ctx.Load(ctx.NewState(ctx.UseProgram(p)))
+	buf.Draw(gfx.Triangles, 0, 3)
+	buf.Delete()
+	buf.Delete()
+	ctx.Check()
+
+	var got []string
+	for _, call := range c.Calls() {
+		got = append(got, call.String())
+	}
+	want := []string{
+		"Context(4).NewBuffer",
+		"Buffer(4).DataFloat32",
+		"Context(0).Load",
+		"Buffer(4).Draw",
+		"Buffer(4).Delete",
+		"Context(0).Check",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Calls = %q, want %q", got, want)
+	}
+	if g := c.GL(); g.Program != p {
+		t.Errorf("GL().Program = %v, want %v", g.Program, p)
+	}
+}
+
+func TestState(t *testing.T) {
+	ctx := New(640, 480)
+	c := ctx.(*Context)
+	g := c.GL()
+	if g.Viewport != [4]int{0, 0, 640, 480} || !g.Enabled(gfx.Dither) || g.Enabled(gfx.Blend) {
+		t.Errorf("default state: %+v", g)
+	}
+	ctx.Load(ctx.NewState(ctx.Enable(gfx.Blend), ctx.LineWidth(2)))
+	fb := ctx.Framebuffer()
+	fb.Load(fb.NewState(fb.ClearColor(1, 0, 0, 1)))
+	fb.Clear(gfx.ColorBuffer)
+	g = c.GL()
+	if !g.Enabled(gfx.Blend) || g.LineWidth != 2 || g.ClearColor != [4]float32{1, 0, 0, 1} {
+		t.Errorf("loaded state: %+v", g)
+	}
+}
+
+func TestFramebuffer(t *testing.T) {
+	ctx := New(4, 4)
+	fb := ctx.NewFramebuffer()
+	if err := fb.Status(); err != gfx.ErrFramebufferIncompleteMissingAttachment {
+		t.Errorf("empty: Status = %v", err)
+	}
+
+	rb := ctx.NewRenderbuffer()
+	fb.Renderbuffer(gfx.ColorAttachment0, rb)
+	if err := fb.Status(); err != gfx.ErrFramebufferIncompleteAttachment {
+		t.Errorf("no storage: Status = %v", err)
+	}
+	rb.Storage(gfx.RGBA4, 8, 8)
+	if err := fb.Status(); err != nil {
+		t.Errorf("color: Status = %v", err)
+	}
+
+	depth := ctx.NewRenderbuffer()
+	depth.Storage(gfx.DepthComponent16, 4, 4)
+	fb.Renderbuffer(gfx.DepthAttachment, depth)
+	if err := fb.Status(); err != gfx.ErrFramebufferIncompleteDimensions {
+		t.Errorf("mismatched sizes: Status = %v", err)
+	}
+
+	tex := ctx.NewTexture(gfx.TextureTypeCubeMap)
+	tex.(*Texture).Storage(4, 4)
+	fb.Texture2D(gfx.ColorAttachment0, gfx.TextureCubeMapNegativeY, tex)
+	if err := fb.Status(); err != nil {
+		t.Errorf("texture: Status = %v", err)
+	}
+	o, target := fb.(*Framebuffer).Attachment(gfx.ColorAttachment0)
+	if o != tex || target != gfx.TextureCubeMapNegativeY {
+		t.Errorf("Attachment = %v, %v, want %v, %v", o, target, tex, gfx.TextureCubeMapNegativeY)
+	}
+	if err := check(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx.Framebuffer().Renderbuffer(gfx.ColorAttachment0, rb)
+	if err := check(ctx); err != gfx.InvalidOperation {
+		t.Errorf("attaching to the default framebuffer: Check = %v, want %v", err, gfx.InvalidOperation)
+	}
+}
+
+func TestProgram(t *testing.T) {
+	ctx := New(4, 4)
+	vert := ctx.NewShader(gfx.VertexShader)
+	if vert.Compile("  ") {
+		t.Error("Compile: expected failure for empty source")
+	}
+	frag := ctx.NewShader(gfx.FragmentShader)
+	frag.Compile("void main() {}")
+	if frag.(*Shader).Source() != "void main() {}" {
+		t.Errorf("Source = %q", frag.(*Shader).Source())
+	}
+	p := ctx.NewProgram()
+	if p.Link(vert, frag) || p.(*Program).Linked() {
+		t.Error("Link: expected failure for uncompiled vertex shader")
+	}
+	if p.Link(frag, frag) {
+		t.Error("Link: expected failure for fragment shader given as vertex shader")
+	}
+	vert.Compile("void main() {}")
+	if !p.Link(vert, frag) {
+		t.Fatal(p.InfoLog())
+	}
+
+	if a, b := p.AttribLocation("a"), p.AttribLocation("b"); a != 0 || b != 1 || p.AttribLocation("a") != 0 {
+		t.Errorf("AttribLocation = %v, %v", a, b)
+	}
+	m := p.UniformLocation("m")
+	p.UniformMatrix2fv(m, true, []float32{1, 2, 3, 4})
+	p.Uniform1iv(p.UniformLocation("i"), []int32{7})
+	pr := p.(*Program)
+	if got := pr.Float("m"); !reflect.DeepEqual(got, []float32{1, 3, 2, 4}) {
+		t.Errorf("Float(m) = %v", got)
+	}
+	if got := pr.Int("i"); !reflect.DeepEqual(got, []int32{7}) {
+		t.Errorf("Int(i) = %v", got)
+	}
+	if err := check(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	ctx := New(4, 4)
+	c := ctx.(*Context)
+	buf := ctx.NewBuffer(gfx.ArrayBuffer)
+	buf.Draw(gfx.Triangles, 0, 3)
+	if err := check(ctx); err != gfx.InvalidOperation {
+		t.Errorf("draw without program: Check = %v, want %v", err, gfx.InvalidOperation)
+	}
+
+	errLost := gfx.ContextLost
+	c.SetError(errLost)
+	c.SetError(gfx.OutOfMemory)
+	if err := check(ctx); err != errLost {
+		t.Errorf("SetError: Check = %v, want %v", err, errLost)
+	}
+
+	errTest := errors.New("test")
+	c.FailOn("Buffer.DataUint8", errTest)
+	buf.DataInt8(nil, gfx.StaticDraw)
+	if err := check(ctx); err != nil {
+		t.Errorf("other method: Check = %v, want nil", err)
+	}
+	buf.DataUint8([]uint8{1}, gfx.StaticDraw)
+	if err := check(ctx); err != errTest {
+		t.Errorf("FailOn: Check = %v, want %v", err, errTest)
+	}
+	if len(buf.(*Buffer).Bytes()) != 1 {
+		t.Error("FailOn: call had no effect")
+	}
+	buf.DataUint8(nil, gfx.StaticDraw)
+	if err := check(ctx); err != nil {
+		t.Errorf("FailOn is one-shot: Check = %v, want nil", err)
+	}
+
+	// Locations past the supported ones are invalid, like in OpenGL.
+	ctx.Load(ctx.NewState(ctx.EnableVertexAttribArray(state.MaxVertexAttribs)))
+	if err := check(ctx); err != gfx.InvalidValue {
+		t.Errorf("EnableVertexAttribArray: Check = %v, want %v", err, gfx.InvalidValue)
+	}
+	ctx.Load(nil)
+	check(ctx)
+
+	// So are nil locations, like a location of -1 in OpenGL.
+	ctx.Load(ctx.NewState(ctx.EnableVertexAttribArray(nil)))
+	if err := check(ctx); err != gfx.InvalidValue {
+		t.Errorf("EnableVertexAttribArray(nil): Check = %v, want %v", err, gfx.InvalidValue)
+	}
+	ctx.Load(nil)
+	check(ctx)
+}
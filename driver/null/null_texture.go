@@ -0,0 +1,14 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package null
+
+import "github.com/slimsag/gfx/internal/emul"
+
+// Texture implements the gfx.Texture interface. Its Storage method allocates
+// storage for the texture, such that it can be attached to a framebuffer, like
+// the Storage method of the soft driver's textures.
+type Texture struct {
+	emul.Texture
+}
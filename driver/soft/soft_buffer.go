@@ -0,0 +1,30 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package soft
+
+import (
+	"github.com/slimsag/gfx"
+	"github.com/slimsag/gfx/internal/emul"
+)
+
+// Buffer implements the gfx.Buffer interface.
+type Buffer struct {
+	emul.Buffer
+	ctx *Context
+}
+
+// Draw implements the gfx.Buffer interface. Element array buffers hold 16-bit
+// indices, and first is the index of the first one to draw.
+func (b *Buffer) Draw(p gfx.Primitive, first, count int) {
+	b.Ctx.Call("Buffer.Draw", b.O)
+	b.ctx.draw(b, p, first, count)
+}
+
+// VertexAttribPointer implements the gfx.Buffer interface. Values are read as
+// 32-bit floats, which are never normalized, so the stride and offset must be
+// multiples of four.
+func (b *Buffer) VertexAttribPointer(l gfx.AttribLocation, size int, normalized bool, stride, offset int) {
+	b.AttribPointer(l, size, normalized, stride, offset, 4)
+}
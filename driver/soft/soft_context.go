@@ -0,0 +1,113 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package soft implements a graphics context using a software rasterizer
+// written entirely in Go.
+//
+// It requires no GPU, no cgo and no window, such that real rendering tests can
+// be run using `go test` on any machine:
+//
+//	ctx := soft.New(640, 480)
+//
+// Shaders are compiled from their GLSL ES 1.00 source and interpreted using
+// the glsl package. Since interpreting shaders is slow, they may instead be
+// written as Go functions and registered under their source using
+// RegisterVertexShader and RegisterFragmentShader, which lets an application
+// use the same source with this driver and the OpenGL ones. Texture lookups
+// in GLSL shaders return 0, 0, 0, 1.
+//
+// Rendering follows the OpenGL ES 2 rules, with any state that gfx does not
+// expose holding its default value: the depth function is LESS, the blend
+// function is ONE, ZERO, the depth range is [0, 1] and polygon offsets are
+// zero. Dithering is never performed.
+//
+// gfx has no API for uploading texture images or sampling textures, so
+// textures may only be used as framebuffer attachments. Their storage is
+// allocated using the Texture.Storage method.
+package soft
+
+import (
+	"fmt"
+
+	"github.com/slimsag/gfx"
+	"github.com/slimsag/gfx/internal/emul"
+)
+
+// Context implements the gfx.Context interface.
+type Context struct {
+	emul.Context
+
+	// The default framebuffer implementation for the context, and its images.
+	fb  Framebuffer
+	def *target
+}
+
+// Framebuffer implements the gfx.Context interface.
+func (c *Context) Framebuffer() gfx.Framebuffer {
+	return &c.fb
+}
+
+// NewFramebuffer implements the gfx.Context interface.
+func (c *Context) NewFramebuffer() gfx.Framebuffer {
+	fb := &Framebuffer{ctx: c}
+	fb.Table = &c.FBState
+	c.NewObject("Context.NewFramebuffer", &fb.Handle, fb)
+	return fb
+}
+
+// NewRenderbuffer implements the gfx.Context interface.
+func (c *Context) NewRenderbuffer() gfx.Renderbuffer {
+	rb := &Renderbuffer{}
+	c.NewObject("Context.NewRenderbuffer", &rb.Handle, rb)
+	return rb
+}
+
+// NewShader implements the gfx.Context interface.
+func (c *Context) NewShader(t gfx.ShaderType) gfx.Shader {
+	s := &Shader{Shader: emul.NewShader(t)}
+	c.NewObject("Context.NewShader", &s.Handle, s)
+	return s
+}
+
+// NewTexture implements the gfx.Context interface.
+func (c *Context) NewTexture(t gfx.TextureType) gfx.Texture {
+	tex := &Texture{Texture: emul.NewTexture(t)}
+	c.NewObject("Context.NewTexture", &tex.Handle, tex)
+	return tex
+}
+
+// NewBuffer implements the gfx.Context interface.
+func (c *Context) NewBuffer(t gfx.BufferType) gfx.Buffer {
+	b := &Buffer{Buffer: emul.NewBuffer(t), ctx: c}
+	c.NewObject("Context.NewBuffer", &b.Handle, b)
+	return b
+}
+
+// NewProgram implements the gfx.Context interface.
+func (c *Context) NewProgram() gfx.Program {
+	p := &Program{}
+	c.NewObject("Context.NewProgram", &p.Handle, p)
+	return p
+}
+
+// New returns a new software graphics context, whose default framebuffer has
+// the given size in pixels. The default framebuffer has a color buffer with 8
+// bits per channel, a 24-bit depth buffer and an 8-bit stencil buffer.
+func New(width, height int) gfx.Context {
+	if width < 0 || height < 0 {
+		panic(fmt.Sprintf("soft: invalid framebuffer size %dx%d", width, height))
+	}
+	ctx := &Context{
+		def: &target{
+			width:   width,
+			height:  height,
+			color:   newColorImage(width, height, [4]uint8{8, 8, 8, 8}),
+			depth:   newDepthImage(width, height, 24),
+			stencil: newStencilImage(width, height),
+		},
+	}
+	ctx.fb.ctx = ctx
+	ctx.Init(width, height, &ctx.fb.Framebuffer)
+	return ctx
+}
@@ -0,0 +1,113 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package soft
+
+import (
+	"github.com/slimsag/gfx"
+	"github.com/slimsag/gfx/internal/emul"
+)
+
+// Framebuffer implements the gfx.Framebuffer interface.
+type Framebuffer struct {
+	emul.Framebuffer
+	ctx *Context
+}
+
+// target returns the images of the framebuffer f, or an error describing why
+// it is incomplete.
+func (c *Context) target(f *emul.Framebuffer) (*target, error) {
+	if f == c.Default {
+		return c.def, nil
+	}
+	if err := f.Err(); err != nil {
+		return nil, err
+	}
+
+	// The framebuffer is complete, so its attached images have storage, the
+	// right formats and the same size.
+	t := &target{}
+	for _, point := range []gfx.FramebufferAttachment{gfx.ColorAttachment0, gfx.DepthAttachment} {
+		o, texTarget := f.Attachment(point)
+		switch a := o.(type) {
+		case *Renderbuffer:
+			if a.O == 0 {
+				continue
+			}
+			t.width, t.height = a.Size()
+			if a.color != nil {
+				t.color = a.color
+			} else {
+				t.depth = a.depth
+			}
+		case *Texture:
+			if a.O == 0 {
+				continue
+			}
+			face, _ := a.Face(texTarget)
+			t.width, t.height = a.Size()
+			t.color = a.faces[face]
+		}
+	}
+	return t, nil
+}
+
+// Clear implements the gfx.Framebuffer interface.
+func (f *Framebuffer) Clear(m gfx.ClearMask) {
+	f.Ctx.Call("Framebuffer.Clear", f.O)
+
+	// Use this framebuffer's state, and perform the clear operation.
+	f.UseState()
+	t, err := f.ctx.target(&f.Framebuffer)
+	if err != nil {
+		f.Ctx.SetError(gfx.InvalidFramebufferOperation)
+		return
+	}
+
+	// Clearing is only affected by the scissor test and the write masks.
+	g := &f.Ctx.GL
+	x0, y0, x1, y1 := bounds(g, t)
+	if m&gfx.ColorBuffer != 0 && t.color != nil {
+		t.color.fill(x0, y0, x1, y1, g.ClearColor, g.ColorMask)
+	}
+	if m&gfx.DepthBuffer != 0 && t.depth != nil && g.DepthMask {
+		t.depth.fill(x0, y0, x1, y1, g.ClearDepth)
+	}
+	if m&gfx.StencilBuffer != 0 && t.stencil != nil {
+		t.stencil.fill(x0, y0, x1, y1, uint8(g.ClearStencil))
+	}
+}
+
+// ReadPixelsUint8 implements the gfx.Framebuffer interface. Pixels outside
+// of the framebuffer are left unmodified in dst.
+func (f *Framebuffer) ReadPixelsUint8(x, y, width, height int, dst []uint8) {
+	f.Ctx.Call("Framebuffer.ReadPixelsUint8", f.O)
+	f.UseState()
+	t, err := f.ctx.target(&f.Framebuffer)
+	switch {
+	case err != nil:
+		f.Ctx.SetError(gfx.InvalidFramebufferOperation)
+		return
+	case t.color == nil:
+		f.Ctx.SetError(gfx.InvalidOperation)
+		return
+	case width < 0 || height < 0 || len(dst) < width*height*4:
+		f.Ctx.SetError(gfx.InvalidValue)
+		return
+	}
+	for j := 0; j < height; j++ {
+		py := y + j
+		if py < 0 || py >= t.height {
+			continue
+		}
+		for i := 0; i < width; i++ {
+			px := x + i
+			if px < 0 || px >= t.width {
+				continue
+			}
+			src := t.color.pix[(py*t.width+px)*4:]
+			copy(dst[(j*width+i)*4:(j*width+i)*4+4], src[:4])
+		}
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package soft
+
+import (
+	"fmt"
+
+	"github.com/slimsag/gfx/glsl"
+)
+
+// The shaders below adapt GLSL shaders interpreted by the glsl package into
+// VertexShader and FragmentShader implementations. A shader that fails at run
+// time (e.g. with an out of range array index) produces undefined values, as
+// it would on a GPU.
+
+// uniformNames returns the names of the uniform leaves of a GLSL shader.
+func uniformNames(s *glsl.Shader) []string {
+	var names []string
+	for _, u := range s.Uniforms {
+		names = append(names, u.Leaves()...)
+	}
+	return names
+}
+
+// loadUniforms copies the values of the named uniforms to a GLSL shader
+// state.
+func loadUniforms(u *Uniforms, st *glsl.State, names []string) {
+	for _, name := range names {
+		dst := st.Var(name)
+		if f := u.Float(name); f != nil {
+			copy(dst, f)
+			continue
+		}
+		i := u.Int(name)
+		for k := range dst {
+			if k < len(i) {
+				dst[k] = float32(i[k])
+			}
+		}
+	}
+}
+
+// glslVertexShader returns the vertex shader running s. Matrix attributes
+// use one location per column, the locations of the columns after the first
+// having no name.
+func glslVertexShader(s *glsl.Shader) *VertexShader {
+	st := s.NewState()
+	vs := &VertexShader{Uniforms: uniformNames(s)}
+	for _, a := range s.Attributes {
+		vs.Attribs = append(vs.Attribs, a.Name)
+		for c := 1; c < a.Type.Cols; c++ {
+			vs.Attribs = append(vs.Attribs, "")
+		}
+	}
+	for _, v := range s.Varyings {
+		vs.Varyings += v.Type.Components()
+	}
+
+	vs.Main = func(v *Vertex) {
+		loadUniforms(v.Uniforms, st, vs.Uniforms)
+		l := 0
+		for _, a := range s.Attributes {
+			dst, rows := st.Var(a.Name), a.Type.Size
+			for c := 0; c < a.Type.Cols || c == 0; c++ {
+				copy(dst[c*rows:(c+1)*rows], v.Attribs[l][:rows])
+				l++
+			}
+		}
+		st.Run()
+		copy(v.Position[:], st.Var("gl_Position"))
+		v.PointSize = st.Var("gl_PointSize")[0]
+		off := 0
+		for _, vary := range s.Varyings {
+			off += copy(v.Varyings[off:], st.Var(vary.Name))
+		}
+	}
+	return vs
+}
+
+// glslFragmentShader returns the fragment shader running s, whose varyings are
+// matched by name with those of the GLSL vertex shader vs (which is nil for a
+// Go vertex shader).
+func glslFragmentShader(s, vs *glsl.Shader) (*FragmentShader, error) {
+	offsets := make(map[string]int)
+	types := make(map[string]string)
+	if vs != nil {
+		off := 0
+		for _, v := range vs.Varyings {
+			offsets[v.Name], types[v.Name] = off, v.Type.String()
+			off += v.Type.Components()
+		}
+	}
+	var inputs []int
+	for _, v := range s.Varyings {
+		t, ok := types[v.Name]
+		if !ok {
+			return nil, fmt.Errorf("varying %s is not written by the vertex shader", v.Name)
+		}
+		if t != v.Type.String() {
+			return nil, fmt.Errorf("varying %s has type %s in the vertex shader and %s in the fragment shader", v.Name, t, v.Type)
+		}
+		inputs = append(inputs, offsets[v.Name])
+	}
+	if vs != nil {
+		vu := make(map[string]string)
+		for _, u := range vs.Uniforms {
+			vu[u.Name] = u.Type.String()
+		}
+		for _, u := range s.Uniforms {
+			if t, ok := vu[u.Name]; ok && t != u.Type.String() {
+				return nil, fmt.Errorf("uniform %s has type %s in the vertex shader and %s in the fragment shader", u.Name, t, u.Type)
+			}
+		}
+	}
+
+	st := s.NewState()
+	fs := &FragmentShader{Uniforms: uniformNames(s)}
+	fs.Main = func(f *Fragment) {
+		loadUniforms(f.Uniforms, st, fs.Uniforms)
+		for i, v := range s.Varyings {
+			dst := st.Var(v.Name)
+			copy(dst, f.Varyings[inputs[i]:inputs[i]+len(dst)])
+		}
+		copy(st.Var("gl_FragCoord"), f.FragCoord[:])
+		st.Var("gl_FrontFacing")[0] = 0
+		if f.FrontFacing {
+			st.Var("gl_FrontFacing")[0] = 1
+		}
+		copy(st.Var("gl_PointCoord"), f.PointCoord[:])
+		if st.Run() == glsl.ErrDiscard {
+			f.Discard = true
+			return
+		}
+		copy(f.Color[:], st.Var("gl_FragColor"))
+	}
+	return fs, nil
+}
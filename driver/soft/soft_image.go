@@ -0,0 +1,155 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package soft
+
+import "math"
+
+// target is the set of images that a complete framebuffer renders to. Any of
+// the images may be nil, but all others have the same size.
+type target struct {
+	width, height int
+	color         *colorImage
+	depth         *depthImage
+	stencil       *stencilImage
+}
+
+// colorImage is an RGBA color image. It is stored with 8 bits per channel,
+// but colors written to it are quantized to the precision of its format.
+type colorImage struct {
+	width, height int
+
+	// bits is the number of bits of each channel of the image's format. A
+	// channel without any bits always reads as one, like the alpha channel
+	// of an RGB format in OpenGL.
+	bits [4]uint8
+
+	// pix holds the pixels, row by row starting at the bottom row (the
+	// window coordinate y=0), in RGBA order.
+	pix []uint8
+}
+
+func newColorImage(width, height int, bits [4]uint8) *colorImage {
+	m := &colorImage{
+		width:  width,
+		height: height,
+		bits:   bits,
+		pix:    make([]uint8, width*height*4),
+	}
+	m.fill(0, 0, width, height, [4]float32{}, [4]bool{true, true, true, true})
+	return m
+}
+
+// at returns the color of the pixel at x, y.
+func (m *colorImage) at(x, y int) [4]float32 {
+	p := m.pix[(y*m.width+x)*4:]
+	return [4]float32{
+		float32(p[0]) / 255,
+		float32(p[1]) / 255,
+		float32(p[2]) / 255,
+		float32(p[3]) / 255,
+	}
+}
+
+// set sets the channels of the pixel at x, y that are selected by mask.
+func (m *colorImage) set(x, y int, c [4]float32, mask [4]bool) {
+	p := m.pix[(y*m.width+x)*4:]
+	for i := range c {
+		if mask[i] {
+			p[i] = quantize(c[i], m.bits[i])
+		}
+	}
+}
+
+// fill sets the channels selected by mask of each pixel in the rectangle
+// [x0, x1) by [y0, y1).
+func (m *colorImage) fill(x0, y0, x1, y1 int, c [4]float32, mask [4]bool) {
+	for y := y0; y < y1; y++ {
+		for x := x0; x < x1; x++ {
+			m.set(x, y, c, mask)
+		}
+	}
+}
+
+// quantize clamps x to the range of [0, 1], rounds it to the precision of the
+// given number of bits and returns it as an 8-bit value.
+func quantize(x float32, bits uint8) uint8 {
+	if bits == 0 {
+		return 255
+	}
+	levels := float64(uint(1)<<bits - 1)
+	q := math.Floor(clamp(float64(x))*levels + 0.5)
+	return uint8(math.Floor(q*255/levels + 0.5))
+}
+
+func clamp(x float64) float64 {
+	if x < 0 {
+		return 0
+	}
+	if x > 1 {
+		return 1
+	}
+	return x
+}
+
+// depthImage is a depth image, whose values are stored in the range of [0, 1]
+// with the precision of its format.
+type depthImage struct {
+	width, height int
+	bits          uint8
+	pix           []float32 // See colorImage.pix.
+}
+
+func newDepthImage(width, height int, bits uint8) *depthImage {
+	m := &depthImage{
+		width:  width,
+		height: height,
+		bits:   bits,
+		pix:    make([]float32, width*height),
+	}
+	m.fill(0, 0, width, height, 1)
+	return m
+}
+
+// quantize clamps z to the range of [0, 1] and rounds it to the precision of
+// the image.
+func (m *depthImage) quantize(z float64) float32 {
+	levels := float64(uint(1)<<m.bits - 1)
+	return float32(math.Floor(clamp(z)*levels+0.5) / levels)
+}
+
+// fill sets each depth value in the rectangle [x0, x1) by [y0, y1).
+func (m *depthImage) fill(x0, y0, x1, y1 int, z float64) {
+	q := m.quantize(z)
+	for y := y0; y < y1; y++ {
+		row := m.pix[y*m.width:]
+		for x := x0; x < x1; x++ {
+			row[x] = q
+		}
+	}
+}
+
+// stencilImage is an 8-bit stencil image.
+type stencilImage struct {
+	width, height int
+	pix           []uint8 // See colorImage.pix.
+}
+
+func newStencilImage(width, height int) *stencilImage {
+	return &stencilImage{
+		width:  width,
+		height: height,
+		pix:    make([]uint8, width*height),
+	}
+}
+
+// fill sets each stencil value in the rectangle [x0, x1) by [y0, y1).
+func (m *stencilImage) fill(x0, y0, x1, y1 int, s uint8) {
+	for y := y0; y < y1; y++ {
+		row := m.pix[y*m.width:]
+		for x := x0; x < x1; x++ {
+			row[x] = s
+		}
+	}
+}
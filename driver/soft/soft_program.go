@@ -0,0 +1,89 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package soft
+
+import (
+	"strings"
+
+	"github.com/slimsag/gfx"
+	"github.com/slimsag/gfx/internal/emul"
+)
+
+// Program implements the gfx.Program interface.
+type Program struct {
+	emul.Program
+
+	// The shaders of the program, once it is linked.
+	vert *VertexShader
+	frag *FragmentShader
+}
+
+// Uniforms holds the values of the uniform variables of a program. Values are
+// set by the Uniform* methods of the program, matrices being stored in
+// column-major order.
+type Uniforms = emul.Uniforms
+
+// Link implements the gfx.Program interface.
+func (p *Program) Link(vert, frag gfx.Shader) bool {
+	p.Ctx.Call("Program.Link", p.O)
+	vs, _ := p.Ctx.Lookup(vert).(*Shader)
+	fs, _ := p.Ctx.Lookup(frag).(*Shader)
+	switch {
+	case vs == nil || vs.Type() != gfx.VertexShader || !vs.Compiled():
+		return p.SetLinked("soft: no compiled vertex shader")
+	case fs == nil || fs.Type() != gfx.FragmentShader || !fs.Compiled():
+		return p.SetLinked("soft: no compiled fragment shader")
+	}
+	p.vert, p.frag = vs.vert, fs.frag
+	if vs.glsl != nil {
+		p.vert = glslVertexShader(vs.glsl)
+	}
+	if fs.glsl != nil {
+		var err error
+		if p.frag, err = glslFragmentShader(fs.glsl, vs.glsl); err != nil {
+			return p.SetLinked("soft: " + err.Error())
+		}
+	}
+
+	// Uniforms shared by both shaders have a single location.
+	var uniforms []string
+	uniforms = append(uniforms, p.vert.Uniforms...)
+	uniforms = append(uniforms, p.frag.Uniforms...)
+	return p.SetLinked("", uniforms...)
+}
+
+// AttribLocation implements the gfx.Program interface.
+func (p *Program) AttribLocation(name string) gfx.AttribLocation {
+	p.Ctx.Call("Program.AttribLocation", p.O)
+	if !p.Linked() {
+		p.Ctx.SetError(gfx.InvalidOperation)
+		return nil
+	}
+	for l, attrib := range p.vert.Attribs {
+		if attrib == name {
+			return gfx.AttribLocation(l)
+		}
+	}
+	return nil
+}
+
+// UniformLocation implements the gfx.Program interface.
+func (p *Program) UniformLocation(name string) gfx.UniformLocation {
+	p.Ctx.Call("Program.UniformLocation", p.O)
+	if !p.Linked() {
+		p.Ctx.SetError(gfx.InvalidOperation)
+		return nil
+	}
+	if l, ok := p.Uniforms.Location(name); ok {
+		return gfx.UniformLocation(l)
+	}
+	// The first element of an array may also be named with its index.
+	if strings.HasSuffix(name, "[0]") {
+		if l, ok := p.Uniforms.Location(strings.TrimSuffix(name, "[0]")); ok {
+			return gfx.UniformLocation(l)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,546 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package soft
+
+import (
+	"encoding/binary"
+	"math"
+
+	"github.com/slimsag/gfx"
+	"github.com/slimsag/gfx/internal/emul"
+)
+
+// attribAt returns the value of the attribute of the vertex pointer a for the
+// vertex with the given index.
+func attribAt(a *emul.AttribPointer, index int) [4]float32 {
+	v := [4]float32{0, 0, 0, 1}
+	data := a.Buffer.Bytes()
+	off := a.Offset + index*strideBytes(a)
+	for i := 0; i < a.Size; i++ {
+		bits := binary.LittleEndian.Uint32(data[off+i*4:])
+		v[i] = math.Float32frombits(bits)
+	}
+	return v
+}
+
+// strideBytes returns the number of bytes between consecutive vertices, where
+// a stride of zero means that the values are tightly packed.
+func strideBytes(a *emul.AttribPointer) int {
+	if a.Stride == 0 {
+		return a.Size * 4
+	}
+	return a.Stride
+}
+
+// bounds returns the rectangle [x0, x1) by [y0, y1) of the pixels that may be
+// written in t, i.e. t intersected with the scissor box if the scissor test is
+// enabled.
+func bounds(g *emul.GL, t *target) (x0, y0, x1, y1 int) {
+	x0, y0, x1, y1 = 0, 0, t.width, t.height
+	if g.Enabled(gfx.ScissorTest) {
+		s := g.Scissor
+		x0, y0 = maxInt(x0, s[0]), maxInt(y0, s[1])
+		x1, y1 = minInt(x1, s[0]+s[2]), minInt(y1, s[1]+s[3])
+		x1, y1 = maxInt(x0, x1), maxInt(y0, y1)
+	}
+	return
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// Factors of the blend function. The gfx package cannot change the blend
+// function, which remains the OpenGL default of ONE for the source and ZERO
+// for the destination. The blend color is only used by the CONSTANT_COLOR and
+// CONSTANT_ALPHA functions, so it is never read.
+const (
+	blendSrcFactor = 1 // ONE
+	blendDstFactor = 0 // ZERO
+)
+
+// blend blends the fragment color src with the color dst of the framebuffer,
+// using the blend equation and the factors of the blend function.
+func blend(g *emul.GL, src, dst [4]float32) [4]float32 {
+	var out [4]float32
+	for i := range src {
+		s := float32(clamp(float64(src[i]))) * blendSrcFactor
+		d := dst[i] * blendDstFactor
+		switch g.BlendEquation {
+		case gfx.FuncAdd:
+			out[i] = s + d
+		case gfx.FuncSubtract:
+			out[i] = s - d
+		case gfx.FuncReverseSubtract:
+			out[i] = d - s
+		}
+	}
+	return out
+}
+
+// clipVertex is a shaded vertex, in clip coordinates.
+type clipVertex struct {
+	pos      [4]float64
+	size     float64
+	varyings []float64
+}
+
+// lerp returns the vertex at t along the line from a to b.
+func lerp(a, b *clipVertex, t float64) *clipVertex {
+	v := &clipVertex{
+		size:     a.size + (b.size-a.size)*t,
+		varyings: make([]float64, len(a.varyings)),
+	}
+	for i := range v.pos {
+		v.pos[i] = a.pos[i] + (b.pos[i]-a.pos[i])*t
+	}
+	for i := range v.varyings {
+		v.varyings[i] = a.varyings[i] + (b.varyings[i]-a.varyings[i])*t
+	}
+	return v
+}
+
+// clipPlanes are the planes of the view volume, -w <= x, y, z <= w. A vertex
+// is inside of a plane if the dot product of the plane and its position is
+// positive.
+var clipPlanes = [6][4]float64{
+	{1, 0, 0, 1},
+	{-1, 0, 0, 1},
+	{0, 1, 0, 1},
+	{0, -1, 0, 1},
+	{0, 0, 1, 1},
+	{0, 0, -1, 1},
+}
+
+func dot(p, v [4]float64) float64 {
+	return p[0]*v[0] + p[1]*v[1] + p[2]*v[2] + p[3]*v[3]
+}
+
+// clipPolygon clips the convex polygon against the view volume, returning
+// the clipped polygon (which may be empty).
+func clipPolygon(poly []*clipVertex) []*clipVertex {
+	for _, plane := range clipPlanes {
+		var out []*clipVertex
+		for i, a := range poly {
+			b := poly[(i+1)%len(poly)]
+			da, db := dot(plane, a.pos), dot(plane, b.pos)
+			if da >= 0 {
+				out = append(out, a)
+			}
+			if (da >= 0) != (db >= 0) {
+				out = append(out, lerp(a, b, da/(da-db)))
+			}
+		}
+		poly = out
+	}
+	return poly
+}
+
+// clipLine clips the line from a to b against the view volume. It returns
+// false if the line is entirely outside of it.
+func clipLine(a, b *clipVertex) (*clipVertex, *clipVertex, bool) {
+	t0, t1 := 0.0, 1.0
+	for _, plane := range clipPlanes {
+		da, db := dot(plane, a.pos), dot(plane, b.pos)
+		switch {
+		case da < 0 && db < 0:
+			return nil, nil, false
+		case da < 0:
+			t0 = math.Max(t0, da/(da-db))
+		case db < 0:
+			t1 = math.Min(t1, da/(da-db))
+		}
+	}
+	if t0 > t1 {
+		return nil, nil, false
+	}
+	return lerp(a, b, t0), lerp(a, b, t1), true
+}
+
+// winVertex is a vertex in window coordinates. Its varyings are divided by
+// its clip w coordinate, for perspective-correct interpolation.
+type winVertex struct {
+	x, y, z, invW float64
+	varyings      []float64
+}
+
+// rasterizer rasterizes primitives into the target of a draw call.
+type rasterizer struct {
+	g *emul.GL
+	t *target
+
+	// The bounds of the pixels that may be written, see bounds.
+	x0, y0, x1, y1 int
+
+	frag *FragmentShader
+	f    Fragment
+
+	// varyings is scratch space for interpolated varyings.
+	varyings []float64
+}
+
+// window transforms v to window coordinates.
+func (r *rasterizer) window(v *clipVertex) *winVertex {
+	vp := r.g.Viewport
+	invW := 1 / v.pos[3]
+	w := &winVertex{
+		x:        float64(vp[0]) + (v.pos[0]*invW+1)*float64(vp[2])/2,
+		y:        float64(vp[1]) + (v.pos[1]*invW+1)*float64(vp[3])/2,
+		z:        (v.pos[2]*invW + 1) / 2,
+		invW:     invW,
+		varyings: make([]float64, len(v.varyings)),
+	}
+	for i, x := range v.varyings {
+		w.varyings[i] = x * invW
+	}
+	return w
+}
+
+// fragment shades the fragment at x, y and performs the per-fragment
+// operations on it.
+func (r *rasterizer) fragment(x, y int, z, invW float64, varyings []float64, front bool, pointCoord [2]float32) {
+	if x < r.x0 || x >= r.x1 || y < r.y0 || y >= r.y1 {
+		return
+	}
+	f := &r.f
+	for i, v := range varyings {
+		f.Varyings[i] = float32(v)
+	}
+	f.FragCoord = [4]float32{float32(x) + 0.5, float32(y) + 0.5, float32(z), float32(invW)}
+	f.FrontFacing = front
+	f.PointCoord = pointCoord
+	f.Color = [4]float32{}
+	f.Discard = false
+	r.frag.Main(f)
+	if f.Discard {
+		return
+	}
+
+	g, t := r.g, r.t
+	if g.Enabled(gfx.DepthTest) && t.depth != nil {
+		i := y*t.width + x
+		q := t.depth.quantize(z)
+		if q >= t.depth.pix[i] {
+			return
+		}
+		if g.DepthMask {
+			t.depth.pix[i] = q
+		}
+	}
+	if t.color == nil {
+		return
+	}
+	c := f.Color
+	if g.Enabled(gfx.Blend) {
+		c = blend(g, c, t.color.at(x, y))
+	}
+	t.color.set(x, y, c, g.ColorMask)
+}
+
+// point rasterizes a point, as a square centered on the vertex.
+func (r *rasterizer) point(v *clipVertex) {
+	for _, plane := range clipPlanes {
+		if dot(plane, v.pos) < 0 {
+			return
+		}
+	}
+	w := r.window(v)
+	size := math.Max(1, math.Floor(v.size+0.5))
+	x0 := int(math.Floor(w.x - size/2 + 0.5))
+	y0 := int(math.Floor(w.y - size/2 + 0.5))
+	for y := y0; y < y0+int(size); y++ {
+		for x := x0; x < x0+int(size); x++ {
+			pc := [2]float32{
+				float32(0.5 + (float64(x)+0.5-w.x)/size),
+				float32(0.5 - (float64(y)+0.5-w.y)/size),
+			}
+			r.fragment(x, y, w.z, w.invW, v.varyings, true, pc)
+		}
+	}
+}
+
+// line rasterizes the line from a to b. One fragment is produced for each
+// pixel center along the major axis, excluding the last one, and the line
+// extends over the line width along the minor axis.
+func (r *rasterizer) line(a, b *clipVertex) {
+	a, b, ok := clipLine(a, b)
+	if !ok {
+		return
+	}
+	wa, wb := r.window(a), r.window(b)
+	width := math.Max(1, math.Floor(float64(r.g.LineWidth)+0.5))
+
+	xMajor := math.Abs(wb.x-wa.x) >= math.Abs(wb.y-wa.y)
+	ma, mb, na, nb := wa.x, wb.x, wa.y, wb.y
+	if !xMajor {
+		ma, mb, na, nb = wa.y, wb.y, wa.x, wb.x
+	}
+	d := mb - ma
+	if d == 0 {
+		return
+	}
+
+	// The pixels [i0, i1) along the major axis whose centers lie between
+	// the start (inclusive) and the end (exclusive) of the line.
+	var i0, i1 int
+	if d > 0 {
+		i0, i1 = int(math.Ceil(ma-0.5)), int(math.Ceil(mb-0.5))
+	} else {
+		i0, i1 = int(math.Floor(mb-0.5))+1, int(math.Floor(ma-0.5))+1
+	}
+	for i := i0; i < i1; i++ {
+		t := (float64(i) + 0.5 - ma) / d
+		z := wa.z + (wb.z-wa.z)*t
+		invW := wa.invW + (wb.invW-wa.invW)*t
+		for k := range r.varyings {
+			r.varyings[k] = (wa.varyings[k] + (wb.varyings[k]-wa.varyings[k])*t) / invW
+		}
+		j0 := int(math.Floor(na + (nb-na)*t - width/2 + 0.5))
+		for j := j0; j < j0+int(width); j++ {
+			x, y := i, j
+			if !xMajor {
+				x, y = j, i
+			}
+			r.fragment(x, y, z, invW, r.varyings, true, [2]float32{})
+		}
+	}
+}
+
+// edge returns twice the signed area of the triangle a, b, (x, y), which is
+// positive if the point lies to the left of the edge from a to b.
+func edge(a, b *winVertex, x, y float64) float64 {
+	return (b.x-a.x)*(y-a.y) - (b.y-a.y)*(x-a.x)
+}
+
+// topLeft tells if the edge from a to b of a counterclockwise triangle is a
+// top or left edge, whose pixel centers belong to the triangle.
+func topLeft(a, b *winVertex) bool {
+	return (a.y == b.y && b.x < a.x) || b.y < a.y
+}
+
+// triangle clips and rasterizes the given triangle, unless it is culled.
+func (r *rasterizer) triangle(a, b, c *clipVertex) {
+	poly := clipPolygon([]*clipVertex{a, b, c})
+	if len(poly) < 3 {
+		return
+	}
+	win := make([]*winVertex, len(poly))
+	area := 0.0
+	for i, v := range poly {
+		if v.pos[3] <= 0 {
+			return
+		}
+		win[i] = r.window(v)
+	}
+	for i, v := range win {
+		n := win[(i+1)%len(win)]
+		area += v.x*n.y - n.x*v.y
+	}
+	if area == 0 {
+		return
+	}
+
+	// Counterclockwise triangles have a positive area.
+	g := r.g
+	front := (area > 0) == (g.FrontFace == gfx.CCW)
+	if g.Enabled(gfx.CullFace) {
+		switch {
+		case g.CullFace == gfx.FrontAndBack:
+			return
+		case g.CullFace == gfx.Front && front, g.CullFace == gfx.Back && !front:
+			return
+		}
+	}
+	for i := 1; i+1 < len(win); i++ {
+		r.fill(win[0], win[i], win[i+1], front)
+	}
+}
+
+// fill fills the triangle a, b, c in window coordinates.
+func (r *rasterizer) fill(a, b, c *winVertex, front bool) {
+	area := edge(a, b, c.x, c.y)
+	if area == 0 {
+		return
+	}
+	if area < 0 {
+		b, c, area = c, b, -area
+	}
+	x0 := maxInt(r.x0, int(math.Floor(math.Min(a.x, math.Min(b.x, c.x)))))
+	y0 := maxInt(r.y0, int(math.Floor(math.Min(a.y, math.Min(b.y, c.y)))))
+	x1 := minInt(r.x1, int(math.Ceil(math.Max(a.x, math.Max(b.x, c.x))))+1)
+	y1 := minInt(r.y1, int(math.Ceil(math.Max(a.y, math.Max(b.y, c.y))))+1)
+	for y := y0; y < y1; y++ {
+		py := float64(y) + 0.5
+		for x := x0; x < x1; x++ {
+			px := float64(x) + 0.5
+			e0, e1, e2 := edge(b, c, px, py), edge(c, a, px, py), edge(a, b, px, py)
+			if e0 < 0 || e1 < 0 || e2 < 0 {
+				continue
+			}
+			if (e0 == 0 && !topLeft(b, c)) || (e1 == 0 && !topLeft(c, a)) || (e2 == 0 && !topLeft(a, b)) {
+				continue
+			}
+			l0, l1, l2 := e0/area, e1/area, e2/area
+			z := l0*a.z + l1*b.z + l2*c.z
+			invW := l0*a.invW + l1*b.invW + l2*c.invW
+			for k := range r.varyings {
+				r.varyings[k] = (l0*a.varyings[k] + l1*b.varyings[k] + l2*c.varyings[k]) / invW
+			}
+			r.fragment(x, y, z, invW, r.varyings, front, [2]float32{})
+		}
+	}
+}
+
+// draw implements Buffer.Draw, drawing count vertices starting at first, or
+// the vertices at count indices starting at first if b is an element array
+// buffer.
+func (c *Context) draw(b *Buffer, mode gfx.Primitive, first, count int) {
+	c.BindBuffer(&b.Buffer)
+	switch {
+	case first < 0 || count < 0:
+		c.SetError(gfx.InvalidValue)
+		return
+	case mode < gfx.Points || mode > gfx.TriangleFan:
+		c.SetError(gfx.InvalidEnum)
+		return
+	}
+	p, _ := c.GL.Program.(*Program)
+	if p == nil {
+		c.SetError(gfx.InvalidOperation)
+		return
+	}
+	t, err := c.target(c.Bound)
+	if err != nil {
+		c.SetError(gfx.InvalidFramebufferOperation)
+		return
+	}
+
+	// Gather the indices of the vertices, and validate them against the
+	// enabled vertex attribute arrays.
+	data := b.Bytes()
+	indices := make([]int, count)
+	for i := range indices {
+		if b.Type() == gfx.ElementArrayBuffer {
+			if (first+i)*2+2 > len(data) {
+				c.SetError(gfx.InvalidOperation)
+				return
+			}
+			indices[i] = int(binary.LittleEndian.Uint16(data[(first+i)*2:]))
+		} else {
+			indices[i] = first + i
+		}
+	}
+	for l := range p.vert.Attribs {
+		if !c.GL.AttribArrays[l] {
+			continue
+		}
+		a := &c.Attribs[l]
+		if a.Buffer == nil {
+			c.SetError(gfx.InvalidOperation)
+			return
+		}
+		for _, index := range indices {
+			if a.Offset+index*strideBytes(a)+a.Size*4 > len(a.Buffer.Bytes()) {
+				c.SetError(gfx.InvalidOperation)
+				return
+			}
+		}
+	}
+
+	// Shade each vertex once.
+	shaded := make(map[int]*clipVertex, count)
+	vertex := func(i int) *clipVertex {
+		index := indices[i]
+		if v, ok := shaded[index]; ok {
+			return v
+		}
+		in := &Vertex{
+			Uniforms:  &p.Uniforms,
+			Attribs:   make([][4]float32, len(p.vert.Attribs)),
+			PointSize: 1,
+			Varyings:  make([]float32, p.vert.Varyings),
+		}
+		for l := range in.Attribs {
+			in.Attribs[l] = [4]float32{0, 0, 0, 1}
+			if c.GL.AttribArrays[l] {
+				in.Attribs[l] = attribAt(&c.Attribs[l], index)
+			}
+		}
+		p.vert.Main(in)
+		v := &clipVertex{
+			size:     float64(in.PointSize),
+			varyings: make([]float64, p.vert.Varyings),
+		}
+		for k, x := range in.Position {
+			v.pos[k] = float64(x)
+		}
+		for k := range v.varyings {
+			if k < len(in.Varyings) {
+				v.varyings[k] = float64(in.Varyings[k])
+			}
+		}
+		shaded[index] = v
+		return v
+	}
+
+	r := &rasterizer{
+		g:        &c.GL,
+		t:        t,
+		frag:     p.frag,
+		varyings: make([]float64, p.vert.Varyings),
+	}
+	r.x0, r.y0, r.x1, r.y1 = bounds(&c.GL, t)
+	r.f = Fragment{
+		Uniforms: &p.Uniforms,
+		Varyings: make([]float32, p.vert.Varyings),
+	}
+
+	// Assemble the primitives.
+	switch mode {
+	case gfx.Points:
+		for i := 0; i < count; i++ {
+			r.point(vertex(i))
+		}
+	case gfx.Lines:
+		for i := 0; i+1 < count; i += 2 {
+			r.line(vertex(i), vertex(i+1))
+		}
+	case gfx.LineStrip, gfx.LineLoop:
+		for i := 0; i+1 < count; i++ {
+			r.line(vertex(i), vertex(i+1))
+		}
+		if mode == gfx.LineLoop && count > 1 {
+			r.line(vertex(count-1), vertex(0))
+		}
+	case gfx.Triangles:
+		for i := 0; i+2 < count; i += 3 {
+			r.triangle(vertex(i), vertex(i+1), vertex(i+2))
+		}
+	case gfx.TriangleStrip:
+		for i := 0; i+2 < count; i++ {
+			// Every other triangle is reversed, to keep the orientation
+			// of the strip.
+			if i%2 == 0 {
+				r.triangle(vertex(i), vertex(i+1), vertex(i+2))
+			} else {
+				r.triangle(vertex(i+1), vertex(i), vertex(i+2))
+			}
+		}
+	case gfx.TriangleFan:
+		for i := 1; i+1 < count; i++ {
+			r.triangle(vertex(0), vertex(i), vertex(i+1))
+		}
+	}
+}
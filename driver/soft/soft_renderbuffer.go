@@ -0,0 +1,37 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package soft
+
+import (
+	"github.com/slimsag/gfx"
+	"github.com/slimsag/gfx/internal/emul"
+)
+
+// Renderbuffer implements the gfx.Renderbuffer interface.
+type Renderbuffer struct {
+	emul.Renderbuffer
+
+	// The renderbuffer's storage, either a color or a depth image.
+	color *colorImage
+	depth *depthImage
+}
+
+// Storage implements the gfx.Renderbuffer interface.
+func (r *Renderbuffer) Storage(internalFormat gfx.RenderbufferFormat, width, height int) {
+	if !r.SetStorage(internalFormat, width, height) {
+		return
+	}
+	r.color, r.depth = nil, nil
+	switch internalFormat {
+	case gfx.RGBA4:
+		r.color = newColorImage(width, height, [4]uint8{4, 4, 4, 4})
+	case gfx.RGB565:
+		r.color = newColorImage(width, height, [4]uint8{5, 6, 5, 0})
+	case gfx.RGB5A1:
+		r.color = newColorImage(width, height, [4]uint8{5, 5, 5, 1})
+	case gfx.DepthComponent16:
+		r.depth = newDepthImage(width, height, 16)
+	}
+}
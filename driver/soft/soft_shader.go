@@ -0,0 +1,164 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package soft
+
+import (
+	"sync"
+
+	"github.com/slimsag/gfx"
+	"github.com/slimsag/gfx/glsl"
+	"github.com/slimsag/gfx/internal/emul"
+)
+
+// VertexShader is a vertex shader written in Go.
+type VertexShader struct {
+	// Attribs are the names of the shader's attribute variables. The
+	// location of each attribute is its index.
+	Attribs []string
+
+	// Uniforms are the names of the shader's uniform variables.
+	Uniforms []string
+
+	// Varyings is the number of varying components written by Main.
+	Varyings int
+
+	// Main computes the position and varyings of a single vertex.
+	Main func(v *Vertex)
+}
+
+// Vertex is the input and output of a vertex shader.
+type Vertex struct {
+	// Uniforms holds the values of the program's uniform variables.
+	Uniforms *Uniforms
+
+	// Attribs are the values of the vertex's attributes, by location.
+	// Components missing from a vertex attribute array (and the components
+	// of disabled arrays) default to 0, 0, 0, 1.
+	Attribs [][4]float32
+
+	// Position is the clip-space position of the vertex (gl_Position). It
+	// must be written by Main.
+	Position [4]float32
+
+	// PointSize is the size in pixels of the point drawn for the vertex
+	// (gl_PointSize), which defaults to one.
+	PointSize float32
+
+	// Varyings are the varying components of the vertex, which are
+	// interpolated across each primitive. They must be written by Main.
+	Varyings []float32
+}
+
+// FragmentShader is a fragment shader written in Go.
+type FragmentShader struct {
+	// Uniforms are the names of the shader's uniform variables.
+	Uniforms []string
+
+	// Main computes the color of a single fragment.
+	Main func(f *Fragment)
+}
+
+// Fragment is the input and output of a fragment shader.
+type Fragment struct {
+	// Uniforms holds the values of the program's uniform variables.
+	Uniforms *Uniforms
+
+	// Varyings are the varying components written by the vertex shader,
+	// interpolated (with perspective correction) for the fragment.
+	Varyings []float32
+
+	// FragCoord is the window-relative position of the fragment
+	// (gl_FragCoord): the center of its pixel, its depth and 1/w.
+	FragCoord [4]float32
+
+	// FrontFacing tells if the fragment belongs to a front-facing triangle,
+	// or to a point or line (gl_FrontFacing).
+	FrontFacing bool
+
+	// PointCoord is the position of the fragment within the point it
+	// belongs to, in the range of [0, 1] from the upper-left corner
+	// (gl_PointCoord).
+	PointCoord [2]float32
+
+	// Color is the color of the fragment (gl_FragColor). It must be written
+	// by Main.
+	Color [4]float32
+
+	// Discard, if set by Main, discards the fragment.
+	Discard bool
+}
+
+// registry holds the registered shaders, keyed by their source.
+var registry struct {
+	sync.RWMutex
+	vertex   map[string]*VertexShader
+	fragment map[string]*FragmentShader
+}
+
+// RegisterVertexShader registers the Go implementation of the vertex shader
+// with the given GLSL source code, such that compiling the source succeeds.
+// Registering the same source again replaces its implementation.
+func RegisterVertexShader(src string, s VertexShader) {
+	if s.Main == nil {
+		panic("soft: RegisterVertexShader called with nil Main function")
+	}
+	registry.Lock()
+	defer registry.Unlock()
+	if registry.vertex == nil {
+		registry.vertex = make(map[string]*VertexShader)
+	}
+	registry.vertex[src] = &s
+}
+
+// RegisterFragmentShader is like RegisterVertexShader, but for a fragment
+// shader.
+func RegisterFragmentShader(src string, s FragmentShader) {
+	if s.Main == nil {
+		panic("soft: RegisterFragmentShader called with nil Main function")
+	}
+	registry.Lock()
+	defer registry.Unlock()
+	if registry.fragment == nil {
+		registry.fragment = make(map[string]*FragmentShader)
+	}
+	registry.fragment[src] = &s
+}
+
+// Shader implements the gfx.Shader interface.
+type Shader struct {
+	emul.Shader
+
+	// The compiled shader, one of which is non-nil after successful
+	// compilation: a registered Go shader depending on the shader's type, or
+	// the interpreted GLSL shader.
+	vert *VertexShader
+	frag *FragmentShader
+	glsl *glsl.Shader
+}
+
+// Compile implements the gfx.Shader interface. If a Go implementation of the
+// shader was registered for the given source it is used, otherwise the source
+// is compiled by the glsl package and interpreted.
+func (s *Shader) Compile(src string) bool {
+	s.Ctx.Call("Shader.Compile", s.O)
+	s.vert, s.frag, s.glsl = nil, nil, nil
+	registry.RLock()
+	switch s.Type() {
+	case gfx.VertexShader:
+		s.vert = registry.vertex[src]
+	case gfx.FragmentShader:
+		s.frag = registry.fragment[src]
+	}
+	registry.RUnlock()
+	if s.vert != nil || s.frag != nil {
+		return s.SetCompiled("")
+	}
+	sh, err := glsl.Compile(src, s.Type())
+	if err != nil {
+		return s.SetCompiled(err.Error())
+	}
+	s.glsl = sh
+	return s.SetCompiled("")
+}
@@ -0,0 +1,499 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package soft
+
+import (
+	"math"
+	"testing"
+
+	"github.com/slimsag/gfx"
+)
+
+// Just for ensuring we meet the interface requirements.
+func init() {
+	_ = gfx.Buffer(&Buffer{})
+	_ = gfx.Context(&Context{})
+	_ = gfx.Framebuffer(&Framebuffer{})
+	_ = gfx.Program(&Program{})
+	_ = gfx.Renderbuffer(&Renderbuffer{})
+	_ = gfx.Shader(&Shader{})
+	_ = gfx.Texture(&Texture{})
+}
+
+// Sources of the shaders used by the tests.
+const (
+	// colorVert passes through the position attribute, and the color
+	// attribute as varyings.
+	colorVert = "soft test: color vertex shader"
+
+	// colorFrag outputs the interpolated color.
+	colorFrag = "soft test: color fragment shader"
+
+	// countFrag counts the fragments of each pixel, see fragmentCounts.
+	countFrag = "soft test: count fragment shader"
+)
+
+// fragmentCounts counts the fragments shaded by countFrag, by pixel, and the
+// front-facing ones amongst them.
+var fragmentCounts struct {
+	all, front map[[2]int]int
+}
+
+func init() {
+	RegisterVertexShader(colorVert, VertexShader{
+		Attribs:  []string{"position", "color"},
+		Varyings: 4,
+		Main: func(v *Vertex) {
+			v.Position = v.Attribs[0]
+			v.PointSize = v.Attribs[1][3]
+			copy(v.Varyings, v.Attribs[1][:])
+		},
+	})
+	RegisterFragmentShader(colorFrag, FragmentShader{
+		Main: func(f *Fragment) {
+			copy(f.Color[:], f.Varyings)
+		},
+	})
+	RegisterFragmentShader(countFrag, FragmentShader{
+		Main: func(f *Fragment) {
+			p := [2]int{int(f.FragCoord[0]), int(f.FragCoord[1])}
+			fragmentCounts.all[p]++
+			if f.FrontFacing {
+				fragmentCounts.front[p]++
+			}
+			f.Color = [4]float32{1, 1, 1, 1}
+		},
+	})
+}
+
+func resetCounts() {
+	fragmentCounts.all = make(map[[2]int]int)
+	fragmentCounts.front = make(map[[2]int]int)
+}
+
+func newProgram(t *testing.T, ctx gfx.Context, vsrc, fsrc string) gfx.Program {
+	vert := ctx.NewShader(gfx.VertexShader)
+	if !vert.Compile(vsrc) {
+		t.Fatal(vert.InfoLog())
+	}
+	frag := ctx.NewShader(gfx.FragmentShader)
+	if !frag.Compile(fsrc) {
+		t.Fatal(frag.InfoLog())
+	}
+	p := ctx.NewProgram()
+	if !p.Link(vert, frag) {
+		t.Fatal(p.InfoLog())
+	}
+	return p
+}
+
+// vertex is the data of a vertex for the colorVert shader. The alpha channel
+// of the color is also used as the point size.
+type vertex struct {
+	x, y, z    float32
+	r, g, b, a float32
+}
+
+// draw draws the vertices with the given program, which uses the colorVert
+// shader, and additional context state values.
+func draw(t *testing.T, ctx gfx.Context, p gfx.Program, mode gfx.Primitive, vertices []vertex, values ...gfx.ContextStateValue) {
+	var data []float32
+	for _, v := range vertices {
+		data = append(data, v.x, v.y, v.z, 1, v.r, v.g, v.b, v.a)
+	}
+	buf := ctx.NewBuffer(gfx.ArrayBuffer)
+	defer buf.Delete()
+	buf.DataFloat32(data, gfx.StaticDraw)
+	pos, color := p.AttribLocation("position"), p.AttribLocation("color")
+	buf.VertexAttribPointer(pos, 4, false, 32, 0)
+	buf.VertexAttribPointer(color, 4, false, 32, 16)
+	ctx.Load(ctx.NewState(append([]gfx.ContextStateValue{
+		ctx.UseProgram(p),
+		ctx.EnableVertexAttribArray(pos),
+		ctx.EnableVertexAttribArray(color),
+	}, values...)...))
+	buf.Draw(mode, 0, len(vertices))
+	ctx.Check()
+}
+
+// pixels returns the pixels of the framebuffer.
+func pixels(fb gfx.Framebuffer, width, height int) []uint8 {
+	pix := make([]uint8, width*height*4)
+	fb.ReadPixelsUint8(0, 0, width, height, pix)
+	return pix
+}
+
+// quad is a triangle strip covering the whole viewport, with the given depth
+// and color.
+func quad(z, r, g, b, a float32) []vertex {
+	return []vertex{
+		{-1, -1, z, r, g, b, a},
+		{1, -1, z, r, g, b, a},
+		{-1, 1, z, r, g, b, a},
+		{1, 1, z, r, g, b, a},
+	}
+}
+
+func TestClearScissor(t *testing.T) {
+	ctx := New(4, 4)
+	fb := ctx.Framebuffer()
+	fb.Load(fb.NewState(fb.ClearColor(1, 0, 0, 1)))
+	ctx.Load(ctx.NewState(ctx.Enable(gfx.ScissorTest), ctx.Scissor(1, 1, 2, 8)))
+	fb.Clear(gfx.ColorBuffer)
+	ctx.Check()
+
+	pix := pixels(fb, 4, 4)
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			want := uint8(0)
+			if x >= 1 && x < 3 && y >= 1 {
+				want = 255
+			}
+			if got := pix[(y*4+x)*4]; got != want {
+				t.Errorf("pixel %d,%d: red = %d, want %d", x, y, got, want)
+			}
+		}
+	}
+}
+
+func TestTriangleCoverage(t *testing.T) {
+	ctx := New(7, 5)
+	p := newProgram(t, ctx, colorVert, countFrag)
+
+	// Each pixel must be covered exactly once, even though the triangles
+	// share an edge that goes through pixel centers.
+	for _, mode := range []gfx.Primitive{gfx.TriangleStrip, gfx.TriangleFan} {
+		resetCounts()
+		v := quad(0, 0, 0, 0, 1)
+		if mode == gfx.TriangleFan {
+			v[2], v[3] = v[3], v[2]
+		}
+		draw(t, ctx, p, mode, v)
+		for y := 0; y < 5; y++ {
+			for x := 0; x < 7; x++ {
+				if n := fragmentCounts.all[[2]int{x, y}]; n != 1 {
+					t.Errorf("%v: pixel %d,%d: %d fragments, want 1", mode, x, y, n)
+				}
+				if n := fragmentCounts.front[[2]int{x, y}]; n != 1 {
+					t.Errorf("%v: pixel %d,%d: %d front-facing fragments, want 1", mode, x, y, n)
+				}
+			}
+		}
+	}
+}
+
+func TestVaryings(t *testing.T) {
+	ctx := New(8, 2)
+	p := newProgram(t, ctx, colorVert, colorFrag)
+	draw(t, ctx, p, gfx.TriangleStrip, []vertex{
+		{-1, -1, 0, 0, 0, 1, 1},
+		{1, -1, 0, 1, 0, 1, 1},
+		{-1, 1, 0, 0, 0, 1, 1},
+		{1, 1, 0, 1, 0, 1, 1},
+	})
+
+	pix := pixels(ctx.Framebuffer(), 8, 2)
+	for x := 0; x < 8; x++ {
+		want := (float64(x) + 0.5) / 8 * 255
+		got := pix[x*4 : x*4+4]
+		if math.Abs(float64(got[0])-want) > 1 || got[1] != 0 || got[2] != 255 || got[3] != 255 {
+			t.Errorf("pixel %d: got %v, want red = %.1f", x, got, want)
+		}
+	}
+}
+
+func TestDepthTest(t *testing.T) {
+	ctx := New(2, 2)
+	p := newProgram(t, ctx, colorVert, colorFrag)
+	draw(t, ctx, p, gfx.TriangleStrip, quad(-0.5, 0, 1, 0, 1), ctx.Enable(gfx.DepthTest))
+	draw(t, ctx, p, gfx.TriangleStrip, quad(0.5, 1, 0, 0, 1), ctx.Enable(gfx.DepthTest))
+	if got := pixels(ctx.Framebuffer(), 2, 2)[:4]; got[0] != 0 || got[1] != 255 {
+		t.Errorf("farther quad drawn with depth test: got %v", got)
+	}
+	draw(t, ctx, p, gfx.TriangleStrip, quad(0.5, 1, 0, 0, 1))
+	if got := pixels(ctx.Framebuffer(), 2, 2)[:4]; got[0] != 255 || got[1] != 0 {
+		t.Errorf("farther quad not drawn without depth test: got %v", got)
+	}
+}
+
+func TestBlend(t *testing.T) {
+	ctx := New(2, 2)
+	p := newProgram(t, ctx, colorVert, colorFrag)
+	fb := ctx.Framebuffer()
+	tests := []struct {
+		eq   gfx.BlendEquation
+		want [4]uint8
+	}{
+		// The blend function is ONE, ZERO: the color of the framebuffer and
+		// the blend color are ignored.
+		{gfx.FuncAdd, [4]uint8{255, 0, 255, 255}},
+		{gfx.FuncSubtract, [4]uint8{255, 0, 255, 255}},
+		{gfx.FuncReverseSubtract, [4]uint8{0, 0, 0, 0}},
+	}
+	for _, tst := range tests {
+		fb.Load(fb.NewState(fb.ClearColor(0, 1, 0, 1)))
+		fb.Clear(gfx.ColorBuffer)
+		draw(t, ctx, p, gfx.TriangleStrip, quad(0, 1, 0, 1, 1),
+			ctx.Enable(gfx.Blend), ctx.BlendEquation(tst.eq), ctx.BlendColor(1, 1, 1, 1))
+		var got [4]uint8
+		copy(got[:], pixels(fb, 2, 2))
+		if got != tst.want {
+			t.Errorf("%v: got %v, want %v", tst.eq, got, tst.want)
+		}
+	}
+}
+
+func TestCullFace(t *testing.T) {
+	ctx := New(4, 4)
+	p := newProgram(t, ctx, colorVert, countFrag)
+
+	// Mirroring the strip makes its triangles clockwise.
+	cw := quad(0, 0, 0, 0, 1)
+	for i := range cw {
+		cw[i].x = -cw[i].x
+	}
+	tests := []struct {
+		values      []gfx.ContextStateValue
+		want, front int
+	}{
+		{nil, 16, 0},
+		{[]gfx.ContextStateValue{ctx.Enable(gfx.CullFace)}, 0, 0},
+		{[]gfx.ContextStateValue{ctx.Enable(gfx.CullFace), ctx.CullFace(gfx.Front)}, 16, 0},
+		{[]gfx.ContextStateValue{ctx.FrontFace(gfx.CW)}, 16, 16},
+		{[]gfx.ContextStateValue{ctx.Enable(gfx.CullFace), ctx.CullFace(gfx.FrontAndBack)}, 0, 0},
+	}
+	for i, tst := range tests {
+		resetCounts()
+		draw(t, ctx, p, gfx.TriangleStrip, cw, tst.values...)
+		all, front := 0, 0
+		for _, n := range fragmentCounts.all {
+			all += n
+		}
+		for _, n := range fragmentCounts.front {
+			front += n
+		}
+		if all != tst.want || front != tst.front {
+			t.Errorf("test %d: got %d fragments (%d front-facing), want %d (%d)", i, all, front, tst.want, tst.front)
+		}
+	}
+}
+
+func TestLinesAndPoints(t *testing.T) {
+	ctx := New(8, 8)
+	p := newProgram(t, ctx, colorVert, countFrag)
+
+	resetCounts()
+	draw(t, ctx, p, gfx.Lines, []vertex{
+		{-1, 0, 0, 0, 0, 0, 1},
+		{1, 0, 0, 0, 0, 0, 1},
+	})
+	for x := 0; x < 8; x++ {
+		if n := fragmentCounts.all[[2]int{x, 4}]; n != 1 {
+			t.Errorf("line: pixel %d,4: %d fragments, want 1", x, n)
+		}
+	}
+	if len(fragmentCounts.all) != 8 {
+		t.Errorf("line: %d pixels drawn, want 8", len(fragmentCounts.all))
+	}
+
+	// Points are squares of pixels centered on the vertex, whose size is
+	// the alpha channel of the vertex color.
+	resetCounts()
+	draw(t, ctx, p, gfx.Points, []vertex{
+		{-0.875, -0.875, 0, 0, 0, 0, 1},
+		{0, 0, 0, 0, 0, 0, 3},
+		{2, 2, 0, 0, 0, 0, 1}, // Clipped.
+	})
+	want := map[[2]int]int{{0, 0}: 1}
+	for y := 3; y < 6; y++ {
+		for x := 3; x < 6; x++ {
+			want[[2]int{x, y}] = 1
+		}
+	}
+	if len(fragmentCounts.all) != len(want) {
+		t.Errorf("points: %d pixels drawn, want %d", len(fragmentCounts.all), len(want))
+	}
+	for p := range want {
+		if fragmentCounts.all[p] != 1 {
+			t.Errorf("points: pixel %v not drawn", p)
+		}
+	}
+}
+
+func TestElementBuffer(t *testing.T) {
+	ctx := New(4, 4)
+	p := newProgram(t, ctx, colorVert, countFrag)
+	var data []float32
+	for _, v := range quad(0, 0, 0, 0, 1) {
+		data = append(data, v.x, v.y, v.z, 1, v.r, v.g, v.b, v.a)
+	}
+	buf := ctx.NewBuffer(gfx.ArrayBuffer)
+	buf.DataFloat32(data, gfx.StaticDraw)
+	pos, color := p.AttribLocation("position"), p.AttribLocation("color")
+	buf.VertexAttribPointer(pos, 4, false, 32, 0)
+	buf.VertexAttribPointer(color, 4, false, 32, 16)
+	ctx.Load(ctx.NewState(
+		ctx.UseProgram(p),
+		ctx.EnableVertexAttribArray(pos),
+		ctx.EnableVertexAttribArray(color),
+	))
+
+	elements := ctx.NewBuffer(gfx.ElementArrayBuffer)
+	elements.DataUint16([]uint16{9, 0, 1, 2, 2, 1, 3}, gfx.StaticDraw)
+	resetCounts()
+	elements.Draw(gfx.Triangles, 1, 6)
+	ctx.Check()
+	if len(fragmentCounts.all) != 16 {
+		t.Errorf("%d pixels drawn, want 16", len(fragmentCounts.all))
+	}
+
+	// Index 9 is out of range of the vertex attribute arrays.
+	elements.Draw(gfx.Triangles, 0, 3)
+	defer func() {
+		if r := recover(); r != gfx.InvalidOperation {
+			t.Errorf("Check: got %v, want %v", r, gfx.InvalidOperation)
+		}
+	}()
+	ctx.Check()
+}
+
+func TestFramebufferObject(t *testing.T) {
+	ctx := New(4, 4)
+	fb := ctx.NewFramebuffer()
+	if err := fb.Status(); err != gfx.ErrFramebufferIncompleteMissingAttachment {
+		t.Errorf("no attachments: got status %v", err)
+	}
+
+	color := ctx.NewRenderbuffer()
+	color.Storage(gfx.RGBA4, 2, 2)
+	depth := ctx.NewRenderbuffer()
+	depth.Storage(gfx.DepthComponent16, 3, 3)
+	fb.Renderbuffer(gfx.ColorAttachment0, depth)
+	if err := fb.Status(); err != gfx.ErrFramebufferIncompleteAttachment {
+		t.Errorf("depth renderbuffer as color: got status %v", err)
+	}
+	fb.Renderbuffer(gfx.ColorAttachment0, color)
+	fb.Renderbuffer(gfx.DepthAttachment, depth)
+	if err := fb.Status(); err != gfx.ErrFramebufferIncompleteDimensions {
+		t.Errorf("mismatched sizes: got status %v", err)
+	}
+	depth.Storage(gfx.DepthComponent16, 2, 2)
+	if err := fb.Status(); err != nil {
+		t.Errorf("complete framebuffer: got status %v", err)
+	}
+
+	// Colors are quantized to the 4 bits per channel of the renderbuffer.
+	fb.Load(fb.NewState(fb.ClearColor(0.5, 0.25, 1, 0)))
+	fb.Clear(gfx.ColorBuffer | gfx.DepthBuffer)
+	ctx.Check()
+	pix := pixels(fb, 2, 2)
+	if want := []uint8{136, 68, 255, 0}; string(pix[:4]) != string(want) {
+		t.Errorf("clear: got %v, want %v", pix[:4], want)
+	}
+
+	// Textures may be attached once they have storage.
+	tex := ctx.NewTexture(gfx.TextureType2D)
+	fb.Renderbuffer(gfx.DepthAttachment, nil)
+	fb.Texture2D(gfx.ColorAttachment0, gfx.Texture2D, tex)
+	if err := fb.Status(); err != gfx.ErrFramebufferIncompleteAttachment {
+		t.Errorf("texture without storage: got status %v", err)
+	}
+	tex.(*Texture).Storage(3, 3)
+	p := newProgram(t, ctx, colorVert, colorFrag)
+	draw(t, ctx, p, gfx.TriangleStrip, quad(0, 0, 1, 0, 1), ctx.Viewport(0, 0, 3, 3))
+	pix = pixels(fb, 3, 3)
+	for i := 0; i < len(pix); i += 4 {
+		if pix[i+1] != 255 {
+			t.Fatalf("texture: pixel %d not drawn: %v", i/4, pix[i:i+4])
+		}
+	}
+
+	// The default framebuffer is left untouched.
+	if pix := pixels(ctx.Framebuffer(), 4, 4); pix[1] != 0 {
+		t.Errorf("default framebuffer drawn to: %v", pix[:4])
+	}
+}
+
+func TestGLSL(t *testing.T) {
+	const vsrc = `
+		attribute vec4 position;
+		attribute vec4 color;
+		varying vec4 vColor;
+		void main() {
+			vColor = color;
+			gl_Position = position;
+		}`
+	const fsrc = `
+		precision mediump float;
+		uniform vec4 tint[2];
+		varying vec4 vColor;
+		void main() {
+			if (gl_FragCoord.x < 1.0) {
+				discard;
+			}
+			gl_FragColor = vColor * tint[1];
+		}`
+	ctx := New(2, 1)
+	p := newProgram(t, ctx, vsrc, fsrc)
+	p.Uniform4fv(p.UniformLocation("tint[0]"), []float32{0, 0, 0, 0, 1, 0.5, 1, 1})
+	draw(t, ctx, p, gfx.TriangleStrip, quad(0, 1, 1, 0, 1))
+
+	pix := pixels(ctx.Framebuffer(), 2, 1)
+	if want := []uint8{0, 0, 0, 0, 255, 128, 0, 255}; string(pix) != string(want) {
+		t.Errorf("got pixels %v, want %v", pix, want)
+	}
+
+	// Varyings of the fragment shader must be written by the vertex shader.
+	frag := ctx.NewShader(gfx.FragmentShader)
+	if !frag.Compile("varying vec2 vColor; void main() {}") {
+		t.Fatal(frag.InfoLog())
+	}
+	vert := ctx.NewShader(gfx.VertexShader)
+	if vert.Compile("void main() { gl_Position = 1; }") {
+		t.Error("Compile: expected error for assignment of int to vec4")
+	}
+	if !vert.Compile(vsrc) {
+		t.Fatal(vert.InfoLog())
+	}
+	if p := ctx.NewProgram(); p.Link(vert, frag) {
+		t.Error("Link: expected error for mismatched varying types")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	ctx := New(1, 1)
+	buf := ctx.NewBuffer(gfx.ArrayBuffer)
+	buf.Draw(gfx.Triangles, 0, 3)
+	defer func() {
+		if r := recover(); r != gfx.InvalidOperation {
+			t.Errorf("Check: got %v, want %v", r, gfx.InvalidOperation)
+		}
+	}()
+	ctx.Check()
+}
+
+func TestNilAttribLocation(t *testing.T) {
+	ctx := New(1, 1)
+	buf := ctx.NewBuffer(gfx.ArrayBuffer)
+	buf.DataFloat32(make([]float32, 4), gfx.StaticDraw)
+	tests := []struct {
+		name string
+		f    func()
+	}{
+		{"VertexAttribPointer", func() { buf.VertexAttribPointer(nil, 4, false, 0, 0) }},
+		{"EnableVertexAttribArray", func() { ctx.Load(ctx.NewState(ctx.EnableVertexAttribArray(nil))) }},
+	}
+	for _, tst := range tests {
+		tst.f()
+		func() {
+			defer func() {
+				if r := recover(); r != gfx.InvalidValue {
+					t.Errorf("%s: Check: got %v, want %v", tst.name, r, gfx.InvalidValue)
+				}
+			}()
+			ctx.Check()
+		}()
+	}
+}
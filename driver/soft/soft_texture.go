@@ -0,0 +1,33 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package soft
+
+import "github.com/slimsag/gfx/internal/emul"
+
+// Texture implements the gfx.Texture interface.
+type Texture struct {
+	emul.Texture
+
+	// faces are the images of the texture, one for a 2D texture or six for a
+	// cube map (in the order of the cube map texture targets). It is nil
+	// until Storage is called.
+	faces []*colorImage
+}
+
+// Storage allocates storage for the texture with 8 bits per RGBA channel,
+// such that it can be attached to a framebuffer. Each face of a cube map has
+// the given size, which must be square.
+//
+// gfx has no API for allocating the storage of a texture, so Storage is
+// specific to this driver.
+func (t *Texture) Storage(width, height int) {
+	if !t.SetStorage(width, height) {
+		return
+	}
+	t.faces = make([]*colorImage, t.Faces())
+	for i := range t.faces {
+		t.faces[i] = newColorImage(width, height, [4]uint8{8, 8, 8, 8})
+	}
+}
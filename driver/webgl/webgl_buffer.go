@@ -23,13 +23,13 @@ type Buffer struct {
 // DataSize implements the gfx.Buffer interface.
 func (b *Buffer) DataSize(size int, usage gfx.BufferUsage) {
 	typ := b.ctx.Enums[int(b.typ)]
-	b.ctx.fastBindBuffer(typ, b.o)
+	b.ctx.fastBindBuffer(b.typ, b.o)
 	b.ctx.O.Call("bufferData", typ, size, b.ctx.Enums[int(usage)])
 }
 
 func (b *Buffer) data(x interface{}, usage gfx.BufferUsage) {
 	typ := b.ctx.Enums[int(b.typ)]
-	b.ctx.fastBindBuffer(typ, b.o)
+	b.ctx.fastBindBuffer(b.typ, b.o)
 	b.ctx.O.Call("bufferData", typ, x, b.ctx.Enums[int(usage)])
 }
 
@@ -75,7 +75,7 @@ func (b *Buffer) DataFloat64(data []float64, usage gfx.BufferUsage) {
 
 func (b *Buffer) subData(offset int, x interface{}) {
 	typ := b.ctx.Enums[int(b.typ)]
-	b.ctx.fastBindBuffer(typ, b.o)
+	b.ctx.fastBindBuffer(b.typ, b.o)
 	b.ctx.O.Call("bufferSubData", typ, offset, x)
 }
 
@@ -121,7 +121,7 @@ func (b *Buffer) SubDataFloat64(offset int, data []float64) {
 
 // Draw implements the gfx.Buffer interface.
 func (b *Buffer) Draw(p gfx.Primitive, first, count int) {
-	b.ctx.fastBindBuffer(b.ctx.Enums[int(b.typ)], b.o)
+	b.ctx.fastBindBuffer(b.typ, b.o)
 	if b.typ == gfx.ArrayBuffer {
 		b.ctx.O.Call("drawArrays", b.ctx.Enums[int(p)], first, count)
 	} else {
@@ -131,7 +131,7 @@ func (b *Buffer) Draw(p gfx.Primitive, first, count int) {
 
 // VertexAttribPointer implements the gfx.Buffer interface.
 func (b *Buffer) VertexAttribPointer(l gfx.AttribLocation, size int, normalized bool, stride, offset int) {
-	b.ctx.fastBindBuffer(b.ctx.Enums[int(b.typ)], b.o)
+	b.ctx.fastBindBuffer(b.typ, b.o)
 	b.ctx.O.Call("vertexAttribPointer", l.(int), size, b.ctx.FLOAT, normalized, stride, offset)
 }
 
@@ -141,6 +141,7 @@ func (b *Buffer) Delete() {
 		return
 	}
 	b.ctx.O.Call("deleteBuffer", b.o)
+	b.ctx.forgetBuffer(b.o)
 	b.o = nil
 }
 
@@ -25,12 +25,21 @@ type Context struct {
 
 	LastBindFramebuffer  *js.Object
 	LastBindRenderbuffer *js.Object
-	LastBindBuffer       *js.Object
 	LastUseProgram       *js.Object
 
+	// LastBindBuffer is the last buffer bound to each buffer type.
+	LastBindBuffer [numBufferTypes]*js.Object
+
 	// The default framebuffer implementation for the context.
 	fb Framebuffer
 
+	// fbState is the framebuffer state table, shared by all framebuffers.
+	fbState state.Table
+
+	// counters counts the work done, and avoided, by the caches of the
+	// context.
+	counters state.Counters
+
 	puts int
 
 	// TODO(slimsag): privatize all below here
@@ -116,6 +125,7 @@ func (c *Context) loadEnums() {
 	c.putEnum(int(gfx.CullFace), "CULL_FACE")
 	c.putEnum(int(gfx.PolygonOffsetFill), "POLYGON_OFFSET_FILL")
 	c.putEnum(int(gfx.ScissorTest), "SCISSOR_TEST")
+	c.putEnum(int(gfx.Dither), "DITHER")
 
 	// Orientations.
 	c.putEnum(int(gfx.CCW), "CCW")
@@ -155,8 +165,63 @@ func (c *Context) loadEnums() {
 	}
 }
 
+// invalidBinding is a bind cache value that never matches an actual object,
+// such that the next bind is always made.
+var invalidBinding = js.Global.Get("Object").New()
+
+// getParameter returns the value of the named WebGL parameter, e.g.
+// "FRAMEBUFFER_BINDING".
+func (c *Context) getParameter(name string) *js.Object {
+	return c.O.Call("getParameter", c.O.Get(name))
+}
+
+// numBufferTypes is the number of gfx.BufferType values.
+const numBufferTypes = int(gfx.ElementArrayBuffer) + 1
+
+// bufferBindings maps each buffer type to the name of the WebGL parameter
+// that queries its binding.
+var bufferBindings = [numBufferTypes]string{
+	gfx.ArrayBuffer:        "ARRAY_BUFFER_BINDING",
+	gfx.ElementArrayBuffer: "ELEMENT_ARRAY_BUFFER_BINDING",
+}
+
+// forgetFramebuffer clears the bind cache of the given framebuffer, which is
+// being deleted. Deleting a bound object reverts its binding to zero.
+func (c *Context) forgetFramebuffer(framebuffer *js.Object) {
+	if c.LastBindFramebuffer == framebuffer {
+		c.LastBindFramebuffer = nil
+	}
+}
+
+// forgetRenderbuffer is like forgetFramebuffer, but for a renderbuffer.
+func (c *Context) forgetRenderbuffer(renderbuffer *js.Object) {
+	if c.LastBindRenderbuffer == renderbuffer {
+		c.LastBindRenderbuffer = nil
+	}
+}
+
+// forgetBuffer is like forgetFramebuffer, but for a buffer bound to any
+// buffer type.
+func (c *Context) forgetBuffer(buffer *js.Object) {
+	for t, b := range c.LastBindBuffer {
+		if b == buffer {
+			c.LastBindBuffer[t] = nil
+		}
+	}
+}
+
+// forgetProgram clears the cache of the given program, which is being
+// deleted. A program in use is only deleted once it is no longer used, so
+// it is unknown which program will be in use by then.
+func (c *Context) forgetProgram(program *js.Object) {
+	if c.LastUseProgram == program {
+		c.LastUseProgram = invalidBinding
+	}
+}
+
 func (c *Context) fastBindFramebuffer(framebuffer *js.Object) bool {
 	if c.LastBindFramebuffer == framebuffer {
+		c.counters.BindsElided++
 		return false
 	}
 	c.LastBindFramebuffer = framebuffer
@@ -166,6 +231,7 @@ func (c *Context) fastBindFramebuffer(framebuffer *js.Object) bool {
 
 func (c *Context) fastBindRenderbuffer(renderbuffer *js.Object) bool {
 	if c.LastBindRenderbuffer == renderbuffer {
+		c.counters.BindsElided++
 		return false
 	}
 	c.LastBindRenderbuffer = renderbuffer
@@ -173,17 +239,19 @@ func (c *Context) fastBindRenderbuffer(renderbuffer *js.Object) bool {
 	return true
 }
 
-func (c *Context) fastBindBuffer(typ int, buffer *js.Object) bool {
-	if c.LastBindBuffer == buffer {
+func (c *Context) fastBindBuffer(t gfx.BufferType, buffer *js.Object) bool {
+	if c.LastBindBuffer[t] == buffer {
+		c.counters.BindsElided++
 		return false
 	}
-	c.LastBindBuffer = buffer
-	c.O.Call("bindBuffer", typ, buffer)
+	c.LastBindBuffer[t] = buffer
+	c.O.Call("bindBuffer", c.Enums[int(t)], buffer)
 	return true
 }
 
 func (c *Context) fastUseProgram(program *js.Object) bool {
 	if c.LastUseProgram == program {
+		c.counters.BindsElided++
 		return false
 	}
 	c.LastUseProgram = program
@@ -199,8 +267,9 @@ func (c *Context) Framebuffer() gfx.Framebuffer {
 // NewFramebuffer implements the gfx.Context interface.
 func (c *Context) NewFramebuffer() gfx.Framebuffer {
 	return &Framebuffer{
-		ctx: c,
-		o:   c.O.Call("createFramebuffer"),
+		Framebuffer: state.Framebuffer{Table: &c.fbState},
+		ctx:         c,
+		o:           c.O.Call("createFramebuffer"),
 	}
 }
 
@@ -284,6 +353,77 @@ func (c *Context) Finish() {
 	c.O.Call("finish")
 }
 
+// Invalidate implements the gfx.Context interface.
+func (c *Context) Invalidate() {
+	c.LastBindFramebuffer = invalidBinding
+	c.LastBindRenderbuffer = invalidBinding
+	c.LastUseProgram = invalidBinding
+	for t := range c.LastBindBuffer {
+		c.LastBindBuffer[t] = invalidBinding
+	}
+	c.Context.Invalidate()
+	c.fbState.Invalidate()
+}
+
+// Resync implements the gfx.Context interface.
+func (c *Context) Resync() {
+	c.LastBindFramebuffer = c.getParameter("FRAMEBUFFER_BINDING")
+	c.LastBindRenderbuffer = c.getParameter("RENDERBUFFER_BINDING")
+	c.LastUseProgram = c.getParameter("CURRENT_PROGRAM")
+	for t, pname := range bufferBindings {
+		c.LastBindBuffer[t] = c.getParameter(pname)
+	}
+
+	c.Context.Resync(c.queryState)
+	c.fbState.Resync(c.queryFramebufferState)
+}
+
+// SetDebugHandler implements the gfx.Context interface. WebGL has no debug
+// output, so the handler is never invoked.
+func (c *Context) SetDebugHandler(h func(m gfx.DebugMessage)) {}
+
+// VerifyCache verifies that the context's caches of WebGL state match the
+// actual WebGL state, and returns an error describing the first mismatch
+// found. It is used by the debug package, and is very slow.
+func (c *Context) VerifyCache() error {
+	type binding struct {
+		name   string
+		cached *js.Object
+		pname  string
+	}
+	bindings := []binding{
+		{"framebuffer", c.LastBindFramebuffer, "FRAMEBUFFER_BINDING"},
+		{"renderbuffer", c.LastBindRenderbuffer, "RENDERBUFFER_BINDING"},
+		{"program", c.LastUseProgram, "CURRENT_PROGRAM"},
+	}
+	bufferNames := [numBufferTypes]string{
+		gfx.ArrayBuffer:        "array buffer",
+		gfx.ElementArrayBuffer: "element array buffer",
+	}
+	for t, pname := range bufferBindings {
+		bindings = append(bindings, binding{bufferNames[t], c.LastBindBuffer[t], pname})
+	}
+	for _, b := range bindings {
+		if b.cached == invalidBinding {
+			continue
+		}
+		if actual := c.getParameter(b.pname); b.cached != actual {
+			return fmt.Errorf("webgl: cached %s binding %v but actually %v", b.name, b.cached, actual)
+		}
+	}
+	if err := c.Context.Verify(c.queryState); err != nil {
+		return err
+	}
+	return c.fbState.Verify(c.queryFramebufferState)
+}
+
+// Counters returns the number of state values applied and elided when
+// loading states, and of bind calls elided, since the context was created. It
+// is used by the stats package.
+func (c *Context) Counters() (applied, elided, bindsElided int) {
+	return c.counters.Applied, c.counters.Elided, c.counters.BindsElided
+}
+
 // Wrap returns a new WebGL rendering context by wrapping the given JavaScript
 // WebGLRenderingContext object.
 func Wrap(o *js.Object) gfx.Context {
@@ -292,7 +432,12 @@ func Wrap(o *js.Object) gfx.Context {
 	}
 	ctx.fb.o = nil // Default framebuffer object.
 	ctx.fb.ctx = ctx
+	ctx.fb.Table = &ctx.fbState
 	ctx.loadEnums()
+	ctx.Context.Init(ctx.defaultState()...)
+	ctx.fbState.Init(ctx.fb.defaultState()...)
+	ctx.Context.SetCounters(&ctx.counters)
+	ctx.fbState.Counters = &ctx.counters
 	return ctx
 }
 
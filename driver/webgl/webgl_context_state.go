@@ -6,219 +6,303 @@
 package webgl
 
 import (
+	"github.com/gopherjs/gopherjs/js"
 	"github.com/slimsag/gfx"
 	s "github.com/slimsag/gfx/internal/state"
 )
 
-const (
-	csBlendColor = iota
-	csBlendEquation
-	csDepthMask
-	csUseProgram
-	csViewport
-	csScissor
-	csLineWidth
-	csColorMask
-	csCullFace
-	csFrontFace
-	csEnable
-	csDisable
-	csEnableVertexAttribArray
-)
+// defaultState returns the default value of each piece of context state, as
+// specified by WebGL.
+func (c *Context) defaultState() []gfx.ContextStateValue {
+	// The viewport and scissor box default to the size of the canvas the
+	// WebGL context was first attached to.
+	viewport := c.O.Call("getParameter", c.O.Get("VIEWPORT"))
+	scissor := c.O.Call("getParameter", c.O.Get("SCISSOR_BOX"))
 
-func (c *Context) glBlendColor(v interface{}) {
-	x := v.([4]float32)
-	c.O.Call("blendColor", x[0], x[1], x[2], x[3])
+	d := []gfx.ContextStateValue{
+		c.BlendColor(0, 0, 0, 0),
+		c.BlendEquation(gfx.FuncAdd),
+		c.DepthMask(true),
+		c.UseProgram(nil),
+		c.Viewport(viewport.Index(0).Int(), viewport.Index(1).Int(), viewport.Index(2).Int(), viewport.Index(3).Int()),
+		c.Scissor(scissor.Index(0).Int(), scissor.Index(1).Int(), scissor.Index(2).Int(), scissor.Index(3).Int()),
+		c.LineWidth(1),
+		c.ColorMask(true, true, true, true),
+		c.CullFace(gfx.Back),
+		c.FrontFace(gfx.CCW),
+	}
+	for f := gfx.Blend; f <= gfx.Dither; f++ {
+		d = append(d, c.feature(f, f == gfx.Dither))
+	}
+	for l := 0; l < s.MaxVertexAttribs; l++ {
+		d = append(d, c.vertexAttribArray(uint32(l), false))
+	}
+	return d
+}
+
+// queryState queries the actual value of the given key from WebGL, see the
+// state.Query type.
+func (c *Context) queryState(k s.Key, v *s.Value) bool {
+	switch k {
+	case s.BlendColor:
+		v.SetColor(float4(c.getParameter("BLEND_COLOR")))
+	case s.BlendEquation:
+		return c.queryEnum("BLEND_EQUATION_RGB", v, int(gfx.FuncAdd), int(gfx.FuncReverseSubtract))
+	case s.DepthMask:
+		v.B[0] = c.getParameter("DEPTH_WRITEMASK").Bool()
+	case s.UseProgram:
+		// The program object can only be known if it is the cached one, or no
+		// program at all.
+		var cached *js.Object
+		if v.P != nil {
+			cached = v.P.(gfx.Program).Object().(*js.Object)
+		}
+		if o := c.getParameter("CURRENT_PROGRAM"); o != cached {
+			if o != nil {
+				return false
+			}
+			v.P = nil
+		}
+	case s.Viewport:
+		v.I = int4(c.getParameter("VIEWPORT"))
+	case s.Scissor:
+		v.I = int4(c.getParameter("SCISSOR_BOX"))
+	case s.LineWidth:
+		v.F[0] = float32(c.getParameter("LINE_WIDTH").Float())
+	case s.ColorMask:
+		mask := c.getParameter("COLOR_WRITEMASK")
+		for i := range v.B {
+			v.B[i] = mask.Index(i).Bool()
+		}
+	case s.CullFace:
+		return c.queryEnum("CULL_FACE_MODE", v, int(gfx.Front), int(gfx.FrontAndBack))
+	case s.FrontFace:
+		return c.queryEnum("FRONT_FACE", v, int(gfx.CCW), int(gfx.CW))
+	default:
+		if k >= s.VertexAttribArray {
+			v.B[0] = c.O.Call("getVertexAttrib", v.U, c.O.Get("VERTEX_ATTRIB_ARRAY_ENABLED")).Bool()
+			return true
+		}
+		// Features hold their WebGL capability in v.U.
+		v.B[0] = c.O.Call("isEnabled", v.U).Bool()
+	}
+	return true
+}
+
+// queryEnum queries a WebGL enumeration, storing it and its gfx enumeration
+// (in the range of [first, last]) in v.
+func (c *Context) queryEnum(pname string, v *s.Value, first, last int) bool {
+	x := c.getParameter(pname).Int()
+	for e := first; e <= last; e++ {
+		if c.Enums[e] == x {
+			v.U, v.E = uint32(x), e
+			return true
+		}
+	}
+	return false
+}
+
+// float4 returns the first four elements of a JavaScript array as floats.
+func float4(a *js.Object) (x [4]float32) {
+	for i := range x {
+		x[i] = float32(a.Index(i).Float())
+	}
+	return
+}
+
+// int4 returns the first four elements of a JavaScript array as integers.
+func int4(a *js.Object) (x [4]int32) {
+	for i := range x {
+		x[i] = int32(a.Index(i).Int())
+	}
+	return
+}
+
+func (c *Context) glBlendColor(v *s.Value) {
+	c.O.Call("blendColor", v.F[0], v.F[1], v.F[2], v.F[3])
 }
 
 // BlendColor implements the gfx.ContextStateProvider interface.
 func (c *Context) BlendColor(r, g, b, a float32) gfx.ContextStateValue {
 	return s.CSV{
-		Value:        [4]float32{r, g, b, a},
-		DefaultValue: [4]float32{0, 0, 0, 0}, // TODO(slimsag): verify
-		Key:          csBlendColor,
-		GLCall:       c.glBlendColor,
+		Key:    s.BlendColor,
+		Value:  s.Value{F: [4]float32{r, g, b, a}},
+		GLCall: c.glBlendColor,
 	}
 }
 
-func (c *Context) glBlendEquation(v interface{}) {
-	c.O.Call("blendEquation", v.(int))
+func (c *Context) glBlendEquation(v *s.Value) {
+	c.O.Call("blendEquation", v.U)
 }
 
 // BlendEquation implements the gfx.ContextStateProvider interface.
 func (c *Context) BlendEquation(eq gfx.BlendEquation) gfx.ContextStateValue {
 	return s.CSV{
-		Value:        c.Enums[int(eq)],
-		DefaultValue: c.O.Get("FUNC_ADD").Int(), // TODO(slimsag): verify
-		Key:          csBlendEquation,
-		GLCall:       c.glBlendEquation,
+		Key:    s.BlendEquation,
+		Value:  s.Value{U: uint32(c.Enums[int(eq)]), E: int(eq)},
+		GLCall: c.glBlendEquation,
 	}
 }
 
-func (c *Context) glDepthMask(v interface{}) {
-	c.O.Call("depthMask", v.(bool))
+func (c *Context) glDepthMask(v *s.Value) {
+	c.O.Call("depthMask", v.B[0])
 }
 
 // DepthMask implements the gfx.ContextStateProvider interface.
 func (c *Context) DepthMask(m bool) gfx.ContextStateValue {
 	return s.CSV{
-		Value:        m,
-		DefaultValue: true, // TODO(slimsag): verify
-		Key:          csDepthMask,
-		GLCall:       c.glDepthMask,
+		Key:    s.DepthMask,
+		Value:  s.Value{B: [4]bool{m}},
+		GLCall: c.glDepthMask,
 	}
 }
 
-func (c *Context) glUseProgram(v interface{}) {
-	c.O.Call("useProgram", v)
+func (c *Context) glUseProgram(v *s.Value) {
+	var o *js.Object
+	if v.P != nil {
+		o = v.P.(gfx.Program).Object().(*js.Object)
+	}
+	c.fastUseProgram(o)
 }
 
 // UseProgram implements the gfx.ContextStateProvider interface.
 func (c *Context) UseProgram(p gfx.Program) gfx.ContextStateValue {
 	return s.CSV{
-		Value:        p,
-		DefaultValue: nil, // TODO(slimsag): verify
-		Key:          csUseProgram,
-		GLCall:       c.glUseProgram,
+		Key:    s.UseProgram,
+		Value:  s.Value{P: p},
+		GLCall: c.glUseProgram,
 	}
 }
 
-func (c *Context) glViewport(v interface{}) {
-	x := v.([4]int)
-	c.O.Call("viewport", x[0], x[1], x[2], x[3])
+func (c *Context) glViewport(v *s.Value) {
+	c.O.Call("viewport", v.I[0], v.I[1], v.I[2], v.I[3])
 }
 
 // Viewport implements the gfx.ContextStateProvider interface.
 func (c *Context) Viewport(x, y, width, height int) gfx.ContextStateValue {
 	return s.CSV{
-		Value:        [4]int{x, y, width, height},
-		DefaultValue: [4]int{0, 0, 0, 0}, // TODO(slimsag): track real default viewport values
-		Key:          csViewport,
-		GLCall:       c.glViewport,
+		Key:    s.Viewport,
+		Value:  s.Value{I: [4]int32{int32(x), int32(y), int32(width), int32(height)}},
+		GLCall: c.glViewport,
 	}
 }
 
-func (c *Context) glScissor(v interface{}) {
-	x := v.([4]int)
-	c.O.Call("scissor", x[0], x[1], x[2], x[3])
+func (c *Context) glScissor(v *s.Value) {
+	c.O.Call("scissor", v.I[0], v.I[1], v.I[2], v.I[3])
 }
 
 // Scissor implements the gfx.ContextStateProvider interface.
 func (c *Context) Scissor(x, y, width, height int) gfx.ContextStateValue {
 	return s.CSV{
-		Value:        [4]int{x, y, width, height},
-		DefaultValue: [4]int{0, 0, 0, 0}, // TODO(slimsag): track real default scissor values
-		Key:          csScissor,
-		GLCall:       c.glScissor,
+		Key:    s.Scissor,
+		Value:  s.Value{I: [4]int32{int32(x), int32(y), int32(width), int32(height)}},
+		GLCall: c.glScissor,
 	}
 }
 
-func (c *Context) glLineWidth(v interface{}) {
-	c.O.Call("lineWidth", v.(float64))
+func (c *Context) glLineWidth(v *s.Value) {
+	c.O.Call("lineWidth", v.F[0])
 }
 
 // LineWidth implements the gfx.ContextStateProvider interface.
 func (c *Context) LineWidth(w float32) gfx.ContextStateValue {
 	return s.CSV{
-		Value:        w,
-		DefaultValue: 1.0,
-		Key:          csLineWidth,
-		GLCall:       c.glLineWidth,
+		Key:    s.LineWidth,
+		Value:  s.Value{F: [4]float32{w}},
+		GLCall: c.glLineWidth,
 	}
 }
 
-func (c *Context) glColorMask(v interface{}) {
-	x := v.([4]bool)
-	c.O.Call("colorMask", x[0], x[1], x[2], x[3])
+func (c *Context) glColorMask(v *s.Value) {
+	c.O.Call("colorMask", v.B[0], v.B[1], v.B[2], v.B[3])
 }
 
 // ColorMask implements the gfx.ContextStateProvider interface.
 func (c *Context) ColorMask(r, g, b, a bool) gfx.ContextStateValue {
 	return s.CSV{
-		Value:        [4]bool{r, g, b, a},
-		DefaultValue: [4]bool{true, true, true, true},
-		Key:          csColorMask,
-		GLCall:       c.glColorMask,
+		Key:    s.ColorMask,
+		Value:  s.Value{B: [4]bool{r, g, b, a}},
+		GLCall: c.glColorMask,
 	}
 }
 
-func (c *Context) glCullFace(v interface{}) {
-	c.O.Call("cullFace", v.(int))
+func (c *Context) glCullFace(v *s.Value) {
+	c.O.Call("cullFace", v.U)
 }
 
 // CullFace implements the gfx.ContextStateProvider interface.
 func (c *Context) CullFace(f gfx.Facet) gfx.ContextStateValue {
 	return s.CSV{
-		Value:        c.Enums[int(f)],
-		DefaultValue: c.O.Get("FRONT").Int(), // TODO(slimsag): verify
-		Key:          csCullFace,
-		GLCall:       c.glCullFace,
+		Key:    s.CullFace,
+		Value:  s.Value{U: uint32(c.Enums[int(f)]), E: int(f)},
+		GLCall: c.glCullFace,
 	}
 }
 
-func (c *Context) glFrontFace(v interface{}) {
-	c.O.Call("frontFace", v.(int))
+func (c *Context) glFrontFace(v *s.Value) {
+	c.O.Call("frontFace", v.U)
 }
 
 // FrontFace implements the gfx.ContextStateProvider interface.
 func (c *Context) FrontFace(o gfx.Orientation) gfx.ContextStateValue {
 	return s.CSV{
-		Value:        c.Enums[int(o)],
-		DefaultValue: c.O.Get("CCW").Int(), // TODO(slimsag): verify
-		Key:          csFrontFace,
-		GLCall:       c.glFrontFace,
+		Key:    s.FrontFace,
+		Value:  s.Value{U: uint32(c.Enums[int(o)]), E: int(o)},
+		GLCall: c.glFrontFace,
 	}
 }
 
-type featureKey struct {
-	csKey int
-	f     gfx.Feature
-}
-
-func (c *Context) glEnable(v interface{}) {
-	c.O.Call("enable", v.(int))
+// glFeature enables or disables the WebGL capability v.U, depending on
+// v.B[0].
+func (c *Context) glFeature(v *s.Value) {
+	if v.B[0] {
+		c.O.Call("enable", v.U)
+		return
+	}
+	c.O.Call("disable", v.U)
 }
 
-// Enable implements the gfx.ContextStateProvider interface.
-func (c *Context) Enable(f gfx.Feature) gfx.ContextStateValue {
+// feature returns a state value for the given feature. All features are
+// disabled by default, except for dithering.
+func (c *Context) feature(f gfx.Feature, enabled bool) gfx.ContextStateValue {
 	return s.CSV{
-		Value:        c.Enums[int(f)],
-		DefaultValue: 0, // TODO(slimsag): default feature values!
-		Key: featureKey{
-			csKey: csEnable,
-			f:     f,
-		},
-		GLCall: c.glEnable,
+		Key:    s.FeatureKey(f),
+		Value:  s.Value{U: uint32(c.Enums[int(f)]), E: int(f), B: [4]bool{enabled}},
+		GLCall: c.glFeature,
 	}
 }
 
-func (c *Context) glDisable(v interface{}) {
-	c.O.Call("disable", v.(int))
+// Enable implements the gfx.ContextStateProvider interface.
+func (c *Context) Enable(f gfx.Feature) gfx.ContextStateValue {
+	return c.feature(f, true)
 }
 
 // Disable implements the gfx.ContextStateProvider interface.
 func (c *Context) Disable(f gfx.Feature) gfx.ContextStateValue {
-	return s.CSV{
-		Value:        c.Enums[int(f)],
-		DefaultValue: 0, // TODO(slimsag): default feature values!
-		Key: featureKey{
-			csKey: csDisable,
-			f:     f,
-		},
-		GLCall: c.glDisable,
+	return c.feature(f, false)
+}
+
+// glVertexAttribArray enables or disables the vertex attribute array at
+// location v.U, depending on v.B[0].
+func (c *Context) glVertexAttribArray(v *s.Value) {
+	if v.B[0] {
+		c.O.Call("enableVertexAttribArray", v.U)
+		return
 	}
+	c.O.Call("disableVertexAttribArray", v.U)
 }
 
-func (c *Context) glEnableVertexAttribArray(v interface{}) {
-	c.O.Call("enableVertexAttribArray", v.(int))
+// vertexAttribArray returns a state value for the vertex attribute array at
+// the given location. All arrays are disabled by default.
+func (c *Context) vertexAttribArray(l uint32, enabled bool) gfx.ContextStateValue {
+	return s.CSV{
+		Key:    s.VertexAttribKey(int(l)),
+		Value:  s.Value{U: l, B: [4]bool{enabled}},
+		GLCall: c.glVertexAttribArray,
+	}
 }
 
 // EnableVertexAttribArray implements the gfx.ContextStateProvider interface.
 func (c *Context) EnableVertexAttribArray(l gfx.AttribLocation) gfx.ContextStateValue {
-	return s.CSV{
-		Value:        l,
-		DefaultValue: 0, // TODO(slimsag): default enable VAA values!
-		Key:          csEnableVertexAttribArray,
-		GLCall:       c.glEnableVertexAttribArray,
-	}
+	return c.vertexAttribArray(uint32(l.(int)), true)
 }
@@ -23,20 +23,16 @@ type Framebuffer struct {
 	o *js.Object
 
 	ctx *Context
-
-	// State tied to this framebuffer object.
-	clearColor   [4]float32
-	clearDepth   float64
-	clearStencil int
 }
 
 // useState binds the global OpenGL state for this local Framebuffer object.
 func (f *Framebuffer) useState() {
 	// Bind the framebuffer now.
-	if f.ctx.fastBindFramebuffer(f.o) {
-		f.GLCall(nil)
-	}
-	f.GLCall(f.Loaded)
+	f.ctx.fastBindFramebuffer(f.o)
+
+	// Framebuffer state is global to the WebGL context, so apply the state
+	// loaded by this framebuffer.
+	f.ctx.fbState.Apply(f.Loaded)
 }
 
 // Clear implements the gfx.Framebuffer interface.
@@ -120,6 +116,7 @@ func (f *Framebuffer) Delete() {
 		return
 	}
 	f.ctx.O.Call("deleteFramebuffer", f.o)
+	f.ctx.forgetFramebuffer(f.o)
 	f.o = nil
 }
 
@@ -128,51 +125,65 @@ func (f *Framebuffer) Object() interface{} {
 	return f.o
 }
 
-const (
-	csClearColor = iota
-	csClearDepth
-	csClearStencil
-)
+// defaultState returns the default value of each piece of framebuffer state,
+// as specified by WebGL.
+func (f *Framebuffer) defaultState() []s.CSV {
+	return []s.CSV{
+		f.ClearColor(0, 0, 0, 0).(s.CSV),
+		f.ClearDepth(1).(s.CSV),
+		f.ClearStencil(0).(s.CSV),
+	}
+}
+
+// queryFramebufferState queries the actual value of the given framebuffer
+// state key from WebGL, see the state.Query type.
+func (c *Context) queryFramebufferState(k s.Key, v *s.Value) bool {
+	switch k {
+	case s.ClearColor:
+		v.SetColor(float4(c.getParameter("COLOR_CLEAR_VALUE")))
+	case s.ClearDepth:
+		v.SetDepth(c.getParameter("DEPTH_CLEAR_VALUE").Float())
+	case s.ClearStencil:
+		v.I[0] = int32(c.getParameter("STENCIL_CLEAR_VALUE").Int())
+	}
+	return true
+}
 
-func (c *Context) glClearColor(v interface{}) {
-	x := v.([4]float32)
-	c.O.Call("clearColor", x[0], x[1], x[2], x[3])
+func (c *Context) glClearColor(v *s.Value) {
+	c.O.Call("clearColor", v.F[0], v.F[1], v.F[2], v.F[3])
 }
 
-// ClearColor implements the gfx.ContextStateProvider interface.
+// ClearColor implements the gfx.FramebufferStateProvider interface.
 func (f *Framebuffer) ClearColor(r, g, b, a float32) gfx.FramebufferStateValue {
 	return s.CSV{
-		Value:        [4]float32{r, g, b, a},
-		DefaultValue: [4]float32{0, 0, 0, 0}, // TODO(slimsag): verify
-		Key:          csClearColor,
-		GLCall:       f.ctx.glClearColor,
+		Key:    s.ClearColor,
+		Value:  s.Value{F: [4]float32{r, g, b, a}},
+		GLCall: f.ctx.glClearColor,
 	}
 }
 
-func (c *Context) glClearDepth(v interface{}) {
-	c.O.Call("clearDepth", v.(float64))
+func (c *Context) glClearDepth(v *s.Value) {
+	c.O.Call("clearDepth", v.D)
 }
 
-// ClearDepth implements the gfx.ContextStateProvider interface.
+// ClearDepth implements the gfx.FramebufferStateProvider interface.
 func (f *Framebuffer) ClearDepth(depth float64) gfx.FramebufferStateValue {
 	return s.CSV{
-		Value:        depth,
-		DefaultValue: 0, // TODO(slimsag): verify
-		Key:          csClearDepth,
-		GLCall:       f.ctx.glClearDepth,
+		Key:    s.ClearDepth,
+		Value:  s.Value{D: depth},
+		GLCall: f.ctx.glClearDepth,
 	}
 }
 
-func (c *Context) glClearStencil(v interface{}) {
-	c.O.Call("clearStencil", v.(int))
+func (c *Context) glClearStencil(v *s.Value) {
+	c.O.Call("clearStencil", v.I[0])
 }
 
-// ClearStencil implements the gfx.ContextStateProvider interface.
+// ClearStencil implements the gfx.FramebufferStateProvider interface.
 func (f *Framebuffer) ClearStencil(stencil int) gfx.FramebufferStateValue {
 	return s.CSV{
-		Value:        stencil,
-		DefaultValue: 0, // TODO(slimsag): verify
-		Key:          csClearStencil,
-		GLCall:       f.ctx.glClearStencil,
+		Key:    s.ClearStencil,
+		Value:  s.Value{I: [4]int32{int32(stencil)}},
+		GLCall: f.ctx.glClearStencil,
 	}
 }
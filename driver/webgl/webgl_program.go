@@ -122,6 +122,7 @@ func (p *Program) Delete() {
 		return
 	}
 	p.ctx.O.Call("deleteProgram", p.o)
+	p.ctx.forgetProgram(p.o)
 	p.o = nil
 }
 
@@ -38,6 +38,7 @@ func (r *Renderbuffer) Delete() {
 		return
 	}
 	r.ctx.O.Call("deleteRenderbuffer", r.o)
+	r.ctx.forgetRenderbuffer(r.o)
 	r.o = nil
 }
 
@@ -142,6 +142,11 @@ const (
 	// ScissorTest abandons fragments outside the scissor rectangle.
 	ScissorTest
 
+	// Dither is a feature that dithers color components before they are
+	// written to the color buffer. Unlike all other features, it is enabled
+	// by default.
+	Dither
+
 	// CCW is a orientation for a counterclockwise winding. It is the initial
 	// orientation.
 	CCW Orientation = iota
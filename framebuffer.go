@@ -84,13 +84,34 @@ type FramebufferState interface{}
 
 // FramebufferStateProvider provides access to a framebuffer's state.
 type FramebufferStateProvider interface {
-	// NewState returns a new framebuffer state for the given values.
+	// NewState returns a new framebuffer state for the given values. If
+	// multiple values are given for the same piece of state then the last one
+	// takes precedence.
 	NewState(values ...FramebufferStateValue) FramebufferState
 
 	// Load loads the given framebuffer state, replacing the
 	// previous one. If s == nil then the default state is loaded.
 	Load(s FramebufferState)
 
+	// Current returns the currently loaded framebuffer state, exactly as it
+	// was given to Load. It returns nil if the default state is loaded.
+	Current() FramebufferState
+
+	// Describe returns a description of every piece of state in the given
+	// framebuffer state, including those which hold their default value. If
+	// s == nil the default state is described.
+	Describe(s FramebufferState) StateInfo
+
+	// PushState pushes the currently loaded framebuffer state onto a stack,
+	// such that it can later be restored by PopState.
+	PushState()
+
+	// PopState removes the framebuffer state most recently pushed by
+	// PushState from the stack, and loads it. If the stack is empty, the
+	// default state is loaded instead (framebuffers from the debug package
+	// panic).
+	PopState()
+
 	// ClearColor sets the color to clear the color buffer to upon a call to
 	// the Clear method.
 	ClearColor(r, g, b, a float32) FramebufferStateValue
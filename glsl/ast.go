@@ -0,0 +1,209 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package glsl
+
+// expr is an expression. The checker records the type of each expression,
+// and resolves the identifiers and calls it contains.
+type expr interface {
+	base() *exprBase
+}
+
+type exprBase struct {
+	line int
+	typ  *Type
+
+	// konst holds the value of a constant expression, once checked.
+	konst []float32
+
+	// effects tells if evaluating the expression may have side effects, from
+	// assignments or function calls.
+	effects bool
+}
+
+func (e *exprBase) base() *exprBase { return e }
+
+type (
+	// identExpr is a reference to a variable.
+	identExpr struct {
+		exprBase
+		name string
+		v    *variable
+	}
+
+	// literalExpr is a literal constant, whose value is in konst.
+	literalExpr struct {
+		exprBase
+	}
+
+	// unaryExpr is a prefix operation: +, -, !, ++ or --.
+	unaryExpr struct {
+		exprBase
+		op string
+		x  expr
+	}
+
+	// postfixExpr is a postfix ++ or -- operation.
+	postfixExpr struct {
+		exprBase
+		op string
+		x  expr
+	}
+
+	// binaryExpr is a binary operation, other than an assignment or a
+	// sequence.
+	binaryExpr struct {
+		exprBase
+		op   string
+		x, y expr
+	}
+
+	// assignExpr is an assignment, with op being "=" or e.g. "+=".
+	assignExpr struct {
+		exprBase
+		op   string
+		x, y expr
+	}
+
+	// seqExpr is a sequence of expressions, separated by commas.
+	seqExpr struct {
+		exprBase
+		list []expr
+	}
+
+	// condExpr is a conditional expression.
+	condExpr struct {
+		exprBase
+		cond, x, y expr
+	}
+
+	// callExpr is a function call or constructor. The checker resolves it to
+	// either a user-defined function, a built-in function or a constructor
+	// of typ.
+	callExpr struct {
+		exprBase
+		name string
+		args []expr
+
+		fn      *function
+		builtin *builtin
+		ctor    bool
+	}
+
+	// indexExpr is the indexing of an array, vector or matrix.
+	indexExpr struct {
+		exprBase
+		x, index expr
+	}
+
+	// fieldExpr is the selection of a structure field, or a swizzle.
+	fieldExpr struct {
+		exprBase
+		x    expr
+		name string
+
+		// Set by the checker: the offset of the field in the structure, or
+		// the components of the swizzle.
+		offset  int
+		swizzle []int
+	}
+)
+
+// stmt is a statement.
+type stmt interface{}
+
+type (
+	blockStmt struct {
+		list []stmt
+
+		// scope tells if the block introduces a new scope, which it does not
+		// for the body of a function or loop.
+		scope bool
+	}
+
+	exprStmt struct {
+		x expr
+	}
+
+	// declStmt declares variables, with optional initializers.
+	declStmt struct {
+		vars  []*variable
+		inits []expr
+	}
+
+	ifStmt struct {
+		cond      expr
+		then, els stmt
+	}
+
+	// forStmt is any loop: a for loop, a while loop, or a do-while loop if
+	// do is set.
+	forStmt struct {
+		init stmt
+		cond expr // nil for no condition.
+		post expr
+		body stmt
+		do   bool
+
+		// condVar is the variable declared by the condition of a while loop,
+		// e.g. while (bool b = f()).
+		condVar *variable
+	}
+
+	returnStmt struct {
+		line int
+		x    expr
+	}
+
+	branchStmt struct {
+		tok string // "break", "continue" or "discard".
+	}
+)
+
+// qualifier is the storage qualifier of a variable.
+type qualifier int
+
+const (
+	qualNone qualifier = iota
+	qualConst
+	qualAttribute
+	qualUniform
+	qualVarying
+
+	// Qualifiers of function parameters.
+	qualIn
+	qualOut
+	qualInOut
+)
+
+// variable is a global or local variable, or a function parameter.
+type variable struct {
+	name string
+	typ  *Type
+	qual qualifier
+	line int
+
+	// global tells if the variable is global, and index is its slot in the
+	// globals of a State or in the locals of a function call.
+	global bool
+	index  int
+
+	// readOnly tells if the variable cannot be assigned to.
+	readOnly bool
+
+	// konst holds the value of a constant variable.
+	konst []float32
+}
+
+// function is a user-defined function.
+type function struct {
+	name   string
+	ret    *Type
+	params []*variable
+	body   *blockStmt
+	line   int
+
+	// locals is the number of local variable slots used by a call.
+	locals int
+}
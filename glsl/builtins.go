@@ -0,0 +1,458 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package glsl
+
+import "math"
+
+// builtin is a built-in function.
+type builtin struct {
+	// check returns the type of the result of a call with arguments of the
+	// given types, or nil if there is no such overload.
+	check func(args []*Type) *Type
+
+	// eval computes the result of a call. st is nil when the call is
+	// evaluated as a constant expression.
+	eval func(st *State, args [][]float32, types []*Type, ret *Type) []float32
+
+	// texture tells if the function is a texture lookup function, which is
+	// never a constant expression, and vertexOnly if it may only be used by
+	// vertex shaders.
+	texture, vertexOnly bool
+}
+
+// isGen tells if t is a genType of the specification: float or a float
+// vector.
+func isGen(t *Type) bool {
+	return t.Kind == Float && t.Cols == 0
+}
+
+// gen matches arguments that are all of the same genType, except for those
+// at the given indices, which may also be floats. It returns the genType.
+func gen(n int, scalars ...int) func(args []*Type) *Type {
+	return func(args []*Type) *Type {
+		if len(args) != n {
+			return nil
+		}
+		var t *Type
+		for i, a := range args {
+			if !isGen(a) {
+				return nil
+			}
+			scalar := false
+			for _, s := range scalars {
+				scalar = scalar || s == i
+			}
+			if scalar && a == floatType {
+				continue
+			}
+			if t != nil && !t.Equal(a) {
+				return nil
+			}
+			t = a
+		}
+		if t == nil {
+			t = floatType
+		}
+		// Scalar arguments are either all floats, or all of the genType.
+		for _, s := range scalars {
+			if args[s] != floatType && args[s] != t {
+				return nil
+			}
+		}
+		return t
+	}
+}
+
+// returning changes the result type of a check function to t.
+func returning(t *Type, check func(args []*Type) *Type) func(args []*Type) *Type {
+	return func(args []*Type) *Type {
+		if check(args) == nil {
+			return nil
+		}
+		return t
+	}
+}
+
+// arg returns component i of an argument, which is broadcast if it is a
+// scalar.
+func arg(a []float32, i int) float64 {
+	if len(a) == 1 {
+		return float64(a[0])
+	}
+	return float64(a[i])
+}
+
+// componentwise returns an eval function that applies f to each component of
+// the arguments.
+func componentwise(f func(x []float64) float64) func(*State, [][]float32, []*Type, *Type) []float32 {
+	return func(_ *State, args [][]float32, _ []*Type, ret *Type) []float32 {
+		r := make([]float32, ret.Components())
+		x := make([]float64, len(args))
+		for i := range r {
+			for k, a := range args {
+				x[k] = arg(a, i)
+			}
+			r[i] = float32(f(x))
+		}
+		return r
+	}
+}
+
+func unary(f func(x float64) float64) *builtin {
+	return &builtin{
+		check: gen(1),
+		eval:  componentwise(func(x []float64) float64 { return f(x[0]) }),
+	}
+}
+
+func dot(x, y []float32) float64 {
+	var s float64
+	for i := range x {
+		s += float64(x[i]) * float64(y[i])
+	}
+	return s
+}
+
+func scalar(v float64) []float32 {
+	return []float32{float32(v)}
+}
+
+func scale(x []float32, s float64) []float32 {
+	r := make([]float32, len(x))
+	for i := range x {
+		r[i] = float32(float64(x[i]) * s)
+	}
+	return r
+}
+
+// vecCompare matches two float or int vectors of the same type, or any vectors
+// of the same type if withBool is set, returning the bvec of the same size.
+func vecCompare(withBool bool) func(args []*Type) *Type {
+	return func(args []*Type) *Type {
+		if len(args) != 2 || !args[0].isVector() || !args[0].Equal(args[1]) {
+			return nil
+		}
+		if args[0].Kind == Bool && !withBool {
+			return nil
+		}
+		return bvecTypes[args[0].Size]
+	}
+}
+
+func compare(f func(x, y float32) bool) *builtin {
+	return &builtin{
+		check: vecCompare(false),
+		eval: func(_ *State, args [][]float32, _ []*Type, ret *Type) []float32 {
+			r := make([]float32, ret.Size)
+			for i := range r {
+				r[i] = b2f(f(args[0][i], args[1][i]))
+			}
+			return r
+		},
+	}
+}
+
+// equality returns the equal or notEqual function.
+func equality(want bool) *builtin {
+	return &builtin{
+		check: vecCompare(true),
+		eval: func(_ *State, args [][]float32, types []*Type, ret *Type) []float32 {
+			r := make([]float32, ret.Size)
+			for i := range r {
+				x, y := args[0][i], args[1][i]
+				if types[0].Kind == Bool {
+					x, y = b2f(x != 0), b2f(y != 0)
+				}
+				r[i] = b2f((x == y) == want)
+			}
+			return r
+		},
+	}
+}
+
+// boolVec matches a single bvec, returning t or the bvec if t is nil.
+func boolVec(t *Type) func(args []*Type) *Type {
+	return func(args []*Type) *Type {
+		if len(args) != 1 || args[0].Kind != Bool || !args[0].isVector() {
+			return nil
+		}
+		if t == nil {
+			return args[0]
+		}
+		return t
+	}
+}
+
+// texture returns a texture lookup function taking a sampler of the given
+// kind, coordinates of one of the given sizes and, if lod is set, a level of
+// detail or, otherwise, an optional bias.
+func texture(kind Kind, sizes []int, lod bool) *builtin {
+	return &builtin{
+		texture:    true,
+		vertexOnly: lod,
+		check: func(args []*Type) *Type {
+			n := len(args)
+			if n < 2 || n > 3 || (lod && n != 3) || args[0].Kind != kind {
+				return nil
+			}
+			if n == 3 && args[2] != floatType {
+				return nil
+			}
+			for _, size := range sizes {
+				if args[1] == vectorType(Float, size) {
+					return vecTypes[4]
+				}
+			}
+			return nil
+		},
+		eval: func(st *State, args [][]float32, types []*Type, ret *Type) []float32 {
+			var coord [3]float32
+			c := args[1]
+			copy(coord[:], c)
+			if kind == Sampler2D && len(c) > 2 {
+				// A projective lookup: divide by the last component.
+				q := c[len(c)-1]
+				coord = [3]float32{c[0] / q, c[1] / q}
+			}
+			color := [4]float32{0, 0, 0, 1}
+			if st.Sampler != nil {
+				color = st.Sampler.Sample(int(args[0][0]), kind == SamplerCube, coord)
+			}
+			return color[:]
+		},
+	}
+}
+
+// builtins are the built-in functions, by name.
+var builtins = map[string]*builtin{
+	"radians": unary(func(x float64) float64 { return x * math.Pi / 180 }),
+	"degrees": unary(func(x float64) float64 { return x * 180 / math.Pi }),
+	"sin":     unary(math.Sin),
+	"cos":     unary(math.Cos),
+	"tan":     unary(math.Tan),
+	"asin":    unary(math.Asin),
+	"acos":    unary(math.Acos),
+	"atan": {
+		check: func(args []*Type) *Type {
+			if len(args) == 1 {
+				return gen(1)(args)
+			}
+			return gen(2)(args)
+		},
+		eval: componentwise(func(x []float64) float64 {
+			if len(x) == 1 {
+				return math.Atan(x[0])
+			}
+			return math.Atan2(x[0], x[1])
+		}),
+	},
+	"pow": {
+		check: gen(2),
+		eval:  componentwise(func(x []float64) float64 { return math.Pow(x[0], x[1]) }),
+	},
+	"exp":  unary(math.Exp),
+	"log":  unary(math.Log),
+	"exp2": unary(math.Exp2),
+	"log2": unary(math.Log2),
+	"sqrt": unary(math.Sqrt),
+	"inversesqrt": unary(func(x float64) float64 {
+		return 1 / math.Sqrt(x)
+	}),
+	"abs": unary(math.Abs),
+	"sign": unary(func(x float64) float64 {
+		switch {
+		case x > 0:
+			return 1
+		case x < 0:
+			return -1
+		}
+		return 0
+	}),
+	"floor": unary(math.Floor),
+	"ceil":  unary(math.Ceil),
+	"fract": unary(func(x float64) float64 { return x - math.Floor(x) }),
+	"mod": {
+		check: gen(2, 1),
+		eval: componentwise(func(x []float64) float64 {
+			return x[0] - x[1]*math.Floor(x[0]/x[1])
+		}),
+	},
+	"min": {
+		check: gen(2, 1),
+		eval:  componentwise(func(x []float64) float64 { return math.Min(x[0], x[1]) }),
+	},
+	"max": {
+		check: gen(2, 1),
+		eval:  componentwise(func(x []float64) float64 { return math.Max(x[0], x[1]) }),
+	},
+	"clamp": {
+		check: gen(3, 1, 2),
+		eval: componentwise(func(x []float64) float64 {
+			return math.Min(math.Max(x[0], x[1]), x[2])
+		}),
+	},
+	"mix": {
+		check: gen(3, 2),
+		eval: componentwise(func(x []float64) float64 {
+			return x[0]*(1-x[2]) + x[1]*x[2]
+		}),
+	},
+	"step": {
+		check: gen(2, 0),
+		eval: componentwise(func(x []float64) float64 {
+			if x[1] < x[0] {
+				return 0
+			}
+			return 1
+		}),
+	},
+	"smoothstep": {
+		check: gen(3, 0, 1),
+		eval: componentwise(func(x []float64) float64 {
+			t := math.Min(math.Max((x[2]-x[0])/(x[1]-x[0]), 0), 1)
+			return t * t * (3 - 2*t)
+		}),
+	},
+	"length": {
+		check: returning(floatType, gen(1)),
+		eval: func(_ *State, args [][]float32, _ []*Type, _ *Type) []float32 {
+			return scalar(math.Sqrt(dot(args[0], args[0])))
+		},
+	},
+	"distance": {
+		check: returning(floatType, gen(2)),
+		eval: func(_ *State, args [][]float32, _ []*Type, _ *Type) []float32 {
+			var s float64
+			for i := range args[0] {
+				d := float64(args[0][i]) - float64(args[1][i])
+				s += d * d
+			}
+			return scalar(math.Sqrt(s))
+		},
+	},
+	"dot": {
+		check: returning(floatType, gen(2)),
+		eval: func(_ *State, args [][]float32, _ []*Type, _ *Type) []float32 {
+			return scalar(dot(args[0], args[1]))
+		},
+	},
+	"cross": {
+		check: func(args []*Type) *Type {
+			if len(args) != 2 || args[0] != vecTypes[3] || args[1] != vecTypes[3] {
+				return nil
+			}
+			return vecTypes[3]
+		},
+		eval: func(_ *State, args [][]float32, _ []*Type, _ *Type) []float32 {
+			x, y := args[0], args[1]
+			return []float32{
+				x[1]*y[2] - y[1]*x[2],
+				x[2]*y[0] - y[2]*x[0],
+				x[0]*y[1] - y[0]*x[1],
+			}
+		},
+	},
+	"normalize": {
+		check: gen(1),
+		eval: func(_ *State, args [][]float32, _ []*Type, _ *Type) []float32 {
+			return scale(args[0], 1/math.Sqrt(dot(args[0], args[0])))
+		},
+	},
+	"faceforward": {
+		check: gen(3),
+		eval: func(_ *State, args [][]float32, _ []*Type, _ *Type) []float32 {
+			if dot(args[2], args[1]) < 0 {
+				return scale(args[0], 1)
+			}
+			return scale(args[0], -1)
+		},
+	},
+	"reflect": {
+		check: gen(2),
+		eval: func(_ *State, args [][]float32, _ []*Type, _ *Type) []float32 {
+			i, n := args[0], args[1]
+			d := 2 * dot(n, i)
+			r := make([]float32, len(i))
+			for k := range r {
+				r[k] = float32(float64(i[k]) - d*float64(n[k]))
+			}
+			return r
+		},
+	},
+	"refract": {
+		check: func(args []*Type) *Type {
+			if len(args) != 3 || args[2] != floatType {
+				return nil
+			}
+			return gen(2)(args[:2])
+		},
+		eval: func(_ *State, args [][]float32, _ []*Type, _ *Type) []float32 {
+			i, n, eta := args[0], args[1], float64(args[2][0])
+			d := dot(n, i)
+			k := 1 - eta*eta*(1-d*d)
+			r := make([]float32, len(i))
+			if k < 0 {
+				return r
+			}
+			for j := range r {
+				r[j] = float32(eta*float64(i[j]) - (eta*d+math.Sqrt(k))*float64(n[j]))
+			}
+			return r
+		},
+	},
+	"matrixCompMult": {
+		check: func(args []*Type) *Type {
+			if len(args) != 2 || !args[0].isMatrix() || !args[0].Equal(args[1]) {
+				return nil
+			}
+			return args[0]
+		},
+		eval: componentwise(func(x []float64) float64 { return x[0] * x[1] }),
+	},
+	"lessThan":         compare(func(x, y float32) bool { return x < y }),
+	"lessThanEqual":    compare(func(x, y float32) bool { return x <= y }),
+	"greaterThan":      compare(func(x, y float32) bool { return x > y }),
+	"greaterThanEqual": compare(func(x, y float32) bool { return x >= y }),
+	"equal":            equality(true),
+	"notEqual":         equality(false),
+	"any": {
+		check: boolVec(boolType),
+		eval: func(_ *State, args [][]float32, _ []*Type, _ *Type) []float32 {
+			for _, x := range args[0] {
+				if x != 0 {
+					return []float32{1}
+				}
+			}
+			return []float32{0}
+		},
+	},
+	"all": {
+		check: boolVec(boolType),
+		eval: func(_ *State, args [][]float32, _ []*Type, _ *Type) []float32 {
+			for _, x := range args[0] {
+				if x == 0 {
+					return []float32{0}
+				}
+			}
+			return []float32{1}
+		},
+	},
+	"not": {
+		check: boolVec(nil),
+		eval: func(_ *State, args [][]float32, _ []*Type, _ *Type) []float32 {
+			r := make([]float32, len(args[0]))
+			for i, x := range args[0] {
+				r[i] = b2f(x == 0)
+			}
+			return r
+		},
+	},
+	"texture2D":        texture(Sampler2D, []int{2}, false),
+	"texture2DProj":    texture(Sampler2D, []int{3, 4}, false),
+	"texture2DLod":     texture(Sampler2D, []int{2}, true),
+	"texture2DProjLod": texture(Sampler2D, []int{3, 4}, true),
+	"textureCube":      texture(SamplerCube, []int{3}, false),
+	"textureCubeLod":   texture(SamplerCube, []int{3}, true),
+}
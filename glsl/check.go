@@ -0,0 +1,472 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package glsl
+
+import (
+	"strings"
+
+	"github.com/slimsag/gfx"
+)
+
+// qualifierNames are the names of the storage qualifiers, for errors.
+var qualifierNames = [...]string{
+	qualNone:      "variable",
+	qualConst:     "constant",
+	qualAttribute: "attribute",
+	qualUniform:   "uniform",
+	qualVarying:   "varying",
+	qualIn:        "parameter",
+	qualOut:       "parameter",
+	qualInOut:     "parameter",
+}
+
+// checkDecl checks the declaration of a variable, and determines whether it
+// is read-only.
+func (p *parser) checkDecl(v *variable, global bool) {
+	t, q := v.typ, v.qual
+	if t.Kind == Array {
+		t = t.Elem
+	}
+	switch {
+	case t.Kind == Void:
+		p.fail(v.line, "variable %s has type void", v.name)
+	case (q == qualAttribute || q == qualUniform || q == qualVarying) && !global:
+		p.fail(v.line, "%s %s must be global", qualifierNames[q], v.name)
+	case q == qualAttribute && p.s.Type != gfx.VertexShader:
+		p.fail(v.line, "attributes are only allowed in vertex shaders")
+	case q == qualAttribute && (t.Kind != Float || v.typ.Kind == Array):
+		p.fail(v.line, "attribute %s must have a float, vector or matrix type", v.name)
+	case q == qualVarying && t.Kind != Float:
+		p.fail(v.line, "varying %s must have a float, vector or matrix type", v.name)
+	case v.typ.hasSampler() && q != qualUniform:
+		p.fail(v.line, "sampler %s must be a uniform", v.name)
+	}
+	v.readOnly = q == qualConst || q == qualAttribute || q == qualUniform ||
+		(q == qualVarying && p.s.Type == gfx.FragmentShader)
+}
+
+// checkInit checks the initializer of a variable.
+func (p *parser) checkInit(v *variable, init expr, global bool) {
+	x := init.base()
+	switch {
+	case v.qual == qualAttribute || v.qual == qualUniform || v.qual == qualVarying:
+		p.fail(v.line, "%s %s cannot be initialized", qualifierNames[v.qual], v.name)
+	case v.typ.Kind == Array:
+		p.fail(v.line, "array %s cannot be initialized", v.name)
+	case !x.typ.Equal(v.typ):
+		p.fail(v.line, "cannot initialize %s of type %s with a value of type %s", v.name, v.typ, x.typ)
+	case (v.qual == qualConst || global) && x.konst == nil:
+		p.fail(v.line, "initializer of %s must be a constant expression", v.name)
+	}
+	if v.qual == qualConst || global {
+		v.konst = x.konst
+	}
+}
+
+// checkReturn checks a return statement.
+func (p *parser) checkReturn(s *returnStmt) {
+	ret := p.fn.ret
+	switch {
+	case s.x == nil && ret != voidType:
+		p.fail(s.line, "missing return value")
+	case s.x != nil && ret == voidType:
+		p.fail(s.line, "function %s cannot return a value", p.fn.name)
+	case s.x != nil && !s.x.base().typ.Equal(ret):
+		p.fail(s.line, "cannot return a value of type %s from function %s of type %s", s.x.base().typ, p.fn.name, ret)
+	}
+}
+
+// boolExpr checks that x is a boolean condition.
+func (p *parser) boolExpr(x expr) expr {
+	if x.base().typ != boolType {
+		p.fail(x.base().line, "condition must be a bool, not %s", x.base().typ)
+	}
+	return x
+}
+
+func (p *parser) checkIdent(name string, line int) expr {
+	switch x := p.lookup(name).(type) {
+	case *variable:
+		e := &identExpr{exprBase: exprBase{line: line, typ: x.typ}, name: name, v: x}
+		if x.qual == qualConst {
+			e.konst = x.konst
+		}
+		switch name {
+		case "gl_FragColor":
+			p.usedFragColor = true
+		case "gl_FragData":
+			p.usedFragData = true
+		}
+		return e
+	case *Type:
+		p.fail(line, "%s is a type, not a value", name)
+	}
+	if len(p.funcs[name]) > 0 || builtins[name] != nil {
+		p.fail(line, "function %s used as a value", name)
+	}
+	p.fail(line, "undeclared identifier %s", name)
+	return nil
+}
+
+// checkLvalue checks that x can be assigned to.
+func (p *parser) checkLvalue(x expr, line int) {
+	p.lvalue(x, line, true)
+}
+
+// lvalue checks that x, or a part of it if whole is false, can be assigned
+// to.
+func (p *parser) lvalue(x expr, line int, whole bool) {
+	switch x := x.(type) {
+	case *identExpr:
+		if x.v.readOnly {
+			p.fail(line, "cannot assign to %s %s", qualifierNames[x.v.qual], x.name)
+		}
+		if (whole && x.typ.Kind == Array) || x.typ.hasSampler() {
+			p.fail(line, "cannot assign to %s of type %s", x.name, x.typ)
+		}
+	case *indexExpr:
+		p.lvalue(x.x, line, false)
+	case *fieldExpr:
+		seen := map[int]bool{}
+		for _, i := range x.swizzle {
+			if seen[i] {
+				p.fail(line, "cannot assign to swizzle %s with repeated components", x.name)
+			}
+			seen[i] = true
+		}
+		p.lvalue(x.x, line, false)
+	default:
+		p.fail(line, "cannot assign to expression")
+	}
+}
+
+// arithType returns the type of the result of an arithmetic operation, or
+// nil if the operands have invalid types.
+func arithType(op string, x, y *Type) *Type {
+	if !x.isNumeric() || !y.isNumeric() || x.Kind != y.Kind {
+		return nil
+	}
+	switch {
+	case x.Equal(y):
+		return x
+	case x.isScalar():
+		return y
+	case y.isScalar():
+		return x
+	case op == "*" && x.isMatrix() && y.isVector() && x.Cols == y.Size:
+		return vecTypes[x.Size]
+	case op == "*" && x.isVector() && y.isMatrix() && x.Size == y.Size:
+		return vecTypes[y.Cols]
+	}
+	return nil
+}
+
+func (p *parser) checkBinary(op string, x, y expr, line int) expr {
+	xt, yt := x.base().typ, y.base().typ
+	var t *Type
+	switch op {
+	case "|", "^", "&", "<<", ">>", "%":
+		p.fail(line, "operator %s is reserved", op)
+	case "||", "&&", "^^":
+		if xt == boolType && yt == boolType {
+			t = boolType
+		}
+	case "==", "!=":
+		if xt.Equal(yt) && xt.Kind != Array && xt.Kind != Void && !xt.hasSampler() {
+			t = boolType
+		}
+	case "<", ">", "<=", ">=":
+		if xt.Equal(yt) && xt.isScalar() && xt.isNumeric() {
+			t = boolType
+		}
+	default:
+		t = arithType(op, xt, yt)
+	}
+	if t == nil {
+		p.fail(line, "invalid operation: %s %s %s", xt, op, yt)
+	}
+	e := &binaryExpr{exprBase: exprBase{line: line, typ: t}, op: op, x: x, y: y}
+	e.effects = x.base().effects || y.base().effects
+	if xk, yk := x.base().konst, y.base().konst; xk != nil && yk != nil {
+		e.konst = binaryOp(op, xt, yt, t, xk, yk)
+	}
+	return e
+}
+
+func (p *parser) checkUnary(op string, x expr, line int) expr {
+	xt := x.base().typ
+	switch op {
+	case "~":
+		p.fail(line, "operator ~ is reserved")
+	case "!":
+		if xt != boolType {
+			p.fail(line, "invalid operation: !%s", xt)
+		}
+	default:
+		if !xt.isNumeric() {
+			p.fail(line, "invalid operation: %s%s", op, xt)
+		}
+	}
+	e := &unaryExpr{exprBase: exprBase{line: line, typ: xt}, op: op, x: x}
+	e.effects = x.base().effects
+	if op == "++" || op == "--" {
+		p.checkLvalue(x, line)
+		e.effects = true
+	} else if k := x.base().konst; k != nil {
+		e.konst = unaryOp(op, xt, k)
+	}
+	return e
+}
+
+func (p *parser) checkPostfix(op string, x expr, line int) expr {
+	xt := x.base().typ
+	if !xt.isNumeric() {
+		p.fail(line, "invalid operation: %s%s", xt, op)
+	}
+	p.checkLvalue(x, line)
+	return &postfixExpr{exprBase: exprBase{line: line, typ: xt, effects: true}, op: op, x: x}
+}
+
+func (p *parser) checkAssign(op string, x, y expr, line int) expr {
+	p.checkLvalue(x, line)
+	xt, yt := x.base().typ, y.base().typ
+	if op == "=" {
+		if !xt.Equal(yt) {
+			p.fail(line, "cannot assign a value of type %s to %s", yt, xt)
+		}
+	} else {
+		bop := op[:len(op)-1]
+		if _, ok := binaryOps[bop]; ok && strings.ContainsAny(bop, "%<>&|^") {
+			p.fail(line, "operator %s is reserved", op)
+		}
+		if t := arithType(bop, xt, yt); t == nil || !t.Equal(xt) {
+			p.fail(line, "invalid operation: %s %s %s", xt, op, yt)
+		}
+	}
+	return &assignExpr{exprBase: exprBase{line: line, typ: xt, effects: true}, op: op, x: x, y: y}
+}
+
+func (p *parser) checkCond(c, x, y expr, line int) expr {
+	xt, yt := x.base().typ, y.base().typ
+	p.boolExpr(c)
+	if !xt.Equal(yt) || xt.Kind == Array {
+		p.fail(line, "mismatched types %s and %s in conditional expression", xt, yt)
+	}
+	e := &condExpr{exprBase: exprBase{line: line, typ: xt}, cond: c, x: x, y: y}
+	e.effects = c.base().effects || x.base().effects || y.base().effects
+	if ck, xk, yk := c.base().konst, x.base().konst, y.base().konst; ck != nil && xk != nil && yk != nil {
+		e.konst = yk
+		if ck[0] != 0 {
+			e.konst = xk
+		}
+	}
+	return e
+}
+
+func (p *parser) checkSeq(s *seqExpr) expr {
+	last := s.list[len(s.list)-1].base()
+	s.line, s.typ = last.line, last.typ
+	for _, x := range s.list {
+		s.effects = s.effects || x.base().effects
+	}
+	return s
+}
+
+func (p *parser) checkIndex(x, index expr, line int) expr {
+	xt := x.base().typ
+	if index.base().typ != intType {
+		p.fail(line, "index must be an int, not %s", index.base().typ)
+	}
+	if xt.Kind != Array && !xt.isVector() && !xt.isMatrix() {
+		p.fail(line, "cannot index a value of type %s", xt)
+	}
+	t, n := elemType(xt)
+	e := &indexExpr{exprBase: exprBase{line: line, typ: t}, x: x, index: index}
+	e.effects = x.base().effects || index.base().effects
+	if k := index.base().konst; k != nil {
+		i := int(k[0])
+		if i < 0 || i >= n {
+			p.fail(line, "index %d out of range [0, %d)", i, n)
+		}
+		if xk := x.base().konst; xk != nil {
+			size := t.Components()
+			e.konst = xk[i*size : (i+1)*size]
+		}
+	}
+	return e
+}
+
+func (p *parser) checkField(x expr, name token, line int) expr {
+	xt := x.base().typ
+	if name.kind != tIdent {
+		p.fail(line, "expected field name, found %s", name.text)
+	}
+	e := &fieldExpr{exprBase: exprBase{line: line}, x: x, name: name.text}
+	e.effects = x.base().effects
+	switch {
+	case xt.Kind == Struct:
+		f, off := xt.field(name.text)
+		if f == nil {
+			p.fail(line, "%s has no field %s", xt, name.text)
+		}
+		e.typ, e.offset = f.Type, off
+		if xk := x.base().konst; xk != nil {
+			e.konst = xk[off : off+f.Type.Components()]
+		}
+	case xt.isVector():
+		e.swizzle = swizzle(name.text, xt.Size)
+		if e.swizzle == nil {
+			p.fail(line, "invalid swizzle %s of %s", name.text, xt)
+		}
+		e.typ = vectorType(xt.Kind, len(e.swizzle))
+		if xk := x.base().konst; xk != nil {
+			e.konst = gather(xk, e.swizzle)
+		}
+	default:
+		p.fail(line, "cannot select %s of a value of type %s", name.text, xt)
+	}
+	return e
+}
+
+// checkCall checks a function call or constructor.
+func (p *parser) checkCall(name string, args []expr, line int) expr {
+	if t, ok := basicTypes[name]; ok {
+		return p.checkConstructor(t, args, line)
+	}
+	switch x := p.lookup(name).(type) {
+	case *Type:
+		return p.checkConstructor(x, args, line)
+	case *variable:
+		if len(p.funcs[name]) == 0 || !x.global {
+			p.fail(line, "%s is not a function", name)
+		}
+	}
+
+	types := make([]*Type, len(args))
+	var sig []string
+	for i, a := range args {
+		types[i] = a.base().typ
+		if types[i] == voidType {
+			p.fail(line, "void value used as an argument of %s", name)
+		}
+		sig = append(sig, types[i].String())
+	}
+	e := &callExpr{exprBase: exprBase{line: line}, name: name, args: args}
+
+outer:
+	for _, fn := range p.funcs[name] {
+		if len(fn.params) != len(args) {
+			continue
+		}
+		for i, v := range fn.params {
+			if !v.typ.Equal(types[i]) {
+				continue outer
+			}
+		}
+		for i, v := range fn.params {
+			if v.qual == qualOut || v.qual == qualInOut {
+				p.checkLvalue(args[i], line)
+			}
+		}
+		e.fn, e.typ, e.effects = fn, fn.ret, true
+		p.calls = append(p.calls, e)
+		return e
+	}
+
+	b := builtins[name]
+	if b == nil && len(p.funcs[name]) == 0 {
+		p.fail(line, "undeclared function %s", name)
+	}
+	if b != nil {
+		e.typ = b.check(types)
+	}
+	if e.typ == nil {
+		p.fail(line, "no matching overload for %s(%s)", name, strings.Join(sig, ", "))
+	}
+	if b.vertexOnly && p.s.Type != gfx.VertexShader {
+		p.fail(line, "%s is only available in vertex shaders", name)
+	}
+	e.builtin = b
+	e.effects = anyEffects(args)
+	if vals := constArgs(args); vals != nil && !b.texture {
+		e.konst = b.eval(nil, vals, types, e.typ)
+	}
+	return e
+}
+
+// checkConstructor checks a constructor of t.
+func (p *parser) checkConstructor(t *Type, args []expr, line int) expr {
+	if len(args) == 0 {
+		p.fail(line, "constructor of %s has no arguments", t)
+	}
+	types := make([]*Type, len(args))
+	for i, a := range args {
+		types[i] = a.base().typ
+	}
+	switch {
+	case t.Kind == Struct:
+		if len(args) != len(t.Fields) {
+			p.fail(line, "constructor of %s has %d arguments, want %d", t, len(args), len(t.Fields))
+		}
+		for i, f := range t.Fields {
+			if !f.Type.Equal(types[i]) {
+				p.fail(line, "cannot use a value of type %s as field %s of %s", types[i], f.Name, t)
+			}
+		}
+	case t.isScalar():
+		if len(args) != 1 || !types[0].isBasic() {
+			p.fail(line, "invalid constructor of %s", t)
+		}
+	case t.isVector() || t.isMatrix():
+		if len(args) == 1 && (types[0].isScalar() || (t.isMatrix() && types[0].isMatrix())) {
+			break
+		}
+		n, total := t.Components(), 0
+		for _, at := range types {
+			switch {
+			case !at.isBasic():
+				p.fail(line, "cannot construct %s from a value of type %s", t, at)
+			case t.isMatrix() && at.isMatrix():
+				p.fail(line, "cannot construct %s from a matrix and other arguments", t)
+			case total >= n:
+				p.fail(line, "too many arguments in constructor of %s", t)
+			}
+			total += at.Components()
+		}
+		if total < n {
+			p.fail(line, "not enough arguments in constructor of %s", t)
+		}
+	default:
+		p.fail(line, "cannot construct a value of type %s", t)
+	}
+
+	e := &callExpr{exprBase: exprBase{line: line, typ: t}, name: t.String(), args: args, ctor: true}
+	e.effects = anyEffects(args)
+	if vals := constArgs(args); vals != nil {
+		e.konst = construct(t, vals, types)
+	}
+	return e
+}
+
+func anyEffects(args []expr) bool {
+	for _, a := range args {
+		if a.base().effects {
+			return true
+		}
+	}
+	return false
+}
+
+// constArgs returns the values of the arguments of a call, or nil if they are
+// not all constant.
+func constArgs(args []expr) [][]float32 {
+	vals := make([][]float32, len(args))
+	for i, a := range args {
+		if vals[i] = a.base().konst; vals[i] == nil {
+			return nil
+		}
+	}
+	return vals
+}
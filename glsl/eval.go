@@ -0,0 +1,318 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package glsl
+
+// maxDepth is the maximum depth of function calls. The specification forbids
+// recursion, which is only detected when it exceeds this depth.
+const maxDepth = 64
+
+// runtimeError is panicked with by the evaluator, and recovered by Run.
+type runtimeError struct {
+	err error
+}
+
+// discardSignal is panicked with by the discard statement.
+type discardSignal struct{}
+
+// frame holds the local variables of a function call.
+type frame struct {
+	locals [][]float32
+	ret    []float32
+}
+
+// control tells how the execution of a statement ended.
+type control int
+
+const (
+	ctlNone control = iota
+	ctlBreak
+	ctlContinue
+	ctlReturn
+)
+
+func clone(v []float32) []float32 {
+	return append([]float32(nil), v...)
+}
+
+// ref refers to the storage of an l-value: the components s[idx[i]], or all
+// of s if idx is nil.
+type ref struct {
+	s   []float32
+	idx []int
+}
+
+func (r ref) get() []float32 {
+	if r.idx == nil {
+		return r.s
+	}
+	return gather(r.s, r.idx)
+}
+
+func (r ref) set(v []float32) {
+	if r.idx == nil {
+		copy(r.s, v)
+		return
+	}
+	for i, k := range r.idx {
+		r.s[k] = v[i]
+	}
+}
+
+// call calls fn with the given arguments, evaluated in the frame of the
+// caller, and returns its result.
+func (st *State) call(fn *function, caller *frame, args []expr) []float32 {
+	if st.depth++; st.depth > maxDepth {
+		panic(runtimeError{errorf(fn.line, "function %s called recursively", fn.name)})
+	}
+	f := &frame{locals: make([][]float32, fn.locals)}
+	outs := make([]ref, len(args))
+	for i, v := range fn.params {
+		switch v.qual {
+		case qualOut:
+			outs[i] = st.ref(caller, args[i])
+			f.locals[v.index] = make([]float32, v.typ.Components())
+		case qualInOut:
+			outs[i] = st.ref(caller, args[i])
+			f.locals[v.index] = clone(outs[i].get())
+		default:
+			f.locals[v.index] = clone(st.eval(caller, args[i]))
+		}
+	}
+	st.exec(f, fn.body)
+	for i, v := range fn.params {
+		if v.qual == qualOut || v.qual == qualInOut {
+			outs[i].set(f.locals[v.index])
+		}
+	}
+	st.depth--
+	if f.ret == nil && fn.ret != voidType {
+		// Falling off the end of a function returns an undefined value.
+		return make([]float32, fn.ret.Components())
+	}
+	return f.ret
+}
+
+// storage returns the storage of a variable.
+func (st *State) storage(f *frame, v *variable) []float32 {
+	if v.global {
+		return st.globals[v.index]
+	}
+	return f.locals[v.index]
+}
+
+// index evaluates the index of an indexing expression, and returns the offset
+// and size of the selected element.
+func (st *State) index(f *frame, e *indexExpr) (int, int) {
+	t, n := elemType(e.x.base().typ)
+	i := int(st.eval(f, e.index)[0])
+	if i < 0 || i >= n {
+		panic(runtimeError{errorf(e.line, "index %d out of range [0, %d)", i, n)})
+	}
+	size := t.Components()
+	return i * size, size
+}
+
+// ref evaluates an l-value.
+func (st *State) ref(f *frame, x expr) ref {
+	switch e := x.(type) {
+	case *identExpr:
+		return ref{s: st.storage(f, e.v)}
+	case *indexExpr:
+		r := st.ref(f, e.x)
+		off, size := st.index(f, e)
+		if r.idx == nil {
+			return ref{s: r.s[off : off+size]}
+		}
+		return ref{s: r.s, idx: r.idx[off : off+size]}
+	case *fieldExpr:
+		r := st.ref(f, e.x)
+		if e.swizzle == nil {
+			return ref{s: r.s[e.offset : e.offset+e.typ.Components()]}
+		}
+		if r.idx == nil {
+			return ref{s: r.s, idx: e.swizzle}
+		}
+		return ref{s: r.s, idx: gather32(r.idx, e.swizzle)}
+	}
+	panic("glsl: expression is not an l-value")
+}
+
+func gather32(x, idx []int) []int {
+	r := make([]int, len(idx))
+	for i, k := range idx {
+		r[i] = x[k]
+	}
+	return r
+}
+
+// evalArgs evaluates a list of expressions in order. Since values may share
+// the storage of variables, each value is copied if a later expression may
+// have side effects.
+func (st *State) evalArgs(f *frame, args []expr) [][]float32 {
+	vals := make([][]float32, len(args))
+	for i, a := range args {
+		vals[i] = st.eval(f, a)
+		if anyEffects(args[i+1:]) {
+			vals[i] = clone(vals[i])
+		}
+	}
+	return vals
+}
+
+// eval evaluates an expression. The returned value may share storage with a
+// variable or a constant, and must not be modified.
+func (st *State) eval(f *frame, x expr) []float32 {
+	if k := x.base().konst; k != nil {
+		return k
+	}
+	switch e := x.(type) {
+	case *identExpr:
+		return st.storage(f, e.v)
+	case *unaryExpr:
+		if e.op != "++" && e.op != "--" {
+			return unaryOp(e.op, e.typ, st.eval(f, e.x))
+		}
+		r := st.ref(f, e.x)
+		v := step(e.op, e.typ, r.get())
+		r.set(v)
+		return v
+	case *postfixExpr:
+		r := st.ref(f, e.x)
+		old := clone(r.get())
+		r.set(step(e.op, e.typ, old))
+		return old
+	case *binaryExpr:
+		xv := st.eval(f, e.x)
+		switch {
+		case e.op == "&&" && xv[0] == 0:
+			return xv
+		case e.op == "||" && xv[0] != 0:
+			return xv
+		case e.y.base().effects:
+			xv = clone(xv)
+		}
+		return binaryOp(e.op, e.x.base().typ, e.y.base().typ, e.typ, xv, st.eval(f, e.y))
+	case *assignExpr:
+		r := st.ref(f, e.x)
+		v := st.eval(f, e.y)
+		if e.op != "=" {
+			v = binaryOp(e.op[:len(e.op)-1], e.typ, e.y.base().typ, e.typ, clone(r.get()), v)
+		}
+		r.set(v)
+		return r.get()
+	case *seqExpr:
+		var v []float32
+		for _, x := range e.list {
+			v = st.eval(f, x)
+		}
+		return v
+	case *condExpr:
+		if st.eval(f, e.cond)[0] != 0 {
+			return st.eval(f, e.x)
+		}
+		return st.eval(f, e.y)
+	case *callExpr:
+		switch {
+		case e.ctor:
+			return construct(e.typ, st.evalArgs(f, e.args), argTypes(e.args))
+		case e.fn != nil:
+			return st.call(e.fn, f, e.args)
+		}
+		return e.builtin.eval(st, st.evalArgs(f, e.args), argTypes(e.args), e.typ)
+	case *indexExpr:
+		v := st.eval(f, e.x)
+		if e.index.base().effects {
+			v = clone(v)
+		}
+		off, size := st.index(f, e)
+		return v[off : off+size]
+	case *fieldExpr:
+		v := st.eval(f, e.x)
+		if e.swizzle != nil {
+			return gather(v, e.swizzle)
+		}
+		return v[e.offset : e.offset+e.typ.Components()]
+	}
+	panic("glsl: unknown expression")
+}
+
+// step increments or decrements a value by one.
+func step(op string, t *Type, v []float32) []float32 {
+	one := []float32{1}
+	return binaryOp(op[:1], t, intType, t, v, one)
+}
+
+func argTypes(args []expr) []*Type {
+	types := make([]*Type, len(args))
+	for i, a := range args {
+		types[i] = a.base().typ
+	}
+	return types
+}
+
+// exec executes a statement.
+func (st *State) exec(f *frame, s stmt) control {
+	switch s := s.(type) {
+	case *blockStmt:
+		for _, s := range s.list {
+			if c := st.exec(f, s); c != ctlNone {
+				return c
+			}
+		}
+	case *exprStmt:
+		st.eval(f, s.x)
+	case *declStmt:
+		for i, v := range s.vars {
+			if s.inits[i] == nil {
+				f.locals[v.index] = make([]float32, v.typ.Components())
+			} else {
+				f.locals[v.index] = clone(st.eval(f, s.inits[i]))
+			}
+		}
+	case *ifStmt:
+		if st.eval(f, s.cond)[0] != 0 {
+			return st.exec(f, s.then)
+		}
+		return st.exec(f, s.els)
+	case *forStmt:
+		return st.loop(f, s)
+	case *returnStmt:
+		if s.x != nil {
+			f.ret = clone(st.eval(f, s.x))
+		}
+		return ctlReturn
+	case *branchStmt:
+		switch s.tok {
+		case "break":
+			return ctlBreak
+		case "continue":
+			return ctlContinue
+		}
+		panic(discardSignal{})
+	}
+	return ctlNone
+}
+
+// loop executes a loop.
+func (st *State) loop(f *frame, s *forStmt) control {
+	st.exec(f, s.init)
+	if v := s.condVar; v != nil {
+		f.locals[v.index] = make([]float32, v.typ.Components())
+	}
+	for first := true; ; first = false {
+		if s.cond != nil && !(s.do && first) && st.eval(f, s.cond)[0] == 0 {
+			return ctlNone
+		}
+		switch st.exec(f, s.body) {
+		case ctlBreak:
+			return ctlNone
+		case ctlReturn:
+			return ctlReturn
+		}
+		if s.post != nil {
+			st.eval(f, s.post)
+		}
+	}
+}
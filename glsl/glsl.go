@@ -0,0 +1,230 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package glsl implements a GLSL ES 1.00 interpreter written entirely in Go.
+//
+// Compile preprocesses, parses and type checks the source of a shader, and
+// reports its attributes, uniforms and varyings. The shader is then run by
+// evaluating its syntax tree, using a State to hold the values of its global
+// variables:
+//
+//	s, err := glsl.Compile(src, gfx.VertexShader)
+//	if err != nil {
+//		// Handle compilation error.
+//	}
+//	st := s.NewState()
+//	copy(st.Var("position"), []float32{1, 2, 3, 1})
+//	if err := st.Run(); err != nil {
+//		// Handle runtime error.
+//	}
+//	pos := st.Var("gl_Position")
+//
+// Values are stored as float32 components, including integers (which are
+// exact up to 2^24) and booleans (which are 0 or 1, any non-zero value being
+// true). Floating-point operations are performed with at least highp
+// precision.
+//
+// No extensions are supported, and the limitations of Appendix A of the GLSL
+// ES specification are not enforced, except that recursion fails at run time.
+package glsl
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/slimsag/gfx"
+)
+
+// Error is an error in the source of a shader.
+type Error struct {
+	Line int
+	Msg  string
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	return fmt.Sprintf("glsl: %d: %s", e.Line, e.Msg)
+}
+
+func errorf(line int, format string, args ...interface{}) error {
+	return &Error{Line: line, Msg: fmt.Sprintf(format, args...)}
+}
+
+// ErrDiscard is returned by State.Run when a fragment shader executes the
+// discard statement.
+var ErrDiscard = errors.New("glsl: fragment discarded")
+
+// Shader is a compiled shader.
+type Shader struct {
+	// Type is the type of the shader.
+	Type gfx.ShaderType
+
+	// Attributes, Uniforms and Varyings are the global variables declared by
+	// the shader with each qualifier, in order of declaration.
+	Attributes []*Variable
+	Uniforms   []*Variable
+	Varyings   []*Variable
+
+	// globals are all global variables, including built-in ones, by index.
+	globals []*variable
+
+	// inputs tells which globals are inputs of the shader, which are not
+	// reset before each run.
+	inputs []bool
+
+	// byName maps the names of the global variables to them.
+	byName map[string]*variable
+
+	main *function
+}
+
+// Compile compiles the source of a shader of the given type.
+func Compile(src string, t gfx.ShaderType) (*Shader, error) {
+	toks, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	toks, err = preprocess(toks, t == gfx.FragmentShader)
+	if err != nil {
+		return nil, err
+	}
+	return parse(toks, t)
+}
+
+// Sampler samples the textures used by the texture lookup functions of a
+// shader.
+type Sampler interface {
+	// Sample returns the color of the texture bound to the given texture
+	// unit at the given coordinates. Only the first two coordinates are used
+	// for 2D textures. Projective lookups are divided before Sample is
+	// called, and level of detail arguments are ignored.
+	Sample(unit int, cube bool, coord [3]float32) [4]float32
+}
+
+// State holds the values of the global variables of a shader, and runs it.
+// A State may not be used by multiple goroutines at once.
+type State struct {
+	// Sampler samples textures. If it is nil, texture lookups return
+	// 0, 0, 0, 1 like the lookups of incomplete textures in OpenGL.
+	Sampler Sampler
+
+	shader  *Shader
+	globals [][]float32
+	depth   int
+}
+
+// NewState returns a new state for running the shader, with each global
+// variable holding its initial value (or zero).
+func (s *Shader) NewState() *State {
+	st := &State{
+		shader:  s,
+		globals: make([][]float32, len(s.globals)),
+	}
+	for i, v := range s.globals {
+		st.globals[i] = make([]float32, v.typ.Components())
+		copy(st.globals[i], v.konst)
+	}
+
+	// gl_FragData[0] is an alias of gl_FragColor.
+	if color, ok := s.byName["gl_FragColor"]; ok {
+		st.globals[s.byName["gl_FragData"].index] = st.globals[color.index]
+	}
+	return st
+}
+
+// Var returns the storage of a global variable or of one of its members (e.g.
+// "light.color" or "lights[1].color"), or nil if there is none with the given
+// name. Inputs of the shader are set by writing to the returned slice, and
+// outputs read from it; built-in variables such as gl_Position and
+// gl_FragCoord are accessed the same way.
+func (st *State) Var(name string) []float32 {
+	end := strings.IndexAny(name, ".[")
+	if end < 0 {
+		end = len(name)
+	}
+	v, ok := st.shader.byName[name[:end]]
+	if !ok {
+		return nil
+	}
+	s, t := st.globals[v.index], v.typ
+	for rest := name[end:]; rest != ""; {
+		switch {
+		case rest[0] == '.' && t.Kind == Struct:
+			end := strings.IndexAny(rest[1:], ".[") + 1
+			if end == 0 {
+				end = len(rest)
+			}
+			f, off := t.field(rest[1:end])
+			if f == nil {
+				return nil
+			}
+			s, t = s[off:off+f.Type.Components()], f.Type
+			rest = rest[end:]
+		case rest[0] == '[' && (t.Kind == Array || t.isVector() || t.isMatrix()):
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil
+			}
+			i, err := strconv.Atoi(rest[1:end])
+			elem, n := elemType(t)
+			if err != nil || i < 0 || i >= n {
+				return nil
+			}
+			size := elem.Components()
+			s, t = s[i*size:(i+1)*size], elem
+			rest = rest[end+1:]
+		default:
+			return nil
+		}
+	}
+	return s
+}
+
+// elemType returns the type of the elements of an array, the components of a
+// vector or the columns of a matrix, and their number.
+func elemType(t *Type) (*Type, int) {
+	switch {
+	case t.Kind == Array:
+		return t.Elem, t.Len
+	case t.isMatrix():
+		return vecTypes[t.Size], t.Cols
+	}
+	return vectorType(t.Kind, 1), t.Size
+}
+
+// Run runs the main function of the shader. Global variables that are not
+// inputs of the shader (uniforms, attributes, the varyings of a fragment
+// shader and built-in inputs) are first reset to their initial values.
+//
+// Run returns ErrDiscard if the shader discards the fragment, or an error
+// describing a failure such as an out of range array index.
+func (st *State) Run() (err error) {
+	s := st.shader
+	for i, v := range s.globals {
+		if !s.inputs[i] {
+			g := st.globals[i]
+			for k := range g {
+				g[k] = 0
+			}
+			copy(g, v.konst)
+		}
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			switch r := r.(type) {
+			case runtimeError:
+				err = r.err
+			case discardSignal:
+				err = ErrDiscard
+			default:
+				panic(r)
+			}
+		}
+	}()
+	st.depth = 0
+	st.call(s.main, nil, nil)
+	return nil
+}
@@ -0,0 +1,418 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package glsl
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/slimsag/gfx"
+)
+
+// run compiles a shader, sets its inputs and runs it.
+func run(t *testing.T, typ gfx.ShaderType, src string, inputs map[string][]float32) (*State, error) {
+	s, err := Compile(src, typ)
+	if err != nil {
+		t.Fatalf("Compile: %v\n%s", err, src)
+	}
+	st := s.NewState()
+	for name, v := range inputs {
+		dst := st.Var(name)
+		if len(dst) != len(v) {
+			t.Fatalf("Var(%q) has %d components, want %d", name, len(dst), len(v))
+		}
+		copy(dst, v)
+	}
+	return st, st.Run()
+}
+
+func near(x, y []float32) bool {
+	if len(x) != len(y) {
+		return false
+	}
+	for i := range x {
+		if math.Abs(float64(x[i]-y[i])) > 1e-5 {
+			return false
+		}
+	}
+	return true
+}
+
+// TestEval evaluates fragment shaders that compute gl_FragColor.
+func TestEval(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []float32
+	}{
+		{"constant", `
+			void main() { gl_FragColor = vec4(0.25, 0.5, 0.75, 1.0); }`,
+			[]float32{0.25, 0.5, 0.75, 1}},
+		{"swizzle", `
+			void main() {
+				vec4 v = vec4(1.0, 2.0, 3.0, 4.0);
+				v.xw = v.wx;
+				gl_FragColor = vec4(v.bgr, v.s);
+			}`,
+			[]float32{3, 2, 4, 4}},
+		{"swizzle index", `
+			void main() {
+				vec3 v = vec3(1.0, 2.0, 3.0);
+				v.zy[1] = 5.0;
+				gl_FragColor = vec4(v, v.zyx[0]);
+			}`,
+			[]float32{1, 5, 3, 3}},
+		{"constructors", `
+			void main() {
+				vec2 a = vec2(3.7);
+				ivec2 b = ivec2(vec2(-1.5, 2.5));
+				bvec2 c = bvec2(0.0, 2.0);
+				gl_FragColor = vec4(a.x, float(b.x + b.y), float(c.x), float(c.y));
+			}`,
+			[]float32{3.7, 1, 0, 1}},
+		{"matrix constructors", `
+			void main() {
+				mat3 m = mat3(2.0);
+				mat2 n = mat2(mat3(1.0, 2.0, 3.0, 4.0, 5.0, 6.0, 7.0, 8.0, 9.0));
+				mat3 o = mat3(n);
+				gl_FragColor = vec4(m[1][1] + m[0][1], n[1][0], o[2][2], o[1][0]);
+			}`,
+			[]float32{2, 4, 1, 4}},
+		{"matrix multiplication", `
+			void main() {
+				mat2 m = mat2(1.0, 2.0, 3.0, 4.0); // Columns (1, 2) and (3, 4).
+				vec2 v = vec2(1.0, 1.0);
+				mat2 mm = m * m;
+				gl_FragColor = vec4(m * v, (v * m).y, mm[1][0]);
+			}`,
+			[]float32{4, 6, 7, 15}},
+		{"arithmetic", `
+			void main() {
+				int i = 7 / 2 + -7 / 2;
+				float f = 1.0;
+				f += 2.0;
+				f *= 2.0;
+				vec2 v = vec2(1.0, 2.0) * 2.0 - 1.0;
+				gl_FragColor = vec4(float(i), f, v);
+			}`,
+			[]float32{0, 6, 1, 3}},
+		{"increment", `
+			void main() {
+				int i = 1;
+				int a = i++;
+				int b = ++i;
+				vec2 v = vec2(0.0);
+				v.y--;
+				gl_FragColor = vec4(float(a), float(b), v);
+			}`,
+			[]float32{1, 3, 0, -1}},
+		{"logic", `
+			bool called = false;
+			bool f() { called = true; return true; }
+			void main() {
+				bool a = false && f();
+				bool b = true ^^ true;
+				bool c = vec2(1.0) == vec2(1.0, 1.0);
+				gl_FragColor = vec4(float(a), float(b), float(c), called ? 1.0 : 0.0);
+			}`,
+			[]float32{0, 0, 1, 0}},
+		{"functions", `
+			float twice(float x) { return 2.0 * x; }
+			void split(in vec2 v, out float x, inout float y) { x = v.x; y += v.y; }
+			void main() {
+				float x, y = 1.0;
+				split(vec2(3.0, 4.0), x, y);
+				gl_FragColor = vec4(twice(x), y, twice(twice(0.5)), 0.0);
+			}`,
+			[]float32{6, 5, 2, 0}},
+		{"prototype", `
+			float f(float x);
+			void main() { gl_FragColor = vec4(f(1.0)); }
+			float f(float x) { float y = x + 1.0; return y; }`,
+			[]float32{2, 2, 2, 2}},
+		{"structs", `
+			struct Light {
+				vec3 color;
+				float intensity;
+			};
+			struct Scene { Light lights[2]; };
+			void main() {
+				Light l = Light(vec3(1.0, 0.5, 0.25), 2.0);
+				Scene s;
+				s.lights[1] = l;
+				s.lights[1].color.g *= 2.0;
+				gl_FragColor = vec4(s.lights[1].color * s.lights[1].intensity, float(l == s.lights[1]));
+			}`,
+			[]float32{2, 2, 0.5, 0}},
+		{"loops", `
+			void main() {
+				float sum = 0.0;
+				for (int i = 0; i < 10; i++) {
+					if (i == 2) continue;
+					if (i == 5) break;
+					sum += float(i);
+				}
+				int n = 0;
+				while (n < 3) n++;
+				int m = 0;
+				do { m++; } while (false);
+				gl_FragColor = vec4(sum, float(n), float(m), 0.0);
+			}`,
+			[]float32{8, 3, 1, 0}},
+		{"arrays", `
+			void main() {
+				float a[3];
+				for (int i = 0; i < 3; i++) a[i] = float(i * i);
+				vec4 v[2];
+				v[1].y = a[2];
+				gl_FragColor = vec4(a[1], v[1].y, v[0].x, 0.0);
+			}`,
+			[]float32{1, 4, 0, 0}},
+		{"builtins", `
+			void main() {
+				gl_FragColor = vec4(
+					clamp(2.0, 0.0, 1.0) + mix(0.0, 10.0, 0.5),
+					dot(vec3(1.0, 2.0, 3.0), vec3(4.0, 5.0, 6.0)),
+					length(vec2(3.0, 4.0)) + mod(-1.0, 3.0),
+					step(0.5, 0.25) + smoothstep(0.0, 1.0, 0.5));
+			}`,
+			[]float32{6, 32, 7, 0.5}},
+		{"vector builtins", `
+			void main() {
+				vec3 c = cross(vec3(1.0, 0.0, 0.0), vec3(0.0, 1.0, 0.0));
+				vec2 r = reflect(vec2(1.0, -1.0), vec2(0.0, 1.0));
+				bvec2 b = lessThan(vec2(1.0, 2.0), vec2(2.0, 1.0));
+				gl_FragColor = vec4(c.z, r.y, float(any(b)), float(all(not(b))));
+			}`,
+			[]float32{1, 1, 1, 0}},
+		{"constants", `
+			const float scale = 2.0;
+			const vec2 offset = vec2(scale, 1.0) * 0.5;
+			float unused[int(scale) + 1];
+			void main() { gl_FragColor = vec4(offset, scale, float(gl_MaxDrawBuffers)); }`,
+			[]float32{1, 0.5, 2, 1}},
+		{"preprocessor", `
+			#define SCALE(x) ((x) * 2.0)
+			#if defined(GL_ES) && __VERSION__ == 100
+			#define VALUE 1.0
+			#else
+			#error not GLSL ES
+			#endif
+			void main() { gl_FragColor = vec4(SCALE(VALUE + 1.0)); }`,
+			[]float32{4, 4, 4, 4}},
+		{"frag data", `
+			void main() { gl_FragData[0] = vec4(1.0, 2.0, 3.0, 4.0); }`,
+			[]float32{1, 2, 3, 4}},
+	}
+	for _, test := range tests {
+		st, err := run(t, gfx.FragmentShader, test.src, nil)
+		if err != nil {
+			t.Errorf("%s: Run: %v", test.name, err)
+			continue
+		}
+		if test.want == nil {
+			continue
+		}
+		if got := st.Var("gl_FragColor"); !near(got, test.want) {
+			t.Errorf("%s: gl_FragColor = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestVertexShader(t *testing.T) {
+	const src = `
+		attribute vec3 position;
+		attribute vec2 uv;
+		uniform mat4 mvp;
+		uniform struct Offset { vec2 uv; } offset[2];
+		varying vec2 vUV;
+		void main() {
+			vUV = uv + offset[1].uv;
+			gl_Position = mvp * vec4(position, 1.0);
+			gl_PointSize = 2.0;
+		}`
+	st, err := run(t, gfx.VertexShader, src, map[string][]float32{
+		"position":      {1, 2, 3},
+		"uv":            {0.5, 0.25},
+		"mvp":           {2, 0, 0, 0, 0, 2, 0, 0, 0, 0, 2, 0, 1, 1, 1, 1},
+		"offset[1].uv":  {1, 1},
+		"offset[0]":     {9, 9},
+		"gl_DepthRange": {0, 1, 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := st.Var("gl_Position"), []float32{3, 5, 7, 1}; !near(got, want) {
+		t.Errorf("gl_Position = %v, want %v", got, want)
+	}
+	if got, want := st.Var("vUV"), []float32{1.5, 1.25}; !near(got, want) {
+		t.Errorf("vUV = %v, want %v", got, want)
+	}
+	if got := st.Var("gl_PointSize"); got[0] != 2 {
+		t.Errorf("gl_PointSize = %v, want 2", got[0])
+	}
+
+	// Uniforms keep their values across runs, while outputs are reset.
+	copy(st.Var("position"), []float32{0, 0, 0})
+	if err := st.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := st.Var("gl_Position"), []float32{1, 1, 1, 1}; !near(got, want) {
+		t.Errorf("second run: gl_Position = %v, want %v", got, want)
+	}
+
+	s := st.shader
+	var names []string
+	for _, v := range append(append(s.Attributes, s.Uniforms...), s.Varyings...) {
+		names = append(names, v.Leaves()...)
+	}
+	want := "position uv mvp offset[0].uv offset[1].uv vUV"
+	if got := strings.Join(names, " "); got != want {
+		t.Errorf("variables = %q, want %q", got, want)
+	}
+}
+
+type testSampler struct{}
+
+func (testSampler) Sample(unit int, cube bool, coord [3]float32) [4]float32 {
+	return [4]float32{float32(unit), coord[0], coord[1], b2f(cube)}
+}
+
+func TestFragmentShader(t *testing.T) {
+	const src = `
+		precision mediump float;
+		uniform sampler2D tex;
+		varying vec2 vUV;
+		void main() {
+			if (vUV.x < 0.0) {
+				discard;
+			}
+			gl_FragColor = texture2DProj(tex, vec3(vUV, 2.0)) + vec4(0.0, 0.0, 0.0, gl_FragCoord.x);
+		}`
+	s, err := Compile(src, gfx.FragmentShader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := s.NewState()
+	st.Sampler = testSampler{}
+	st.Var("tex")[0] = 3
+	copy(st.Var("vUV"), []float32{1, 0.5})
+	st.Var("gl_FragCoord")[0] = 10
+	if err := st.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := st.Var("gl_FragColor"), []float32{3, 0.5, 0.25, 10}; !near(got, want) {
+		t.Errorf("gl_FragColor = %v, want %v", got, want)
+	}
+
+	st.Var("vUV")[0] = -1
+	if err := st.Run(); err != ErrDiscard {
+		t.Errorf("Run = %v, want ErrDiscard", err)
+	}
+}
+
+func TestRuntimeErrors(t *testing.T) {
+	tests := []struct {
+		src, err string
+	}{
+		{`
+			uniform int i;
+			void main() { float a[2]; a[i + 2] = 1.0; }`,
+			"glsl: 3: index 2 out of range [0, 2)"},
+		{`
+			float f(float x);
+			float g(float x) { return f(x); }
+			float f(float x) { return g(x); }
+			void main() { gl_FragColor = vec4(f(1.0)); }`,
+			"called recursively"},
+	}
+	for _, test := range tests {
+		_, err := run(t, gfx.FragmentShader, test.src, nil)
+		if _, ok := err.(*Error); !ok || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("Run = %v, want error containing %q\n%s", err, test.err, test.src)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		typ      gfx.ShaderType
+		src, err string
+	}{
+		{gfx.FragmentShader, `void main() { gl_FragColor = 1.0; }`,
+			"cannot assign a value of type float to vec4"},
+		{gfx.FragmentShader, `void main() { float x = 1; }`,
+			"cannot initialize x of type float with a value of type int"},
+		{gfx.FragmentShader, `void main() { vec2 v; v.xx = vec2(1.0); }`,
+			"repeated components"},
+		{gfx.FragmentShader, `void main() { vec2 v; v.z = 1.0; }`,
+			"invalid swizzle z of vec2"},
+		{gfx.FragmentShader, `attribute vec4 a; void main() {}`,
+			"attributes are only allowed in vertex shaders"},
+		{gfx.VertexShader, `uniform float u; void main() { u = 1.0; }`,
+			"cannot assign to uniform u"},
+		{gfx.VertexShader, `void main() { gl_Position = vec4(f()); }`,
+			"undeclared function f"},
+		{gfx.VertexShader, `void f() {}`,
+			"main"},
+		{gfx.VertexShader, `void main() { discard; }`,
+			"discard outside of a fragment shader"},
+		{gfx.VertexShader, `void main() { int x = 1 % 2; }`,
+			"operator % is reserved"},
+		{gfx.FragmentShader, `void main() { gl_FragColor = vec4(1.0); gl_FragData[0] = vec4(1.0); }`,
+			"gl_FragColor"},
+		{gfx.FragmentShader, `uniform sampler2D s; void main() { gl_FragColor = texture2DLod(s, vec2(0.0), 0.0); }`,
+			"only available in vertex shaders"},
+		{gfx.VertexShader, "#version 110\nvoid main() {}",
+			"version"},
+		{gfx.VertexShader, "void main() {\n\tbreak;\n}",
+			"glsl: 2: break outside of a loop"},
+		{gfx.VertexShader, `void main() { float a[2]; a[2] = 1.0; }`,
+			"out of range"},
+		{gfx.VertexShader, `float f(); void main() { f(); }`,
+			"function f is declared but not defined"},
+	}
+	for _, test := range tests {
+		_, err := Compile(test.src, test.typ)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("Compile = %v, want error containing %q\n%s", err, test.err, test.src)
+		}
+	}
+}
+
+func TestVar(t *testing.T) {
+	const src = `
+		struct S { vec3 v; mat2 m[2]; };
+		uniform S s;
+		void main() {}`
+	s, err := Compile(src, gfx.VertexShader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := s.NewState()
+	tests := []struct {
+		name string
+		n    int
+	}{
+		{"s", 11},
+		{"s.v", 3},
+		{"s.v[2]", 1},
+		{"s.m", 8},
+		{"s.m[1]", 4},
+		{"s.m[1][0]", 2},
+		{"s.m[1][0][1]", 1},
+		{"s.m[2]", -1},
+		{"s.w", -1},
+		{"s.v.x", -1},
+		{"t", -1},
+	}
+	for _, test := range tests {
+		got := st.Var(test.name)
+		if (got == nil) != (test.n < 0) || (got != nil && len(got) != test.n) {
+			t.Errorf("Var(%q) has %d components, want %d", test.name, len(got), test.n)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package glsl
+
+import (
+	"errors"
+	"strings"
+)
+
+// tokenKind is the kind of a token.
+type tokenKind int
+
+const (
+	tEOF tokenKind = iota
+	tIdent
+	tInt
+	tFloat
+	tPunct
+)
+
+// token is a single preprocessing token.
+type token struct {
+	kind tokenKind
+	text string
+	line int
+
+	// bol tells if the token is the first one on its line, and space if it
+	// is preceded by whitespace (or a comment).
+	bol, space bool
+}
+
+// puncts are the punctuators, longest first such that they are matched
+// greedily.
+var puncts = []string{
+	"<<=", ">>=",
+	"++", "--", "<=", ">=", "==", "!=", "&&", "||", "^^", "+=", "-=", "*=",
+	"/=", "%=", "&=", "|=", "^=", "<<", ">>", "##",
+	"(", ")", "[", "]", "{", "}", ".", ",", ";", "+", "-", "*", "/", "%",
+	"<", ">", "=", "!", "~", "?", ":", "&", "|", "^", "#",
+}
+
+func isLetter(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isHexDigit(c byte) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+// lex splits the source into tokens, removing comments.
+func lex(src string) ([]token, error) {
+	var (
+		toks  []token
+		line  = 1
+		bol   = true
+		space = false
+	)
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			bol, space = true, true
+			i++
+			continue
+		case c == ' ' || c == '\t' || c == '\r' || c == '\v' || c == '\f':
+			space = true
+			i++
+			continue
+		case strings.HasPrefix(src[i:], "//"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+			space = true
+			continue
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return nil, errorf(line, "unterminated comment")
+			}
+			line += strings.Count(src[i:i+2+end], "\n")
+			i += end + 4
+			space = true
+			continue
+		}
+
+		t := token{line: line, bol: bol, space: space}
+		start := i
+		switch {
+		case isLetter(c):
+			for i < len(src) && (isLetter(src[i]) || isDigit(src[i])) {
+				i++
+			}
+			t.kind = tIdent
+		case isDigit(c) || (c == '.' && i+1 < len(src) && isDigit(src[i+1])):
+			var err error
+			t.kind, i, err = lexNumber(src, i)
+			if err != nil {
+				return nil, errorf(line, "%v", err)
+			}
+		default:
+			for _, p := range puncts {
+				if strings.HasPrefix(src[i:], p) {
+					i += len(p)
+					t.kind = tPunct
+					break
+				}
+			}
+			if t.kind != tPunct {
+				return nil, errorf(line, "invalid character %q", c)
+			}
+		}
+		t.text = src[start:i]
+		toks = append(toks, t)
+		bol, space = false, false
+	}
+	return toks, nil
+}
+
+// lexNumber lexes the integer or floating-point constant at src[i:], returning
+// its kind and the end of the constant.
+func lexNumber(src string, i int) (tokenKind, int, error) {
+	start := i
+	if strings.HasPrefix(src[i:], "0x") || strings.HasPrefix(src[i:], "0X") {
+		i += 2
+		for i < len(src) && isHexDigit(src[i]) {
+			i++
+		}
+		if i == start+2 {
+			return 0, 0, errors.New("invalid hexadecimal constant")
+		}
+		return tInt, i, checkSuffix(src, i)
+	}
+	kind := tInt
+	for i < len(src) && isDigit(src[i]) {
+		i++
+	}
+	if i < len(src) && src[i] == '.' {
+		kind = tFloat
+		i++
+		for i < len(src) && isDigit(src[i]) {
+			i++
+		}
+	}
+	if i < len(src) && (src[i] == 'e' || src[i] == 'E') {
+		kind = tFloat
+		i++
+		if i < len(src) && (src[i] == '+' || src[i] == '-') {
+			i++
+		}
+		digits := i
+		for i < len(src) && isDigit(src[i]) {
+			i++
+		}
+		if i == digits {
+			return 0, 0, errors.New("invalid exponent in floating-point constant")
+		}
+	}
+	if kind == tInt && src[start] == '0' {
+		for _, c := range src[start:i] {
+			if c > '7' {
+				return 0, 0, errors.New("invalid octal constant")
+			}
+		}
+	}
+	return kind, i, checkSuffix(src, i)
+}
+
+// checkSuffix returns an error if the constant ending at src[i:] is followed
+// by a letter, like the suffixes of C, which GLSL ES does not have.
+func checkSuffix(src string, i int) error {
+	if i < len(src) && (isLetter(src[i]) || isDigit(src[i])) {
+		return errors.New("invalid suffix on constant")
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package glsl
+
+import "math"
+
+// The operations below compute the values of operators and constructors. They
+// are used both to fold constant expressions and to evaluate shaders, and
+// never modify their operands.
+
+func b2f(b bool) float32 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// conv converts a scalar component from one kind to another.
+func conv(to, from Kind, v float32) float32 {
+	switch {
+	case to == Bool:
+		return b2f(v != 0)
+	case to == Int && from == Float:
+		return float32(math.Trunc(float64(v)))
+	}
+	return v
+}
+
+// gather returns the components of x at the given indices.
+func gather(x []float32, idx []int) []float32 {
+	r := make([]float32, len(idx))
+	for i, k := range idx {
+		r[i] = x[k]
+	}
+	return r
+}
+
+func unaryOp(op string, t *Type, x []float32) []float32 {
+	r := make([]float32, len(x))
+	for i, v := range x {
+		switch op {
+		case "-":
+			r[i] = -v
+		case "!":
+			r[i] = b2f(v == 0)
+		default:
+			r[i] = v
+		}
+	}
+	return r
+}
+
+// binaryOp computes x op y, where x and y have types xt and yt and the result
+// has type t.
+func binaryOp(op string, xt, yt, t *Type, x, y []float32) []float32 {
+	switch op {
+	case "&&":
+		return []float32{b2f(x[0] != 0 && y[0] != 0)}
+	case "||":
+		return []float32{b2f(x[0] != 0 || y[0] != 0)}
+	case "^^":
+		return []float32{b2f((x[0] != 0) != (y[0] != 0))}
+	case "==", "!=":
+		eq := true
+		for i := range x {
+			eq = eq && x[i] == y[i]
+		}
+		return []float32{b2f(eq == (op == "=="))}
+	case "<":
+		return []float32{b2f(x[0] < y[0])}
+	case ">":
+		return []float32{b2f(x[0] > y[0])}
+	case "<=":
+		return []float32{b2f(x[0] <= y[0])}
+	case ">=":
+		return []float32{b2f(x[0] >= y[0])}
+	case "*":
+		switch {
+		case xt.isMatrix() && yt.isMatrix():
+			return matMul(x, y, xt.Size, xt.Cols, yt.Cols)
+		case xt.isMatrix() && yt.isVector():
+			return matMul(x, y, xt.Size, xt.Cols, 1)
+		case xt.isVector() && yt.isMatrix():
+			return matMul(x, y, 1, yt.Size, yt.Cols)
+		}
+	}
+
+	r := make([]float32, t.Components())
+	for i := range r {
+		a, b := arg(x, i), arg(y, i)
+		var v float64
+		switch op {
+		case "+":
+			v = a + b
+		case "-":
+			v = a - b
+		case "*":
+			v = a * b
+		case "/":
+			switch {
+			case t.Kind != Int:
+				v = a / b
+			case b != 0:
+				// Integer division by zero is undefined; it yields zero.
+				v = math.Trunc(a / b)
+			}
+		}
+		r[i] = float32(v)
+	}
+	return r
+}
+
+// matMul multiplies the column-major rows×inner matrix x by the inner×cols
+// matrix y.
+func matMul(x, y []float32, rows, inner, cols int) []float32 {
+	r := make([]float32, rows*cols)
+	for c := 0; c < cols; c++ {
+		for row := 0; row < rows; row++ {
+			var s float64
+			for k := 0; k < inner; k++ {
+				s += float64(x[k*rows+row]) * float64(y[c*inner+k])
+			}
+			r[c*rows+row] = float32(s)
+		}
+	}
+	return r
+}
+
+// construct computes the value of a constructor of t, whose arguments have
+// the given values and types.
+func construct(t *Type, args [][]float32, types []*Type) []float32 {
+	r := make([]float32, t.Components())
+	if t.Kind == Struct {
+		r = r[:0]
+		for _, a := range args {
+			r = append(r, a...)
+		}
+		return r
+	}
+
+	from := types[0]
+	switch {
+	case len(args) == 1 && from.isScalar() && t.isMatrix():
+		v := conv(t.Kind, from.Kind, args[0][0])
+		for i := 0; i < t.Cols; i++ {
+			r[i*t.Size+i] = v
+		}
+		return r
+	case len(args) == 1 && from.isScalar():
+		v := conv(t.Kind, from.Kind, args[0][0])
+		for i := range r {
+			r[i] = v
+		}
+		return r
+	case len(args) == 1 && from.isMatrix() && t.isMatrix():
+		// The overlapping part of the matrix is copied over the identity.
+		for c := 0; c < t.Cols; c++ {
+			for row := 0; row < t.Size; row++ {
+				switch {
+				case c < from.Cols && row < from.Size:
+					r[c*t.Size+row] = args[0][c*from.Size+row]
+				case c == row:
+					r[c*t.Size+row] = 1
+				}
+			}
+		}
+		return r
+	}
+
+	i := 0
+	for k, a := range args {
+		for _, v := range a {
+			if i == len(r) {
+				return r
+			}
+			r[i] = conv(t.Kind, types[k].Kind, v)
+			i++
+		}
+	}
+	return r
+}
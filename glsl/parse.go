@@ -0,0 +1,903 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package glsl
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/slimsag/gfx"
+)
+
+// parser parses and type checks the tokens of a shader.
+type parser struct {
+	toks []token
+	pos  int
+	eof  token
+
+	s      *Shader
+	scopes []map[string]interface{} // *variable or *Type (of a structure).
+	funcs  map[string][]*function
+
+	fn    *function // The function being parsed, or nil.
+	loops int       // The depth of nested loops.
+
+	usedFragColor, usedFragData bool
+
+	// calls are the calls of user-defined functions, which must be defined.
+	calls []*callExpr
+}
+
+// parse parses the preprocessed tokens of a shader of the given type.
+func parse(toks []token, t gfx.ShaderType) (s *Shader, err error) {
+	p := &parser{
+		toks: toks,
+		eof:  token{kind: tEOF, text: "end of shader", line: lastLine(toks)},
+		s: &Shader{
+			Type:   t,
+			byName: make(map[string]*variable),
+		},
+		funcs: make(map[string][]*function),
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			e, ok := r.(*Error)
+			if !ok {
+				panic(r)
+			}
+			s, err = nil, e
+		}
+	}()
+	p.push()
+	p.declareBuiltins()
+	p.push() // The global scope of the shader, which may hide built-ins.
+	for p.peek().kind != tEOF {
+		p.external()
+	}
+
+	if p.usedFragColor && p.usedFragData {
+		p.fail(p.eof.line, "both gl_FragColor and gl_FragData are used")
+	}
+	for _, fns := range p.funcs {
+		for _, fn := range fns {
+			if fn.name == "main" && len(fn.params) == 0 && fn.ret == voidType && fn.body != nil {
+				p.s.main = fn
+			}
+		}
+	}
+	if p.s.main == nil {
+		p.fail(p.eof.line, "missing main function")
+	}
+	for _, c := range p.calls {
+		if c.fn.body == nil {
+			p.fail(c.line, "function %s is declared but not defined", c.name)
+		}
+	}
+	return p.s, nil
+}
+
+// fail reports an error, by panicking with it.
+func (p *parser) fail(line int, format string, args ...interface{}) {
+	panic(errorf(line, format, args...))
+}
+
+func (p *parser) peek() token {
+	return p.peekN(0)
+}
+
+func (p *parser) peekN(n int) token {
+	if p.pos+n < len(p.toks) {
+		return p.toks[p.pos+n]
+	}
+	return p.eof
+}
+
+func (p *parser) next() token {
+	t := p.peek()
+	if p.pos < len(p.toks) {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it is a keyword or punctuator with the
+// given text.
+func (p *parser) accept(text string) bool {
+	if t := p.peek(); t.text == text && t.kind != tEOF {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(text string) token {
+	t := p.next()
+	if t.text != text || t.kind == tEOF {
+		p.fail(t.line, "expected %s, found %s", text, t.text)
+	}
+	return t
+}
+
+// keywords are the keywords and reserved words, which cannot be used as
+// identifiers.
+var keywords = map[string]bool{}
+
+func init() {
+	for _, k := range strings.Fields(`
+		attribute const uniform varying break continue do for while if else
+		in out inout float int void bool true false lowp mediump highp
+		precision invariant discard return mat2 mat3 mat4 vec2 vec3 vec4
+		ivec2 ivec3 ivec4 bvec2 bvec3 bvec4 sampler2D samplerCube struct
+		asm class union enum typedef template this packed goto switch default
+		inline noinline volatile public static extern external interface flat
+		long short double half fixed unsigned superp input output hvec2 hvec3
+		hvec4 dvec2 dvec3 dvec4 fvec2 fvec3 fvec4 sampler1D sampler3D
+		sampler1DShadow sampler2DShadow sampler2DRect sampler3DRect
+		sampler2DRectShadow sizeof cast namespace using`) {
+		keywords[k] = true
+	}
+}
+
+// ident consumes an identifier.
+func (p *parser) ident() token {
+	t := p.next()
+	if t.kind != tIdent || keywords[t.text] {
+		p.fail(t.line, "expected identifier, found %s", t.text)
+	}
+	return t
+}
+
+func (p *parser) push() {
+	p.scopes = append(p.scopes, make(map[string]interface{}))
+}
+
+func (p *parser) pop() {
+	p.scopes = p.scopes[:len(p.scopes)-1]
+}
+
+// lookup returns the variable or structure type with the given name.
+func (p *parser) lookup(name string) interface{} {
+	for i := len(p.scopes) - 1; i >= 0; i-- {
+		if x, ok := p.scopes[i][name]; ok {
+			return x
+		}
+	}
+	return nil
+}
+
+// declare declares a variable or structure type in the current scope.
+func (p *parser) declare(name string, line int, x interface{}) {
+	if len(p.scopes) > 1 && (strings.HasPrefix(name, "gl_") || strings.Contains(name, "__")) {
+		p.fail(line, "identifier %s is reserved", name)
+	}
+	scope := p.scopes[len(p.scopes)-1]
+	if _, ok := scope[name]; ok {
+		p.fail(line, "%s redeclared in this scope", name)
+	}
+	if len(p.scopes) == 2 && len(p.funcs[name]) > 0 {
+		p.fail(line, "%s redeclared as a variable", name)
+	}
+	scope[name] = x
+}
+
+// declareGlobal declares a global variable.
+func (p *parser) declareGlobal(v *variable, input bool) {
+	v.global = true
+	v.index = len(p.s.globals)
+	p.s.globals = append(p.s.globals, v)
+	p.s.inputs = append(p.s.inputs, input)
+	p.s.byName[v.name] = v
+	p.declare(v.name, v.line, v)
+}
+
+// declareBuiltins declares the built-in variables and constants.
+func (p *parser) declareBuiltins() {
+	add := func(name string, t *Type, readOnly, input bool, init ...float32) {
+		p.declareGlobal(&variable{name: name, typ: t, readOnly: readOnly, konst: init}, input)
+	}
+	if p.s.Type == gfx.VertexShader {
+		add("gl_Position", vecTypes[4], false, false)
+		add("gl_PointSize", floatType, false, false, 1)
+	} else {
+		add("gl_FragCoord", vecTypes[4], true, true)
+		add("gl_FrontFacing", boolType, true, true, 1)
+		add("gl_PointCoord", vecTypes[2], true, true)
+		add("gl_FragColor", vecTypes[4], false, false)
+		add("gl_FragData", &Type{Kind: Array, Elem: vecTypes[4], Len: 1}, false, false)
+	}
+
+	depthRange := &Type{Kind: Struct, Name: "gl_DepthRangeParameters", Fields: []Field{
+		{"near", floatType},
+		{"far", floatType},
+		{"diff", floatType},
+	}}
+	p.declare(depthRange.Name, 0, depthRange)
+	add("gl_DepthRange", depthRange, true, true, 0, 1, 1)
+
+	for _, c := range []struct {
+		name  string
+		value float32
+	}{
+		{"gl_MaxVertexAttribs", 8},
+		{"gl_MaxVertexUniformVectors", 128},
+		{"gl_MaxVaryingVectors", 8},
+		{"gl_MaxVertexTextureImageUnits", 0},
+		{"gl_MaxCombinedTextureImageUnits", 8},
+		{"gl_MaxTextureImageUnits", 8},
+		{"gl_MaxFragmentUniformVectors", 16},
+		{"gl_MaxDrawBuffers", 1},
+	} {
+		v := &variable{name: c.name, typ: intType, qual: qualConst, readOnly: true, konst: []float32{c.value}}
+		p.declare(c.name, 0, v)
+	}
+}
+
+// external parses a declaration or function definition at global scope.
+func (p *parser) external() {
+	t := p.peek()
+	switch {
+	case t.text == "precision":
+		p.precision()
+		return
+	case t.text == "invariant" && p.peekN(1).kind == tIdent && !keywords[p.peekN(1).text]:
+		p.invariant()
+		return
+	}
+	q, typ := p.fullType()
+	if p.accept(";") {
+		return // A structure declaration.
+	}
+	name := p.ident()
+	if p.peek().text == "(" {
+		if q != qualNone {
+			p.fail(name.line, "functions cannot have storage qualifiers")
+		}
+		p.function(typ, name)
+		return
+	}
+	p.declarators(q, typ, name)
+}
+
+// precision parses a default precision statement, which has no effect.
+func (p *parser) precision() {
+	p.expect("precision")
+	if !p.precisionQualifier() {
+		p.fail(p.peek().line, "expected precision qualifier")
+	}
+	t := p.typeSpecifier()
+	if t != floatType && t != intType && t != sampler2DType && t != samplerCubeType {
+		p.fail(p.peek().line, "invalid type %s in precision statement", t)
+	}
+	p.expect(";")
+}
+
+// invariant parses an invariant statement, which has no effect.
+func (p *parser) invariant() {
+	p.expect("invariant")
+	for {
+		t := p.ident()
+		v, ok := p.lookup(t.text).(*variable)
+		if !ok || !(v.qual == qualVarying || v.name == "gl_Position" || v.name == "gl_PointSize") || p.s.Type != gfx.VertexShader {
+			p.fail(t.line, "%s cannot be declared invariant", t.text)
+		}
+		if !p.accept(",") {
+			break
+		}
+	}
+	p.expect(";")
+}
+
+// precisionQualifier consumes an optional precision qualifier.
+func (p *parser) precisionQualifier() bool {
+	switch p.peek().text {
+	case "lowp", "mediump", "highp":
+		p.next()
+		return true
+	}
+	return false
+}
+
+// fullType parses the qualifiers and type of a declaration.
+func (p *parser) fullType() (qualifier, *Type) {
+	line := p.peek().line
+	invariant := p.accept("invariant")
+	q := qualNone
+	switch p.peek().text {
+	case "const":
+		q = qualConst
+	case "attribute":
+		q = qualAttribute
+	case "uniform":
+		q = qualUniform
+	case "varying":
+		q = qualVarying
+	}
+	if q != qualNone {
+		p.next()
+	}
+	if invariant && q != qualVarying {
+		p.fail(line, "only varyings can be declared invariant")
+	}
+	p.precisionQualifier()
+	return q, p.typeSpecifier()
+}
+
+// isType tells if the token is the name of a type.
+func (p *parser) isType(t token) bool {
+	if t.kind != tIdent {
+		return false
+	}
+	if _, ok := basicTypes[t.text]; ok {
+		return true
+	}
+	_, ok := p.lookup(t.text).(*Type)
+	return ok
+}
+
+// typeSpecifier parses a type, or a structure declaration.
+func (p *parser) typeSpecifier() *Type {
+	t := p.next()
+	if t.text == "struct" {
+		return p.structType()
+	}
+	if typ, ok := basicTypes[t.text]; ok && t.kind == tIdent {
+		return typ
+	}
+	if typ, ok := p.lookup(t.text).(*Type); ok && t.kind == tIdent {
+		return typ
+	}
+	p.fail(t.line, "expected type, found %s", t.text)
+	return nil
+}
+
+// structType parses a structure declaration, after the struct keyword.
+func (p *parser) structType() *Type {
+	t := &Type{Kind: Struct}
+	line := p.peek().line
+	if p.peek().text != "{" {
+		name := p.ident()
+		t.Name, line = name.text, name.line
+	}
+	p.expect("{")
+	p.push()
+	for !p.accept("}") {
+		p.precisionQualifier()
+		typ := p.typeSpecifier()
+		for {
+			name := p.ident()
+			ft := p.arraySize(typ)
+			if ft.Kind == Void {
+				p.fail(name.line, "field %s has type void", name.text)
+			}
+			for _, f := range t.Fields {
+				if f.Name == name.text {
+					p.fail(name.line, "duplicate field %s", name.text)
+				}
+			}
+			t.Fields = append(t.Fields, Field{Name: name.text, Type: ft})
+			if !p.accept(",") {
+				break
+			}
+		}
+		p.expect(";")
+	}
+	p.pop()
+	if len(t.Fields) == 0 {
+		p.fail(line, "structure has no fields")
+	}
+	if t.Name != "" {
+		p.declare(t.Name, line, t)
+	} else {
+		t.Name = "struct"
+	}
+	return t
+}
+
+// arraySize parses an optional array size following a declarator, returning
+// the type of the declared variable.
+func (p *parser) arraySize(t *Type) *Type {
+	if !p.accept("[") {
+		return t
+	}
+	line := p.peek().line
+	n := p.expression()
+	p.expect("]")
+	if n.base().typ != intType || n.base().konst == nil {
+		p.fail(line, "array size must be a constant integer expression")
+	}
+	size := int(n.base().konst[0])
+	if size <= 0 {
+		p.fail(line, "array size must be positive")
+	}
+	if t.Kind == Array {
+		p.fail(line, "arrays of arrays are not allowed")
+	}
+	return &Type{Kind: Array, Elem: t, Len: size}
+}
+
+// declarators parses the declarators of a variable declaration, starting with
+// the given name, and returns the declared variables and their initializers.
+func (p *parser) declarators(q qualifier, typ *Type, name token) *declStmt {
+	d := &declStmt{}
+	global := p.fn == nil
+	for {
+		v := &variable{name: name.text, typ: p.arraySize(typ), qual: q, line: name.line}
+		p.checkDecl(v, global)
+
+		var init expr
+		if p.accept("=") {
+			init = p.assignment()
+			p.checkInit(v, init, global)
+		} else if q == qualConst {
+			p.fail(v.line, "constant %s must be initialized", v.name)
+		}
+		if global {
+			input := q == qualUniform || q == qualAttribute || (q == qualVarying && p.s.Type == gfx.FragmentShader)
+			p.declareGlobal(v, input)
+			gv := &Variable{Name: v.name, Type: v.typ}
+			switch q {
+			case qualAttribute:
+				p.s.Attributes = append(p.s.Attributes, gv)
+			case qualUniform:
+				p.s.Uniforms = append(p.s.Uniforms, gv)
+			case qualVarying:
+				p.s.Varyings = append(p.s.Varyings, gv)
+			}
+		} else {
+			v.index = p.fn.locals
+			p.fn.locals++
+			p.declare(v.name, v.line, v)
+			d.vars = append(d.vars, v)
+			d.inits = append(d.inits, init)
+		}
+		if !p.accept(",") {
+			break
+		}
+		name = p.ident()
+	}
+	p.expect(";")
+	return d
+}
+
+// function parses a function prototype or definition, after its name.
+func (p *parser) function(ret *Type, name token) {
+	if p.fn != nil {
+		p.fail(name.line, "functions cannot be declared in functions")
+	}
+	if ret.Kind == Array {
+		p.fail(name.line, "functions cannot return arrays")
+	}
+	fn := &function{name: name.text, ret: ret, line: name.line}
+	p.expect("(")
+	if p.peek().text == "void" && p.peekN(1).text == ")" {
+		p.next()
+	}
+	for !p.accept(")") {
+		if len(fn.params) > 0 {
+			p.expect(",")
+		}
+		fn.params = append(fn.params, p.param(fn))
+	}
+
+	if _, ok := p.lookup(name.text).(*variable); ok && len(p.scopes) == 2 {
+		p.fail(name.line, "%s redeclared as a function", name.text)
+	}
+	if _, ok := p.scopes[1][name.text].(*Type); ok {
+		p.fail(name.line, "%s redeclared as a function", name.text)
+	}
+	decl := p.findFunc(fn)
+	if decl == nil {
+		p.funcs[fn.name] = append(p.funcs[fn.name], fn)
+		decl = fn
+	} else if !decl.ret.Equal(fn.ret) {
+		p.fail(name.line, "function %s redeclared with a different return type", fn.name)
+	}
+	if p.accept(";") {
+		return
+	}
+	if decl.body != nil {
+		p.fail(name.line, "function %s redefined", fn.name)
+	}
+
+	// Calls of a prototype refer to the function declared first, which gets
+	// the parameters and body of the definition.
+	decl.params = fn.params
+	decl.locals = fn.locals
+	p.fn = decl
+	p.push()
+	for _, v := range decl.params {
+		if v.name != "" {
+			p.declare(v.name, v.line, v)
+		}
+	}
+	p.expect("{")
+	decl.body = p.block(false)
+	p.pop()
+	p.fn = nil
+}
+
+// findFunc returns the previously declared function with the same name and
+// parameter types as fn, or nil.
+func (p *parser) findFunc(fn *function) *function {
+outer:
+	for _, f := range p.funcs[fn.name] {
+		if len(f.params) != len(fn.params) {
+			continue
+		}
+		for i, v := range f.params {
+			if !v.typ.Equal(fn.params[i].typ) {
+				continue outer
+			}
+			if v.qual != fn.params[i].qual {
+				p.fail(fn.line, "function %s redeclared with different parameter qualifiers", fn.name)
+			}
+		}
+		return f
+	}
+	return nil
+}
+
+// param parses a function parameter, whose local variable slot is allocated
+// in fn.
+func (p *parser) param(fn *function) *variable {
+	line := p.peek().line
+	konst := p.accept("const")
+	q := qualIn
+	switch p.peek().text {
+	case "in":
+		p.next()
+	case "out":
+		q = qualOut
+		p.next()
+	case "inout":
+		q = qualInOut
+		p.next()
+	}
+	if konst && q != qualIn {
+		p.fail(line, "const parameters cannot be out or inout")
+	}
+	p.precisionQualifier()
+	typ := p.typeSpecifier()
+	v := &variable{typ: typ, qual: q, line: line, readOnly: konst, index: fn.locals}
+	fn.locals++
+	if p.peek().kind == tIdent && !keywords[p.peek().text] {
+		name := p.ident()
+		v.name, v.line = name.text, name.line
+		v.typ = p.arraySize(typ)
+	}
+	switch {
+	case v.typ.Kind == Void:
+		p.fail(line, "parameter has type void")
+	case v.typ.hasSampler() && q != qualIn:
+		p.fail(line, "sampler parameters cannot be out or inout")
+	}
+	return v
+}
+
+// block parses the statements of a block, after the opening brace.
+func (p *parser) block(scope bool) *blockStmt {
+	b := &blockStmt{scope: scope}
+	if scope {
+		p.push()
+	}
+	for !p.accept("}") {
+		if p.peek().kind == tEOF {
+			p.fail(p.eof.line, "missing }")
+		}
+		if s := p.statement(); s != nil {
+			b.list = append(b.list, s)
+		}
+	}
+	if scope {
+		p.pop()
+	}
+	return b
+}
+
+// isDecl tells if a declaration starts at the next token.
+func (p *parser) isDecl() bool {
+	t := p.peek()
+	switch t.text {
+	case "const", "attribute", "uniform", "varying", "invariant", "struct", "lowp", "mediump", "highp":
+		return t.kind == tIdent
+	}
+	return p.isType(t) && p.peekN(1).kind == tIdent
+}
+
+// scoped parses the body of a control statement in a new scope.
+func (p *parser) scoped() stmt {
+	p.push()
+	s := p.statement()
+	p.pop()
+	return s
+}
+
+// statement parses a statement, returning nil for an empty statement.
+func (p *parser) statement() stmt {
+	t := p.peek()
+	switch t.text {
+	case "{":
+		p.next()
+		return p.block(true)
+	case ";":
+		p.next()
+		return nil
+	case "precision":
+		p.precision()
+		return nil
+	case "if":
+		p.next()
+		p.expect("(")
+		s := &ifStmt{cond: p.boolExpr(p.expression())}
+		p.expect(")")
+		s.then = p.scoped()
+		if p.accept("else") {
+			s.els = p.scoped()
+		}
+		return s
+	case "for":
+		p.next()
+		p.expect("(")
+		p.push()
+		defer p.pop()
+		s := &forStmt{}
+		if !p.accept(";") {
+			s.init = p.simpleStatement()
+		}
+		if p.peek().text != ";" {
+			s.cond, s.condVar = p.condition()
+		}
+		p.expect(";")
+		if p.peek().text != ")" {
+			s.post = p.expression()
+		}
+		p.expect(")")
+		s.body = p.loopBody()
+		return s
+	case "while":
+		p.next()
+		p.expect("(")
+		p.push()
+		defer p.pop()
+		s := &forStmt{}
+		s.cond, s.condVar = p.condition()
+		p.expect(")")
+		s.body = p.loopBody()
+		return s
+	case "do":
+		p.next()
+		s := &forStmt{do: true}
+		s.body = p.loopBody()
+		p.expect("while")
+		p.expect("(")
+		s.cond = p.boolExpr(p.expression())
+		p.expect(")")
+		p.expect(";")
+		return s
+	case "return":
+		p.next()
+		s := &returnStmt{line: t.line}
+		if !p.accept(";") {
+			s.x = p.expression()
+			p.expect(";")
+		}
+		p.checkReturn(s)
+		return s
+	case "break", "continue":
+		p.next()
+		p.expect(";")
+		if p.loops == 0 {
+			p.fail(t.line, "%s outside of a loop", t.text)
+		}
+		return &branchStmt{tok: t.text}
+	case "discard":
+		p.next()
+		p.expect(";")
+		if p.s.Type != gfx.FragmentShader {
+			p.fail(t.line, "discard outside of a fragment shader")
+		}
+		return &branchStmt{tok: t.text}
+	}
+	return p.simpleStatement()
+}
+
+// simpleStatement parses a declaration or expression statement.
+func (p *parser) simpleStatement() stmt {
+	if p.isDecl() {
+		q, typ := p.fullType()
+		if p.accept(";") {
+			return nil
+		}
+		return p.declarators(q, typ, p.ident())
+	}
+	s := &exprStmt{x: p.expression()}
+	p.expect(";")
+	return s
+}
+
+// loopBody parses the body of a loop.
+func (p *parser) loopBody() stmt {
+	p.loops++
+	s := p.scoped()
+	p.loops--
+	return s
+}
+
+// condition parses the condition of a for or while loop, which may declare a
+// variable.
+func (p *parser) condition() (expr, *variable) {
+	if !p.isDecl() {
+		return p.boolExpr(p.expression()), nil
+	}
+	p.precisionQualifier()
+	typ := p.typeSpecifier()
+	name := p.ident()
+	v := &variable{name: name.text, typ: typ, line: name.line, index: p.fn.locals}
+	p.fn.locals++
+	p.declare(v.name, v.line, v)
+	p.expect("=")
+	init := p.assignment()
+	p.checkInit(v, init, false)
+	x := &identExpr{exprBase: exprBase{line: name.line, typ: typ}, name: v.name, v: v}
+	return p.boolExpr(&assignExpr{exprBase: exprBase{line: name.line, typ: typ, effects: true}, op: "=", x: x, y: init}), v
+}
+
+// expression parses an expression, including sequences.
+func (p *parser) expression() expr {
+	x := p.assignment()
+	if p.peek().text != "," {
+		return x
+	}
+	s := &seqExpr{list: []expr{x}}
+	for p.accept(",") {
+		s.list = append(s.list, p.assignment())
+	}
+	return p.checkSeq(s)
+}
+
+// assignOps are the assignment operators.
+var assignOps = map[string]bool{
+	"=": true, "+=": true, "-=": true, "*=": true, "/=": true,
+	"%=": true, "<<=": true, ">>=": true, "&=": true, "^=": true, "|=": true,
+}
+
+func (p *parser) assignment() expr {
+	x := p.conditional()
+	t := p.peek()
+	if t.kind != tPunct || !assignOps[t.text] {
+		return x
+	}
+	p.next()
+	y := p.assignment()
+	return p.checkAssign(t.text, x, y, t.line)
+}
+
+func (p *parser) conditional() expr {
+	c := p.binary(0)
+	t := p.peek()
+	if !p.accept("?") {
+		return c
+	}
+	x := p.expression()
+	p.expect(":")
+	y := p.assignment()
+	return p.checkCond(c, x, y, t.line)
+}
+
+// binaryOps are the binary operators, by precedence.
+var binaryOps = map[string]int{
+	"||": 1,
+	"^^": 2,
+	"&&": 3,
+	"|":  4,
+	"^":  5,
+	"&":  6,
+	"==": 7, "!=": 7,
+	"<": 8, ">": 8, "<=": 8, ">=": 8,
+	"<<": 9, ">>": 9,
+	"+": 10, "-": 10,
+	"*": 11, "/": 11, "%": 11,
+}
+
+// binary parses an expression whose binary operators have a precedence
+// greater than prec.
+func (p *parser) binary(prec int) expr {
+	x := p.unary()
+	for {
+		t := p.peek()
+		op, ok := binaryOps[t.text]
+		if !ok || t.kind != tPunct || op <= prec {
+			return x
+		}
+		p.next()
+		y := p.binary(op)
+		x = p.checkBinary(t.text, x, y, t.line)
+	}
+}
+
+func (p *parser) unary() expr {
+	t := p.peek()
+	switch t.text {
+	case "+", "-", "!", "~", "++", "--":
+		if t.kind == tPunct {
+			p.next()
+			return p.checkUnary(t.text, p.unary(), t.line)
+		}
+	}
+	return p.postfix()
+}
+
+func (p *parser) postfix() expr {
+	x := p.primary()
+	for {
+		t := p.peek()
+		switch {
+		case p.accept("["):
+			i := p.expression()
+			p.expect("]")
+			x = p.checkIndex(x, i, t.line)
+		case p.accept("."):
+			x = p.checkField(x, p.next(), t.line)
+		case p.accept("++"), p.accept("--"):
+			x = p.checkPostfix(t.text, x, t.line)
+		default:
+			return x
+		}
+	}
+}
+
+func (p *parser) primary() expr {
+	t := p.next()
+	switch t.kind {
+	case tInt:
+		v, err := strconv.ParseInt(t.text, 0, 64)
+		if err != nil || v > 1<<31-1 {
+			p.fail(t.line, "integer constant %s out of range", t.text)
+		}
+		return literal(t.line, intType, float32(v))
+	case tFloat:
+		v, err := strconv.ParseFloat(t.text, 32)
+		if err != nil {
+			p.fail(t.line, "invalid floating-point constant %s", t.text)
+		}
+		return literal(t.line, floatType, float32(v))
+	case tIdent:
+		switch {
+		case t.text == "true":
+			return literal(t.line, boolType, 1)
+		case t.text == "false":
+			return literal(t.line, boolType, 0)
+		case t.text == "struct":
+			p.fail(t.line, "unexpected struct")
+		case p.peek().text == "(":
+			p.next()
+			var args []expr
+			if p.peek().text == "void" && p.peekN(1).text == ")" {
+				p.next()
+			}
+			for !p.accept(")") {
+				if len(args) > 0 {
+					p.expect(",")
+				}
+				args = append(args, p.assignment())
+			}
+			return p.checkCall(t.text, args, t.line)
+		case keywords[t.text]:
+			p.fail(t.line, "unexpected %s", t.text)
+		}
+		return p.checkIdent(t.text, t.line)
+	}
+	if t.text == "(" {
+		x := p.expression()
+		p.expect(")")
+		return x
+	}
+	p.fail(t.line, "unexpected %s", t.text)
+	return nil
+}
+
+func literal(line int, t *Type, v float32) expr {
+	return &literalExpr{exprBase{line: line, typ: t, konst: []float32{v}}}
+}
@@ -0,0 +1,546 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package glsl
+
+import (
+	"strconv"
+	"strings"
+)
+
+// macro is a macro defined by #define.
+type macro struct {
+	// funcLike tells if the macro is function-like, with the given
+	// parameters.
+	funcLike bool
+	params   []string
+
+	body []token
+}
+
+// cond is the state of a #if, #ifdef or #ifndef section.
+type cond struct {
+	active  bool // The current group is active.
+	taken   bool // A group of the section was active.
+	sawElse bool
+}
+
+// preprocessor runs the directives and expands the macros of a shader.
+type preprocessor struct {
+	macros map[string]*macro
+	conds  []cond
+	out    []token
+}
+
+// preprocess runs the preprocessor on the tokens of a shader.
+func preprocess(toks []token, fragment bool) ([]token, error) {
+	p := &preprocessor{macros: make(map[string]*macro)}
+	def := func(name, value string) {
+		p.macros[name] = &macro{body: []token{{kind: tInt, text: value}}}
+	}
+	def("GL_ES", "1")
+	def("__VERSION__", "100")
+	def("__FILE__", "0")
+	if fragment {
+		def("GL_FRAGMENT_PRECISION_HIGH", "1")
+	}
+
+	var pending []token
+	flush := func() error {
+		exp, err := p.expand(pending, nil)
+		if err != nil {
+			return err
+		}
+		p.out = append(p.out, exp...)
+		pending = pending[:0]
+		return nil
+	}
+	for i := 0; i < len(toks); {
+		// Split off the next line.
+		end := i + 1
+		for end < len(toks) && !toks[end].bol {
+			end++
+		}
+		line := toks[i:end]
+		first := i == 0
+		i = end
+
+		if line[0].bol && line[0].text == "#" {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			if err := p.directive(line, first); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if p.active() {
+			pending = append(pending, line...)
+		}
+	}
+	if len(p.conds) > 0 {
+		return nil, errorf(lastLine(toks), "missing #endif")
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return p.out, nil
+}
+
+func lastLine(toks []token) int {
+	if len(toks) == 0 {
+		return 1
+	}
+	return toks[len(toks)-1].line
+}
+
+// active tells if the current group of lines is active.
+func (p *preprocessor) active() bool {
+	return len(p.conds) == 0 || p.conds[len(p.conds)-1].active
+}
+
+// directive runs the directive on the given line, which starts with "#".
+// first tells if the directive is the first token of the shader.
+func (p *preprocessor) directive(line []token, first bool) error {
+	if len(line) == 1 {
+		return nil // The null directive.
+	}
+	ln := line[0].line
+	name, args := line[1].text, line[2:]
+
+	// Conditionals are processed even in inactive groups.
+	switch name {
+	case "if", "ifdef", "ifndef":
+		c := cond{}
+		if p.active() {
+			v, err := p.condition(name, args, ln)
+			if err != nil {
+				return err
+			}
+			c.active, c.taken = v, v
+		} else {
+			c.taken = true
+		}
+		p.conds = append(p.conds, c)
+		return nil
+	case "elif", "else", "endif":
+		if len(p.conds) == 0 {
+			return errorf(ln, "#%s without #if", name)
+		}
+		c := &p.conds[len(p.conds)-1]
+		switch name {
+		case "elif":
+			if c.sawElse {
+				return errorf(ln, "#elif after #else")
+			}
+			c.active = false
+			if !c.taken {
+				v, err := p.condition("if", args, ln)
+				if err != nil {
+					return err
+				}
+				c.active, c.taken = v, v
+			}
+		case "else":
+			if c.sawElse {
+				return errorf(ln, "#else after #else")
+			}
+			c.sawElse = true
+			c.active, c.taken = !c.taken, true
+		case "endif":
+			p.conds = p.conds[:len(p.conds)-1]
+		}
+		return nil
+	}
+	if !p.active() {
+		return nil
+	}
+
+	switch name {
+	case "define":
+		return p.define(args, ln)
+	case "undef":
+		if len(args) != 1 || args[0].kind != tIdent {
+			return errorf(ln, "invalid #undef")
+		}
+		if strings.HasPrefix(args[0].text, "GL_") || strings.HasPrefix(args[0].text, "__") {
+			return errorf(ln, "cannot undefine predefined macro %s", args[0].text)
+		}
+		delete(p.macros, args[0].text)
+	case "version":
+		if !first {
+			return errorf(ln, "#version must be the first directive")
+		}
+		if len(args) != 1 || args[0].text != "100" {
+			return errorf(ln, "unsupported #version, only 100 is supported")
+		}
+	case "extension":
+		if len(args) != 3 || args[0].kind != tIdent || args[1].text != ":" {
+			return errorf(ln, "invalid #extension")
+		}
+		switch args[2].text {
+		case "require":
+			return errorf(ln, "extension %s is not supported", args[0].text)
+		case "enable":
+			if args[0].text == "all" {
+				return errorf(ln, "#extension all cannot be enabled")
+			}
+		case "warn", "disable":
+		default:
+			return errorf(ln, "invalid #extension behavior %s", args[2].text)
+		}
+	case "error":
+		var msg []string
+		for _, t := range args {
+			msg = append(msg, t.text)
+		}
+		return errorf(ln, "#error %s", strings.Join(msg, " "))
+	case "pragma", "line":
+		// Pragmas have no effect, and line numbers are never reported in
+		// terms of the #line directive.
+	default:
+		return errorf(ln, "invalid directive #%s", name)
+	}
+	return nil
+}
+
+// define runs a #define directive.
+func (p *preprocessor) define(args []token, ln int) error {
+	if len(args) == 0 || args[0].kind != tIdent {
+		return errorf(ln, "invalid #define")
+	}
+	name := args[0].text
+	if strings.HasPrefix(name, "GL_") || strings.Contains(name, "__") {
+		return errorf(ln, "macro name %s is reserved", name)
+	}
+	m := &macro{}
+	body := args[1:]
+	if len(body) > 0 && body[0].text == "(" && !body[0].space {
+		// A function-like macro.
+		m.funcLike = true
+		i := 1
+		for ; i < len(body) && body[i].text != ")"; i++ {
+			if len(m.params) > 0 {
+				if body[i].text != "," || i+1 >= len(body) {
+					return errorf(ln, "invalid macro parameters")
+				}
+				i++
+			}
+			if body[i].kind != tIdent {
+				return errorf(ln, "invalid macro parameter %s", body[i].text)
+			}
+			m.params = append(m.params, body[i].text)
+		}
+		if i >= len(body) {
+			return errorf(ln, "missing ) in macro parameters")
+		}
+		body = body[i+1:]
+	}
+	m.body = body
+	if old, ok := p.macros[name]; ok && !sameMacro(old, m) {
+		return errorf(ln, "macro %s redefined", name)
+	}
+	p.macros[name] = m
+	return nil
+}
+
+func sameMacro(a, b *macro) bool {
+	if a.funcLike != b.funcLike || len(a.params) != len(b.params) || len(a.body) != len(b.body) {
+		return false
+	}
+	for i := range a.params {
+		if a.params[i] != b.params[i] {
+			return false
+		}
+	}
+	for i := range a.body {
+		if a.body[i].text != b.body[i].text {
+			return false
+		}
+	}
+	return true
+}
+
+// expand expands the macros in toks. Macros in hide are not expanded, as
+// they are being expanded already.
+func (p *preprocessor) expand(toks []token, hide map[string]bool) ([]token, error) {
+	var out []token
+	for i := 0; i < len(toks); i++ {
+		t := toks[i]
+		if t.kind != tIdent || hide[t.text] {
+			out = append(out, t)
+			continue
+		}
+		if t.text == "__LINE__" {
+			out = append(out, token{kind: tInt, text: strconv.Itoa(t.line), line: t.line})
+			continue
+		}
+		m, ok := p.macros[t.text]
+		if !ok || (m.funcLike && (i+1 >= len(toks) || toks[i+1].text != "(")) {
+			out = append(out, t)
+			continue
+		}
+
+		body := m.body
+		if m.funcLike {
+			args, end, err := macroArgs(toks, i+2, t.line)
+			if err != nil {
+				return nil, err
+			}
+			if len(m.params) == 0 && len(args) == 1 && len(args[0]) == 0 {
+				args = nil
+			}
+			if len(args) != len(m.params) {
+				return nil, errorf(t.line, "macro %s expects %d arguments, got %d", t.text, len(m.params), len(args))
+			}
+			body = nil
+			for _, b := range m.body {
+				arg := -1
+				for k, param := range m.params {
+					if b.kind == tIdent && b.text == param {
+						arg = k
+					}
+				}
+				if arg < 0 {
+					body = append(body, b)
+					continue
+				}
+				exp, err := p.expand(args[arg], hide)
+				if err != nil {
+					return nil, err
+				}
+				body = append(body, exp...)
+			}
+			i = end
+		}
+
+		h := map[string]bool{t.text: true}
+		for name := range hide {
+			h[name] = true
+		}
+		exp, err := p.expand(relocate(body, t.line), h)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, exp...)
+	}
+	return out, nil
+}
+
+// relocate returns a copy of the tokens of a macro expansion, moved to the line
+// of the macro invocation.
+func relocate(toks []token, line int) []token {
+	r := make([]token, len(toks))
+	for i, t := range toks {
+		t.line, t.bol = line, false
+		r[i] = t
+	}
+	return r
+}
+
+// macroArgs collects the arguments of a function-like macro invocation that
+// start at toks[i], after the opening parenthesis. It returns the index of
+// the closing parenthesis.
+func macroArgs(toks []token, i, line int) ([][]token, int, error) {
+	var (
+		args  [][]token
+		arg   = []token{}
+		depth = 0
+	)
+	for ; i < len(toks); i++ {
+		switch t := toks[i]; {
+		case t.text == "(":
+			depth++
+		case t.text == ")" && depth == 0:
+			return append(args, arg), i, nil
+		case t.text == ")":
+			depth--
+		case t.text == "," && depth == 0:
+			args = append(args, arg)
+			arg = []token{}
+			continue
+		}
+		arg = append(arg, toks[i])
+	}
+	return nil, 0, errorf(line, "unterminated macro invocation")
+}
+
+// condition evaluates the condition of a #if, #ifdef or #ifndef directive.
+func (p *preprocessor) condition(name string, args []token, ln int) (bool, error) {
+	if name != "if" {
+		if len(args) != 1 || args[0].kind != tIdent {
+			return false, errorf(ln, "invalid #%s", name)
+		}
+		_, ok := p.macros[args[0].text]
+		return ok == (name == "ifdef"), nil
+	}
+
+	// Replace the defined operators, then expand macros.
+	var toks []token
+	for i := 0; i < len(args); i++ {
+		if args[i].text != "defined" {
+			toks = append(toks, args[i])
+			continue
+		}
+		var id token
+		switch {
+		case i+1 < len(args) && args[i+1].kind == tIdent:
+			id = args[i+1]
+			i++
+		case i+3 < len(args) && args[i+1].text == "(" && args[i+2].kind == tIdent && args[i+3].text == ")":
+			id = args[i+2]
+			i += 3
+		default:
+			return false, errorf(ln, "invalid defined operator")
+		}
+		v := "0"
+		if _, ok := p.macros[id.text]; ok {
+			v = "1"
+		}
+		toks = append(toks, token{kind: tInt, text: v, line: ln})
+	}
+	toks, err := p.expand(toks, nil)
+	if err != nil {
+		return false, err
+	}
+	e := &ppExpr{toks: toks, line: ln}
+	v, err := e.parse(0)
+	if err == nil && e.pos < len(e.toks) {
+		err = errorf(ln, "unexpected %s in #if", e.toks[e.pos].text)
+	}
+	return v != 0, err
+}
+
+// ppExpr evaluates the integer expression of a #if directive.
+type ppExpr struct {
+	toks []token
+	pos  int
+	line int
+}
+
+// ppBinary are the binary operators of #if expressions, by precedence.
+var ppBinary = map[string]int{
+	"||": 1,
+	"&&": 2,
+	"|":  3,
+	"^":  4,
+	"&":  5,
+	"==": 6, "!=": 6,
+	"<": 7, ">": 7, "<=": 7, ">=": 7,
+	"<<": 8, ">>": 8,
+	"+": 9, "-": 9,
+	"*": 10, "/": 10, "%": 10,
+}
+
+// parse parses an expression whose binary operators have a precedence
+// greater than prec.
+func (e *ppExpr) parse(prec int) (int64, error) {
+	x, err := e.unary()
+	if err != nil {
+		return 0, err
+	}
+	for e.pos < len(e.toks) {
+		op := e.toks[e.pos].text
+		p, ok := ppBinary[op]
+		if !ok || p <= prec {
+			break
+		}
+		e.pos++
+		y, err := e.parse(p)
+		if err != nil {
+			return 0, err
+		}
+		if x, err = e.binary(op, x, y); err != nil {
+			return 0, err
+		}
+	}
+	return x, nil
+}
+
+func b2i(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func (e *ppExpr) binary(op string, x, y int64) (int64, error) {
+	switch op {
+	case "||":
+		return b2i(x != 0 || y != 0), nil
+	case "&&":
+		return b2i(x != 0 && y != 0), nil
+	case "|":
+		return x | y, nil
+	case "^":
+		return x ^ y, nil
+	case "&":
+		return x & y, nil
+	case "==":
+		return b2i(x == y), nil
+	case "!=":
+		return b2i(x != y), nil
+	case "<":
+		return b2i(x < y), nil
+	case ">":
+		return b2i(x > y), nil
+	case "<=":
+		return b2i(x <= y), nil
+	case ">=":
+		return b2i(x >= y), nil
+	case "<<":
+		return x << uint(y&63), nil
+	case ">>":
+		return x >> uint(y&63), nil
+	case "+":
+		return x + y, nil
+	case "-":
+		return x - y, nil
+	case "*":
+		return x * y, nil
+	}
+	if y == 0 {
+		return 0, errorf(e.line, "division by zero in #if")
+	}
+	if op == "/" {
+		return x / y, nil
+	}
+	return x % y, nil
+}
+
+func (e *ppExpr) unary() (int64, error) {
+	if e.pos >= len(e.toks) {
+		return 0, errorf(e.line, "missing expression in #if")
+	}
+	t := e.toks[e.pos]
+	e.pos++
+	switch {
+	case t.text == "(":
+		x, err := e.parse(0)
+		if err != nil {
+			return 0, err
+		}
+		if e.pos >= len(e.toks) || e.toks[e.pos].text != ")" {
+			return 0, errorf(e.line, "missing ) in #if")
+		}
+		e.pos++
+		return x, nil
+	case t.text == "+" || t.text == "-" || t.text == "!" || t.text == "~":
+		x, err := e.unary()
+		switch t.text {
+		case "-":
+			x = -x
+		case "!":
+			x = b2i(x == 0)
+		case "~":
+			x = ^x
+		}
+		return x, err
+	case t.kind == tInt:
+		return strconv.ParseInt(t.text, 0, 64)
+	case t.kind == tIdent:
+		return 0, errorf(e.line, "undefined identifier %s in #if", t.text)
+	}
+	return 0, errorf(e.line, "unexpected %s in #if", t.text)
+}
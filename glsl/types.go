@@ -0,0 +1,296 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package glsl
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Kind is the kind of a type.
+type Kind int
+
+const (
+	// Void is the kind of the return type of functions without a value.
+	Void Kind = iota
+
+	// Bool, Int and Float are the kinds of scalar, vector and matrix types.
+	// Matrices are always of the Float kind.
+	Bool
+	Int
+	Float
+
+	// Sampler2D and SamplerCube are the kinds of the sampler types.
+	Sampler2D
+	SamplerCube
+
+	// Struct is the kind of structure types.
+	Struct
+
+	// Array is the kind of array types.
+	Array
+)
+
+// Type is a GLSL type.
+type Type struct {
+	Kind Kind
+
+	// Size is the number of components of a vector, the number of rows of a
+	// matrix, or one for a scalar.
+	Size int
+
+	// Cols is the number of columns of a matrix, or zero for any other type.
+	Cols int
+
+	// Name and Fields are the name and fields of a structure.
+	Name   string
+	Fields []Field
+
+	// Elem and Len are the element type and length of an array.
+	Elem *Type
+	Len  int
+}
+
+// Field is a field of a structure.
+type Field struct {
+	Name string
+	Type *Type
+}
+
+// The predeclared types.
+var (
+	voidType        = &Type{Kind: Void}
+	boolType        = &Type{Kind: Bool, Size: 1}
+	intType         = &Type{Kind: Int, Size: 1}
+	floatType       = &Type{Kind: Float, Size: 1}
+	sampler2DType   = &Type{Kind: Sampler2D, Size: 1}
+	samplerCubeType = &Type{Kind: SamplerCube, Size: 1}
+
+	vecTypes  = [5]*Type{2: {Kind: Float, Size: 2}, 3: {Kind: Float, Size: 3}, 4: {Kind: Float, Size: 4}}
+	ivecTypes = [5]*Type{2: {Kind: Int, Size: 2}, 3: {Kind: Int, Size: 3}, 4: {Kind: Int, Size: 4}}
+	bvecTypes = [5]*Type{2: {Kind: Bool, Size: 2}, 3: {Kind: Bool, Size: 3}, 4: {Kind: Bool, Size: 4}}
+	matTypes  = [5]*Type{2: {Kind: Float, Size: 2, Cols: 2}, 3: {Kind: Float, Size: 3, Cols: 3}, 4: {Kind: Float, Size: 4, Cols: 4}}
+)
+
+// basicTypes maps the names of the predeclared types to them.
+var basicTypes = map[string]*Type{
+	"void":        voidType,
+	"bool":        boolType,
+	"int":         intType,
+	"float":       floatType,
+	"vec2":        vecTypes[2],
+	"vec3":        vecTypes[3],
+	"vec4":        vecTypes[4],
+	"bvec2":       bvecTypes[2],
+	"bvec3":       bvecTypes[3],
+	"bvec4":       bvecTypes[4],
+	"ivec2":       ivecTypes[2],
+	"ivec3":       ivecTypes[3],
+	"ivec4":       ivecTypes[4],
+	"mat2":        matTypes[2],
+	"mat3":        matTypes[3],
+	"mat4":        matTypes[4],
+	"sampler2D":   sampler2DType,
+	"samplerCube": samplerCubeType,
+}
+
+// vectorType returns the scalar or vector type of the given kind and size.
+func vectorType(k Kind, size int) *Type {
+	switch {
+	case size == 1 && k == Bool:
+		return boolType
+	case size == 1 && k == Int:
+		return intType
+	case size == 1:
+		return floatType
+	case k == Bool:
+		return bvecTypes[size]
+	case k == Int:
+		return ivecTypes[size]
+	}
+	return vecTypes[size]
+}
+
+// Components returns the number of scalar components of a value of the type.
+// Values are stored as their components, in order: the columns of a matrix,
+// the fields of a structure and the elements of an array.
+func (t *Type) Components() int {
+	switch t.Kind {
+	case Void:
+		return 0
+	case Struct:
+		n := 0
+		for _, f := range t.Fields {
+			n += f.Type.Components()
+		}
+		return n
+	case Array:
+		return t.Len * t.Elem.Components()
+	}
+	if t.Cols > 0 {
+		return t.Size * t.Cols
+	}
+	return t.Size
+}
+
+// field returns the field with the given name and its offset in components,
+// or nil if there is no such field.
+func (t *Type) field(name string) (*Field, int) {
+	off := 0
+	for i := range t.Fields {
+		f := &t.Fields[i]
+		if f.Name == name {
+			return f, off
+		}
+		off += f.Type.Components()
+	}
+	return nil, 0
+}
+
+func (t *Type) isScalar() bool {
+	return (t.Kind == Bool || t.Kind == Int || t.Kind == Float) && t.Size == 1 && t.Cols == 0
+}
+
+func (t *Type) isVector() bool {
+	return (t.Kind == Bool || t.Kind == Int || t.Kind == Float) && t.Size > 1 && t.Cols == 0
+}
+
+func (t *Type) isMatrix() bool {
+	return t.Cols > 0
+}
+
+// isNumeric tells if the type is an int or float scalar, vector or matrix.
+func (t *Type) isNumeric() bool {
+	return t.Kind == Int || t.Kind == Float
+}
+
+// isBasic tells if the type is a scalar, vector or matrix.
+func (t *Type) isBasic() bool {
+	return t.Kind == Bool || t.Kind == Int || t.Kind == Float
+}
+
+// hasSampler tells if the type is or contains a sampler.
+func (t *Type) hasSampler() bool {
+	switch t.Kind {
+	case Sampler2D, SamplerCube:
+		return true
+	case Struct:
+		for _, f := range t.Fields {
+			if f.Type.hasSampler() {
+				return true
+			}
+		}
+	case Array:
+		return t.Elem.hasSampler()
+	}
+	return false
+}
+
+// Equal tells if t and u are the same type. Structures are equal only if
+// they are the same declaration.
+func (t *Type) Equal(u *Type) bool {
+	if t == u {
+		return true
+	}
+	if t.Kind != u.Kind {
+		return false
+	}
+	switch t.Kind {
+	case Struct:
+		return false
+	case Array:
+		return t.Len == u.Len && t.Elem.Equal(u.Elem)
+	}
+	return t.Size == u.Size && t.Cols == u.Cols
+}
+
+// String returns the GLSL name of the type.
+func (t *Type) String() string {
+	switch t.Kind {
+	case Void:
+		return "void"
+	case Sampler2D:
+		return "sampler2D"
+	case SamplerCube:
+		return "samplerCube"
+	case Struct:
+		return t.Name
+	case Array:
+		return fmt.Sprintf("%s[%d]", t.Elem, t.Len)
+	}
+	if t.Cols > 0 {
+		return fmt.Sprintf("mat%d", t.Cols)
+	}
+	if t.Size == 1 {
+		return [...]string{Bool: "bool", Int: "int", Float: "float"}[t.Kind]
+	}
+	return fmt.Sprintf("%svec%d", [...]string{Bool: "b", Int: "i", Float: ""}[t.Kind], t.Size)
+}
+
+// leaves appends the names of the scalar, vector, matrix, sampler and array
+// of such members of a value of the type named name.
+func (t *Type) leaves(name string, names []string) []string {
+	switch {
+	case t.Kind == Struct:
+		for _, f := range t.Fields {
+			names = f.Type.leaves(name+"."+f.Name, names)
+		}
+	case t.Kind == Array && t.Elem.Kind == Struct:
+		for i := 0; i < t.Len; i++ {
+			names = t.Elem.leaves(fmt.Sprintf("%s[%d]", name, i), names)
+		}
+	default:
+		names = append(names, name)
+	}
+	return names
+}
+
+// Variable is a global variable declared by a shader.
+type Variable struct {
+	Name string
+	Type *Type
+}
+
+// Leaves returns the names by which the members of the variable can be
+// accessed using State.Var: the variable itself, unless it is or contains a
+// structure, in which case the name of each member with a non-structure type
+// (e.g. "light.color" or "lights[1].color").
+func (v *Variable) Leaves() []string {
+	return v.Type.leaves(v.Name, nil)
+}
+
+// String returns the declaration of the variable, e.g. "vec4 color".
+func (v *Variable) String() string {
+	if v.Type.Kind == Array {
+		return fmt.Sprintf("%s %s[%d]", v.Type.Elem, v.Name, v.Type.Len)
+	}
+	return v.Type.String() + " " + v.Name
+}
+
+// swizzleSets are the sets of component names that swizzles are made of.
+var swizzleSets = [...]string{"xyzw", "rgba", "stpq"}
+
+// swizzle parses the swizzle s of a vector with the given size, returning the
+// selected components or nil if s is not a valid swizzle.
+func swizzle(s string, size int) []int {
+	if len(s) == 0 || len(s) > 4 {
+		return nil
+	}
+	for _, set := range swizzleSets {
+		idx := make([]int, len(s))
+		ok := true
+		for i, c := range s {
+			idx[i] = strings.IndexRune(set, c)
+			if idx[i] < 0 || idx[i] >= size {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return idx
+		}
+	}
+	return nil
+}
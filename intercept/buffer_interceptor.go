@@ -0,0 +1,178 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package intercept
+
+import "github.com/slimsag/gfx"
+
+// bufferInterceptor is like the interceptor type, but for a gfx.Buffer.
+type bufferInterceptor struct {
+	b   gfx.Buffer
+	ctx *interceptor
+}
+
+// DataSize implements the gfx.Buffer interface.
+func (b *bufferInterceptor) DataSize(size int, usage gfx.BufferUsage) {
+	call := b.ctx.before(b, "Buffer", "DataSize", size, usage)
+	defer b.ctx.done(call)
+	b.b.DataSize(size, usage)
+	b.ctx.after(call, nil)
+}
+
+// DataInt8 implements the gfx.Buffer interface.
+func (b *bufferInterceptor) DataInt8(data []int8, usage gfx.BufferUsage) {
+	call := b.ctx.before(b, "Buffer", "DataInt8", data, usage)
+	defer b.ctx.done(call)
+	b.b.DataInt8(data, usage)
+	b.ctx.after(call, nil)
+}
+
+// DataUint8 implements the gfx.Buffer interface.
+func (b *bufferInterceptor) DataUint8(data []uint8, usage gfx.BufferUsage) {
+	call := b.ctx.before(b, "Buffer", "DataUint8", data, usage)
+	defer b.ctx.done(call)
+	b.b.DataUint8(data, usage)
+	b.ctx.after(call, nil)
+}
+
+// DataInt16 implements the gfx.Buffer interface.
+func (b *bufferInterceptor) DataInt16(data []int16, usage gfx.BufferUsage) {
+	call := b.ctx.before(b, "Buffer", "DataInt16", data, usage)
+	defer b.ctx.done(call)
+	b.b.DataInt16(data, usage)
+	b.ctx.after(call, nil)
+}
+
+// DataUint16 implements the gfx.Buffer interface.
+func (b *bufferInterceptor) DataUint16(data []uint16, usage gfx.BufferUsage) {
+	call := b.ctx.before(b, "Buffer", "DataUint16", data, usage)
+	defer b.ctx.done(call)
+	b.b.DataUint16(data, usage)
+	b.ctx.after(call, nil)
+}
+
+// DataInt32 implements the gfx.Buffer interface.
+func (b *bufferInterceptor) DataInt32(data []int32, usage gfx.BufferUsage) {
+	call := b.ctx.before(b, "Buffer", "DataInt32", data, usage)
+	defer b.ctx.done(call)
+	b.b.DataInt32(data, usage)
+	b.ctx.after(call, nil)
+}
+
+// DataUint32 implements the gfx.Buffer interface.
+func (b *bufferInterceptor) DataUint32(data []uint32, usage gfx.BufferUsage) {
+	call := b.ctx.before(b, "Buffer", "DataUint32", data, usage)
+	defer b.ctx.done(call)
+	b.b.DataUint32(data, usage)
+	b.ctx.after(call, nil)
+}
+
+// DataFloat32 implements the gfx.Buffer interface.
+func (b *bufferInterceptor) DataFloat32(data []float32, usage gfx.BufferUsage) {
+	call := b.ctx.before(b, "Buffer", "DataFloat32", data, usage)
+	defer b.ctx.done(call)
+	b.b.DataFloat32(data, usage)
+	b.ctx.after(call, nil)
+}
+
+// DataFloat64 implements the gfx.Buffer interface.
+func (b *bufferInterceptor) DataFloat64(data []float64, usage gfx.BufferUsage) {
+	call := b.ctx.before(b, "Buffer", "DataFloat64", data, usage)
+	defer b.ctx.done(call)
+	b.b.DataFloat64(data, usage)
+	b.ctx.after(call, nil)
+}
+
+// SubDataInt8 implements the gfx.Buffer interface.
+func (b *bufferInterceptor) SubDataInt8(offset int, data []int8) {
+	call := b.ctx.before(b, "Buffer", "SubDataInt8", offset, data)
+	defer b.ctx.done(call)
+	b.b.SubDataInt8(offset, data)
+	b.ctx.after(call, nil)
+}
+
+// SubDataUint8 implements the gfx.Buffer interface.
+func (b *bufferInterceptor) SubDataUint8(offset int, data []uint8) {
+	call := b.ctx.before(b, "Buffer", "SubDataUint8", offset, data)
+	defer b.ctx.done(call)
+	b.b.SubDataUint8(offset, data)
+	b.ctx.after(call, nil)
+}
+
+// SubDataInt16 implements the gfx.Buffer interface.
+func (b *bufferInterceptor) SubDataInt16(offset int, data []int16) {
+	call := b.ctx.before(b, "Buffer", "SubDataInt16", offset, data)
+	defer b.ctx.done(call)
+	b.b.SubDataInt16(offset, data)
+	b.ctx.after(call, nil)
+}
+
+// SubDataUint16 implements the gfx.Buffer interface.
+func (b *bufferInterceptor) SubDataUint16(offset int, data []uint16) {
+	call := b.ctx.before(b, "Buffer", "SubDataUint16", offset, data)
+	defer b.ctx.done(call)
+	b.b.SubDataUint16(offset, data)
+	b.ctx.after(call, nil)
+}
+
+// SubDataInt32 implements the gfx.Buffer interface.
+func (b *bufferInterceptor) SubDataInt32(offset int, data []int32) {
+	call := b.ctx.before(b, "Buffer", "SubDataInt32", offset, data)
+	defer b.ctx.done(call)
+	b.b.SubDataInt32(offset, data)
+	b.ctx.after(call, nil)
+}
+
+// SubDataUint32 implements the gfx.Buffer interface.
+func (b *bufferInterceptor) SubDataUint32(offset int, data []uint32) {
+	call := b.ctx.before(b, "Buffer", "SubDataUint32", offset, data)
+	defer b.ctx.done(call)
+	b.b.SubDataUint32(offset, data)
+	b.ctx.after(call, nil)
+}
+
+// SubDataFloat32 implements the gfx.Buffer interface.
+func (b *bufferInterceptor) SubDataFloat32(offset int, data []float32) {
+	call := b.ctx.before(b, "Buffer", "SubDataFloat32", offset, data)
+	defer b.ctx.done(call)
+	b.b.SubDataFloat32(offset, data)
+	b.ctx.after(call, nil)
+}
+
+// SubDataFloat64 implements the gfx.Buffer interface.
+func (b *bufferInterceptor) SubDataFloat64(offset int, data []float64) {
+	call := b.ctx.before(b, "Buffer", "SubDataFloat64", offset, data)
+	defer b.ctx.done(call)
+	b.b.SubDataFloat64(offset, data)
+	b.ctx.after(call, nil)
+}
+
+// Draw implements the gfx.Buffer interface.
+func (b *bufferInterceptor) Draw(p gfx.Primitive, first, count int) {
+	call := b.ctx.before(b, "Buffer", "Draw", p, first, count)
+	defer b.ctx.done(call)
+	b.b.Draw(p, first, count)
+	b.ctx.after(call, nil)
+}
+
+// VertexAttribPointer implements the gfx.Buffer interface.
+func (b *bufferInterceptor) VertexAttribPointer(l gfx.AttribLocation, size int, normalized bool, stride, offset int) {
+	call := b.ctx.before(b, "Buffer", "VertexAttribPointer", l, size, normalized, stride, offset)
+	defer b.ctx.done(call)
+	b.b.VertexAttribPointer(l, size, normalized, stride, offset)
+	b.ctx.after(call, nil)
+}
+
+// Delete implements the gfx.Object interface.
+func (b *bufferInterceptor) Delete() {
+	call := b.ctx.before(b, "Buffer", "Delete")
+	defer b.ctx.done(call)
+	b.b.Delete()
+	b.ctx.after(call, nil)
+}
+
+// Object implements the gfx.Object interface. It is not intercepted.
+func (b *bufferInterceptor) Object() interface{} {
+	return b.b.Object()
+}
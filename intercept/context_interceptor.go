@@ -0,0 +1,329 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package intercept
+
+import "github.com/slimsag/gfx"
+
+// interceptor is a gfx.Context that invokes hooks around each function call
+// made on the underlying context.
+type interceptor struct {
+	fb    *fbInterceptor
+	ctx   gfx.Context
+	hooks Hooks
+}
+
+// before returns the description of a call of a method of the intercepted
+// context or object o, after invoking the Before hook with it.
+func (c *interceptor) before(o interface{}, iface, method string, args ...interface{}) *Call {
+	call := &Call{Interface: iface, Method: method, Object: o, Args: args}
+	if c.hooks.Before != nil {
+		c.hooks.Before(call)
+	}
+	return call
+}
+
+// after invokes the After hook with the given call and its result.
+func (c *interceptor) after(call *Call, result interface{}) {
+	call.Result = result
+	call.returned = true
+	if c.hooks.After != nil {
+		c.hooks.After(call)
+	}
+}
+
+// done is deferred after before returns, and invokes the Panicked hook with
+// the given call if it did not return.
+func (c *interceptor) done(call *Call) {
+	if !call.returned && c.hooks.Panicked != nil {
+		c.hooks.Panicked(call)
+	}
+}
+
+// Framebuffer implements the gfx.Context interface.
+func (c *interceptor) Framebuffer() gfx.Framebuffer {
+	call := c.before(c, "Context", "Framebuffer")
+	defer c.done(call)
+	c.after(call, c.fb)
+	return c.fb
+}
+
+// NewFramebuffer implements the gfx.Context interface.
+func (c *interceptor) NewFramebuffer() gfx.Framebuffer {
+	call := c.before(c, "Context", "NewFramebuffer")
+	defer c.done(call)
+	fb := &fbInterceptor{fb: c.ctx.NewFramebuffer(), ctx: c}
+	c.after(call, fb)
+	return fb
+}
+
+// NewRenderbuffer implements the gfx.Context interface.
+func (c *interceptor) NewRenderbuffer() gfx.Renderbuffer {
+	call := c.before(c, "Context", "NewRenderbuffer")
+	defer c.done(call)
+	rb := &rbInterceptor{rb: c.ctx.NewRenderbuffer(), ctx: c}
+	c.after(call, rb)
+	return rb
+}
+
+// NewShader implements the gfx.Context interface.
+func (c *interceptor) NewShader(t gfx.ShaderType) gfx.Shader {
+	call := c.before(c, "Context", "NewShader", t)
+	defer c.done(call)
+	s := &shaderInterceptor{s: c.ctx.NewShader(t), ctx: c}
+	c.after(call, s)
+	return s
+}
+
+// NewTexture implements the gfx.Context interface.
+func (c *interceptor) NewTexture(t gfx.TextureType) gfx.Texture {
+	call := c.before(c, "Context", "NewTexture", t)
+	defer c.done(call)
+	tex := &textureInterceptor{t: c.ctx.NewTexture(t), ctx: c}
+	c.after(call, tex)
+	return tex
+}
+
+// NewBuffer implements the gfx.Context interface.
+func (c *interceptor) NewBuffer(t gfx.BufferType) gfx.Buffer {
+	call := c.before(c, "Context", "NewBuffer", t)
+	defer c.done(call)
+	b := &bufferInterceptor{b: c.ctx.NewBuffer(t), ctx: c}
+	c.after(call, b)
+	return b
+}
+
+// NewProgram implements the gfx.Context interface.
+func (c *interceptor) NewProgram() gfx.Program {
+	call := c.before(c, "Context", "NewProgram")
+	defer c.done(call)
+	p := &programInterceptor{p: c.ctx.NewProgram(), ctx: c}
+	c.after(call, p)
+	return p
+}
+
+// NewState implements the gfx.Context interface.
+func (c *interceptor) NewState(values ...gfx.ContextStateValue) gfx.ContextState {
+	call := c.before(c, "Context", "NewState", values)
+	defer c.done(call)
+	s := c.ctx.NewState(values...)
+	c.after(call, s)
+	return s
+}
+
+// Load implements the gfx.Context interface.
+func (c *interceptor) Load(s gfx.ContextState) {
+	call := c.before(c, "Context", "Load", s)
+	defer c.done(call)
+	c.ctx.Load(s)
+	c.after(call, nil)
+}
+
+// Bake implements the gfx.Context interface.
+func (c *interceptor) Bake(s gfx.ContextState) gfx.PipelineState {
+	call := c.before(c, "Context", "Bake", s)
+	defer c.done(call)
+	p := c.ctx.Bake(s)
+	c.after(call, p)
+	return p
+}
+
+// Current implements the gfx.Context interface.
+func (c *interceptor) Current() gfx.ContextState {
+	call := c.before(c, "Context", "Current")
+	defer c.done(call)
+	s := c.ctx.Current()
+	c.after(call, s)
+	return s
+}
+
+// Describe implements the gfx.Context interface.
+func (c *interceptor) Describe(s gfx.ContextState) gfx.StateInfo {
+	call := c.before(c, "Context", "Describe", s)
+	defer c.done(call)
+	info := c.ctx.Describe(s)
+	c.after(call, info)
+	return info
+}
+
+// PushState implements the gfx.Context interface.
+func (c *interceptor) PushState() {
+	call := c.before(c, "Context", "PushState")
+	defer c.done(call)
+	c.ctx.PushState()
+	c.after(call, nil)
+}
+
+// PopState implements the gfx.Context interface.
+func (c *interceptor) PopState() {
+	call := c.before(c, "Context", "PopState")
+	defer c.done(call)
+	c.ctx.PopState()
+	c.after(call, nil)
+}
+
+// BlendColor implements the gfx.Context interface.
+func (c *interceptor) BlendColor(r, g, b, a float32) gfx.ContextStateValue {
+	call := c.before(c, "Context", "BlendColor", r, g, b, a)
+	defer c.done(call)
+	v := c.ctx.BlendColor(r, g, b, a)
+	c.after(call, v)
+	return v
+}
+
+// BlendEquation implements the gfx.Context interface.
+func (c *interceptor) BlendEquation(eq gfx.BlendEquation) gfx.ContextStateValue {
+	call := c.before(c, "Context", "BlendEquation", eq)
+	defer c.done(call)
+	v := c.ctx.BlendEquation(eq)
+	c.after(call, v)
+	return v
+}
+
+// DepthMask implements the gfx.Context interface.
+func (c *interceptor) DepthMask(m bool) gfx.ContextStateValue {
+	call := c.before(c, "Context", "DepthMask", m)
+	defer c.done(call)
+	v := c.ctx.DepthMask(m)
+	c.after(call, v)
+	return v
+}
+
+// Enable implements the gfx.Context interface.
+func (c *interceptor) Enable(f gfx.Feature) gfx.ContextStateValue {
+	call := c.before(c, "Context", "Enable", f)
+	defer c.done(call)
+	v := c.ctx.Enable(f)
+	c.after(call, v)
+	return v
+}
+
+// Disable implements the gfx.Context interface.
+func (c *interceptor) Disable(f gfx.Feature) gfx.ContextStateValue {
+	call := c.before(c, "Context", "Disable", f)
+	defer c.done(call)
+	v := c.ctx.Disable(f)
+	c.after(call, v)
+	return v
+}
+
+// UseProgram implements the gfx.Context interface.
+func (c *interceptor) UseProgram(p gfx.Program) gfx.ContextStateValue {
+	call := c.before(c, "Context", "UseProgram", p)
+	defer c.done(call)
+	v := c.ctx.UseProgram(p)
+	c.after(call, v)
+	return v
+}
+
+// Viewport implements the gfx.Context interface.
+func (c *interceptor) Viewport(x, y, width, height int) gfx.ContextStateValue {
+	call := c.before(c, "Context", "Viewport", x, y, width, height)
+	defer c.done(call)
+	v := c.ctx.Viewport(x, y, width, height)
+	c.after(call, v)
+	return v
+}
+
+// Scissor implements the gfx.Context interface.
+func (c *interceptor) Scissor(x, y, width, height int) gfx.ContextStateValue {
+	call := c.before(c, "Context", "Scissor", x, y, width, height)
+	defer c.done(call)
+	v := c.ctx.Scissor(x, y, width, height)
+	c.after(call, v)
+	return v
+}
+
+// LineWidth implements the gfx.Context interface.
+func (c *interceptor) LineWidth(w float32) gfx.ContextStateValue {
+	call := c.before(c, "Context", "LineWidth", w)
+	defer c.done(call)
+	v := c.ctx.LineWidth(w)
+	c.after(call, v)
+	return v
+}
+
+// ColorMask implements the gfx.Context interface.
+func (c *interceptor) ColorMask(r, g, b, a bool) gfx.ContextStateValue {
+	call := c.before(c, "Context", "ColorMask", r, g, b, a)
+	defer c.done(call)
+	v := c.ctx.ColorMask(r, g, b, a)
+	c.after(call, v)
+	return v
+}
+
+// CullFace implements the gfx.Context interface.
+func (c *interceptor) CullFace(f gfx.Facet) gfx.ContextStateValue {
+	call := c.before(c, "Context", "CullFace", f)
+	defer c.done(call)
+	v := c.ctx.CullFace(f)
+	c.after(call, v)
+	return v
+}
+
+// FrontFace implements the gfx.Context interface.
+func (c *interceptor) FrontFace(o gfx.Orientation) gfx.ContextStateValue {
+	call := c.before(c, "Context", "FrontFace", o)
+	defer c.done(call)
+	v := c.ctx.FrontFace(o)
+	c.after(call, v)
+	return v
+}
+
+// EnableVertexAttribArray implements the gfx.Context interface.
+func (c *interceptor) EnableVertexAttribArray(l gfx.AttribLocation) gfx.ContextStateValue {
+	call := c.before(c, "Context", "EnableVertexAttribArray", l)
+	defer c.done(call)
+	v := c.ctx.EnableVertexAttribArray(l)
+	c.after(call, v)
+	return v
+}
+
+// Invalidate implements the gfx.Context interface.
+func (c *interceptor) Invalidate() {
+	call := c.before(c, "Context", "Invalidate")
+	defer c.done(call)
+	c.ctx.Invalidate()
+	c.after(call, nil)
+}
+
+// Resync implements the gfx.Context interface.
+func (c *interceptor) Resync() {
+	call := c.before(c, "Context", "Resync")
+	defer c.done(call)
+	c.ctx.Resync()
+	c.after(call, nil)
+}
+
+// SetDebugHandler implements the gfx.Context interface.
+func (c *interceptor) SetDebugHandler(h func(m gfx.DebugMessage)) {
+	call := c.before(c, "Context", "SetDebugHandler", h)
+	defer c.done(call)
+	c.ctx.SetDebugHandler(h)
+	c.after(call, nil)
+}
+
+// Check implements the gfx.Context interface.
+func (c *interceptor) Check() {
+	call := c.before(c, "Context", "Check")
+	defer c.done(call)
+	c.ctx.Check()
+	c.after(call, nil)
+}
+
+// Flush implements the gfx.Context interface.
+func (c *interceptor) Flush() {
+	call := c.before(c, "Context", "Flush")
+	defer c.done(call)
+	c.ctx.Flush()
+	c.after(call, nil)
+}
+
+// Finish implements the gfx.Context interface.
+func (c *interceptor) Finish() {
+	call := c.before(c, "Context", "Finish")
+	defer c.done(call)
+	c.ctx.Finish()
+	c.after(call, nil)
+}
@@ -0,0 +1,145 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package intercept
+
+import "github.com/slimsag/gfx"
+
+// fbInterceptor is like the interceptor type, but for a gfx.Framebuffer.
+type fbInterceptor struct {
+	fb  gfx.Framebuffer
+	ctx *interceptor
+}
+
+// NewState implements the gfx.FramebufferStateProvider interface.
+func (f *fbInterceptor) NewState(values ...gfx.FramebufferStateValue) gfx.FramebufferState {
+	call := f.ctx.before(f, "Framebuffer", "NewState", values)
+	defer f.ctx.done(call)
+	v := f.fb.NewState(values...)
+	f.ctx.after(call, v)
+	return v
+}
+
+// Load implements the gfx.FramebufferStateProvider interface.
+func (f *fbInterceptor) Load(s gfx.FramebufferState) {
+	call := f.ctx.before(f, "Framebuffer", "Load", s)
+	defer f.ctx.done(call)
+	f.fb.Load(s)
+	f.ctx.after(call, nil)
+}
+
+// Current implements the gfx.FramebufferStateProvider interface.
+func (f *fbInterceptor) Current() gfx.FramebufferState {
+	call := f.ctx.before(f, "Framebuffer", "Current")
+	defer f.ctx.done(call)
+	v := f.fb.Current()
+	f.ctx.after(call, v)
+	return v
+}
+
+// Describe implements the gfx.FramebufferStateProvider interface.
+func (f *fbInterceptor) Describe(s gfx.FramebufferState) gfx.StateInfo {
+	call := f.ctx.before(f, "Framebuffer", "Describe", s)
+	defer f.ctx.done(call)
+	v := f.fb.Describe(s)
+	f.ctx.after(call, v)
+	return v
+}
+
+// PushState implements the gfx.FramebufferStateProvider interface.
+func (f *fbInterceptor) PushState() {
+	call := f.ctx.before(f, "Framebuffer", "PushState")
+	defer f.ctx.done(call)
+	f.fb.PushState()
+	f.ctx.after(call, nil)
+}
+
+// PopState implements the gfx.FramebufferStateProvider interface.
+func (f *fbInterceptor) PopState() {
+	call := f.ctx.before(f, "Framebuffer", "PopState")
+	defer f.ctx.done(call)
+	f.fb.PopState()
+	f.ctx.after(call, nil)
+}
+
+// ClearColor implements the gfx.FramebufferStateProvider interface.
+func (f *fbInterceptor) ClearColor(r, g, b, a float32) gfx.FramebufferStateValue {
+	call := f.ctx.before(f, "Framebuffer", "ClearColor", r, g, b, a)
+	defer f.ctx.done(call)
+	v := f.fb.ClearColor(r, g, b, a)
+	f.ctx.after(call, v)
+	return v
+}
+
+// ClearDepth implements the gfx.FramebufferStateProvider interface.
+func (f *fbInterceptor) ClearDepth(depth float64) gfx.FramebufferStateValue {
+	call := f.ctx.before(f, "Framebuffer", "ClearDepth", depth)
+	defer f.ctx.done(call)
+	v := f.fb.ClearDepth(depth)
+	f.ctx.after(call, v)
+	return v
+}
+
+// ClearStencil implements the gfx.FramebufferStateProvider interface.
+func (f *fbInterceptor) ClearStencil(stencil int) gfx.FramebufferStateValue {
+	call := f.ctx.before(f, "Framebuffer", "ClearStencil", stencil)
+	defer f.ctx.done(call)
+	v := f.fb.ClearStencil(stencil)
+	f.ctx.after(call, v)
+	return v
+}
+
+// Clear implements the gfx.Framebuffer interface.
+func (f *fbInterceptor) Clear(m gfx.ClearMask) {
+	call := f.ctx.before(f, "Framebuffer", "Clear", m)
+	defer f.ctx.done(call)
+	f.fb.Clear(m)
+	f.ctx.after(call, nil)
+}
+
+// ReadPixelsUint8 implements the gfx.Framebuffer interface.
+func (f *fbInterceptor) ReadPixelsUint8(x, y, width, height int, dst []uint8) {
+	call := f.ctx.before(f, "Framebuffer", "ReadPixelsUint8", x, y, width, height, dst)
+	defer f.ctx.done(call)
+	f.fb.ReadPixelsUint8(x, y, width, height, dst)
+	f.ctx.after(call, nil)
+}
+
+// Texture2D implements the gfx.Framebuffer interface.
+func (f *fbInterceptor) Texture2D(attachment gfx.FramebufferAttachment, target gfx.TextureTarget, tex gfx.Texture) {
+	call := f.ctx.before(f, "Framebuffer", "Texture2D", attachment, target, tex)
+	defer f.ctx.done(call)
+	f.fb.Texture2D(attachment, target, tex)
+	f.ctx.after(call, nil)
+}
+
+// Renderbuffer implements the gfx.Framebuffer interface.
+func (f *fbInterceptor) Renderbuffer(attachment gfx.FramebufferAttachment, buf gfx.Renderbuffer) {
+	call := f.ctx.before(f, "Framebuffer", "Renderbuffer", attachment, buf)
+	defer f.ctx.done(call)
+	f.fb.Renderbuffer(attachment, buf)
+	f.ctx.after(call, nil)
+}
+
+// Status implements the gfx.Framebuffer interface.
+func (f *fbInterceptor) Status() error {
+	call := f.ctx.before(f, "Framebuffer", "Status")
+	defer f.ctx.done(call)
+	v := f.fb.Status()
+	f.ctx.after(call, v)
+	return v
+}
+
+// Delete implements the gfx.Object interface.
+func (f *fbInterceptor) Delete() {
+	call := f.ctx.before(f, "Framebuffer", "Delete")
+	defer f.ctx.done(call)
+	f.fb.Delete()
+	f.ctx.after(call, nil)
+}
+
+// Object implements the gfx.Object interface. It is not intercepted.
+func (f *fbInterceptor) Object() interface{} {
+	return f.fb.Object()
+}
@@ -0,0 +1,120 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package intercept implements interception of the calls made on graphics
+// contexts, for writing debugging, validation or profiling tools.
+//
+// A context wrapped by Context invokes user hooks before and after each method
+// call made on it, or on any object gotten from it (e.g. a Buffer), with a
+// description of the call:
+//
+//	ctx = intercept.Context(ctx, intercept.Hooks{
+//	    Before: func(c *intercept.Call) {
+//	        log.Println(c)
+//	    },
+//	})
+//
+// Hooks may panic, e.g. to report an invalid call with a stack trace pointing
+// to it, in which case the call is not made (if Before panics) or its result
+// is not returned (if After panics). Hooks holding resources across a call
+// (e.g. a lock) can release them in the Panicked hook if the call panics.
+package intercept
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/slimsag/gfx"
+)
+
+// Call describes a method call made on an intercepted context or object.
+type Call struct {
+	// Interface is the name of the gfx interface declaring the method:
+	// "Context", "Framebuffer", "Renderbuffer", "Shader", "Texture", "Buffer"
+	// or "Program".
+	Interface string
+
+	// Method is the name of the method, e.g. "DataFloat32".
+	Method string
+
+	// Object is the intercepted context or object whose method is called, as
+	// seen by the caller. Hooks may use Unwrap to call the methods of the
+	// underlying one without being intercepted.
+	Object interface{}
+
+	// Args are the arguments of the call, in order. Variadic arguments (the
+	// values of NewState) are given as a single slice. Objects are given as
+	// passed by the caller, i.e. usually intercepted ones. Hooks must not
+	// modify them.
+	Args []interface{}
+
+	// Result is the value returned by the call, or nil if it returns
+	// nothing. It is only set for the After hook. New objects are given
+	// intercepted, as returned to the caller.
+	Result interface{}
+
+	// returned is whether the call returned.
+	returned bool
+}
+
+// String returns e.g. "Buffer.Draw(42, 0, 3)".
+func (c *Call) String() string {
+	args := make([]string, len(c.Args))
+	for i, a := range c.Args {
+		args[i] = fmt.Sprint(a)
+	}
+	return c.Interface + "." + c.Method + "(" + strings.Join(args, ", ") + ")"
+}
+
+// Hooks are the functions invoked around the calls of an intercepted context.
+// Either may be nil.
+type Hooks struct {
+	// Before is invoked before each call is made on the underlying context
+	// or object.
+	Before func(c *Call)
+
+	// After is invoked after each call returned, with its Result set. It is
+	// not invoked if the call panics.
+	After func(c *Call)
+
+	// Panicked is invoked instead of After if the call panics, while the
+	// panic unwinds the stack: it cannot recover it. It is not invoked if the
+	// Before hook panics.
+	Panicked func(c *Call)
+}
+
+// Context wraps the given graphics context such that the hooks are invoked
+// around each method call made on the context, or on any object gotten from
+// it, e.g. a Framebuffer or a Buffer.
+//
+// The Object methods of the objects are not intercepted, as drivers call them
+// to get the underlying objects of those passed as arguments (e.g. the program
+// given to UseProgram), which intercepted objects may thus be.
+func Context(ctx gfx.Context, h Hooks) gfx.Context {
+	c := &interceptor{ctx: ctx, hooks: h}
+	c.fb = &fbInterceptor{fb: ctx.Framebuffer(), ctx: c}
+	return c
+}
+
+// Unwrap returns the underlying context or object of an intercepted one, or
+// o itself if it is not intercepted.
+func Unwrap(o interface{}) interface{} {
+	switch v := o.(type) {
+	case *interceptor:
+		return v.ctx
+	case *fbInterceptor:
+		return v.fb
+	case *rbInterceptor:
+		return v.rb
+	case *shaderInterceptor:
+		return v.s
+	case *textureInterceptor:
+		return v.t
+	case *bufferInterceptor:
+		return v.b
+	case *programInterceptor:
+		return v.p
+	}
+	return o
+}
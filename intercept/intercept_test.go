@@ -0,0 +1,173 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package intercept
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/slimsag/gfx"
+	"github.com/slimsag/gfx/driver/null"
+)
+
+// Just for ensuring we meet the interface requirements.
+func init() {
+	_ = gfx.Context(&interceptor{})
+	_ = gfx.Buffer(&bufferInterceptor{})
+	_ = gfx.Framebuffer(&fbInterceptor{})
+	_ = gfx.Program(&programInterceptor{})
+	_ = gfx.Renderbuffer(&rbInterceptor{})
+	_ = gfx.Shader(&shaderInterceptor{})
+	_ = gfx.Texture(&textureInterceptor{})
+}
+
+func TestHooks(t *testing.T) {
+	inner := null.New(4, 4)
+	var got []string
+	ctx := Context(inner, Hooks{
+		Before: func(c *Call) {
+			if c.Result != nil {
+				t.Errorf("%s: result set before the call", c)
+			}
+			got = append(got, "before "+c.String())
+		},
+		After: func(c *Call) {
+			got = append(got, fmt.Sprintf("after %s = %v", c, c.Result))
+		},
+	})
+
+	vs, fs := ctx.NewShader(gfx.VertexShader), ctx.NewShader(gfx.FragmentShader)
+	vs.Compile("void main() {}")
+	fs.Compile("void main() {}")
+	got = nil
+	p := ctx.NewProgram()
+	p.Link(vs, fs)
+	ctx.Load(ctx.NewState(ctx.UseProgram(p), ctx.Enable(gfx.Blend)))
+	fb := ctx.Framebuffer()
+	fb.Clear(gfx.ColorBuffer)
+	b := ctx.NewBuffer(gfx.ArrayBuffer)
+	b.Draw(gfx.Triangles, 0, 3)
+	b.Delete()
+
+	want := []string{
+		"before Context.NewProgram()",
+		fmt.Sprintf("after Context.NewProgram() = %v", p),
+		fmt.Sprintf("before Program.Link(%v, %v)", vs, fs),
+		fmt.Sprintf("after Program.Link(%v, %v) = true", vs, fs),
+		fmt.Sprintf("before Context.UseProgram(%v)", p),
+	}
+	if !reflect.DeepEqual(got[:len(want)], want) {
+		t.Errorf("got\n%q\nwant\n%q", got[:len(want)], want)
+	}
+	for _, s := range []string{
+		"before Context.Enable(Blend)",
+		"before Framebuffer.Clear(1)",
+		fmt.Sprintf("before Buffer.Draw(%d, 0, 3)", gfx.Triangles),
+		"after Buffer.Delete() = <nil>",
+	} {
+		found := false
+		for _, g := range got {
+			found = found || g == s
+		}
+		if !found {
+			t.Errorf("missing %q in\n%q", s, got)
+		}
+	}
+
+	// The intercepted program was given to the driver.
+	if g := inner.(*null.Context).GL(); g.Program == nil || !g.Enabled(gfx.Blend) {
+		t.Errorf("GL state = %+v", g)
+	}
+}
+
+func TestUnwrap(t *testing.T) {
+	inner := null.New(4, 4)
+	var objects []interface{}
+	ctx := Context(inner, Hooks{After: func(c *Call) {
+		objects = append(objects, c.Object)
+	}})
+	if Unwrap(ctx) != inner {
+		t.Error("Unwrap(ctx) is not the underlying context")
+	}
+	fb := ctx.Framebuffer()
+	if Unwrap(fb) != inner.Framebuffer() {
+		t.Error("Unwrap(fb) is not the underlying framebuffer")
+	}
+	b := ctx.NewBuffer(gfx.ArrayBuffer)
+	b.DataSize(4, gfx.StaticDraw)
+	if _, ok := Unwrap(b).(*null.Buffer); !ok {
+		t.Errorf("Unwrap(b) = %T", Unwrap(b))
+	}
+	if b.Object() != Unwrap(b).(gfx.Buffer).Object() {
+		t.Error("Object differs from the one of the underlying buffer")
+	}
+	if want := []interface{}{ctx, ctx, b}; !reflect.DeepEqual(objects, want) {
+		t.Errorf("objects = %v, want %v", objects, want)
+	}
+	if Unwrap(42) != 42 {
+		t.Error("Unwrap(42) != 42")
+	}
+}
+
+func TestBeforePanics(t *testing.T) {
+	inner := null.New(4, 4)
+	ctx := Context(inner, Hooks{
+		Before: func(c *Call) {
+			if c.Method == "DataSize" && c.Args[0].(int) < 0 {
+				panic("negative size")
+			}
+		},
+		After: func(c *Call) {
+			if c.Method == "DataSize" {
+				t.Errorf("%s: After called", c)
+			}
+		},
+	})
+	b := ctx.NewBuffer(gfx.ArrayBuffer)
+	inner.(*null.Context).ClearCalls()
+	func() {
+		defer func() {
+			if r := recover(); r != "negative size" {
+				t.Errorf("recovered %v", r)
+			}
+		}()
+		b.DataSize(-1, gfx.StaticDraw)
+	}()
+	if calls := inner.(*null.Context).Calls(); len(calls) != 0 {
+		t.Errorf("calls made: %v", calls)
+	}
+}
+
+func TestPanicked(t *testing.T) {
+	// The inner context panics on Flush.
+	inner := Context(null.New(4, 4), Hooks{Before: func(c *Call) {
+		if c.Method == "Flush" {
+			panic("flush")
+		}
+	}})
+	var got []string
+	ctx := Context(inner, Hooks{
+		After: func(c *Call) {
+			got = append(got, "after "+c.String())
+		},
+		Panicked: func(c *Call) {
+			got = append(got, "panicked "+c.String())
+		},
+	})
+	ctx.Finish()
+	func() {
+		defer func() {
+			if r := recover(); r != "flush" {
+				t.Errorf("recovered %v", r)
+			}
+		}()
+		ctx.Flush()
+	}()
+	want := []string{"after Context.Finish()", "panicked Context.Flush()"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package intercept
+
+import "github.com/slimsag/gfx"
+
+// programInterceptor is like the interceptor type, but for a gfx.Program.
+type programInterceptor struct {
+	p   gfx.Program
+	ctx *interceptor
+}
+
+// Link implements the gfx.Program interface.
+func (p *programInterceptor) Link(vert, frag gfx.Shader) bool {
+	call := p.ctx.before(p, "Program", "Link", vert, frag)
+	defer p.ctx.done(call)
+	v := p.p.Link(vert, frag)
+	p.ctx.after(call, v)
+	return v
+}
+
+// InfoLog implements the gfx.Program interface.
+func (p *programInterceptor) InfoLog() string {
+	call := p.ctx.before(p, "Program", "InfoLog")
+	defer p.ctx.done(call)
+	v := p.p.InfoLog()
+	p.ctx.after(call, v)
+	return v
+}
+
+// AttribLocation implements the gfx.Program interface.
+func (p *programInterceptor) AttribLocation(name string) gfx.AttribLocation {
+	call := p.ctx.before(p, "Program", "AttribLocation", name)
+	defer p.ctx.done(call)
+	v := p.p.AttribLocation(name)
+	p.ctx.after(call, v)
+	return v
+}
+
+// UniformLocation implements the gfx.Program interface.
+func (p *programInterceptor) UniformLocation(name string) gfx.UniformLocation {
+	call := p.ctx.before(p, "Program", "UniformLocation", name)
+	defer p.ctx.done(call)
+	v := p.p.UniformLocation(name)
+	p.ctx.after(call, v)
+	return v
+}
+
+// Uniform1fv implements the gfx.Program interface.
+func (p *programInterceptor) Uniform1fv(l gfx.UniformLocation, data []float32) {
+	call := p.ctx.before(p, "Program", "Uniform1fv", l, data)
+	defer p.ctx.done(call)
+	p.p.Uniform1fv(l, data)
+	p.ctx.after(call, nil)
+}
+
+// Uniform1iv implements the gfx.Program interface.
+func (p *programInterceptor) Uniform1iv(l gfx.UniformLocation, data []int32) {
+	call := p.ctx.before(p, "Program", "Uniform1iv", l, data)
+	defer p.ctx.done(call)
+	p.p.Uniform1iv(l, data)
+	p.ctx.after(call, nil)
+}
+
+// Uniform2fv implements the gfx.Program interface.
+func (p *programInterceptor) Uniform2fv(l gfx.UniformLocation, data []float32) {
+	call := p.ctx.before(p, "Program", "Uniform2fv", l, data)
+	defer p.ctx.done(call)
+	p.p.Uniform2fv(l, data)
+	p.ctx.after(call, nil)
+}
+
+// Uniform2iv implements the gfx.Program interface.
+func (p *programInterceptor) Uniform2iv(l gfx.UniformLocation, data []int32) {
+	call := p.ctx.before(p, "Program", "Uniform2iv", l, data)
+	defer p.ctx.done(call)
+	p.p.Uniform2iv(l, data)
+	p.ctx.after(call, nil)
+}
+
+// Uniform3fv implements the gfx.Program interface.
+func (p *programInterceptor) Uniform3fv(l gfx.UniformLocation, data []float32) {
+	call := p.ctx.before(p, "Program", "Uniform3fv", l, data)
+	defer p.ctx.done(call)
+	p.p.Uniform3fv(l, data)
+	p.ctx.after(call, nil)
+}
+
+// Uniform3iv implements the gfx.Program interface.
+func (p *programInterceptor) Uniform3iv(l gfx.UniformLocation, data []int32) {
+	call := p.ctx.before(p, "Program", "Uniform3iv", l, data)
+	defer p.ctx.done(call)
+	p.p.Uniform3iv(l, data)
+	p.ctx.after(call, nil)
+}
+
+// Uniform4fv implements the gfx.Program interface.
+func (p *programInterceptor) Uniform4fv(l gfx.UniformLocation, data []float32) {
+	call := p.ctx.before(p, "Program", "Uniform4fv", l, data)
+	defer p.ctx.done(call)
+	p.p.Uniform4fv(l, data)
+	p.ctx.after(call, nil)
+}
+
+// Uniform4iv implements the gfx.Program interface.
+func (p *programInterceptor) Uniform4iv(l gfx.UniformLocation, data []int32) {
+	call := p.ctx.before(p, "Program", "Uniform4iv", l, data)
+	defer p.ctx.done(call)
+	p.p.Uniform4iv(l, data)
+	p.ctx.after(call, nil)
+}
+
+// UniformMatrix2fv implements the gfx.Program interface.
+func (p *programInterceptor) UniformMatrix2fv(l gfx.UniformLocation, transpose bool, data []float32) {
+	call := p.ctx.before(p, "Program", "UniformMatrix2fv", l, transpose, data)
+	defer p.ctx.done(call)
+	p.p.UniformMatrix2fv(l, transpose, data)
+	p.ctx.after(call, nil)
+}
+
+// UniformMatrix3fv implements the gfx.Program interface.
+func (p *programInterceptor) UniformMatrix3fv(l gfx.UniformLocation, transpose bool, data []float32) {
+	call := p.ctx.before(p, "Program", "UniformMatrix3fv", l, transpose, data)
+	defer p.ctx.done(call)
+	p.p.UniformMatrix3fv(l, transpose, data)
+	p.ctx.after(call, nil)
+}
+
+// UniformMatrix4fv implements the gfx.Program interface.
+func (p *programInterceptor) UniformMatrix4fv(l gfx.UniformLocation, transpose bool, data []float32) {
+	call := p.ctx.before(p, "Program", "UniformMatrix4fv", l, transpose, data)
+	defer p.ctx.done(call)
+	p.p.UniformMatrix4fv(l, transpose, data)
+	p.ctx.after(call, nil)
+}
+
+// Delete implements the gfx.Object interface.
+func (p *programInterceptor) Delete() {
+	call := p.ctx.before(p, "Program", "Delete")
+	defer p.ctx.done(call)
+	p.p.Delete()
+	p.ctx.after(call, nil)
+}
+
+// Object implements the gfx.Object interface. It is not intercepted.
+func (p *programInterceptor) Object() interface{} {
+	return p.p.Object()
+}
@@ -0,0 +1,34 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package intercept
+
+import "github.com/slimsag/gfx"
+
+// rbInterceptor is like the interceptor type, but for a gfx.Renderbuffer.
+type rbInterceptor struct {
+	rb  gfx.Renderbuffer
+	ctx *interceptor
+}
+
+// Storage implements the gfx.Renderbuffer interface.
+func (r *rbInterceptor) Storage(internalFormat gfx.RenderbufferFormat, width, height int) {
+	call := r.ctx.before(r, "Renderbuffer", "Storage", internalFormat, width, height)
+	defer r.ctx.done(call)
+	r.rb.Storage(internalFormat, width, height)
+	r.ctx.after(call, nil)
+}
+
+// Delete implements the gfx.Object interface.
+func (r *rbInterceptor) Delete() {
+	call := r.ctx.before(r, "Renderbuffer", "Delete")
+	defer r.ctx.done(call)
+	r.rb.Delete()
+	r.ctx.after(call, nil)
+}
+
+// Object implements the gfx.Object interface. It is not intercepted.
+func (r *rbInterceptor) Object() interface{} {
+	return r.rb.Object()
+}
@@ -0,0 +1,44 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package intercept
+
+import "github.com/slimsag/gfx"
+
+// shaderInterceptor is like the interceptor type, but for a gfx.Shader.
+type shaderInterceptor struct {
+	s   gfx.Shader
+	ctx *interceptor
+}
+
+// Compile implements the gfx.Shader interface.
+func (s *shaderInterceptor) Compile(src string) bool {
+	call := s.ctx.before(s, "Shader", "Compile", src)
+	defer s.ctx.done(call)
+	v := s.s.Compile(src)
+	s.ctx.after(call, v)
+	return v
+}
+
+// InfoLog implements the gfx.Shader interface.
+func (s *shaderInterceptor) InfoLog() string {
+	call := s.ctx.before(s, "Shader", "InfoLog")
+	defer s.ctx.done(call)
+	v := s.s.InfoLog()
+	s.ctx.after(call, v)
+	return v
+}
+
+// Delete implements the gfx.Object interface.
+func (s *shaderInterceptor) Delete() {
+	call := s.ctx.before(s, "Shader", "Delete")
+	defer s.ctx.done(call)
+	s.s.Delete()
+	s.ctx.after(call, nil)
+}
+
+// Object implements the gfx.Object interface. It is not intercepted.
+func (s *shaderInterceptor) Object() interface{} {
+	return s.s.Object()
+}
@@ -0,0 +1,35 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package intercept
+
+import "github.com/slimsag/gfx"
+
+// textureInterceptor is like the interceptor type, but for a gfx.Texture.
+type textureInterceptor struct {
+	t   gfx.Texture
+	ctx *interceptor
+}
+
+// Type implements the gfx.Texture interface.
+func (t *textureInterceptor) Type() gfx.TextureType {
+	call := t.ctx.before(t, "Texture", "Type")
+	defer t.ctx.done(call)
+	v := t.t.Type()
+	t.ctx.after(call, v)
+	return v
+}
+
+// Delete implements the gfx.Object interface.
+func (t *textureInterceptor) Delete() {
+	call := t.ctx.before(t, "Texture", "Delete")
+	defer t.ctx.done(call)
+	t.t.Delete()
+	t.ctx.after(call, nil)
+}
+
+// Object implements the gfx.Object interface. It is not intercepted.
+func (t *textureInterceptor) Object() interface{} {
+	return t.t.Object()
+}
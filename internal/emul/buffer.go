@@ -0,0 +1,267 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package emul
+
+import (
+	"encoding/binary"
+	"math"
+
+	"github.com/slimsag/gfx"
+)
+
+// Buffer is an emulated buffer. It implements the gfx.Buffer interface,
+// except for the Draw method, which drivers implement.
+type Buffer struct {
+	Handle
+
+	typ   gfx.BufferType
+	usage gfx.BufferUsage
+	data  []byte // Little-endian, like the data of OpenGL buffers in practice.
+}
+
+// NewBuffer returns a buffer of the given type.
+func NewBuffer(t gfx.BufferType) Buffer {
+	return Buffer{typ: t}
+}
+
+// Type returns the type of the buffer.
+func (b *Buffer) Type() gfx.BufferType {
+	return b.typ
+}
+
+// Usage returns the usage hint given when the data of the buffer was last
+// specified.
+func (b *Buffer) Usage() gfx.BufferUsage {
+	return b.usage
+}
+
+// Bytes returns the contents of the buffer, in little-endian byte order. The
+// returned slice must not be modified.
+func (b *Buffer) Bytes() []byte {
+	return b.data
+}
+
+// setData replaces the contents of the buffer.
+func (b *Buffer) setData(method string, data []byte, usage gfx.BufferUsage) {
+	b.Ctx.Call(method, b.O)
+	b.Ctx.BindBuffer(b)
+	b.data, b.usage = data, usage
+}
+
+// DataSize implements the gfx.Buffer interface.
+func (b *Buffer) DataSize(size int, usage gfx.BufferUsage) {
+	if size < 0 {
+		b.Ctx.Call("Buffer.DataSize", b.O)
+		b.Ctx.BindBuffer(b)
+		b.Ctx.SetError(gfx.InvalidValue)
+		return
+	}
+	b.setData("Buffer.DataSize", make([]byte, size), usage)
+}
+
+// DataInt8 implements the gfx.Buffer interface.
+func (b *Buffer) DataInt8(data []int8, usage gfx.BufferUsage) {
+	b.setData("Buffer.DataInt8", bytesInt8(data), usage)
+}
+
+// DataUint8 implements the gfx.Buffer interface.
+func (b *Buffer) DataUint8(data []uint8, usage gfx.BufferUsage) {
+	b.setData("Buffer.DataUint8", append([]byte(nil), data...), usage)
+}
+
+// DataInt16 implements the gfx.Buffer interface.
+func (b *Buffer) DataInt16(data []int16, usage gfx.BufferUsage) {
+	b.setData("Buffer.DataInt16", bytesInt16(data), usage)
+}
+
+// DataUint16 implements the gfx.Buffer interface.
+func (b *Buffer) DataUint16(data []uint16, usage gfx.BufferUsage) {
+	b.setData("Buffer.DataUint16", bytesUint16(data), usage)
+}
+
+// DataInt32 implements the gfx.Buffer interface.
+func (b *Buffer) DataInt32(data []int32, usage gfx.BufferUsage) {
+	b.setData("Buffer.DataInt32", bytesInt32(data), usage)
+}
+
+// DataUint32 implements the gfx.Buffer interface.
+func (b *Buffer) DataUint32(data []uint32, usage gfx.BufferUsage) {
+	b.setData("Buffer.DataUint32", bytesUint32(data), usage)
+}
+
+// DataFloat32 implements the gfx.Buffer interface.
+func (b *Buffer) DataFloat32(data []float32, usage gfx.BufferUsage) {
+	b.setData("Buffer.DataFloat32", bytesFloat32(data), usage)
+}
+
+// DataFloat64 implements the gfx.Buffer interface.
+func (b *Buffer) DataFloat64(data []float64, usage gfx.BufferUsage) {
+	b.setData("Buffer.DataFloat64", bytesFloat64(data), usage)
+}
+
+// subData copies data into the buffer at the given byte offset.
+func (b *Buffer) subData(method string, offset int, data []byte) {
+	b.Ctx.Call(method, b.O)
+	b.Ctx.BindBuffer(b)
+	if offset < 0 || offset+len(data) > len(b.data) {
+		b.Ctx.SetError(gfx.InvalidValue)
+		return
+	}
+	copy(b.data[offset:], data)
+}
+
+// SubDataInt8 implements the gfx.Buffer interface.
+func (b *Buffer) SubDataInt8(offset int, data []int8) {
+	b.subData("Buffer.SubDataInt8", offset, bytesInt8(data))
+}
+
+// SubDataUint8 implements the gfx.Buffer interface.
+func (b *Buffer) SubDataUint8(offset int, data []uint8) {
+	b.subData("Buffer.SubDataUint8", offset, data)
+}
+
+// SubDataInt16 implements the gfx.Buffer interface.
+func (b *Buffer) SubDataInt16(offset int, data []int16) {
+	b.subData("Buffer.SubDataInt16", offset*2, bytesInt16(data))
+}
+
+// SubDataUint16 implements the gfx.Buffer interface.
+func (b *Buffer) SubDataUint16(offset int, data []uint16) {
+	b.subData("Buffer.SubDataUint16", offset*2, bytesUint16(data))
+}
+
+// SubDataInt32 implements the gfx.Buffer interface.
+func (b *Buffer) SubDataInt32(offset int, data []int32) {
+	b.subData("Buffer.SubDataInt32", offset*4, bytesInt32(data))
+}
+
+// SubDataUint32 implements the gfx.Buffer interface.
+func (b *Buffer) SubDataUint32(offset int, data []uint32) {
+	b.subData("Buffer.SubDataUint32", offset*4, bytesUint32(data))
+}
+
+// SubDataFloat32 implements the gfx.Buffer interface.
+func (b *Buffer) SubDataFloat32(offset int, data []float32) {
+	b.subData("Buffer.SubDataFloat32", offset*4, bytesFloat32(data))
+}
+
+// SubDataFloat64 implements the gfx.Buffer interface.
+func (b *Buffer) SubDataFloat64(offset int, data []float64) {
+	b.subData("Buffer.SubDataFloat64", offset*8, bytesFloat64(data))
+}
+
+// AttribPointer is a vertex attribute pointer, as set by
+// Buffer.VertexAttribPointer.
+type AttribPointer struct {
+	// Buffer is the buffer holding the values of the attribute, or nil.
+	Buffer *Buffer
+
+	Size, Stride, Offset int
+	Normalized           bool
+}
+
+// VertexAttribPointer implements the gfx.Buffer interface.
+func (b *Buffer) VertexAttribPointer(l gfx.AttribLocation, size int, normalized bool, stride, offset int) {
+	b.AttribPointer(l, size, normalized, stride, offset, 1)
+}
+
+// AttribPointer is like VertexAttribPointer, but also generates
+// InvalidOperation unless the stride and offset are multiples of align, for
+// drivers which only read aligned values.
+func (b *Buffer) AttribPointer(l gfx.AttribLocation, size int, normalized bool, stride, offset, align int) {
+	c := b.Ctx
+	c.Call("Buffer.VertexAttribPointer", b.O)
+	c.BindBuffer(b)
+	loc, ok := AttribLocation(l)
+	switch {
+	case !ok:
+		c.SetError(gfx.InvalidValue)
+	case size < 1 || size > 4 || stride < 0 || stride > 255 || offset < 0:
+		c.SetError(gfx.InvalidValue)
+	case b.typ != gfx.ArrayBuffer || stride%align != 0 || offset%align != 0:
+		c.SetError(gfx.InvalidOperation)
+	default:
+		c.Attribs[loc] = AttribPointer{
+			Buffer:     b,
+			Size:       size,
+			Stride:     stride,
+			Offset:     offset,
+			Normalized: normalized,
+		}
+	}
+}
+
+// Delete implements the gfx.Object interface.
+func (b *Buffer) Delete() {
+	name := b.O
+	if !b.delete("Buffer.Delete") {
+		return
+	}
+	c := b.Ctx
+	c.forgetBuffer(name)
+
+	// Deleting a buffer resets the vertex attribute pointers into it.
+	for i := range c.Attribs {
+		if c.Attribs[i].Buffer == b {
+			c.Attribs[i] = AttribPointer{}
+		}
+	}
+}
+
+func bytesInt8(data []int8) []byte {
+	buf := make([]byte, len(data))
+	for i, x := range data {
+		buf[i] = byte(x)
+	}
+	return buf
+}
+
+func bytesInt16(data []int16) []byte {
+	buf := make([]byte, len(data)*2)
+	for i, x := range data {
+		binary.LittleEndian.PutUint16(buf[i*2:], uint16(x))
+	}
+	return buf
+}
+
+func bytesUint16(data []uint16) []byte {
+	buf := make([]byte, len(data)*2)
+	for i, x := range data {
+		binary.LittleEndian.PutUint16(buf[i*2:], x)
+	}
+	return buf
+}
+
+func bytesInt32(data []int32) []byte {
+	buf := make([]byte, len(data)*4)
+	for i, x := range data {
+		binary.LittleEndian.PutUint32(buf[i*4:], uint32(x))
+	}
+	return buf
+}
+
+func bytesUint32(data []uint32) []byte {
+	buf := make([]byte, len(data)*4)
+	for i, x := range data {
+		binary.LittleEndian.PutUint32(buf[i*4:], x)
+	}
+	return buf
+}
+
+func bytesFloat32(data []float32) []byte {
+	buf := make([]byte, len(data)*4)
+	for i, x := range data {
+		binary.LittleEndian.PutUint32(buf[i*4:], math.Float32bits(x))
+	}
+	return buf
+}
+
+func bytesFloat64(data []float64) []byte {
+	buf := make([]byte, len(data)*8)
+	for i, x := range data {
+		binary.LittleEndian.PutUint64(buf[i*8:], math.Float64bits(x))
+	}
+	return buf
+}
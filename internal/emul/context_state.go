@@ -0,0 +1,254 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package emul
+
+import (
+	"github.com/slimsag/gfx"
+	s "github.com/slimsag/gfx/internal/state"
+)
+
+// defaultState returns the default value of each piece of context state, as
+// specified by OpenGL for a default framebuffer of the given size.
+func (c *Context) defaultState(width, height int) []gfx.ContextStateValue {
+	d := []gfx.ContextStateValue{
+		c.BlendColor(0, 0, 0, 0),
+		c.BlendEquation(gfx.FuncAdd),
+		c.DepthMask(true),
+		c.UseProgram(nil),
+		c.Viewport(0, 0, width, height),
+		c.Scissor(0, 0, width, height),
+		c.LineWidth(1),
+		c.ColorMask(true, true, true, true),
+		c.CullFace(gfx.Back),
+		c.FrontFace(gfx.CCW),
+	}
+	for f := gfx.Blend; f <= gfx.Dither; f++ {
+		d = append(d, c.feature(f, f == gfx.Dither))
+	}
+	for l := 0; l < s.MaxVertexAttribs; l++ {
+		d = append(d, c.vertexAttribArray(uint32(l), false))
+	}
+
+	// Unlike OpenGL, the state starts out empty, so make the GL calls of the
+	// defaults.
+	for _, v := range d {
+		csv := v.(s.CSV)
+		csv.GLCall(&csv.Value)
+	}
+	return d
+}
+
+func (c *Context) glBlendColor(v *s.Value) {
+	c.GL.BlendColor = v.F
+}
+
+// BlendColor implements the gfx.ContextStateProvider interface.
+func (c *Context) BlendColor(r, g, b, a float32) gfx.ContextStateValue {
+	return s.CSV{
+		Key:    s.BlendColor,
+		Value:  s.Value{F: [4]float32{r, g, b, a}},
+		GLCall: c.glBlendColor,
+	}
+}
+
+func (c *Context) glBlendEquation(v *s.Value) {
+	c.GL.BlendEquation = gfx.BlendEquation(v.E)
+}
+
+// BlendEquation implements the gfx.ContextStateProvider interface.
+func (c *Context) BlendEquation(eq gfx.BlendEquation) gfx.ContextStateValue {
+	return s.CSV{
+		Key:    s.BlendEquation,
+		Value:  s.Value{U: uint32(eq), E: int(eq)},
+		GLCall: c.glBlendEquation,
+	}
+}
+
+func (c *Context) glDepthMask(v *s.Value) {
+	c.GL.DepthMask = v.B[0]
+}
+
+// DepthMask implements the gfx.ContextStateProvider interface.
+func (c *Context) DepthMask(m bool) gfx.ContextStateValue {
+	return s.CSV{
+		Key:    s.DepthMask,
+		Value:  s.Value{B: [4]bool{m}},
+		GLCall: c.glDepthMask,
+	}
+}
+
+func (c *Context) glUseProgram(v *s.Value) {
+	if v.U == 0 {
+		c.GL.Program = nil
+		return
+	}
+	p, ok := c.objects[v.U].(program)
+	switch {
+	case !ok:
+		c.SetError(gfx.InvalidValue)
+	case !p.program().linked:
+		c.SetError(gfx.InvalidOperation)
+	default:
+		c.GL.Program = p
+	}
+}
+
+// UseProgram implements the gfx.ContextStateProvider interface.
+func (c *Context) UseProgram(p gfx.Program) gfx.ContextStateValue {
+	var o uint32
+	if p != nil {
+		o = p.Object().(uint32)
+	}
+	return s.CSV{
+		Key:    s.UseProgram,
+		Value:  s.Value{U: o, P: p},
+		GLCall: c.glUseProgram,
+	}
+}
+
+func (c *Context) glViewport(v *s.Value) {
+	c.GL.Viewport = [4]int{int(v.I[0]), int(v.I[1]), int(v.I[2]), int(v.I[3])}
+}
+
+// Viewport implements the gfx.ContextStateProvider interface.
+func (c *Context) Viewport(x, y, width, height int) gfx.ContextStateValue {
+	return s.CSV{
+		Key:    s.Viewport,
+		Value:  s.Value{I: [4]int32{int32(x), int32(y), int32(width), int32(height)}},
+		GLCall: c.glViewport,
+	}
+}
+
+func (c *Context) glScissor(v *s.Value) {
+	c.GL.Scissor = [4]int{int(v.I[0]), int(v.I[1]), int(v.I[2]), int(v.I[3])}
+}
+
+// Scissor implements the gfx.ContextStateProvider interface.
+func (c *Context) Scissor(x, y, width, height int) gfx.ContextStateValue {
+	return s.CSV{
+		Key:    s.Scissor,
+		Value:  s.Value{I: [4]int32{int32(x), int32(y), int32(width), int32(height)}},
+		GLCall: c.glScissor,
+	}
+}
+
+func (c *Context) glLineWidth(v *s.Value) {
+	c.GL.LineWidth = v.F[0]
+}
+
+// LineWidth implements the gfx.ContextStateProvider interface.
+func (c *Context) LineWidth(w float32) gfx.ContextStateValue {
+	return s.CSV{
+		Key:    s.LineWidth,
+		Value:  s.Value{F: [4]float32{w}},
+		GLCall: c.glLineWidth,
+	}
+}
+
+func (c *Context) glColorMask(v *s.Value) {
+	c.GL.ColorMask = v.B
+}
+
+// ColorMask implements the gfx.ContextStateProvider interface.
+func (c *Context) ColorMask(r, g, b, a bool) gfx.ContextStateValue {
+	return s.CSV{
+		Key:    s.ColorMask,
+		Value:  s.Value{B: [4]bool{r, g, b, a}},
+		GLCall: c.glColorMask,
+	}
+}
+
+func (c *Context) glCullFace(v *s.Value) {
+	c.GL.CullFace = gfx.Facet(v.E)
+}
+
+// CullFace implements the gfx.ContextStateProvider interface.
+func (c *Context) CullFace(f gfx.Facet) gfx.ContextStateValue {
+	return s.CSV{
+		Key:    s.CullFace,
+		Value:  s.Value{U: uint32(f), E: int(f)},
+		GLCall: c.glCullFace,
+	}
+}
+
+func (c *Context) glFrontFace(v *s.Value) {
+	c.GL.FrontFace = gfx.Orientation(v.E)
+}
+
+// FrontFace implements the gfx.ContextStateProvider interface.
+func (c *Context) FrontFace(o gfx.Orientation) gfx.ContextStateValue {
+	return s.CSV{
+		Key:    s.FrontFace,
+		Value:  s.Value{U: uint32(o), E: int(o)},
+		GLCall: c.glFrontFace,
+	}
+}
+
+// glFeature enables or disables the feature v.E, depending on v.B[0].
+func (c *Context) glFeature(v *s.Value) {
+	c.GL.features[gfx.Feature(v.E)-gfx.Blend] = v.B[0]
+}
+
+// feature returns a state value for the given feature. All features are
+// disabled by default, except for dithering.
+func (c *Context) feature(f gfx.Feature, enabled bool) gfx.ContextStateValue {
+	return s.CSV{
+		Key:    s.FeatureKey(f),
+		Value:  s.Value{U: uint32(f), E: int(f), B: [4]bool{enabled}},
+		GLCall: c.glFeature,
+	}
+}
+
+// Enable implements the gfx.ContextStateProvider interface.
+func (c *Context) Enable(f gfx.Feature) gfx.ContextStateValue {
+	return c.feature(f, true)
+}
+
+// Disable implements the gfx.ContextStateProvider interface.
+func (c *Context) Disable(f gfx.Feature) gfx.ContextStateValue {
+	return c.feature(f, false)
+}
+
+// glVertexAttribArray enables or disables the vertex attribute array at
+// location v.U, depending on v.B[0]. Like OpenGL, it generates InvalidValue
+// for locations past the supported ones.
+func (c *Context) glVertexAttribArray(v *s.Value) {
+	if v.U >= s.MaxVertexAttribs {
+		c.SetError(gfx.InvalidValue)
+		return
+	}
+	c.GL.AttribArrays[v.U] = v.B[0]
+}
+
+// vertexAttribArray returns a state value for the vertex attribute array at
+// the given location. All arrays are disabled by default.
+func (c *Context) vertexAttribArray(l uint32, enabled bool) gfx.ContextStateValue {
+	return s.CSV{
+		Key:    s.VertexAttribKey(int(l)),
+		Value:  s.Value{U: l, B: [4]bool{enabled}},
+		GLCall: c.glVertexAttribArray,
+	}
+}
+
+// EnableVertexAttribArray implements the gfx.ContextStateProvider interface.
+// Like a location of -1 in OpenGL, an invalid location (see AttribLocation)
+// generates InvalidValue when the state is loaded. Invalid locations all
+// share the key of the first location past the supported ones.
+func (c *Context) EnableVertexAttribArray(l gfx.AttribLocation) gfx.ContextStateValue {
+	loc, ok := AttribLocation(l)
+	if !ok {
+		loc = s.MaxVertexAttribs
+	}
+	return c.vertexAttribArray(uint32(loc), true)
+}
+
+// AttribLocation returns the vertex attribute location l, as returned by the
+// AttribLocation method of programs, and false if it is not a supported
+// location: if it is nil (the location of unknown attributes), was returned
+// by another driver, or is past the supported locations.
+func AttribLocation(l gfx.AttribLocation) (int, bool) {
+	loc, ok := l.(int)
+	return loc, ok && loc >= 0 && loc < s.MaxVertexAttribs
+}
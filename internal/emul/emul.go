@@ -0,0 +1,302 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package emul implements the parts of a graphics context shared by the
+// drivers which emulate OpenGL in Go instead of calling it, i.e. driver/null
+// and driver/soft.
+//
+// It tracks the names of objects, errors, the state set by context and
+// framebuffer state values, the bindings of buffers and vertex attribute
+// pointers, and the objects themselves as far as they do not depend on
+// rendering: the contents of buffers, the storage of renderbuffers and
+// textures, the attachments of framebuffers and the uniform values of
+// programs. Drivers embed its types in theirs, and add rendering.
+package emul
+
+import (
+	"github.com/slimsag/gfx"
+	"github.com/slimsag/gfx/internal/state"
+)
+
+// Context is an emulated graphics context. It implements the gfx.Context
+// interface, except for the Framebuffer method and the methods creating
+// objects, which drivers implement using NewObject.
+type Context struct {
+	state.Context
+
+	// FBState is the framebuffer state table, shared by all framebuffers.
+	FBState state.Table
+
+	// GL is the current state, as set by the GL calls of context and
+	// framebuffer state values.
+	GL GL
+
+	// Default is the default framebuffer, and Bound the framebuffer that was
+	// used most recently, like the framebuffer binding of OpenGL.
+	Default, Bound *Framebuffer
+
+	// Attribs are the vertex attribute pointers, by location.
+	Attribs [state.MaxVertexAttribs]AttribPointer
+
+	// OnCall, if not nil, is called with the name of each method called on
+	// the context or one of its objects, qualified by the name of its type
+	// (e.g. "Buffer.DataFloat32"), and the name of the object, or zero for
+	// the context and its default framebuffer.
+	OnCall func(method string, o uint32)
+
+	// counters counts the work done, and avoided, by the caches of the
+	// context.
+	counters state.Counters
+
+	// lastBindBuffer is the buffer that the context believes is bound to each
+	// buffer type, like the bind cache of other drivers, see BindBuffer.
+	lastBindBuffer [numBufferTypes]uint32
+
+	// objects maps the name of each object that has not been deleted to the
+	// object of the driver. Like OpenGL implementations, the names of deleted
+	// objects are reused, the most recently deleted first.
+	objects  map[uint32]interface{}
+	lastName uint32
+	free     []uint32
+
+	// err is the first error that occurred since the last call to Check.
+	err error
+
+	// debug is the debug handler, or nil.
+	debug func(m gfx.DebugMessage)
+}
+
+// GL is the state of a context, the equivalent of the global OpenGL state.
+type GL struct {
+	BlendColor    [4]float32
+	BlendEquation gfx.BlendEquation
+	DepthMask     bool
+	Program       gfx.Program // The program in use, or nil.
+	Viewport      [4]int
+	Scissor       [4]int
+	LineWidth     float32
+	ColorMask     [4]bool
+	CullFace      gfx.Facet
+	FrontFace     gfx.Orientation
+	AttribArrays  [state.MaxVertexAttribs]bool
+
+	// The name of the buffer bound to each buffer type, or zero, see
+	// BufferBinding.
+	buffers [numBufferTypes]uint32
+
+	// The clear values of the framebuffer that was used most recently.
+	ClearColor   [4]float32
+	ClearDepth   float64
+	ClearStencil int
+
+	features [state.NumFeatures]bool
+}
+
+// Enabled tells if the given feature is enabled.
+func (g *GL) Enabled(f gfx.Feature) bool {
+	return g.features[f-gfx.Blend]
+}
+
+// BufferBinding returns the name of the buffer bound to the given buffer
+// type, or zero if none is.
+func (g *GL) BufferBinding(t gfx.BufferType) uint32 {
+	return g.buffers[t-gfx.ArrayBuffer]
+}
+
+// Init initializes the context, whose default framebuffer def has the given
+// size in pixels, and sets the default state.
+func (c *Context) Init(width, height int, def *Framebuffer) {
+	c.objects = make(map[uint32]interface{})
+	def.Ctx = c
+	def.Table = &c.FBState
+	c.Default, c.Bound = def, def
+	c.Context.Init(c.defaultState(width, height)...)
+	c.FBState.Init(def.defaultState()...)
+	c.Context.SetCounters(&c.counters)
+	c.FBState.Counters = &c.counters
+}
+
+// Call records a call of the given method on the object with the given name,
+// see OnCall.
+func (c *Context) Call(method string, o uint32) {
+	if c.OnCall != nil {
+		c.OnCall(method, o)
+	}
+}
+
+// NewObject records a new object of the driver under a new name, given to h,
+// the Handle embedded in it, and records a call of the given method (e.g.
+// "Context.NewBuffer") on it.
+func (c *Context) NewObject(method string, h *Handle, object interface{}) {
+	var name uint32
+	if n := len(c.free); n > 0 {
+		name, c.free = c.free[n-1], c.free[:n-1]
+	} else {
+		c.lastName++
+		name = c.lastName
+	}
+	c.objects[name] = object
+	h.Ctx, h.O = c, name
+	c.Call(method, name)
+}
+
+// Lookup returns the object of the driver with the name of the given object
+// (which may be e.g. wrapped by the debug package), or nil if it was deleted
+// or does not belong to this context.
+func (c *Context) Lookup(o gfx.Object) interface{} {
+	if o == nil {
+		return nil
+	}
+	name, ok := o.Object().(uint32)
+	if !ok {
+		return nil
+	}
+	return c.objects[name]
+}
+
+// numBufferTypes is the number of gfx.BufferType values.
+const numBufferTypes = int(gfx.ElementArrayBuffer-gfx.ArrayBuffer) + 1
+
+// BindBuffer binds the given buffer to its buffer type, unless the context
+// believes it is already bound, in which case the bind is counted as elided.
+func (c *Context) BindBuffer(b *Buffer) {
+	t := b.typ - gfx.ArrayBuffer
+	if c.lastBindBuffer[t] == b.O {
+		c.counters.BindsElided++
+		return
+	}
+	c.lastBindBuffer[t] = b.O
+	c.GL.buffers[t] = b.O
+}
+
+// forgetBuffer unbinds the given buffer, which is being deleted, from every
+// buffer type it is bound to, and clears the bind cache accordingly.
+func (c *Context) forgetBuffer(buffer uint32) {
+	for t := range c.GL.buffers {
+		if c.GL.buffers[t] == buffer {
+			c.GL.buffers[t] = 0
+		}
+		if c.lastBindBuffer[t] == buffer {
+			c.lastBindBuffer[t] = 0
+		}
+	}
+}
+
+// errorIDs are the IDs of the debug messages of errors, i.e. their OpenGL
+// error codes.
+var errorIDs = map[error]uint32{
+	gfx.InvalidEnum:                 0x0500,
+	gfx.InvalidValue:                0x0501,
+	gfx.InvalidOperation:            0x0502,
+	gfx.StackOverflow:               0x0503,
+	gfx.StackUnderflow:              0x0504,
+	gfx.OutOfMemory:                 0x0505,
+	gfx.InvalidFramebufferOperation: 0x0506,
+	gfx.ContextLost:                 0x0507,
+}
+
+// SetError records err as if it occurred in the context, such that the next
+// call to Check panics with it (unless an earlier error is pending, as OpenGL
+// records only the first error), and passes it to the debug handler, if any.
+func (c *Context) SetError(err error) {
+	if c.debug != nil {
+		c.debug(gfx.DebugMessage{
+			Source:   gfx.DebugSourceAPI,
+			Type:     gfx.DebugTypeError,
+			Severity: gfx.DebugSeverityHigh,
+			ID:       errorIDs[err],
+			Message:  err.Error(),
+		})
+	}
+	if c.err == nil {
+		c.err = err
+	}
+}
+
+// Load implements the gfx.ContextStateProvider interface.
+func (c *Context) Load(s gfx.ContextState) {
+	c.Call("Context.Load", 0)
+	c.Context.Load(s)
+}
+
+// Check implements the gfx.Context interface.
+func (c *Context) Check() {
+	c.Call("Context.Check", 0)
+	err := c.err
+	if err == nil {
+		return
+	}
+	c.err = nil
+	panic(err)
+}
+
+// Flush implements the gfx.Context interface. Rendering is performed
+// immediately, so it does nothing.
+func (c *Context) Flush() {
+	c.Call("Context.Flush", 0)
+}
+
+// Finish implements the gfx.Context interface. Rendering is performed
+// immediately, so it does nothing.
+func (c *Context) Finish() {
+	c.Call("Context.Finish", 0)
+}
+
+// Invalidate implements the gfx.Context interface. Foreign code cannot modify
+// the state of the context, so it does nothing.
+func (c *Context) Invalidate() {
+	c.Call("Context.Invalidate", 0)
+}
+
+// Resync implements the gfx.Context interface. Foreign code cannot modify the
+// state of the context, so it does nothing.
+func (c *Context) Resync() {
+	c.Call("Context.Resync", 0)
+}
+
+// SetDebugHandler implements the gfx.Context interface. The handler is passed
+// the errors which occur in the context, including those recorded using
+// SetError, with their OpenGL error codes as IDs.
+func (c *Context) SetDebugHandler(h func(m gfx.DebugMessage)) {
+	c.Call("Context.SetDebugHandler", 0)
+	c.debug = h
+}
+
+// Counters returns the number of state values applied and elided when
+// loading states, and of bind calls elided, since the context was created. It
+// is used by the stats package.
+func (c *Context) Counters() (applied, elided, bindsElided int) {
+	return c.counters.Applied, c.counters.Elided, c.counters.BindsElided
+}
+
+// Handle is the part of an object of an emulated context shared by all types
+// of objects: its context and name.
+type Handle struct {
+	// Ctx is the context of the object.
+	Ctx *Context
+
+	// O is the name of the object, or zero once it is deleted (and for the
+	// default framebuffer).
+	O uint32
+}
+
+// Object implements the gfx.Object interface.
+func (h *Handle) Object() interface{} {
+	return h.O
+}
+
+// delete records a call of the given method (e.g. "Buffer.Delete") and
+// deletes the object, whose name may then be reused. It returns false if the
+// object was already deleted, in which case nothing is recorded.
+func (h *Handle) delete(method string) bool {
+	if h.O == 0 {
+		return false
+	}
+	c := h.Ctx
+	c.Call(method, h.O)
+	delete(c.objects, h.O)
+	c.free = append(c.free, h.O)
+	h.O = 0
+	return true
+}
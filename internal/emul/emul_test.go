@@ -0,0 +1,92 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package emul
+
+import (
+	"testing"
+
+	"github.com/slimsag/gfx"
+	"github.com/slimsag/gfx/internal/state"
+)
+
+func newContext() *Context {
+	c := &Context{}
+	c.Init(4, 4, &Framebuffer{})
+	return c
+}
+
+// check calls c.Check, and returns the error it panics with.
+func check(c *Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = r.(error)
+		}
+	}()
+	c.Check()
+	return nil
+}
+
+func TestAttribLocation(t *testing.T) {
+	tests := []struct {
+		l    gfx.AttribLocation
+		want bool
+	}{
+		{nil, false},
+		{-1, false},
+		{0, true},
+		{state.MaxVertexAttribs - 1, true},
+		{state.MaxVertexAttribs, false},
+		{int32(0), false}, // Another driver's location.
+	}
+	for _, tst := range tests {
+		if _, ok := AttribLocation(tst.l); ok != tst.want {
+			t.Errorf("AttribLocation(%#v) = %v, want %v", tst.l, ok, tst.want)
+		}
+	}
+}
+
+func TestInvalidAttribLocation(t *testing.T) {
+	c := newContext()
+	b := NewBuffer(gfx.ArrayBuffer)
+	c.NewObject("Context.NewBuffer", &b.Handle, &b)
+
+	// Both uses of a location reject the same locations.
+	for _, l := range []gfx.AttribLocation{nil, -1, state.MaxVertexAttribs, int32(0)} {
+		b.VertexAttribPointer(l, 4, false, 0, 0)
+		if err := check(c); err != gfx.InvalidValue {
+			t.Errorf("VertexAttribPointer(%#v): Check = %v, want %v", l, err, gfx.InvalidValue)
+		}
+		c.Load(c.NewState(c.EnableVertexAttribArray(l)))
+		if err := check(c); err != gfx.InvalidValue {
+			t.Errorf("EnableVertexAttribArray(%#v): Check = %v, want %v", l, err, gfx.InvalidValue)
+		}
+		c.Load(nil)
+		check(c)
+	}
+}
+
+func TestNames(t *testing.T) {
+	c := newContext()
+	var objs [3]Shader
+	for i := range objs {
+		c.NewObject("Context.NewShader", &objs[i].Handle, &objs[i])
+	}
+	objs[0].Delete()
+	objs[1].Delete()
+	objs[1].Delete()
+
+	// The names of deleted objects are reused, the most recently deleted
+	// first.
+	for _, want := range []uint32{2, 1, 4} {
+		var s Shader
+		c.NewObject("Context.NewShader", &s.Handle, &s)
+		if s.O != want {
+			t.Errorf("name = %d, want %d", s.O, want)
+		}
+	}
+	if c.Lookup(&objs[2]) != &objs[2] {
+		t.Error("Lookup did not return the object")
+	}
+}
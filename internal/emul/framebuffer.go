@@ -0,0 +1,228 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package emul
+
+import (
+	"github.com/slimsag/gfx"
+	s "github.com/slimsag/gfx/internal/state"
+)
+
+// Framebuffer is an emulated framebuffer. It implements the gfx.Framebuffer
+// interface, except for the Clear and ReadPixelsUint8 methods, which drivers
+// implement.
+type Framebuffer struct {
+	s.Framebuffer
+	Handle
+
+	// attachments are the objects attached to a framebuffer object, indexed
+	// by attachment point.
+	attachments [gfx.DepthStencilAttachment + 1]attachment
+}
+
+// attachment is an image attached to a framebuffer object, either a
+// renderbuffer or a face of a texture, and the object of the driver it
+// belongs to.
+type attachment struct {
+	obj    gfx.Object
+	rb     *Renderbuffer
+	tex    *Texture
+	target gfx.TextureTarget
+}
+
+// Attachment returns the renderbuffer or texture of the driver attached to
+// the given attachment point, or nil if there is none. For textures, the
+// target given to Texture2D is also returned.
+func (f *Framebuffer) Attachment(a gfx.FramebufferAttachment) (gfx.Object, gfx.TextureTarget) {
+	if a < gfx.ColorAttachment0 || a > gfx.DepthStencilAttachment {
+		return nil, 0
+	}
+	at := f.attachments[a]
+	return at.obj, at.target
+}
+
+// UseState makes this framebuffer the one drawn to, and applies its state.
+func (f *Framebuffer) UseState() {
+	f.Ctx.Bound = f
+
+	// Framebuffer state is global to the context, so apply the state loaded
+	// by this framebuffer.
+	f.Ctx.FBState.Apply(f.Loaded)
+}
+
+// Err returns an error describing why the framebuffer is incomplete, or nil.
+// The default framebuffer is always complete.
+func (f *Framebuffer) Err() error {
+	if f == f.Ctx.Default {
+		return nil
+	}
+	var width, height int
+	attached := false
+	for i, a := range f.attachments {
+		var w, h int
+		switch {
+		case a.rb != nil && a.rb.O != 0:
+			w, h = a.rb.width, a.rb.height
+			ok := false
+			switch gfx.FramebufferAttachment(i) {
+			case gfx.ColorAttachment0:
+				ok = a.rb.format != gfx.DepthComponent16
+			case gfx.DepthAttachment:
+				ok = a.rb.format == gfx.DepthComponent16
+			}
+			if !ok || !a.rb.storage || w == 0 || h == 0 {
+				// The renderbuffer has no storage, or a format that cannot
+				// be attached to the attachment point (there are no
+				// stencil formats).
+				return gfx.ErrFramebufferIncompleteAttachment
+			}
+		case a.tex != nil && a.tex.O != 0:
+			w, h = a.tex.width, a.tex.height
+			if gfx.FramebufferAttachment(i) != gfx.ColorAttachment0 || w == 0 || h == 0 {
+				return gfx.ErrFramebufferIncompleteAttachment
+			}
+		default:
+			continue
+		}
+		if attached && (w != width || h != height) {
+			return gfx.ErrFramebufferIncompleteDimensions
+		}
+		width, height = w, h
+		attached = true
+	}
+	if !attached {
+		return gfx.ErrFramebufferIncompleteMissingAttachment
+	}
+	return nil
+}
+
+// Load implements the gfx.FramebufferStateProvider interface.
+func (f *Framebuffer) Load(st gfx.FramebufferState) {
+	f.Ctx.Call("Framebuffer.Load", f.O)
+	f.Framebuffer.Load(st)
+}
+
+// attach attaches a, unless the framebuffer is the default framebuffer or the
+// attachment point is invalid.
+func (f *Framebuffer) attach(point gfx.FramebufferAttachment, a attachment) {
+	switch {
+	case f == f.Ctx.Default:
+		f.Ctx.SetError(gfx.InvalidOperation)
+	case point < gfx.ColorAttachment0 || point > gfx.DepthStencilAttachment:
+		f.Ctx.SetError(gfx.InvalidEnum)
+	default:
+		f.attachments[point] = a
+	}
+}
+
+// Texture2D implements the gfx.Framebuffer interface.
+func (f *Framebuffer) Texture2D(point gfx.FramebufferAttachment, target gfx.TextureTarget, tex gfx.Texture) {
+	f.Ctx.Call("Framebuffer.Texture2D", f.O)
+	f.UseState()
+	if tex == nil {
+		f.attach(point, attachment{})
+		return
+	}
+	obj := f.Ctx.Lookup(tex)
+	t, ok := obj.(texture)
+	if !ok {
+		f.Ctx.SetError(gfx.InvalidOperation)
+		return
+	}
+	if _, ok := t.texture().Face(target); !ok {
+		f.Ctx.SetError(gfx.InvalidOperation)
+		return
+	}
+	f.attach(point, attachment{obj: obj.(gfx.Object), tex: t.texture(), target: target})
+}
+
+// Renderbuffer implements the gfx.Framebuffer interface.
+func (f *Framebuffer) Renderbuffer(point gfx.FramebufferAttachment, buf gfx.Renderbuffer) {
+	f.Ctx.Call("Framebuffer.Renderbuffer", f.O)
+	f.UseState()
+	if buf == nil {
+		f.attach(point, attachment{})
+		return
+	}
+	obj := f.Ctx.Lookup(buf)
+	rb, ok := obj.(renderbuffer)
+	if !ok {
+		f.Ctx.SetError(gfx.InvalidOperation)
+		return
+	}
+	f.attach(point, attachment{obj: obj.(gfx.Object), rb: rb.renderbuffer()})
+}
+
+// Status implements the gfx.Framebuffer interface.
+func (f *Framebuffer) Status() error {
+	f.Ctx.Call("Framebuffer.Status", f.O)
+	f.UseState()
+	return f.Err()
+}
+
+// Delete implements the gfx.Object interface.
+func (f *Framebuffer) Delete() {
+	if !f.delete("Framebuffer.Delete") {
+		return
+	}
+	if f.Ctx.Bound == f {
+		// Deleting the bound framebuffer binds the default one.
+		f.Ctx.Bound = f.Ctx.Default
+	}
+}
+
+// defaultState returns the default value of each piece of framebuffer state,
+// as specified by OpenGL.
+func (f *Framebuffer) defaultState() []s.CSV {
+	d := []s.CSV{
+		f.ClearColor(0, 0, 0, 0).(s.CSV),
+		f.ClearDepth(1).(s.CSV),
+		f.ClearStencil(0).(s.CSV),
+	}
+
+	// See Context.defaultState.
+	for _, v := range d {
+		v.GLCall(&v.Value)
+	}
+	return d
+}
+
+func (c *Context) glClearColor(v *s.Value) {
+	c.GL.ClearColor = v.F
+}
+
+// ClearColor implements the gfx.FramebufferStateProvider interface.
+func (f *Framebuffer) ClearColor(r, g, b, a float32) gfx.FramebufferStateValue {
+	return s.CSV{
+		Key:    s.ClearColor,
+		Value:  s.Value{F: [4]float32{r, g, b, a}},
+		GLCall: f.Ctx.glClearColor,
+	}
+}
+
+func (c *Context) glClearDepth(v *s.Value) {
+	c.GL.ClearDepth = v.D
+}
+
+// ClearDepth implements the gfx.FramebufferStateProvider interface.
+func (f *Framebuffer) ClearDepth(depth float64) gfx.FramebufferStateValue {
+	return s.CSV{
+		Key:    s.ClearDepth,
+		Value:  s.Value{D: depth},
+		GLCall: f.Ctx.glClearDepth,
+	}
+}
+
+func (c *Context) glClearStencil(v *s.Value) {
+	c.GL.ClearStencil = int(v.I[0])
+}
+
+// ClearStencil implements the gfx.FramebufferStateProvider interface.
+func (f *Framebuffer) ClearStencil(stencil int) gfx.FramebufferStateValue {
+	return s.CSV{
+		Key:    s.ClearStencil,
+		Value:  s.Value{I: [4]int32{int32(stencil)}},
+		GLCall: f.Ctx.glClearStencil,
+	}
+}
@@ -0,0 +1,226 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package emul
+
+import "github.com/slimsag/gfx"
+
+// Program is an emulated program. It implements the gfx.Program interface,
+// except for the Link, AttribLocation and UniformLocation methods, which
+// drivers implement using SetLinked and the locations of the uniforms.
+type Program struct {
+	Handle
+
+	// Uniforms holds the values of the uniforms, once the program is linked.
+	Uniforms Uniforms
+
+	linked bool
+	log    string
+}
+
+// program is implemented by the programs of drivers, which embed a Program.
+type program interface {
+	gfx.Program
+	program() *Program
+}
+
+func (p *Program) program() *Program {
+	return p
+}
+
+// Uniforms holds the values of the uniform variables of a program. Values are
+// set by the Uniform* methods of the program, matrices being stored in
+// column-major order.
+type Uniforms struct {
+	index  map[string]int
+	values []uniform
+}
+
+// uniform is the value of a single uniform variable, set either as floats or
+// as integers.
+type uniform struct {
+	f []float32
+	i []int32
+}
+
+// Float returns the value of the named uniform variable, as set by one of
+// the Uniform*fv or UniformMatrix*fv methods, or nil if it was not set.
+func (u *Uniforms) Float(name string) []float32 {
+	if i, ok := u.index[name]; ok {
+		return u.values[i].f
+	}
+	return nil
+}
+
+// Int returns the value of the named uniform variable, as set by one of the
+// Uniform*iv methods, or nil if it was not set.
+func (u *Uniforms) Int(name string) []int32 {
+	if i, ok := u.index[name]; ok {
+		return u.values[i].i
+	}
+	return nil
+}
+
+// Location returns the location of the named uniform variable, and false if
+// the program has no such uniform.
+func (u *Uniforms) Location(name string) (int, bool) {
+	l, ok := u.index[name]
+	return l, ok
+}
+
+// add adds the named uniform variable, unless it was already added, and
+// returns its location. Locations are assigned in order.
+func (u *Uniforms) add(name string) int {
+	l, ok := u.index[name]
+	if !ok {
+		l = len(u.values)
+		u.index[name] = l
+		u.values = append(u.values, uniform{})
+	}
+	return l
+}
+
+// SetLinked records the result of linking the program: if log is empty, the
+// program is linked with the named uniforms (see AddUniform), otherwise
+// linking failed with the given info log. It returns whether the program is
+// linked.
+func (p *Program) SetLinked(log string, uniforms ...string) bool {
+	p.linked, p.log = log == "", log
+	if !p.linked {
+		return false
+	}
+
+	// Linking resets the locations and values of the uniforms.
+	p.Uniforms = Uniforms{index: make(map[string]int)}
+	for _, name := range uniforms {
+		p.Uniforms.add(name)
+	}
+	return true
+}
+
+// AddUniform adds the named uniform variable to the linked program, unless it
+// already has it, and returns its location, for drivers which assign
+// locations on demand.
+func (p *Program) AddUniform(name string) int {
+	return p.Uniforms.add(name)
+}
+
+// Linked tells if the program was linked successfully.
+func (p *Program) Linked() bool {
+	return p.linked
+}
+
+// InfoLog implements the gfx.Program interface.
+func (p *Program) InfoLog() string {
+	return p.log
+}
+
+// uniform records a call of the given method, and returns the uniform at the
+// given location, or nil if the location is nil (which is silently ignored,
+// like a location of -1 in OpenGL).
+func (p *Program) uniform(method string, l gfx.UniformLocation) *uniform {
+	p.Ctx.Call(method, p.O)
+	if l == nil {
+		return nil
+	}
+	i, ok := l.(int)
+	if !ok || !p.linked || i < 0 || i >= len(p.Uniforms.values) {
+		p.Ctx.SetError(gfx.InvalidOperation)
+		return nil
+	}
+	return &p.Uniforms.values[i]
+}
+
+func (p *Program) uniformf(method string, l gfx.UniformLocation, data []float32) {
+	if u := p.uniform(method, l); u != nil {
+		u.f, u.i = append([]float32(nil), data...), nil
+	}
+}
+
+func (p *Program) uniformi(method string, l gfx.UniformLocation, data []int32) {
+	if u := p.uniform(method, l); u != nil {
+		u.f, u.i = nil, append([]int32(nil), data...)
+	}
+}
+
+// uniformMatrix sets n by n matrices, transposing each one if needed.
+func (p *Program) uniformMatrix(method string, l gfx.UniformLocation, n int, transpose bool, data []float32) {
+	u := p.uniform(method, l)
+	if u == nil {
+		return
+	}
+	u.f, u.i = append([]float32(nil), data...), nil
+	if !transpose {
+		return
+	}
+	for m := 0; m+n*n <= len(u.f); m += n * n {
+		for i := 0; i < n; i++ {
+			for j := i + 1; j < n; j++ {
+				a, b := m+i*n+j, m+j*n+i
+				u.f[a], u.f[b] = u.f[b], u.f[a]
+			}
+		}
+	}
+}
+
+// Uniform1fv implements the gfx.Program interface.
+func (p *Program) Uniform1fv(l gfx.UniformLocation, data []float32) {
+	p.uniformf("Program.Uniform1fv", l, data)
+}
+
+// Uniform1iv implements the gfx.Program interface.
+func (p *Program) Uniform1iv(l gfx.UniformLocation, data []int32) {
+	p.uniformi("Program.Uniform1iv", l, data)
+}
+
+// Uniform2fv implements the gfx.Program interface.
+func (p *Program) Uniform2fv(l gfx.UniformLocation, data []float32) {
+	p.uniformf("Program.Uniform2fv", l, data)
+}
+
+// Uniform2iv implements the gfx.Program interface.
+func (p *Program) Uniform2iv(l gfx.UniformLocation, data []int32) {
+	p.uniformi("Program.Uniform2iv", l, data)
+}
+
+// Uniform3fv implements the gfx.Program interface.
+func (p *Program) Uniform3fv(l gfx.UniformLocation, data []float32) {
+	p.uniformf("Program.Uniform3fv", l, data)
+}
+
+// Uniform3iv implements the gfx.Program interface.
+func (p *Program) Uniform3iv(l gfx.UniformLocation, data []int32) {
+	p.uniformi("Program.Uniform3iv", l, data)
+}
+
+// Uniform4fv implements the gfx.Program interface.
+func (p *Program) Uniform4fv(l gfx.UniformLocation, data []float32) {
+	p.uniformf("Program.Uniform4fv", l, data)
+}
+
+// Uniform4iv implements the gfx.Program interface.
+func (p *Program) Uniform4iv(l gfx.UniformLocation, data []int32) {
+	p.uniformi("Program.Uniform4iv", l, data)
+}
+
+// UniformMatrix2fv implements the gfx.Program interface.
+func (p *Program) UniformMatrix2fv(l gfx.UniformLocation, transpose bool, data []float32) {
+	p.uniformMatrix("Program.UniformMatrix2fv", l, 2, transpose, data)
+}
+
+// UniformMatrix3fv implements the gfx.Program interface.
+func (p *Program) UniformMatrix3fv(l gfx.UniformLocation, transpose bool, data []float32) {
+	p.uniformMatrix("Program.UniformMatrix3fv", l, 3, transpose, data)
+}
+
+// UniformMatrix4fv implements the gfx.Program interface.
+func (p *Program) UniformMatrix4fv(l gfx.UniformLocation, transpose bool, data []float32) {
+	p.uniformMatrix("Program.UniformMatrix4fv", l, 4, transpose, data)
+}
+
+// Delete implements the gfx.Object interface. Like in OpenGL, a program that
+// is in use remains usable until another program is used.
+func (p *Program) Delete() {
+	p.delete("Program.Delete")
+}
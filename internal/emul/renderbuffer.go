@@ -0,0 +1,66 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package emul
+
+import "github.com/slimsag/gfx"
+
+// Renderbuffer is an emulated renderbuffer. It implements the
+// gfx.Renderbuffer interface.
+type Renderbuffer struct {
+	Handle
+
+	// The renderbuffer's storage, once allocated.
+	storage       bool
+	format        gfx.RenderbufferFormat
+	width, height int
+}
+
+// renderbuffer is implemented by the renderbuffers of drivers, which embed a
+// Renderbuffer.
+type renderbuffer interface {
+	renderbuffer() *Renderbuffer
+}
+
+func (r *Renderbuffer) renderbuffer() *Renderbuffer {
+	return r
+}
+
+// Storage implements the gfx.Renderbuffer interface.
+func (r *Renderbuffer) Storage(internalFormat gfx.RenderbufferFormat, width, height int) {
+	r.SetStorage(internalFormat, width, height)
+}
+
+// SetStorage is like Storage, for drivers which allocate images: it returns
+// false if an error was generated, leaving the storage unchanged.
+func (r *Renderbuffer) SetStorage(internalFormat gfx.RenderbufferFormat, width, height int) bool {
+	r.Ctx.Call("Renderbuffer.Storage", r.O)
+	switch {
+	case internalFormat < gfx.RGBA4 || internalFormat > gfx.DepthComponent16:
+		r.Ctx.SetError(gfx.InvalidEnum)
+		return false
+	case width < 0 || height < 0:
+		r.Ctx.SetError(gfx.InvalidValue)
+		return false
+	}
+	r.storage = true
+	r.format, r.width, r.height = internalFormat, width, height
+	return true
+}
+
+// Format returns the format of the renderbuffer's storage, and false if no
+// storage was allocated.
+func (r *Renderbuffer) Format() (gfx.RenderbufferFormat, bool) {
+	return r.format, r.storage
+}
+
+// Size returns the size of the renderbuffer's storage.
+func (r *Renderbuffer) Size() (width, height int) {
+	return r.width, r.height
+}
+
+// Delete implements the gfx.Object interface.
+func (r *Renderbuffer) Delete() {
+	r.delete("Renderbuffer.Delete")
+}
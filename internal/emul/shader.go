@@ -0,0 +1,50 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package emul
+
+import "github.com/slimsag/gfx"
+
+// Shader is an emulated shader. It implements the gfx.Shader interface,
+// except for the Compile method, which drivers implement using SetCompiled.
+type Shader struct {
+	Handle
+
+	typ      gfx.ShaderType
+	compiled bool
+	log      string
+}
+
+// NewShader returns a shader of the given type.
+func NewShader(t gfx.ShaderType) Shader {
+	return Shader{typ: t}
+}
+
+// SetCompiled records the result of compiling the shader: it is compiled if
+// log is empty, otherwise compilation failed with the given info log. It
+// returns whether the shader is compiled.
+func (s *Shader) SetCompiled(log string) bool {
+	s.compiled, s.log = log == "", log
+	return s.compiled
+}
+
+// Compiled tells if the shader was compiled successfully.
+func (s *Shader) Compiled() bool {
+	return s.compiled
+}
+
+// Type returns the type of the shader.
+func (s *Shader) Type() gfx.ShaderType {
+	return s.typ
+}
+
+// InfoLog implements the gfx.Shader interface.
+func (s *Shader) InfoLog() string {
+	return s.log
+}
+
+// Delete implements the gfx.Object interface.
+func (s *Shader) Delete() {
+	s.delete("Shader.Delete")
+}
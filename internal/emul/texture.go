@@ -0,0 +1,89 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package emul
+
+import "github.com/slimsag/gfx"
+
+// Texture is an emulated texture. It implements the gfx.Texture interface.
+type Texture struct {
+	Handle
+
+	typ gfx.TextureType
+
+	// The size of the texture (of each face of a cube map), which is zero
+	// until Storage is called.
+	width, height int
+}
+
+// NewTexture returns a texture of the given type.
+func NewTexture(t gfx.TextureType) Texture {
+	return Texture{typ: t}
+}
+
+// texture is implemented by the textures of drivers, which embed a Texture.
+type texture interface {
+	texture() *Texture
+}
+
+func (t *Texture) texture() *Texture {
+	return t
+}
+
+// Storage allocates storage for the texture, such that it can be attached to
+// a framebuffer. Each face of a cube map has the given size, which must be
+// square.
+//
+// gfx has no API for allocating the storage of a texture, so drivers export
+// Storage as a method specific to them.
+func (t *Texture) Storage(width, height int) {
+	t.SetStorage(width, height)
+}
+
+// SetStorage is like Storage, for drivers which allocate images: it returns
+// false if an error was generated, leaving the storage unchanged.
+func (t *Texture) SetStorage(width, height int) bool {
+	t.Ctx.Call("Texture.Storage", t.O)
+	if width < 0 || height < 0 || (t.typ == gfx.TextureTypeCubeMap && width != height) {
+		t.Ctx.SetError(gfx.InvalidValue)
+		return false
+	}
+	t.width, t.height = width, height
+	return true
+}
+
+// Size returns the size of the texture's storage.
+func (t *Texture) Size() (width, height int) {
+	return t.width, t.height
+}
+
+// Faces returns the number of faces of the texture: one for a 2D texture, or
+// six for a cube map.
+func (t *Texture) Faces() int {
+	if t.typ == gfx.TextureTypeCubeMap {
+		return 6
+	}
+	return 1
+}
+
+// Face returns the index of the face of the texture for the given target (in
+// the order of the cube map texture targets), or false if the target does not
+// match the texture's type.
+func (t *Texture) Face(target gfx.TextureTarget) (int, bool) {
+	if t.typ == gfx.TextureTypeCubeMap {
+		i := int(target - gfx.TextureCubeMapPositiveX)
+		return i, i >= 0 && i < 6
+	}
+	return 0, target == gfx.Texture2D
+}
+
+// Type implements the gfx.Texture interface.
+func (t *Texture) Type() gfx.TextureType {
+	return t.typ
+}
+
+// Delete implements the gfx.Object interface.
+func (t *Texture) Delete() {
+	t.delete("Texture.Delete")
+}
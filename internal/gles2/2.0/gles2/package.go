@@ -90,6 +90,7 @@ package gles2
 // typedef GLuint  (APIENTRYP GPCREATESHADER)(GLenum  type);
 // typedef void  (APIENTRYP GPCULLFACE)(GLenum  mode);
 // typedef void  (APIENTRYP GPDEBUGMESSAGECALLBACKARB)(GLDEBUGPROCARB  callback, const void * userParam);
+// typedef void  (APIENTRYP GPDEBUGMESSAGECALLBACKKHR)(GLDEBUGPROCKHR  callback, const void * userParam);
 // typedef void  (APIENTRYP GPDELETEBUFFERS)(GLsizei  n, const GLuint * buffers);
 // typedef void  (APIENTRYP GPDELETEFRAMEBUFFERS)(GLsizei  n, const GLuint * framebuffers);
 // typedef void  (APIENTRYP GPDELETEPROGRAM)(GLuint  program);
@@ -125,6 +126,7 @@ package gles2
 // typedef void  (APIENTRYP GPGETSHADERIV)(GLuint  shader, GLenum  pname, GLint * params);
 // typedef const GLubyte * (APIENTRYP GPGETSTRING)(GLenum  name);
 // typedef GLint  (APIENTRYP GPGETUNIFORMLOCATION)(GLuint  program, const GLchar * name);
+// typedef void  (APIENTRYP GPGETVERTEXATTRIBIV)(GLuint  index, GLenum  pname, GLint * params);
 // typedef void  (APIENTRYP GPLINEWIDTH)(GLfloat  width);
 // typedef void  (APIENTRYP GPLINKPROGRAM)(GLuint  program);
 // typedef void  (APIENTRYP GPPOLYGONOFFSET)(GLfloat  factor, GLfloat  units);
@@ -222,6 +224,9 @@ package gles2
 // static void  glowDebugMessageCallbackARB(GPDEBUGMESSAGECALLBACKARB fnptr, GLDEBUGPROCARB  callback, const void * userParam) {
 //   (*fnptr)(glowCDebugCallback, userParam);
 // }
+// static void  glowDebugMessageCallbackKHR(GPDEBUGMESSAGECALLBACKKHR fnptr, GLDEBUGPROCKHR  callback, const void * userParam) {
+//   (*fnptr)(glowCDebugCallback, userParam);
+// }
 // static void  glowDeleteBuffers(GPDELETEBUFFERS fnptr, GLsizei  n, const GLuint * buffers) {
 //   (*fnptr)(n, buffers);
 // }
@@ -327,6 +332,9 @@ package gles2
 // static GLint  glowGetUniformLocation(GPGETUNIFORMLOCATION fnptr, GLuint  program, const GLchar * name) {
 //   return (*fnptr)(program, name);
 // }
+// static void  glowGetVertexAttribiv(GPGETVERTEXATTRIBIV fnptr, GLuint  index, GLenum  pname, GLint * params) {
+//   (*fnptr)(index, pname, params);
+// }
 // static void  glowLineWidth(GPLINEWIDTH fnptr, GLfloat  width) {
 //   (*fnptr)(width);
 // }
@@ -421,6 +429,7 @@ const (
 	ALPHA_BITS                                = 0x0D55
 	ALWAYS                                    = 0x0207
 	ARRAY_BUFFER                              = 0x8892
+	ARRAY_BUFFER_BINDING                      = 0x8894
 	BACK                                      = 0x0405
 	BGRA                                      = 0x80E1
 	BLEND                                     = 0x0BE2
@@ -447,10 +456,15 @@ const (
 	CULL_FACE_MODE                            = 0x0B45
 	CURRENT_PROGRAM                           = 0x8B8D
 	CW                                        = 0x0900
+	DEBUG_OUTPUT                              = 0x92E0
+	DEBUG_OUTPUT_KHR                          = 0x92E0
 	DEBUG_OUTPUT_SYNCHRONOUS_ARB              = 0x8242
+	DEBUG_OUTPUT_SYNCHRONOUS_KHR              = 0x8242
 	DEBUG_SEVERITY_HIGH                       = 0x9146
 	DEBUG_SEVERITY_LOW                        = 0x9148
 	DEBUG_SEVERITY_MEDIUM                     = 0x9147
+	DEBUG_SOURCE_API                          = 0x8246
+	DEBUG_SOURCE_OTHER                        = 0x824B
 	DEBUG_TYPE_DEPRECATED_BEHAVIOR            = 0x824D
 	DEBUG_TYPE_ERROR                          = 0x824C
 	DEBUG_TYPE_OTHER                          = 0x8251
@@ -476,11 +490,13 @@ const (
 	DST_COLOR                                 = 0x0306
 	DYNAMIC_DRAW                              = 0x88E8
 	ELEMENT_ARRAY_BUFFER                      = 0x8893
+	ELEMENT_ARRAY_BUFFER_BINDING              = 0x8895
 	EQUAL                                     = 0x0202
 	EXTENSIONS                                = 0x1F03
 	FLOAT                                     = 0x1406
 	FRAGMENT_SHADER                           = 0x8B30
 	FRAMEBUFFER                               = 0x8D40
+	FRAMEBUFFER_BINDING                       = 0x8CA6
 	FRAMEBUFFER_COMPLETE                      = 0x8CD5
 	FRAMEBUFFER_INCOMPLETE_ATTACHMENT         = 0x8CD6
 	FRAMEBUFFER_INCOMPLETE_DIMENSIONS         = 0x8CD9
@@ -492,6 +508,7 @@ const (
 	FRAMEBUFFER_UNSUPPORTED                   = 0x8CDD
 	FRONT                                     = 0x0404
 	FRONT_AND_BACK                            = 0x0408
+	FRONT_FACE                                = 0x0B46
 	FUNC_ADD                                  = 0x8006
 	FUNC_REVERSE_SUBTRACT                     = 0x800B
 	FUNC_SUBTRACT                             = 0x800A
@@ -515,11 +532,13 @@ const (
 	LINES                                     = 0x0001
 	LINE_LOOP                                 = 0x0002
 	LINE_STRIP                                = 0x0003
+	LINE_WIDTH                                = 0x0B21
 	LINK_STATUS                               = 0x8B82
 	MAX_FRAGMENT_UNIFORM_VECTORS              = 0x8DFD
 	MAX_SAMPLES                               = 0x8D57
 	MAX_TEXTURE_SIZE                          = 0x0D33
 	MAX_VARYING_VECTORS                       = 0x8DFC
+	MAX_VERTEX_ATTRIBS                        = 0x8869
 	MAX_VERTEX_UNIFORM_VECTORS                = 0x8DFB
 	MIRRORED_REPEAT                           = 0x8370
 	NEAREST                                   = 0x2600
@@ -541,6 +560,7 @@ const (
 	POLYGON_OFFSET_FILL                       = 0x8037
 	RED_BITS                                  = 0x0D52
 	RENDERBUFFER                              = 0x8D41
+	RENDERBUFFER_BINDING                      = 0x8CA7
 	RENDERER                                  = 0x1F01
 	REPEAT                                    = 0x2901
 	REPLACE                                   = 0x1E01
@@ -602,6 +622,7 @@ const (
 	UNSIGNED_SHORT                            = 0x1403
 	VENDOR                                    = 0x1F00
 	VERSION                                   = 0x1F02
+	VERTEX_ATTRIB_ARRAY_ENABLED               = 0x8622
 	VERTEX_SHADER                             = 0x8B31
 	VIEWPORT                                  = 0x0BA2
 	ZERO                                      = 0
@@ -631,6 +652,7 @@ var (
 	gpCreateShader                   C.GPCREATESHADER
 	gpCullFace                       C.GPCULLFACE
 	gpDebugMessageCallbackARB        C.GPDEBUGMESSAGECALLBACKARB
+	gpDebugMessageCallbackKHR        C.GPDEBUGMESSAGECALLBACKKHR
 	gpDeleteBuffers                  C.GPDELETEBUFFERS
 	gpDeleteFramebuffers             C.GPDELETEFRAMEBUFFERS
 	gpDeleteProgram                  C.GPDELETEPROGRAM
@@ -666,6 +688,7 @@ var (
 	gpGetShaderiv                    C.GPGETSHADERIV
 	gpGetString                      C.GPGETSTRING
 	gpGetUniformLocation             C.GPGETUNIFORMLOCATION
+	gpGetVertexAttribiv              C.GPGETVERTEXATTRIBIV
 	gpLineWidth                      C.GPLINEWIDTH
 	gpLinkProgram                    C.GPLINKPROGRAM
 	gpPolygonOffset                  C.GPPOLYGONOFFSET
@@ -816,6 +839,10 @@ func DebugMessageCallbackARB(callback DebugProc, userParam unsafe.Pointer) {
 	userDebugCallback = callback
 	C.glowDebugMessageCallbackARB(gpDebugMessageCallbackARB, (C.GLDEBUGPROCARB)(unsafe.Pointer(&callback)), userParam)
 }
+func DebugMessageCallbackKHR(callback DebugProc, userParam unsafe.Pointer) {
+	userDebugCallback = callback
+	C.glowDebugMessageCallbackKHR(gpDebugMessageCallbackKHR, (C.GLDEBUGPROCKHR)(unsafe.Pointer(&callback)), userParam)
+}
 
 // delete named buffer objects
 func DeleteBuffers(n int32, buffers *uint32) {
@@ -986,6 +1013,11 @@ func GetUniformLocation(program uint32, name *uint8) int32 {
 	return (int32)(ret)
 }
 
+// Return a generic vertex attribute parameter
+func GetVertexAttribiv(index uint32, pname uint32, params *int32) {
+	C.glowGetVertexAttribiv(gpGetVertexAttribiv, (C.GLuint)(index), (C.GLenum)(pname), (*C.GLint)(unsafe.Pointer(params)))
+}
+
 // specify the width of rasterized lines
 func LineWidth(width float32) {
 	C.glowLineWidth(gpLineWidth, (C.GLfloat)(width))
@@ -1218,6 +1250,7 @@ func InitWithProcAddrFunc(getProcAddr func(name string) unsafe.Pointer) error {
 		return errors.New("glCullFace")
 	}
 	gpDebugMessageCallbackARB = (C.GPDEBUGMESSAGECALLBACKARB)(getProcAddr("glDebugMessageCallbackARB"))
+	gpDebugMessageCallbackKHR = (C.GPDEBUGMESSAGECALLBACKKHR)(getProcAddr("glDebugMessageCallbackKHR"))
 	gpDeleteBuffers = (C.GPDELETEBUFFERS)(getProcAddr("glDeleteBuffers"))
 	if gpDeleteBuffers == nil {
 		return errors.New("glDeleteBuffers")
@@ -1358,6 +1391,10 @@ func InitWithProcAddrFunc(getProcAddr func(name string) unsafe.Pointer) error {
 	if gpGetUniformLocation == nil {
 		return errors.New("glGetUniformLocation")
 	}
+	gpGetVertexAttribiv = (C.GPGETVERTEXATTRIBIV)(getProcAddr("glGetVertexAttribiv"))
+	if gpGetVertexAttribiv == nil {
+		return errors.New("glGetVertexAttribiv")
+	}
 	gpLineWidth = (C.GPLINEWIDTH)(getProcAddr("glLineWidth"))
 	if gpLineWidth == nil {
 		return errors.New("glLineWidth")
@@ -1,70 +1,125 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
 package state
 
 import "github.com/slimsag/gfx"
 
-type CSV struct {
-	Key                 interface{}
-	Value, DefaultValue interface{}
-	GLCall              func(value interface{})
+// Context implements the state management portion of the
+// gfx.ContextStateProvider interface.
+type Context struct {
+	table Table
+
+	// current is the currently loaded state, as given to Load.
+	current gfx.ContextState
+
+	// stack is the stack of states pushed by PushState.
+	stack []gfx.ContextState
 }
 
-type ContextState []gfx.ContextStateValue
-
-func (c ContextState) Find(k interface{}) (index int, pair CSV) {
-	var i interface{}
-	for index, i = range c {
-		pair = i.(CSV)
-		if pair.Key != k {
-			// Non-equal keys.
-			continue
-		}
-		return index, pair
+// Init initializes the context's state table with the given default values,
+// see Table.Init.
+func (c *Context) Init(defaults ...gfx.ContextStateValue) {
+	d := make([]CSV, len(defaults))
+	for i, v := range defaults {
+		d[i] = v.(CSV)
 	}
-	return -1, CSV{}
+	c.table.Init(d...)
 }
 
-type Context struct {
-	current ContextState
+// SetCounters sets the counters of the context's state table, see
+// Table.Counters.
+func (c *Context) SetCounters(n *Counters) {
+	c.table.Counters = n
 }
 
+// NewState implements the gfx.ContextStateProvider interface.
 func (c *Context) NewState(values ...gfx.ContextStateValue) gfx.ContextState {
-	return ContextState(values)
+	st := &State{
+		values: make([]CSV, 0, len(values)),
+	}
+	for _, v := range values {
+		st.add(v.(CSV))
+	}
+	return st
 }
 
+// Load implements the gfx.ContextStateProvider interface.
 func (c *Context) Load(s gfx.ContextState) {
-	var st ContextState
-	if s != nil {
-		st = s.(ContextState)
+	switch st := s.(type) {
+	case *Pipeline:
+		c.table.ApplyPipeline(st)
+	case *State:
+		c.table.Apply(st)
+	case nil:
+		c.table.Apply(nil)
+	default:
+		panic("state: Load called with an invalid context state")
 	}
+	c.current = s
+}
+
+// Bake implements the gfx.ContextStateProvider interface.
+func (c *Context) Bake(s gfx.ContextState) gfx.PipelineState {
+	switch st := s.(type) {
+	case *Pipeline:
+		return st
+	case *State:
+		return NewPipeline(st)
+	case nil:
+		return NewPipeline(nil)
+	default:
+		panic("state: Bake called with an invalid context state")
+	}
+}
 
-	// For any state not explicitly mentioned in the current state, revert it
-	// to the default state.
-	for _, curI := range c.current {
-		cur := curI.(CSV)
-		if index, _ := st.Find(cur.Key); index != -1 {
-			continue
-		}
-
-		// Revert to the default state.
-		if cur.Value == cur.DefaultValue {
-			// Already using this value! Do nothing.
-			continue
-		}
-		cur.GLCall(cur.DefaultValue)
+// Current implements the gfx.ContextStateProvider interface.
+func (c *Context) Current() gfx.ContextState {
+	return c.current
+}
+
+// Describe implements the gfx.ContextStateProvider interface.
+func (c *Context) Describe(s gfx.ContextState) gfx.StateInfo {
+	switch st := s.(type) {
+	case *Pipeline:
+		return c.table.Describe(&st.State)
+	case *State:
+		return c.table.Describe(st)
+	case nil:
+		return c.table.Describe(nil)
+	default:
+		panic("state: Describe called with an invalid context state")
 	}
+}
 
-	// For each state explicitly mentioned in the destination state, apply it
-	// if needed.
-	for _, dstI := range st {
-		dst := dstI.(CSV)
-		found, cur := c.current.Find(dst.Key)
-		if found != -1 && cur.Value == dst.Value {
-			// Already using this value! Do nothing.
-			continue
-		}
-
-		// Did not find a matching previous value.
-		dst.GLCall(dst.Value)
+// PushState implements the gfx.ContextStateProvider interface.
+func (c *Context) PushState() {
+	c.stack = append(c.stack, c.current)
+}
+
+// PopState implements the gfx.ContextStateProvider interface.
+func (c *Context) PopState() {
+	var s gfx.ContextState
+	if n := len(c.stack); n > 0 {
+		s = c.stack[n-1]
+		c.stack[n-1] = nil
+		c.stack = c.stack[:n-1]
 	}
-	c.current = st
+	c.Load(s)
+}
+
+// Invalidate invalidates the context's state table, see Table.Invalidate.
+func (c *Context) Invalidate() {
+	c.table.Invalidate()
+}
+
+// Resync resynchronizes the context's state table, see Table.Resync.
+func (c *Context) Resync(query Query) {
+	c.table.Resync(query)
+}
+
+// Verify verifies the context's state table, see Table.Verify.
+func (c *Context) Verify(query Query) error {
+	return c.table.Verify(query)
 }
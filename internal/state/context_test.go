@@ -0,0 +1,413 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package state
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/slimsag/gfx"
+)
+
+// glCall records a single GLCall made by a state value.
+type glCall struct {
+	key   Key
+	value Value
+}
+
+// recorder records GLCalls made by state values that it creates, in place of
+// a real OpenGL context.
+type recorder struct {
+	calls []glCall
+}
+
+func (r *recorder) csv(key Key, value Value) CSV {
+	return CSV{
+		Key:   key,
+		Value: value,
+		GLCall: func(v *Value) {
+			r.calls = append(r.calls, glCall{key, *v})
+		},
+	}
+}
+
+// flush returns and clears the recorded calls.
+func (r *recorder) flush() []glCall {
+	c := r.calls
+	r.calls = nil
+	return c
+}
+
+// enableVertexAttribArray mirrors the drivers: all vertex attribute arrays
+// are disabled by default.
+func (r *recorder) enableVertexAttribArray(l int, enabled bool) CSV {
+	return r.csv(VertexAttribKey(l), Value{U: uint32(l), B: [4]bool{enabled}})
+}
+
+// feature mirrors the drivers: all features are disabled by default except
+// for dithering.
+func (r *recorder) feature(f gfx.Feature, enabled bool) CSV {
+	return r.csv(FeatureKey(f), Value{U: uint32(f), B: [4]bool{enabled}})
+}
+
+// newContext returns a new context whose state table has default values for
+// each feature and vertex attribute array.
+func (r *recorder) newContext() *Context {
+	var defaults []gfx.ContextStateValue
+	for f := gfx.Blend; f <= gfx.Dither; f++ {
+		defaults = append(defaults, r.feature(f, f == gfx.Dither))
+	}
+	for l := 0; l < MaxVertexAttribs; l++ {
+		defaults = append(defaults, r.enableVertexAttribArray(l, false))
+	}
+	ctx := &Context{}
+	ctx.Init(defaults...)
+	return ctx
+}
+
+type loadTest struct {
+	state []gfx.ContextStateValue
+	want  []glCall
+}
+
+func testLoad(t *testing.T, r *recorder, ctx *Context, tests []loadTest) {
+	for i, tst := range tests {
+		if tst.state == nil {
+			ctx.Load(nil)
+		} else {
+			ctx.Load(ctx.NewState(tst.state...))
+		}
+		got := r.flush()
+		if !reflect.DeepEqual(got, tst.want) {
+			t.Errorf("test %d: got GL calls %v, want %v", i, got, tst.want)
+		}
+	}
+}
+
+func TestContextVertexAttribArrays(t *testing.T) {
+	r := &recorder{}
+	attrib := func(l int, enabled bool) glCall {
+		return glCall{VertexAttribKey(l), Value{U: uint32(l), B: [4]bool{enabled}}}
+	}
+	testLoad(t, r, r.newContext(), []loadTest{
+		// Both attributes are enabled.
+		{
+			state: []gfx.ContextStateValue{r.enableVertexAttribArray(0, true), r.enableVertexAttribArray(1, true)},
+			want:  []glCall{attrib(0, true), attrib(1, true)},
+		},
+
+		// Attribute one drops out of the state, and is disabled.
+		{
+			state: []gfx.ContextStateValue{r.enableVertexAttribArray(0, true)},
+			want:  []glCall{attrib(1, false)},
+		},
+
+		// Attribute two is enabled, attribute zero is already enabled.
+		{
+			state: []gfx.ContextStateValue{r.enableVertexAttribArray(2, true), r.enableVertexAttribArray(0, true)},
+			want:  []glCall{attrib(2, true)},
+		},
+
+		// The default state disables all attributes.
+		{
+			state: nil,
+			want:  []glCall{attrib(0, false), attrib(2, false)},
+		},
+	})
+}
+
+func TestContextVertexAttribOverflow(t *testing.T) {
+	r := &recorder{}
+	attrib := func(l int, enabled bool) glCall {
+		return glCall{VertexAttribKey(l), Value{U: uint32(l), B: [4]bool{enabled}}}
+	}
+	const l = MaxVertexAttribs + 4
+	ctx := r.newContext()
+	testLoad(t, r, ctx, []loadTest{
+		// Arrays past the table are enabled like the others.
+		{
+			state: []gfx.ContextStateValue{r.enableVertexAttribArray(l, true), r.enableVertexAttribArray(0, true)},
+			want:  []glCall{attrib(0, true), attrib(l, true)},
+		},
+		{
+			state: []gfx.ContextStateValue{r.enableVertexAttribArray(l, true)},
+			want:  []glCall{attrib(0, false)},
+		},
+
+		// And disabled when they drop out of the state.
+		{
+			state: nil,
+			want:  []glCall{attrib(l, false)},
+		},
+	})
+
+	// Pipelines enable and disable them too.
+	on := ctx.Bake(ctx.NewState(r.enableVertexAttribArray(l, true)))
+	off := ctx.Bake(nil)
+	ctx.Load(off)
+	ctx.Load(on)
+	ctx.Load(off)
+	if got, want := r.flush(), []glCall{attrib(l, true), attrib(l, false)}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got GL calls %v, want %v", got, want)
+	}
+
+	// Their current value is unknown after Invalidate.
+	ctx.Invalidate()
+	r.flush()
+	ctx.Load(on)
+	ctx.Load(off)
+	ctx.Load(off)
+	if got, want := r.flush()[int(NumFeatures)+MaxVertexAttribs:], []glCall{attrib(l, true), attrib(l, false)}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got GL calls %v, want %v", got, want)
+	}
+}
+
+func TestContextFeatures(t *testing.T) {
+	r := &recorder{}
+	feature := func(f gfx.Feature, enabled bool) glCall {
+		return glCall{FeatureKey(f), Value{U: uint32(f), B: [4]bool{enabled}}}
+	}
+	testLoad(t, r, r.newContext(), []loadTest{
+		// Blending is enabled.
+		{
+			state: []gfx.ContextStateValue{r.feature(gfx.Blend, true)},
+			want:  []glCall{feature(gfx.Blend, true)},
+		},
+
+		// Blending is absent from the state, and is disabled.
+		{
+			state: []gfx.ContextStateValue{r.feature(gfx.DepthTest, true)},
+			want:  []glCall{feature(gfx.Blend, false), feature(gfx.DepthTest, true)},
+		},
+
+		// Dithering is disabled, depth testing reverts to the default.
+		{
+			state: []gfx.ContextStateValue{r.feature(gfx.Dither, false)},
+			want:  []glCall{feature(gfx.DepthTest, false), feature(gfx.Dither, false)},
+		},
+
+		// The default state enables dithering again.
+		{
+			state: nil,
+			want:  []glCall{feature(gfx.Dither, true)},
+		},
+
+		// The last value for a feature takes precedence, and disabled
+		// blending is already the default.
+		{
+			state: []gfx.ContextStateValue{r.feature(gfx.Blend, true), r.feature(gfx.Blend, false)},
+			want:  nil,
+		},
+	})
+}
+
+func TestContextLoadAllocs(t *testing.T) {
+	r := &recorder{}
+	ctx := r.newContext()
+	a := ctx.NewState(r.feature(gfx.Blend, true), r.enableVertexAttribArray(0, true))
+	b := ctx.NewState(r.feature(gfx.DepthTest, true), r.enableVertexAttribArray(1, true))
+	r.calls = make([]glCall, 0, 1024)
+	allocs := testing.AllocsPerRun(100, func() {
+		ctx.Load(a)
+		ctx.Load(b)
+		ctx.Load(nil)
+		r.calls = r.calls[:0]
+	})
+	if allocs != 0 {
+		t.Fatalf("Load allocated %v times, want zero", allocs)
+	}
+}
+
+// newBenchContext returns a new context modeled after the one in the gfx
+// package's BenchmarkContextState, whose GL calls do nothing.
+func newBenchContext() (ctx *Context, lighting, shadows, physics func(on bool) gfx.ContextStateValue) {
+	glCall := func(v *Value) {}
+	value := func(k Key) func(on bool) gfx.ContextStateValue {
+		return func(on bool) gfx.ContextStateValue {
+			return CSV{Key: k, Value: Value{B: [4]bool{on}}, GLCall: glCall}
+		}
+	}
+	lighting = value(FeatureKey(gfx.Blend))
+	shadows = value(FeatureKey(gfx.DepthTest))
+	physics = value(FeatureKey(gfx.CullFace))
+
+	ctx = &Context{}
+	ctx.Init(lighting(true), shadows(true), physics(true))
+	return
+}
+
+func BenchmarkContextState(b *testing.B) {
+	ctx, lighting, shadows, _ := newBenchContext()
+
+	// shadows: true (default).
+	// lighting: false (explicit).
+	// physics: true (default).
+	lightsOff := ctx.NewState(
+		lighting(false),
+	)
+
+	// shadows: false (explicit).
+	// lighting: explicit (false).
+	// physics: true (default).
+	lightsAndShadowsOff := ctx.NewState(
+		shadows(false),
+		lighting(false),
+	)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		switch i % 3 {
+		case 0:
+			ctx.Load(lightsOff)
+		case 1:
+			ctx.Load(lightsAndShadowsOff)
+		case 2:
+			// shadows: true (default).
+			// lighting: true (default).
+			// physics: true (default).
+			ctx.Load(nil)
+		}
+	}
+}
+
+// BenchmarkContextStateLarge switches between two states that each set every
+// key of the table.
+func BenchmarkContextStateLarge(b *testing.B) {
+	glCall := func(v *Value) {}
+	var (
+		defaults []gfx.ContextStateValue
+		x, y     []gfx.ContextStateValue
+	)
+	for k := Key(0); k < NumKeys; k++ {
+		defaults = append(defaults, CSV{Key: k, GLCall: glCall})
+		x = append(x, CSV{Key: k, Value: Value{U: 1}, GLCall: glCall})
+		y = append(y, CSV{Key: k, Value: Value{U: uint32(k % 2)}, GLCall: glCall})
+	}
+	ctx := &Context{}
+	ctx.Init(defaults...)
+	sx, sy := ctx.NewState(x...), ctx.NewState(y...)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if i%2 == 0 {
+			ctx.Load(sx)
+		} else {
+			ctx.Load(sy)
+		}
+	}
+}
+
+func BenchmarkContextNewState(b *testing.B) {
+	ctx, lighting, shadows, physics := newBenchContext()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ctx.NewState(physics(false), shadows(false), lighting(false))
+	}
+}
+
+func TestContextPushPopState(t *testing.T) {
+	r := &recorder{}
+	ctx := r.newContext()
+	a := ctx.NewState(r.feature(gfx.Blend, true))
+	b := ctx.Bake(ctx.NewState(r.feature(gfx.DepthTest, true)))
+
+	ctx.Load(a)
+	ctx.PushState()
+	ctx.Load(b)
+	ctx.PushState()
+	ctx.Load(nil)
+	r.flush()
+
+	ctx.PopState()
+	if ctx.Current() != b {
+		t.Fatalf("Current() = %v, want %v", ctx.Current(), b)
+	}
+	ctx.PopState()
+	if ctx.Current() != a {
+		t.Fatalf("Current() = %v, want %v", ctx.Current(), a)
+	}
+	want := []glCall{
+		{FeatureKey(gfx.DepthTest), Value{U: uint32(gfx.DepthTest), B: [4]bool{true}}},
+		{FeatureKey(gfx.DepthTest), Value{U: uint32(gfx.DepthTest)}},
+		{FeatureKey(gfx.Blend), Value{U: uint32(gfx.Blend), B: [4]bool{true}}},
+	}
+	if got := r.flush(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got GL calls %v, want %v", got, want)
+	}
+
+	// Popping an empty stack loads the default state.
+	ctx.PopState()
+	if ctx.Current() != nil {
+		t.Fatalf("Current() = %v, want nil", ctx.Current())
+	}
+}
+
+func TestContextInvalidate(t *testing.T) {
+	r := &recorder{}
+	ctx := r.newContext()
+	blend := ctx.NewState(r.feature(gfx.Blend, true))
+	ctx.Load(blend)
+	r.flush()
+
+	// Every key is applied again, even though the state is unchanged.
+	ctx.Invalidate()
+	ctx.Load(blend)
+	got := r.flush()
+	if want := int(NumFeatures) + MaxVertexAttribs; len(got) != want {
+		t.Fatalf("got %d GL calls, want %d", len(got), want)
+	}
+	ctx.Load(blend)
+	if got := r.flush(); got != nil {
+		t.Errorf("got GL calls %v, want none", got)
+	}
+}
+
+// fakeQuery returns a Query reporting the given actual values. Features not
+// in the map hold their current value, and vertex attribute arrays cannot be
+// queried.
+func fakeQuery(actual map[gfx.Feature]bool) Query {
+	return func(k Key, v *Value) bool {
+		if k >= VertexAttribArray {
+			return false
+		}
+		if b, ok := actual[gfx.Feature(v.U)]; ok {
+			v.B[0] = b
+		}
+		return true
+	}
+}
+
+func TestContextResync(t *testing.T) {
+	r := &recorder{}
+	ctx := r.newContext()
+	feature := func(f gfx.Feature, enabled bool) glCall {
+		return glCall{FeatureKey(f), Value{U: uint32(f), B: [4]bool{enabled}}}
+	}
+
+	// Foreign code enabled depth testing and disabled dithering.
+	query := fakeQuery(map[gfx.Feature]bool{gfx.DepthTest: true, gfx.Dither: false})
+	if err := ctx.Verify(query); err == nil {
+		t.Fatal("Verify: expected error before Resync")
+	}
+	ctx.Resync(query)
+	if err := ctx.Verify(query); err != nil {
+		t.Fatalf("Verify: unexpected error after Resync: %v", err)
+	}
+
+	// Only the keys that differ from the actual state, or that could not be
+	// queried, are applied.
+	ctx.Load(ctx.NewState(r.feature(gfx.DepthTest, true), r.enableVertexAttribArray(0, true)))
+	got := r.flush()
+	want := []glCall{feature(gfx.Dither, true)}
+	for l := 1; l < MaxVertexAttribs; l++ {
+		want = append(want, glCall{VertexAttribKey(l), Value{U: uint32(l)}})
+	}
+	want = append(want, glCall{VertexAttribKey(0), Value{U: 0, B: [4]bool{true}}})
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got GL calls %v, want %v", got, want)
+	}
+}
@@ -1,70 +1,77 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
 package state
 
 import "github.com/slimsag/gfx"
 
-type FramebufferState []gfx.FramebufferStateValue
+// Framebuffer implements the state management portion of the
+// gfx.FramebufferStateProvider interface.
+//
+// Framebuffer state is global to the OpenGL context, so a framebuffer only
+// stores the state it has loaded. Drivers apply it to a Table shared by all
+// framebuffers of the context when the framebuffer is used.
+type Framebuffer struct {
+	// Loaded is the state loaded by the framebuffer, or nil for the default
+	// state.
+	Loaded *State
 
-func (c FramebufferState) Find(k interface{}) (index int, pair CSV) {
-	var i interface{}
-	for index, i = range c {
-		pair = i.(CSV)
-		if pair.Key != k {
-			// Non-equal keys.
-			continue
-		}
-		return index, pair
-	}
-	return -1, CSV{}
-}
+	// Table is the framebuffer state table shared by all framebuffers of the
+	// context, used to describe states.
+	Table *Table
 
-type Framebuffer struct {
-	current FramebufferState
-	Loaded  gfx.FramebufferState
+	// stack is the stack of states pushed by PushState.
+	stack []*State
 }
 
+// NewState implements the gfx.FramebufferStateProvider interface.
 func (f *Framebuffer) NewState(values ...gfx.FramebufferStateValue) gfx.FramebufferState {
-	return FramebufferState(values)
+	st := &State{
+		values: make([]CSV, 0, len(values)),
+	}
+	for _, v := range values {
+		st.add(v.(CSV))
+	}
+	return st
 }
 
+// Load implements the gfx.FramebufferStateProvider interface.
 func (f *Framebuffer) Load(s gfx.FramebufferState) {
-	f.Loaded = s
-}
-
-func (f *Framebuffer) GLCall(ld gfx.FramebufferState) {
-	s := ld
-	var st FramebufferState
+	f.Loaded = nil
 	if s != nil {
-		st = s.(FramebufferState)
+		f.Loaded = s.(*State)
 	}
+}
 
-	// For any state not explicitly mentioned in the current state, revert it
-	// to the default state.
-	for _, curI := range f.current {
-		cur := curI.(CSV)
-		if index, _ := st.Find(cur.Key); index != -1 {
-			continue
-		}
+// Current implements the gfx.FramebufferStateProvider interface.
+func (f *Framebuffer) Current() gfx.FramebufferState {
+	if f.Loaded == nil {
+		return nil
+	}
+	return f.Loaded
+}
 
-		// Revert to the default state.
-		if cur.Value == cur.DefaultValue {
-			// Already using this value! Do nothing.
-			continue
-		}
-		cur.GLCall(cur.DefaultValue)
+// Describe implements the gfx.FramebufferStateProvider interface.
+func (f *Framebuffer) Describe(s gfx.FramebufferState) gfx.StateInfo {
+	var st *State
+	if s != nil {
+		st = s.(*State)
 	}
+	return f.Table.Describe(st)
+}
 
-	// For each state explicitly mentioned in the destination state, apply it
-	// if needed.
-	for _, dstI := range st {
-		dst := dstI.(CSV)
-		found, cur := f.current.Find(dst.Key)
-		if found != -1 && cur.Value == dst.Value {
-			// Already using this value! Do nothing.
-			continue
-		}
+// PushState implements the gfx.FramebufferStateProvider interface.
+func (f *Framebuffer) PushState() {
+	f.stack = append(f.stack, f.Loaded)
+}
 
-		// Did not find a matching previous value.
-		dst.GLCall(dst.Value)
+// PopState implements the gfx.FramebufferStateProvider interface.
+func (f *Framebuffer) PopState() {
+	f.Loaded = nil
+	if n := len(f.stack); n > 0 {
+		f.Loaded = f.stack[n-1]
+		f.stack[n-1] = nil
+		f.stack = f.stack[:n-1]
 	}
-	f.current = st
 }
@@ -0,0 +1,80 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package state
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFramebufferSharedTable(t *testing.T) {
+	r := &recorder{}
+	clearColor := func(v float32) CSV {
+		return r.csv(ClearColor, Value{F: [4]float32{v, v, v, v}})
+	}
+
+	var table Table
+	table.Init(clearColor(0))
+
+	var a, b Framebuffer
+	a.Load(a.NewState(clearColor(1)))
+	b.Load(b.NewState(clearColor(0.5)))
+
+	tests := []struct {
+		fb   *Framebuffer
+		want []glCall
+	}{
+		{&a, []glCall{{ClearColor, Value{F: [4]float32{1, 1, 1, 1}}}}},
+		{&a, nil},
+		{&b, []glCall{{ClearColor, Value{F: [4]float32{0.5, 0.5, 0.5, 0.5}}}}},
+		{&Framebuffer{}, []glCall{{ClearColor, Value{}}}},
+		{&a, []glCall{{ClearColor, Value{F: [4]float32{1, 1, 1, 1}}}}},
+	}
+	for i, tst := range tests {
+		table.Apply(tst.fb.Loaded)
+		if got := r.flush(); !reflect.DeepEqual(got, tst.want) {
+			t.Errorf("test %d: got GL calls %v, want %v", i, got, tst.want)
+		}
+	}
+}
+
+func TestFramebufferPopState(t *testing.T) {
+	var f Framebuffer
+	s := f.NewState()
+	f.Load(s)
+	f.PushState()
+	f.Load(nil)
+	f.PopState()
+	if f.Current() != s {
+		t.Errorf("Current() = %v, want the pushed state", f.Current())
+	}
+
+	// The popped slot no longer references the state.
+	if stale := f.stack[:1][0]; stale != nil {
+		t.Errorf("popped slot holds %v", stale)
+	}
+}
+
+func BenchmarkFramebufferState(b *testing.B) {
+	glCall := func(v *Value) {}
+	var table Table
+	table.Init(
+		CSV{Key: ClearColor, GLCall: glCall},
+		CSV{Key: ClearDepth, Value: Value{D: 1}, GLCall: glCall},
+	)
+	var f Framebuffer
+	red := f.NewState(CSV{Key: ClearColor, Value: Value{F: [4]float32{1, 0, 0, 1}}, GLCall: glCall})
+	far := f.NewState(CSV{Key: ClearDepth, Value: Value{D: 0}, GLCall: glCall})
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if i%2 == 0 {
+			f.Load(red)
+		} else {
+			f.Load(far)
+		}
+		table.Apply(f.Loaded)
+	}
+}
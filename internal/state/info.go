@@ -0,0 +1,117 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package state
+
+import (
+	"fmt"
+
+	"github.com/slimsag/gfx"
+)
+
+var keyNames = [...]string{
+	BlendColor:    "BlendColor",
+	BlendEquation: "BlendEquation",
+	DepthMask:     "DepthMask",
+	UseProgram:    "UseProgram",
+	Viewport:      "Viewport",
+	Scissor:       "Scissor",
+	LineWidth:     "LineWidth",
+	ColorMask:     "ColorMask",
+	CullFace:      "CullFaceMode",
+	FrontFace:     "FrontFace",
+	ClearColor:    "ClearColor",
+	ClearDepth:    "ClearDepth",
+	ClearStencil:  "ClearStencil",
+}
+
+// String returns the name of the key, e.g. "Viewport", "Blend" or
+// "VertexAttribArray[2]". Like in OpenGL, the CullFace key is named
+// "CullFaceMode" to distinguish it from the CullFace feature.
+func (k Key) String() string {
+	switch {
+	case k < Feature:
+		return keyNames[k]
+	case k < VertexAttribArray:
+		return (gfx.Blend + gfx.Feature(k-Feature)).String()
+	default:
+		return fmt.Sprintf("VertexAttribArray[%d]", k-VertexAttribArray)
+	}
+}
+
+// Interface returns the driver-independent representation of a value of the
+// given key, as described by the gfx.StateValueInfo type.
+func (v *Value) Interface(k Key) interface{} {
+	switch k {
+	case BlendColor, ClearColor:
+		return v.F
+	case BlendEquation:
+		return gfx.BlendEquation(v.E)
+	case UseProgram:
+		p, _ := v.P.(gfx.Program)
+		return p
+	case Viewport, Scissor:
+		return [4]int{int(v.I[0]), int(v.I[1]), int(v.I[2]), int(v.I[3])}
+	case LineWidth:
+		return v.F[0]
+	case ColorMask:
+		return v.B
+	case CullFace:
+		return gfx.Facet(v.E)
+	case FrontFace:
+		return gfx.Orientation(v.E)
+	case ClearDepth:
+		return v.D
+	case ClearStencil:
+		return int(v.I[0])
+	default:
+		// DepthMask, features, and vertex attribute arrays.
+		return v.B[0]
+	}
+}
+
+// Describe returns a description of each key of the table in the given state,
+// or in the default state if s == nil. Keys without a default value are
+// omitted.
+func (t *Table) Describe(s *State) gfx.StateInfo {
+	var info gfx.StateInfo
+	for k := Key(0); k < NumKeys; k++ {
+		bit := uint64(1) << k
+		if t.defined&bit == 0 {
+			continue
+		}
+		d := &t.defaults[k].Value
+		v := d
+		set := s != nil && s.mask&bit != 0
+		if set {
+			for i := range s.values {
+				if s.values[i].Key == k {
+					v = &s.values[i].Value
+					break
+				}
+			}
+		}
+		info = append(info, gfx.StateValueInfo{
+			Name:    k.String(),
+			Value:   v.Interface(k),
+			Default: d.Interface(k),
+			Set:     set,
+		})
+	}
+
+	// The arrays past the table are only described if the state enables them.
+	if s != nil && s.overflows() {
+		for i := range s.values {
+			if v := &s.values[i]; v.Key >= NumKeys {
+				info = append(info, gfx.StateValueInfo{
+					Name:    v.Key.String(),
+					Value:   v.Value.Interface(v.Key),
+					Default: false,
+					Set:     true,
+				})
+			}
+		}
+	}
+	return info
+}
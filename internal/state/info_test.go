@@ -0,0 +1,68 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package state
+
+import (
+	"testing"
+
+	"github.com/slimsag/gfx"
+)
+
+func TestKeyString(t *testing.T) {
+	tests := map[Key]string{
+		Viewport:               "Viewport",
+		ClearStencil:           "ClearStencil",
+		FeatureKey(gfx.Blend):  "Blend",
+		FeatureKey(gfx.Dither): "Dither",
+		VertexAttribKey(2):     "VertexAttribArray[2]",
+		VertexAttribKey(40):    "VertexAttribArray[40]",
+	}
+	for k, want := range tests {
+		if got := k.String(); got != want {
+			t.Errorf("Key(%d).String() = %q, want %q", k, got, want)
+		}
+	}
+}
+
+func TestContextDescribe(t *testing.T) {
+	r := &recorder{}
+	ctx := &Context{}
+	ctx.Init(
+		r.feature(gfx.Blend, false),
+		r.csv(Viewport, Value{I: [4]int32{0, 0, 640, 480}}),
+		r.csv(CullFace, Value{E: int(gfx.Back)}),
+	)
+	if ctx.Current() != nil {
+		t.Fatalf("Current() = %v, want nil", ctx.Current())
+	}
+
+	st := ctx.NewState(
+		r.feature(gfx.Blend, true),
+		r.csv(CullFace, Value{E: int(gfx.Front)}),
+	)
+	ctx.Load(st)
+	if ctx.Current() != st {
+		t.Fatalf("Current() = %v, want %v", ctx.Current(), st)
+	}
+
+	info := ctx.Describe(ctx.Current())
+	if got, want := info.String(), "Viewport=0,0,640,480, CullFaceMode=Front, Blend=on"; got != want {
+		t.Errorf("got description %q, want %q", got, want)
+	}
+	for _, v := range info {
+		if isDefault := v.Name == "Viewport"; v.IsDefault() != isDefault {
+			t.Errorf("%s: IsDefault() = %v, want %v", v.Name, v.IsDefault(), isDefault)
+		}
+	}
+
+	want := "Viewport=0,0,640,480, CullFaceMode=Back, Blend=off"
+	if got := ctx.Describe(nil).String(); got != want {
+		t.Errorf("got default description %q, want %q", got, want)
+	}
+	ctx.Load(nil)
+	if ctx.Current() != nil {
+		t.Fatalf("Current() = %v, want nil", ctx.Current())
+	}
+}
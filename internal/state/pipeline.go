@@ -0,0 +1,133 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package state
+
+import (
+	"math"
+	"math/bits"
+)
+
+// maxTransitions is the maximum number of transitions cached by a single
+// pipeline, such that a pipeline switched to many others cannot grow without
+// bound.
+const maxTransitions = 64
+
+// Pipeline is an immutable, precompiled State. It implements the
+// gfx.PipelineState interface.
+type Pipeline struct {
+	State
+	hash uint64
+
+	// transitions caches the GL calls needed to switch from this pipeline to
+	// another one, in the order they must be made.
+	transitions map[*Pipeline][]*CSV
+}
+
+// Hash implements the gfx.PipelineState interface.
+func (p *Pipeline) Hash() uint64 {
+	return p.hash
+}
+
+// NewPipeline returns a new pipeline precompiled from the given state. If
+// s == nil a pipeline of the default state is returned.
+func NewPipeline(s *State) *Pipeline {
+	p := &Pipeline{}
+	if s != nil {
+		p.State = *s
+	}
+	p.hash = p.State.hash()
+	return p
+}
+
+const (
+	fnvOffset64 = 14695981039346656037
+	fnvPrime64  = 1099511628211
+)
+
+// hash returns the 64-bit FNV-1a hash of the state's keys and values. The P
+// field of values is not hashed, as it has no stable numeric representation.
+func (s *State) hash() uint64 {
+	h := uint64(fnvOffset64)
+	word := func(x uint64) {
+		for i := 0; i < 8; i++ {
+			h ^= x & 0xff
+			h *= fnvPrime64
+			x >>= 8
+		}
+	}
+	for i := range s.values {
+		v := &s.values[i]
+		word(uint64(v.Key))
+		for j := 0; j < 4; j++ {
+			word(uint64(math.Float32bits(v.Value.F[j])))
+			word(uint64(uint32(v.Value.I[j])))
+			if v.Value.B[j] {
+				word(1)
+			} else {
+				word(0)
+			}
+		}
+		word(uint64(v.Value.U))
+		word(math.Float64bits(v.Value.D))
+	}
+	return h
+}
+
+// transition returns the GL calls needed to switch from pipeline p to the
+// pipeline to, assuming that the table currently holds p's values. The calls
+// are made in the same order as Apply would make them.
+func (t *Table) transition(p, to *Pipeline) []*CSV {
+	var calls []*CSV
+	for revert := p.mask &^ to.mask; revert != 0; revert &= revert - 1 {
+		d := &t.defaults[bits.TrailingZeros64(revert)]
+		if t.current[d.Key] != d.Value {
+			calls = append(calls, d)
+		}
+	}
+	for i := range to.values {
+		v := &to.values[i]
+		if t.current[v.Key] != v.Value {
+			calls = append(calls, v)
+		}
+	}
+	return calls
+}
+
+// ApplyPipeline applies the given pipeline like Apply does, except that it
+// does nothing if the pipeline is already applied, and that the GL calls made
+// to switch between two pipelines are cached.
+func (t *Table) ApplyPipeline(p *Pipeline) {
+	from := t.pipeline
+	if p == from {
+		t.count(0, len(p.values))
+		return
+	}
+	if from == nil || from.overflows() || p.overflows() {
+		// Transitions involving keys past the table are not cached.
+		t.Apply(&p.State)
+		t.pipeline = p
+		return
+	}
+
+	calls, ok := from.transitions[p]
+	if !ok {
+		calls = t.transition(from, p)
+		if from.transitions == nil {
+			from.transitions = make(map[*Pipeline][]*CSV)
+		}
+		if len(from.transitions) < maxTransitions {
+			from.transitions[p] = calls
+		}
+	}
+	for _, c := range calls {
+		c.GLCall(&c.Value)
+		t.current[c.Key] = c.Value
+	}
+
+	// The transition considered the values of both pipelines.
+	t.count(len(calls), bits.OnesCount64(from.mask|p.mask)-len(calls))
+	t.set = p.mask
+	t.pipeline = p
+}
@@ -0,0 +1,134 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package state
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/slimsag/gfx"
+)
+
+func TestPipelineHash(t *testing.T) {
+	r := &recorder{}
+	ctx := r.newContext()
+	bake := func(values ...gfx.ContextStateValue) uint64 {
+		return ctx.Bake(ctx.NewState(values...)).Hash()
+	}
+
+	a := bake(r.feature(gfx.Blend, true), r.enableVertexAttribArray(0, true))
+	b := bake(r.enableVertexAttribArray(0, true), r.feature(gfx.Blend, true))
+	if a != b {
+		t.Errorf("equal pipelines have different hashes %x and %x", a, b)
+	}
+	if c := bake(r.feature(gfx.Blend, true)); a == c {
+		t.Errorf("differing pipelines have equal hash %x", a)
+	}
+	if d, e := bake(), ctx.Bake(nil).Hash(); d != e {
+		t.Errorf("default pipelines have different hashes %x and %x", d, e)
+	}
+}
+
+func TestPipelineTransitions(t *testing.T) {
+	r := &recorder{}
+	states := [][]gfx.ContextStateValue{
+		{r.feature(gfx.Blend, true), r.enableVertexAttribArray(0, true)},
+		{r.feature(gfx.DepthTest, true), r.enableVertexAttribArray(1, true)},
+		{r.feature(gfx.Dither, false), r.enableVertexAttribArray(0, true)},
+		nil,
+	}
+
+	// Load each state as a plain state and as a pipeline, in an order that
+	// switches between every pair of states twice (once with an empty cache,
+	// once with a cached transition), and compare the GL calls made.
+	plain, baked := r.newContext(), r.newContext()
+	var plainCounters, bakedCounters Counters
+	plain.SetCounters(&plainCounters)
+	baked.SetCounters(&bakedCounters)
+	var pipelines []gfx.PipelineState
+	for _, s := range states {
+		pipelines = append(pipelines, baked.Bake(baked.NewState(s...)))
+	}
+	var order []int
+	for n := 0; n < 2; n++ {
+		for i := range states {
+			for j := range states {
+				order = append(order, i, j)
+			}
+		}
+	}
+	for n, i := range order {
+		plain.Load(plain.NewState(states[i]...))
+		want := r.flush()
+		baked.Load(pipelines[i])
+		got := r.flush()
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("load %d (state %d): got GL calls %v, want %v", n, i, got, want)
+		}
+	}
+	if plainCounters != bakedCounters {
+		t.Errorf("pipeline counters %+v, want %+v", bakedCounters, plainCounters)
+	}
+	if plainCounters.Elided == 0 {
+		t.Errorf("no values elided: %+v", plainCounters)
+	}
+
+	// Mixing pipelines with plain states must not use stale transitions.
+	baked.Load(pipelines[0])
+	baked.Load(baked.NewState(states[1]...))
+	r.flush()
+	baked.Load(pipelines[0])
+	if got := r.flush(); len(got) != 4 {
+		t.Errorf("got GL calls %v, want 4 calls", got)
+	}
+}
+
+func TestPipelineLoadAllocs(t *testing.T) {
+	r := &recorder{}
+	ctx := r.newContext()
+	a := ctx.Bake(ctx.NewState(r.feature(gfx.Blend, true), r.enableVertexAttribArray(0, true)))
+	b := ctx.Bake(ctx.NewState(r.feature(gfx.DepthTest, true), r.enableVertexAttribArray(1, true)))
+
+	// Populate the transition cache.
+	ctx.Load(a)
+	ctx.Load(b)
+	ctx.Load(a)
+
+	r.calls = make([]glCall, 0, 1024)
+	allocs := testing.AllocsPerRun(100, func() {
+		ctx.Load(b)
+		ctx.Load(b)
+		ctx.Load(a)
+		r.calls = r.calls[:0]
+	})
+	if allocs != 0 {
+		t.Fatalf("Load allocated %v times, want zero", allocs)
+	}
+}
+
+func BenchmarkPipelineState(b *testing.B) {
+	ctx, lighting, shadows, _ := newBenchContext()
+	lightsOff := ctx.Bake(ctx.NewState(
+		lighting(false),
+	))
+	lightsAndShadowsOff := ctx.Bake(ctx.NewState(
+		shadows(false),
+		lighting(false),
+	))
+	defaults := ctx.Bake(nil)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		switch i % 3 {
+		case 0:
+			ctx.Load(lightsOff)
+		case 1:
+			ctx.Load(lightsAndShadowsOff)
+		case 2:
+			ctx.Load(defaults)
+		}
+	}
+}
@@ -0,0 +1,457 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package state implements context and framebuffer state tracking shared by
+// the graphics drivers.
+//
+// Each piece of state is stored in a fixed slot of a table, indexed by a Key.
+// Loading a state compares values slot-by-slot and only invokes the GL calls
+// needed to reach the new state, without allocating.
+package state
+
+import (
+	"fmt"
+	"math/bits"
+	"sort"
+
+	"github.com/slimsag/gfx"
+)
+
+// Key identifies a single slot of a state table. Keys of NumKeys and above
+// identify the vertex attribute arrays past the table, see VertexAttribKey.
+type Key uint16
+
+const (
+	// Context state keys.
+	BlendColor Key = iota
+	BlendEquation
+	DepthMask
+	UseProgram
+	Viewport
+	Scissor
+	LineWidth
+	ColorMask
+	CullFace
+	FrontFace
+
+	// Framebuffer state keys.
+	ClearColor
+	ClearDepth
+	ClearStencil
+
+	// Feature is the first of NumFeatures keys, one for each gfx.Feature (see
+	// the FeatureKey function).
+	Feature
+
+	// VertexAttribArray is the first of the keys of the vertex attribute
+	// arrays, one for each location (see the VertexAttribKey function).
+	VertexAttribArray = Feature + NumFeatures
+
+	// NumKeys is the total number of keys, i.e. the size of a state table.
+	NumKeys = VertexAttribArray + MaxVertexAttribs
+)
+
+const (
+	// NumFeatures is the number of gfx.Feature values.
+	NumFeatures = Key(gfx.Dither-gfx.Blend) + 1
+
+	// MaxVertexAttribs is the number of vertex attribute locations whose
+	// arrays have a slot in state tables. It is the minimum number of
+	// locations supported by OpenGL 2.0 implementations; the arrays at higher
+	// locations are tracked separately, and more slowly.
+	MaxVertexAttribs = 16
+)
+
+// A state table's keys must fit into a single uint64 bitmask.
+var _ [64 - NumKeys]struct{}
+
+// FeatureKey returns the key for the given feature.
+func FeatureKey(f gfx.Feature) Key {
+	return Feature + Key(f-gfx.Blend)
+}
+
+// VertexAttribKey returns the key for the vertex attribute array at the given
+// location, which must not be negative. Locations of MaxVertexAttribs and
+// above have no slot in state tables, and their keys are NumKeys and above.
+func VertexAttribKey(l int) Key {
+	return VertexAttribArray + Key(l)
+}
+
+// bit returns the bit of the key in state masks, or zero for keys past the
+// table, which are not part of masks.
+func (k Key) bit() uint64 {
+	if k >= NumKeys {
+		return 0
+	}
+	return 1 << k
+}
+
+// Value is the typed value of a single state slot. Each key makes use of only
+// the fields it needs, leaving the rest zero, such that values can be
+// compared using ==.
+type Value struct {
+	F [4]float32  // e.g. BlendColor, LineWidth (F[0]).
+	I [4]int32    // e.g. Viewport, Scissor, ClearStencil (I[0]).
+	B [4]bool     // e.g. ColorMask, DepthMask, features (B[0]).
+	U uint32      // An OpenGL enumeration or location, e.g. BlendEquation.
+	E int         // The gfx enumeration that U was derived from, if any.
+	D float64     // e.g. ClearDepth.
+	P interface{} // A comparable object, e.g. the gfx.Program for UseProgram.
+}
+
+// CSV is a single context or framebuffer state value, as returned by the
+// state value methods of the drivers.
+type CSV struct {
+	Key   Key
+	Value Value
+
+	// GLCall applies the given value of this key to the OpenGL context.
+	GLCall func(v *Value)
+}
+
+// State is a set of state values, sorted by key, with at most one value for
+// each key. States are immutable once created.
+type State struct {
+	mask   uint64
+	values []CSV
+}
+
+// add adds the value to the state, replacing any existing value for the same
+// key.
+func (s *State) add(v CSV) {
+	bit := v.Key.bit()
+	if s.mask&bit != 0 || bit == 0 {
+		for i := range s.values {
+			if s.values[i].Key == v.Key {
+				s.values[i] = v
+				return
+			}
+		}
+	}
+	s.mask |= bit
+
+	// Insert the value in sorted order, states are small so a linear search
+	// is fine here.
+	i := len(s.values)
+	s.values = append(s.values, v)
+	for i > 0 && s.values[i-1].Key > v.Key {
+		s.values[i] = s.values[i-1]
+		i--
+	}
+	s.values[i] = v
+}
+
+// overflows tells if the state holds values of keys past the table. Values
+// are sorted by key, so such values come last.
+func (s *State) overflows() bool {
+	n := len(s.values)
+	return n > 0 && s.values[n-1].Key >= NumKeys
+}
+
+// Table is a table of the current state values of an OpenGL context, with one
+// slot per key.
+type Table struct {
+	// defaults are the default values of each key, which also hold the GL
+	// calls used to revert to them.
+	defaults [NumKeys]CSV
+
+	// current is the current value of each key.
+	current [NumKeys]Value
+
+	// set is a bitmask of the keys set by the last applied state. All other
+	// keys hold their default value.
+	set uint64
+
+	// defined is a bitmask of the keys that have a default value.
+	defined uint64
+
+	// invalid is a bitmask of the keys whose current value is unknown, e.g.
+	// because foreign code may have changed it. They are always applied by
+	// the next Apply.
+	invalid uint64
+
+	// pipeline is the last applied pipeline, or nil if the last applied state
+	// was not a pipeline.
+	pipeline *Pipeline
+
+	// overflow holds the keys past the table which were ever applied, sorted
+	// by key, see overflowSlot.
+	overflow []overflowSlot
+
+	// Counters, if not nil, counts the values applied and elided by Apply and
+	// ApplyPipeline.
+	Counters *Counters
+}
+
+// Counters counts the work done, and avoided, by the caches of a driver. A
+// driver shares a single one between its state tables.
+type Counters struct {
+	// Applied is the number of state values applied using GL calls.
+	Applied int
+
+	// Elided is the number of state values that were not applied, because
+	// they were already current.
+	Elided int
+
+	// BindsElided is the number of GL bind calls that were not made, because
+	// the object was already bound.
+	BindsElided int
+}
+
+// overflowSlot is the slot of a key past the table, i.e. of a vertex attribute
+// array at a location of MaxVertexAttribs and above. Its default value is the
+// disabled array.
+type overflowSlot struct {
+	// CSV holds the current value, and the GL call used to change it.
+	CSV
+
+	// set is whether the key was set by the last applied state, and invalid
+	// whether its current value is unknown.
+	set, invalid bool
+}
+
+// defaultValue returns the default value of the key of the slot.
+func (o *overflowSlot) defaultValue() Value {
+	return Value{U: o.Value.U}
+}
+
+// slot returns the slot of the given key past the table, adding it if needed.
+// A new slot holds the default value, using the GL call of v.
+func (t *Table) slot(v *CSV) *overflowSlot {
+	i := sort.Search(len(t.overflow), func(i int) bool {
+		return t.overflow[i].Key >= v.Key
+	})
+	if i == len(t.overflow) || t.overflow[i].Key != v.Key {
+		t.overflow = append(t.overflow, overflowSlot{})
+		copy(t.overflow[i+1:], t.overflow[i:])
+		t.overflow[i] = overflowSlot{CSV: *v}
+		t.overflow[i].Value = t.overflow[i].defaultValue()
+	}
+	return &t.overflow[i]
+}
+
+// Init initializes the table with the given default values, which are
+// assumed to be the current values. A default value must be given for every
+// key that will be used with the table.
+func (t *Table) Init(defaults ...CSV) {
+	for _, d := range defaults {
+		t.defaults[d.Key] = d
+		t.current[d.Key] = d.Value
+		t.defined |= 1 << d.Key
+	}
+	t.set = 0
+	t.invalid = 0
+	t.pipeline = nil
+	t.overflow = nil
+}
+
+// Apply applies the given state, invoking GL calls only for the values that
+// differ from the current ones. Any values not present in the state are
+// reverted to their defaults. If s == nil the default state is applied.
+func (t *Table) Apply(s *State) {
+	var (
+		mask   uint64
+		values []CSV
+	)
+	if s != nil {
+		mask, values = s.mask, s.values
+	}
+
+	// For any state not explicitly mentioned in the new state, revert it to
+	// the default state.
+	invalid := t.invalid
+	for revert := (t.set | invalid) &^ mask; revert != 0; revert &= revert - 1 {
+		k := Key(bits.TrailingZeros64(revert))
+		d := &t.defaults[k]
+		if invalid&(1<<k) == 0 && t.current[k] == d.Value {
+			// Already using this value! Do nothing.
+			t.count(0, 1)
+			continue
+		}
+		d.GLCall(&d.Value)
+		t.current[k] = d.Value
+		t.count(1, 0)
+	}
+	t.revertOverflow(values)
+
+	// For each state explicitly mentioned in the new state, apply it if
+	// needed.
+	for i := range values {
+		v := &values[i]
+		if v.Key >= NumKeys {
+			t.applyOverflow(v)
+			continue
+		}
+		if invalid&(1<<v.Key) == 0 && t.current[v.Key] == v.Value {
+			// Already using this value! Do nothing.
+			t.count(0, 1)
+			continue
+		}
+		v.GLCall(&v.Value)
+		t.current[v.Key] = v.Value
+		t.count(1, 0)
+	}
+	t.set = mask
+	t.invalid = 0
+	t.pipeline = nil
+}
+
+// revertOverflow reverts the keys past the table which are not given a value
+// by the sorted values of a state to their default value, like Apply does.
+func (t *Table) revertOverflow(values []CSV) {
+	j := 0
+	for i := range t.overflow {
+		o := &t.overflow[i]
+		for j < len(values) && values[j].Key < o.Key {
+			j++
+		}
+		if j < len(values) && values[j].Key == o.Key {
+			continue
+		}
+		d := o.defaultValue()
+		switch {
+		case !o.invalid && o.Value == d:
+			if o.set {
+				t.count(0, 1)
+			}
+		default:
+			o.Value = d
+			o.GLCall(&o.Value)
+			t.count(1, 0)
+		}
+		o.set, o.invalid = false, false
+	}
+}
+
+// applyOverflow applies the value of a key past the table, if needed.
+func (t *Table) applyOverflow(v *CSV) {
+	o := t.slot(v)
+	if !o.invalid && o.Value == v.Value {
+		t.count(0, 1)
+	} else {
+		v.GLCall(&v.Value)
+		o.CSV = *v
+		t.count(1, 0)
+	}
+	o.set, o.invalid = true, false
+}
+
+// count adds to the counters of the table, if any.
+func (t *Table) count(applied, elided int) {
+	if t.Counters != nil {
+		t.Counters.Applied += applied
+		t.Counters.Elided += elided
+	}
+}
+
+// Invalidate marks the current value of every key as unknown, such that the
+// next Apply invokes the GL calls for every key.
+func (t *Table) Invalidate() {
+	t.invalid = t.defined
+	t.pipeline = nil
+	for i := range t.overflow {
+		t.overflow[i].invalid = true
+	}
+}
+
+// Query queries the actual value of a key from OpenGL. v holds the value the
+// table believes is current, and query overwrites it with the actual value.
+// It returns false if the actual value cannot be queried (or cannot be
+// represented, e.g. a program unknown to the driver).
+type Query func(k Key, v *Value) bool
+
+// Resync updates the current value of every key to its actual value, as
+// given by the query function. Keys that cannot be queried are invalidated,
+// see Invalidate.
+func (t *Table) Resync(query Query) {
+	t.pipeline = nil
+	for defined := t.defined; defined != 0; defined &= defined - 1 {
+		k := Key(bits.TrailingZeros64(defined))
+		bit := uint64(1) << k
+		v := t.current[k]
+		if !query(k, &v) {
+			t.invalid |= bit
+			continue
+		}
+		t.invalid &^= bit
+		t.current[k] = v
+
+		// The next Apply must revert the key, unless it holds its default.
+		if v != t.defaults[k].Value {
+			t.set |= bit
+		}
+	}
+	for i := range t.overflow {
+		o := &t.overflow[i]
+		v := o.Value
+		if !query(o.Key, &v) {
+			o.invalid = true
+			continue
+		}
+		o.Value, o.invalid = v, false
+		o.set = v != o.defaultValue()
+	}
+}
+
+// Verify verifies that the current value of every valid key matches its
+// actual value, as given by the query function. Keys that cannot be queried
+// are skipped. It returns an error describing the first mismatch found.
+func (t *Table) Verify(query Query) error {
+	for valid := t.defined &^ t.invalid; valid != 0; valid &= valid - 1 {
+		k := Key(bits.TrailingZeros64(valid))
+		v := t.current[k]
+		if !query(k, &v) {
+			continue
+		}
+		if v != t.current[k] {
+			return fmt.Errorf("state: cached %v but actually %v", info(k, &t.current[k]), info(k, &v))
+		}
+	}
+	for i := range t.overflow {
+		o := &t.overflow[i]
+		v := o.Value
+		if o.invalid || !query(o.Key, &v) {
+			continue
+		}
+		if v != o.Value {
+			return fmt.Errorf("state: cached %v but actually %v", info(o.Key, &o.Value), info(o.Key, &v))
+		}
+	}
+	return nil
+}
+
+// info returns a description of a value of the given key.
+func info(k Key, v *Value) gfx.StateValueInfo {
+	return gfx.StateValueInfo{Name: k.String(), Value: v.Interface(k)}
+}
+
+// SetColor sets v.F to the actual value of a color, which OpenGL clamps to
+// the range of [0, 1], unless v.F already clamps to that value.
+func (v *Value) SetColor(actual [4]float32) {
+	for i, x := range v.F {
+		if clamp(float64(x)) != float64(actual[i]) {
+			v.F = actual
+			return
+		}
+	}
+}
+
+// SetDepth sets v.D to the actual value of a depth, which OpenGL clamps to
+// the range of [0, 1] and may store with single precision, unless v.D
+// already clamps to that value.
+func (v *Value) SetDepth(actual float64) {
+	if float32(clamp(v.D)) != float32(actual) {
+		v.D = actual
+	}
+}
+
+func clamp(x float64) float64 {
+	if x < 0 {
+		return 0
+	}
+	if x > 1 {
+		return 1
+	}
+	return x
+}
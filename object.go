@@ -16,6 +16,8 @@ type Object interface {
 	//  gfx/gl2:    uint32
 	//  gfx/gles2:  uint32
 	//  gfx/webgl: *js.Object
+	//  gfx/soft:   uint32
+	//  gfx/null:   uint32
 	//
 	Object() interface{}
 }
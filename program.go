@@ -35,6 +35,8 @@ type Program interface {
 
 	// Uniform{1,2,3,4}{f,i}v sets values for a N component floating-point or
 	// integer vector into a uniform location as a vector or vector array.
+	//
+	// The length of data must be a non-zero multiple of N.
 	Uniform1fv(l UniformLocation, data []float32)
 	Uniform1iv(l UniformLocation, data []int32)
 	Uniform2fv(l UniformLocation, data []float32)
@@ -46,6 +48,8 @@ type Program interface {
 
 	// UniformMatrix{2,3,4}{f,i}v sets values for a 4x4 floating point or integer
 	// vector matrix into a uniform location as a matrix or a matrix array.
+	//
+	// The length of data must be a non-zero multiple of N*N.
 	UniformMatrix2fv(l UniformLocation, transpose bool, data []float32)
 	UniformMatrix3fv(l UniformLocation, transpose bool, data []float32)
 	UniformMatrix4fv(l UniformLocation, transpose bool, data []float32)
@@ -4,6 +4,11 @@
 
 package gfx
 
+import (
+	"fmt"
+	"strings"
+)
+
 // ContextStateValue represents a single value as part of a context's state,
 // for example a boolean representing whether multisampling is enabled or not.
 //
@@ -18,15 +23,62 @@ type ContextStateValue interface{}
 // assumptions about it.
 type ContextState interface{}
 
+// PipelineState is an immutable context state that has been precompiled by a
+// context's Bake method. It may be passed to Load like any other context
+// state.
+//
+// Loading the pipeline that is already loaded does nothing, and the GL calls
+// required to switch between two pipelines are cached after the first switch,
+// making pipelines the fastest way to switch between frequently used states.
+type PipelineState interface {
+	ContextState
+
+	// Hash returns a hash of the pipeline's state values, which is stable for
+	// the lifetime of the context. Pipelines with equal state values have equal
+	// hashes (regardless of the order the values were given in), which makes it
+	// suitable for sorting draw calls by pipeline.
+	//
+	// Pipelines with differing state values may have equal hashes, for example
+	// if they differ only by which program is used on a platform that cannot
+	// identify programs numerically.
+	Hash() uint64
+}
+
 // ContextStateProvider provides access to a graphics context's state.
 type ContextStateProvider interface {
-	// NewState returns a new context state for the given values.
+	// NewState returns a new context state for the given values. If multiple
+	// values are given for the same piece of state (e.g. both Enable(Blend)
+	// and Disable(Blend)) then the last one takes precedence.
 	NewState(values ...ContextStateValue) ContextState
 
 	// Load loads the given context state, replacing the previous one. If
 	// s == nil then the default state is loaded.
 	Load(s ContextState)
 
+	// Bake returns an immutable pipeline state precompiled from the given
+	// context state. If s == nil then a pipeline of the default state is
+	// returned. If s is already a pipeline state, it is returned as-is.
+	Bake(s ContextState) PipelineState
+
+	// Current returns the currently loaded context state, exactly as it was
+	// given to Load. It returns nil if the default state is loaded.
+	Current() ContextState
+
+	// Describe returns a description of every piece of state in the given
+	// context state, including those which hold their default value. If
+	// s == nil the default state is described.
+	Describe(s ContextState) StateInfo
+
+	// PushState pushes the currently loaded context state onto a stack, such
+	// that it can later be restored by PopState. It allows e.g. a library to
+	// load its own state and restore the application's afterwards.
+	PushState()
+
+	// PopState removes the context state most recently pushed by PushState
+	// from the stack, and loads it. If the stack is empty, the default state
+	// is loaded instead (contexts from the debug package panic).
+	PopState()
+
 	// BlendColor specifies the blend color used to calculate source and
 	// destination blending.
 	BlendColor(r, g, b, a float32) ContextStateValue
@@ -40,6 +92,9 @@ type ContextStateProvider interface {
 	DepthMask(m bool) ContextStateValue
 
 	// Enable enables the given feature.
+	//
+	// All features are disabled by default, except for Dither which is
+	// enabled by default.
 	Enable(f Feature) ContextStateValue
 
 	// Disable disables the given feature.
@@ -83,5 +138,76 @@ type ContextStateProvider interface {
 
 	// EnableVertexAttribArray enables the given vertex attribute array for use
 	// during rendering.
+	//
+	// Each attribute location is a distinct state value, so multiple arrays
+	// may be enabled by a single state. Any vertex attribute array not enabled
+	// by the loaded state is disabled.
 	EnableVertexAttribArray(a AttribLocation) ContextStateValue
 }
+
+// StateValueInfo is a driver-independent description of a single piece of
+// context or framebuffer state.
+type StateValueInfo struct {
+	// Name is the name of the state value, e.g. "Blend", "Viewport" or
+	// "VertexAttribArray[2]". The facet set by CullFace is named
+	// "CullFaceMode", as in OpenGL.
+	Name string
+
+	// Value and Default are the value and default value of the state. Their
+	// types depend on the state value, e.g. bool for features, [4]int for the
+	// viewport, Facet for CullFace, or Program for UseProgram.
+	Value, Default interface{}
+
+	// Set tells if the value was explicitly specified by the state, rather
+	// than assumed to be the default.
+	Set bool
+}
+
+// IsDefault tells if the state holds its default value.
+func (v StateValueInfo) IsDefault() bool {
+	return v.Value == v.Default
+}
+
+// String returns a string like "Blend=on" or "Viewport=0,0,640,480".
+func (v StateValueInfo) String() string {
+	return v.Name + "=" + formatStateValue(v.Value)
+}
+
+// formatStateValue formats a StateValueInfo value for display.
+func formatStateValue(v interface{}) string {
+	switch x := v.(type) {
+	case bool:
+		if x {
+			return "on"
+		}
+		return "off"
+	case [4]bool:
+		return fmt.Sprintf("%s,%s,%s,%s", formatStateValue(x[0]), formatStateValue(x[1]), formatStateValue(x[2]), formatStateValue(x[3]))
+	case [4]int:
+		return fmt.Sprintf("%d,%d,%d,%d", x[0], x[1], x[2], x[3])
+	case [4]float32:
+		return fmt.Sprintf("%g,%g,%g,%g", x[0], x[1], x[2], x[3])
+	case Program:
+		if x == nil {
+			return "none"
+		}
+		return fmt.Sprint(x.Object())
+	case nil:
+		return "none"
+	default:
+		return fmt.Sprint(x)
+	}
+}
+
+// StateInfo is a driver-independent description of a context or framebuffer
+// state.
+type StateInfo []StateValueInfo
+
+// String returns a string like "Blend=on, Viewport=0,0,640,480, ...".
+func (s StateInfo) String() string {
+	parts := make([]string, len(s))
+	for i, v := range s {
+		parts[i] = v.String()
+	}
+	return strings.Join(parts, ", ")
+}
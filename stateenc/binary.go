@@ -0,0 +1,276 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stateenc
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/slimsag/gfx"
+)
+
+// binaryVersion is the version of the binary encoding, which is its first
+// byte.
+const binaryVersion = 1
+
+var errShortData = errors.New("stateenc: unexpected end of data")
+
+// EncodeContextState returns the compact binary encoding of the given context
+// state, which must have been created by ctx.
+func EncodeContextState(ctx gfx.ContextStateProvider, s gfx.ContextState) ([]byte, error) {
+	vs, err := values(contextFields, ctx.Describe(s))
+	if err != nil {
+		return nil, err
+	}
+	return encodeBinary(contextFields, vs), nil
+}
+
+// DecodeContextState decodes a binary encoded context state into a new
+// context state created by ctx.
+func DecodeContextState(ctx gfx.ContextStateProvider, data []byte) (gfx.ContextState, error) {
+	vs, err := decodeBinary(contextFields, data)
+	if err != nil {
+		return nil, err
+	}
+	return newContextState(ctx, vs), nil
+}
+
+// EncodeFramebufferState returns the compact binary encoding of the given
+// framebuffer state, which must have been created by fb.
+func EncodeFramebufferState(fb gfx.FramebufferStateProvider, s gfx.FramebufferState) ([]byte, error) {
+	vs, err := values(framebufferFields, fb.Describe(s))
+	if err != nil {
+		return nil, err
+	}
+	return encodeBinary(framebufferFields, vs), nil
+}
+
+// DecodeFramebufferState decodes a binary encoded framebuffer state into a
+// new framebuffer state created by fb.
+func DecodeFramebufferState(fb gfx.FramebufferStateProvider, data []byte) (gfx.FramebufferState, error) {
+	vs, err := decodeBinary(framebufferFields, data)
+	if err != nil {
+		return nil, err
+	}
+	return newFramebufferState(fb, vs), nil
+}
+
+// encodeBinary encodes the values. The encoding is the version byte, followed
+// by the number of values as a uvarint, followed by each value's field index
+// as a uvarint and its payload.
+func encodeBinary(fields []field, vs []value) []byte {
+	buf := []byte{binaryVersion}
+	buf = appendUvarint(buf, uint64(len(vs)))
+	for _, v := range vs {
+		buf = appendUvarint(buf, uint64(v.field))
+		switch fields[v.field].kind {
+		case kindBool:
+			buf = append(buf, boolByte(v.v.(bool)))
+		case kindBool4:
+			x := v.v.([4]bool)
+			var b byte
+			for i := range x {
+				b |= boolByte(x[i]) << uint(i)
+			}
+			buf = append(buf, b)
+		case kindInt:
+			buf = appendVarint(buf, int64(v.v.(int)))
+		case kindInt4:
+			for _, x := range v.v.([4]int) {
+				buf = appendVarint(buf, int64(x))
+			}
+		case kindFloat:
+			buf = appendUint32(buf, math.Float32bits(v.v.(float32)))
+		case kindFloat4:
+			for _, x := range v.v.([4]float32) {
+				buf = appendUint32(buf, math.Float32bits(x))
+			}
+		case kindDouble:
+			buf = appendUint64(buf, math.Float64bits(v.v.(float64)))
+		case kindBlendEquation:
+			buf = append(buf, byte(enumIndex(len(blendEquations), func(i int) bool {
+				return blendEquations[i] == v.v
+			})))
+		case kindFacet:
+			buf = append(buf, byte(enumIndex(len(facets), func(i int) bool {
+				return facets[i] == v.v
+			})))
+		case kindOrientation:
+			buf = append(buf, byte(enumIndex(len(orientations), func(i int) bool {
+				return orientations[i] == v.v
+			})))
+		case kindProgram:
+			// No payload, the program is always nil.
+		}
+	}
+	return buf
+}
+
+// enumIndex returns the index of an enumeration in its list of n values, for
+// which eq reports true.
+func enumIndex(n int, eq func(i int) bool) int {
+	for i := 0; i < n; i++ {
+		if eq(i) {
+			return i
+		}
+	}
+	panic("stateenc: invalid enumeration")
+}
+
+func appendUvarint(buf []byte, x uint64) []byte {
+	var b [binary.MaxVarintLen64]byte
+	return append(buf, b[:binary.PutUvarint(b[:], x)]...)
+}
+
+func appendVarint(buf []byte, x int64) []byte {
+	var b [binary.MaxVarintLen64]byte
+	return append(buf, b[:binary.PutVarint(b[:], x)]...)
+}
+
+func appendUint32(buf []byte, x uint32) []byte {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], x)
+	return append(buf, b[:]...)
+}
+
+func appendUint64(buf []byte, x uint64) []byte {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], x)
+	return append(buf, b[:]...)
+}
+
+func boolByte(b bool) byte {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// decoder decodes the binary encoding, recording the first error.
+type decoder struct {
+	data []byte
+	err  error
+}
+
+func (d *decoder) uvarint() uint64 {
+	x, n := binary.Uvarint(d.data)
+	if n <= 0 {
+		d.fail()
+		return 0
+	}
+	d.data = d.data[n:]
+	return x
+}
+
+func (d *decoder) varint() int64 {
+	x, n := binary.Varint(d.data)
+	if n <= 0 {
+		d.fail()
+		return 0
+	}
+	d.data = d.data[n:]
+	return x
+}
+
+func (d *decoder) bytes(n int) []byte {
+	if len(d.data) < n {
+		d.fail()
+		return make([]byte, n)
+	}
+	b := d.data[:n]
+	d.data = d.data[n:]
+	return b
+}
+
+func (d *decoder) fail() {
+	if d.err == nil {
+		d.err = errShortData
+	}
+	d.data = nil
+}
+
+// enum decodes an enumeration index, which must be less than n. Invalid
+// indices are recorded as an error, and zero is returned.
+func (d *decoder) enum(n int) int {
+	i := int(d.bytes(1)[0])
+	if i >= n {
+		if d.err == nil {
+			d.err = fmt.Errorf("stateenc: invalid enumeration index %d", i)
+		}
+		return 0
+	}
+	return i
+}
+
+// decodeBinary decodes binary encoded values, see encodeBinary.
+func decodeBinary(fields []field, data []byte) ([]value, error) {
+	if len(data) == 0 {
+		return nil, errShortData
+	}
+	if data[0] != binaryVersion {
+		return nil, fmt.Errorf("stateenc: unsupported binary encoding version %d", data[0])
+	}
+	d := &decoder{data: data[1:]}
+	n := d.uvarint()
+	var vs []value
+	for i := uint64(0); i < n && d.err == nil; i++ {
+		f := d.uvarint()
+		if f >= uint64(len(fields)) {
+			if d.err == nil {
+				d.err = fmt.Errorf("stateenc: invalid field index %d", f)
+			}
+			break
+		}
+		v := value{field: int(f)}
+		switch fields[f].kind {
+		case kindBool:
+			v.v = d.bytes(1)[0] != 0
+		case kindBool4:
+			b := d.bytes(1)[0]
+			var x [4]bool
+			for i := range x {
+				x[i] = b&(1<<uint(i)) != 0
+			}
+			v.v = x
+		case kindInt:
+			v.v = int(d.varint())
+		case kindInt4:
+			var x [4]int
+			for i := range x {
+				x[i] = int(d.varint())
+			}
+			v.v = x
+		case kindFloat:
+			v.v = math.Float32frombits(binary.LittleEndian.Uint32(d.bytes(4)))
+		case kindFloat4:
+			var x [4]float32
+			for i := range x {
+				x[i] = math.Float32frombits(binary.LittleEndian.Uint32(d.bytes(4)))
+			}
+			v.v = x
+		case kindDouble:
+			v.v = math.Float64frombits(binary.LittleEndian.Uint64(d.bytes(8)))
+		case kindBlendEquation:
+			v.v = blendEquations[d.enum(len(blendEquations))]
+		case kindFacet:
+			v.v = facets[d.enum(len(facets))]
+		case kindOrientation:
+			v.v = orientations[d.enum(len(orientations))]
+		case kindProgram:
+			v.v = nil
+		}
+		vs = append(vs, v)
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	if len(d.data) != 0 {
+		return nil, errors.New("stateenc: unexpected data after state")
+	}
+	sortValues(vs)
+	return vs, nil
+}
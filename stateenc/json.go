@@ -0,0 +1,175 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stateenc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/slimsag/gfx"
+)
+
+// MarshalContextState returns the JSON encoding of the given context state,
+// which must have been created by ctx. The encoding is a JSON object, e.g.:
+//
+//	{"Viewport":[0,0,640,480],"CullFaceMode":"Front","Blend":true}
+func MarshalContextState(ctx gfx.ContextStateProvider, s gfx.ContextState) ([]byte, error) {
+	vs, err := values(contextFields, ctx.Describe(s))
+	if err != nil {
+		return nil, err
+	}
+	return marshalJSON(contextFields, vs)
+}
+
+// UnmarshalContextState decodes a JSON encoded context state into a new
+// context state created by ctx.
+func UnmarshalContextState(ctx gfx.ContextStateProvider, data []byte) (gfx.ContextState, error) {
+	vs, err := unmarshalJSON(contextFields, data)
+	if err != nil {
+		return nil, err
+	}
+	return newContextState(ctx, vs), nil
+}
+
+// MarshalFramebufferState returns the JSON encoding of the given framebuffer
+// state, which must have been created by fb.
+func MarshalFramebufferState(fb gfx.FramebufferStateProvider, s gfx.FramebufferState) ([]byte, error) {
+	vs, err := values(framebufferFields, fb.Describe(s))
+	if err != nil {
+		return nil, err
+	}
+	return marshalJSON(framebufferFields, vs)
+}
+
+// UnmarshalFramebufferState decodes a JSON encoded framebuffer state into a
+// new framebuffer state created by fb.
+func UnmarshalFramebufferState(fb gfx.FramebufferStateProvider, data []byte) (gfx.FramebufferState, error) {
+	vs, err := unmarshalJSON(framebufferFields, data)
+	if err != nil {
+		return nil, err
+	}
+	return newFramebufferState(fb, vs), nil
+}
+
+// marshalJSON encodes the values as a JSON object, with keys in field order.
+func marshalJSON(fields []field, vs []value) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, v := range vs {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, _ := json.Marshal(fields[v.field].name)
+		buf.Write(name)
+		buf.WriteByte(':')
+
+		var x interface{}
+		switch fields[v.field].kind {
+		case kindBlendEquation, kindFacet, kindOrientation:
+			x = v.v.(fmt.Stringer).String()
+		case kindProgram:
+			x = nil
+		default:
+			x = v.v
+		}
+		data, err := json.Marshal(x)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(data)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// unmarshalJSON decodes the values of a JSON object, in field order.
+func unmarshalJSON(fields []field, data []byte) ([]value, error) {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	var vs []value
+	for name, raw := range m {
+		i := lookup(fields, name)
+		if i == -1 {
+			return nil, fmt.Errorf("stateenc: unknown state value %q", name)
+		}
+		v, err := unmarshalJSONValue(fields[i].kind, raw)
+		if err != nil {
+			return nil, fmt.Errorf("stateenc: %s: %v", name, err)
+		}
+		vs = append(vs, value{field: i, v: v})
+	}
+	sortValues(vs)
+	return vs, nil
+}
+
+// unmarshalJSONValue decodes a single JSON value of the given kind.
+func unmarshalJSONValue(k kind, raw json.RawMessage) (interface{}, error) {
+	var err error
+	switch k {
+	case kindBool:
+		var x bool
+		err = json.Unmarshal(raw, &x)
+		return x, err
+	case kindBool4:
+		var x [4]bool
+		err = json.Unmarshal(raw, &x)
+		return x, err
+	case kindInt:
+		var x int
+		err = json.Unmarshal(raw, &x)
+		return x, err
+	case kindInt4:
+		var x [4]int
+		err = json.Unmarshal(raw, &x)
+		return x, err
+	case kindFloat:
+		var x float32
+		err = json.Unmarshal(raw, &x)
+		return x, err
+	case kindFloat4:
+		var x [4]float32
+		err = json.Unmarshal(raw, &x)
+		return x, err
+	case kindDouble:
+		var x float64
+		err = json.Unmarshal(raw, &x)
+		return x, err
+	case kindProgram:
+		if string(raw) != "null" {
+			return nil, fmt.Errorf("programs cannot be decoded")
+		}
+		return nil, nil
+	}
+
+	// Enumerations are encoded by name.
+	var name string
+	if err = json.Unmarshal(raw, &name); err != nil {
+		return nil, err
+	}
+	switch k {
+	case kindBlendEquation:
+		for _, e := range blendEquations {
+			if e.String() == name {
+				return e, nil
+			}
+		}
+	case kindFacet:
+		for _, e := range facets {
+			if e.String() == name {
+				return e, nil
+			}
+		}
+	case kindOrientation:
+		for _, e := range orientations {
+			if e.String() == name {
+				return e, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("invalid enumeration %q", name)
+}
@@ -0,0 +1,202 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package stateenc implements JSON and binary encodings of context and
+// framebuffer states.
+//
+// States are encoded in a driver-independent way, with each state value keyed
+// by a stable name (the same name given by gfx.StateValueInfo, e.g. "Blend" or
+// "Viewport"). Only the values explicitly specified by a state are encoded, so
+// a state encoded using one driver may be decoded using any other driver.
+//
+// Programs and vertex attribute locations only exist at runtime, so states
+// which use a program or enable a vertex attribute array cannot be encoded.
+package stateenc
+
+import (
+	"fmt"
+
+	"github.com/slimsag/gfx"
+)
+
+// kind is the kind of a field's value, which determines its Go type (as given
+// by gfx.StateValueInfo) and its encoding.
+type kind uint8
+
+const (
+	kindBool          kind = iota // bool
+	kindBool4                     // [4]bool
+	kindInt                       // int
+	kindInt4                      // [4]int
+	kindFloat                     // float32
+	kindFloat4                    // [4]float32
+	kindDouble                    // float64
+	kindBlendEquation             // gfx.BlendEquation
+	kindFacet                     // gfx.Facet
+	kindOrientation               // gfx.Orientation
+	kindProgram                   // gfx.Program, which must be nil.
+)
+
+// field describes a single encodable state value.
+type field struct {
+	name string
+	kind kind
+}
+
+// contextFields are the encodable context state values. The index of a field
+// is its binary encoding, so new fields must only ever be appended.
+var contextFields = []field{
+	{"BlendColor", kindFloat4},
+	{"BlendEquation", kindBlendEquation},
+	{"DepthMask", kindBool},
+	{"UseProgram", kindProgram},
+	{"Viewport", kindInt4},
+	{"Scissor", kindInt4},
+	{"LineWidth", kindFloat},
+	{"ColorMask", kindBool4},
+	{"CullFaceMode", kindFacet},
+	{"FrontFace", kindOrientation},
+	{"Blend", kindBool},
+	{"DepthTest", kindBool},
+	{"CullFace", kindBool},
+	{"PolygonOffsetFill", kindBool},
+	{"ScissorTest", kindBool},
+	{"Dither", kindBool},
+}
+
+// framebufferFields are the encodable framebuffer state values, see
+// contextFields.
+var framebufferFields = []field{
+	{"ClearColor", kindFloat4},
+	{"ClearDepth", kindDouble},
+	{"ClearStencil", kindInt},
+}
+
+// features maps the names of features to the features themselves.
+var features = map[string]gfx.Feature{
+	"Blend":             gfx.Blend,
+	"DepthTest":         gfx.DepthTest,
+	"CullFace":          gfx.CullFace,
+	"PolygonOffsetFill": gfx.PolygonOffsetFill,
+	"ScissorTest":       gfx.ScissorTest,
+	"Dither":            gfx.Dither,
+}
+
+// lookup returns the index of the named field, or -1 if there is none.
+func lookup(fields []field, name string) int {
+	for i, f := range fields {
+		if f.name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// value is a single encodable state value.
+type value struct {
+	field int         // Index into the fields slice.
+	v     interface{} // Go value, whose type depends on the field's kind.
+}
+
+// values returns the values explicitly specified by the given state
+// description, in field order.
+func values(fields []field, info gfx.StateInfo) ([]value, error) {
+	var vs []value
+	for _, v := range info {
+		if !v.Set {
+			continue
+		}
+		i := lookup(fields, v.Name)
+		if i == -1 || (fields[i].kind == kindProgram && v.Value != nil) {
+			return nil, fmt.Errorf("stateenc: %s cannot be encoded", v.Name)
+		}
+		vs = append(vs, value{field: i, v: v.Value})
+	}
+	sortValues(vs)
+	return vs, nil
+}
+
+// sortValues sorts the values into field order.
+func sortValues(vs []value) {
+	for i := 1; i < len(vs); i++ {
+		for j := i; j > 0 && vs[j-1].field > vs[j].field; j-- {
+			vs[j-1], vs[j] = vs[j], vs[j-1]
+		}
+	}
+}
+
+// contextValue returns the context state value for the given value.
+func contextValue(ctx gfx.ContextStateProvider, v value) gfx.ContextStateValue {
+	f := contextFields[v.field]
+	switch f.name {
+	case "BlendColor":
+		x := v.v.([4]float32)
+		return ctx.BlendColor(x[0], x[1], x[2], x[3])
+	case "BlendEquation":
+		return ctx.BlendEquation(v.v.(gfx.BlendEquation))
+	case "DepthMask":
+		return ctx.DepthMask(v.v.(bool))
+	case "UseProgram":
+		return ctx.UseProgram(nil)
+	case "Viewport":
+		x := v.v.([4]int)
+		return ctx.Viewport(x[0], x[1], x[2], x[3])
+	case "Scissor":
+		x := v.v.([4]int)
+		return ctx.Scissor(x[0], x[1], x[2], x[3])
+	case "LineWidth":
+		return ctx.LineWidth(v.v.(float32))
+	case "ColorMask":
+		x := v.v.([4]bool)
+		return ctx.ColorMask(x[0], x[1], x[2], x[3])
+	case "CullFaceMode":
+		return ctx.CullFace(v.v.(gfx.Facet))
+	case "FrontFace":
+		return ctx.FrontFace(v.v.(gfx.Orientation))
+	default:
+		if v.v.(bool) {
+			return ctx.Enable(features[f.name])
+		}
+		return ctx.Disable(features[f.name])
+	}
+}
+
+// framebufferValue returns the framebuffer state value for the given value.
+func framebufferValue(fb gfx.FramebufferStateProvider, v value) gfx.FramebufferStateValue {
+	switch framebufferFields[v.field].name {
+	case "ClearColor":
+		x := v.v.([4]float32)
+		return fb.ClearColor(x[0], x[1], x[2], x[3])
+	case "ClearDepth":
+		return fb.ClearDepth(v.v.(float64))
+	default:
+		return fb.ClearStencil(v.v.(int))
+	}
+}
+
+// newContextState returns a new context state with the given values.
+func newContextState(ctx gfx.ContextStateProvider, vs []value) gfx.ContextState {
+	csv := make([]gfx.ContextStateValue, len(vs))
+	for i, v := range vs {
+		csv[i] = contextValue(ctx, v)
+	}
+	return ctx.NewState(csv...)
+}
+
+// newFramebufferState returns a new framebuffer state with the given values.
+func newFramebufferState(fb gfx.FramebufferStateProvider, vs []value) gfx.FramebufferState {
+	fsv := make([]gfx.FramebufferStateValue, len(vs))
+	for i, v := range vs {
+		fsv[i] = framebufferValue(fb, v)
+	}
+	return fb.NewState(fsv...)
+}
+
+// Enumerations, in the order used by the binary encoding. New values must only
+// ever be appended.
+var (
+	blendEquations = []gfx.BlendEquation{gfx.FuncAdd, gfx.FuncSubtract, gfx.FuncReverseSubtract}
+	facets         = []gfx.Facet{gfx.Front, gfx.Back, gfx.FrontAndBack}
+	orientations   = []gfx.Orientation{gfx.CCW, gfx.CW}
+)
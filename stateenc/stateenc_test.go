@@ -0,0 +1,241 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stateenc
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/slimsag/gfx"
+	"github.com/slimsag/gfx/internal/state"
+)
+
+// provider implements the gfx.ContextStateProvider interface without any
+// driver. The GL calls of its values do nothing.
+type provider struct {
+	state.Context
+	fb fbProvider
+}
+
+// fbProvider is like provider, but implements gfx.FramebufferStateProvider.
+type fbProvider struct {
+	state.Framebuffer
+	table state.Table
+}
+
+func newProvider() *provider {
+	p := &provider{}
+	p.Context.Init(
+		p.BlendColor(0, 0, 0, 0),
+		p.BlendEquation(gfx.FuncAdd),
+		p.DepthMask(true),
+		p.UseProgram(nil),
+		p.Viewport(0, 0, 640, 480),
+		p.Scissor(0, 0, 640, 480),
+		p.LineWidth(1),
+		p.ColorMask(true, true, true, true),
+		p.CullFace(gfx.Back),
+		p.FrontFace(gfx.CCW),
+		p.Disable(gfx.Blend),
+		p.Disable(gfx.CullFace),
+		p.Enable(gfx.Dither),
+		csv(state.VertexAttribKey(0), state.Value{}),
+	)
+	p.fb.table.Init(
+		csv(state.ClearColor, state.Value{}),
+		csv(state.ClearDepth, state.Value{D: 1}),
+		csv(state.ClearStencil, state.Value{}),
+	)
+	p.fb.Table = &p.fb.table
+	return p
+}
+
+func csv(k state.Key, v state.Value) state.CSV {
+	return state.CSV{Key: k, Value: v, GLCall: func(v *state.Value) {}}
+}
+
+func (p *provider) BlendColor(r, g, b, a float32) gfx.ContextStateValue {
+	return csv(state.BlendColor, state.Value{F: [4]float32{r, g, b, a}})
+}
+
+func (p *provider) BlendEquation(eq gfx.BlendEquation) gfx.ContextStateValue {
+	return csv(state.BlendEquation, state.Value{E: int(eq)})
+}
+
+func (p *provider) DepthMask(m bool) gfx.ContextStateValue {
+	return csv(state.DepthMask, state.Value{B: [4]bool{m}})
+}
+
+func (p *provider) Enable(f gfx.Feature) gfx.ContextStateValue {
+	return csv(state.FeatureKey(f), state.Value{B: [4]bool{true}})
+}
+
+func (p *provider) Disable(f gfx.Feature) gfx.ContextStateValue {
+	return csv(state.FeatureKey(f), state.Value{})
+}
+
+func (p *provider) UseProgram(prog gfx.Program) gfx.ContextStateValue {
+	return csv(state.UseProgram, state.Value{P: prog})
+}
+
+func (p *provider) Viewport(x, y, width, height int) gfx.ContextStateValue {
+	return csv(state.Viewport, state.Value{I: [4]int32{int32(x), int32(y), int32(width), int32(height)}})
+}
+
+func (p *provider) Scissor(x, y, width, height int) gfx.ContextStateValue {
+	return csv(state.Scissor, state.Value{I: [4]int32{int32(x), int32(y), int32(width), int32(height)}})
+}
+
+func (p *provider) LineWidth(w float32) gfx.ContextStateValue {
+	return csv(state.LineWidth, state.Value{F: [4]float32{w}})
+}
+
+func (p *provider) ColorMask(r, g, b, a bool) gfx.ContextStateValue {
+	return csv(state.ColorMask, state.Value{B: [4]bool{r, g, b, a}})
+}
+
+func (p *provider) CullFace(f gfx.Facet) gfx.ContextStateValue {
+	return csv(state.CullFace, state.Value{E: int(f)})
+}
+
+func (p *provider) FrontFace(o gfx.Orientation) gfx.ContextStateValue {
+	return csv(state.FrontFace, state.Value{E: int(o)})
+}
+
+func (p *provider) EnableVertexAttribArray(l gfx.AttribLocation) gfx.ContextStateValue {
+	return csv(state.VertexAttribKey(l.(int)), state.Value{U: uint32(l.(int)), B: [4]bool{true}})
+}
+
+func (f *fbProvider) ClearColor(r, g, b, a float32) gfx.FramebufferStateValue {
+	return csv(state.ClearColor, state.Value{F: [4]float32{r, g, b, a}})
+}
+
+func (f *fbProvider) ClearDepth(depth float64) gfx.FramebufferStateValue {
+	return csv(state.ClearDepth, state.Value{D: depth})
+}
+
+func (f *fbProvider) ClearStencil(stencil int) gfx.FramebufferStateValue {
+	return csv(state.ClearStencil, state.Value{I: [4]int32{int32(stencil)}})
+}
+
+var (
+	_ gfx.ContextStateProvider     = &provider{}
+	_ gfx.FramebufferStateProvider = &fbProvider{}
+)
+
+func TestContextState(t *testing.T) {
+	p := newProvider()
+	s := p.NewState(
+		p.Enable(gfx.Blend),
+		p.Enable(gfx.CullFace),
+		p.CullFace(gfx.Front),
+		p.Viewport(0, 0, 640, 480), // Explicitly the default value.
+		p.BlendColor(0.5, 0.25, 1, 1),
+		p.BlendEquation(gfx.FuncReverseSubtract),
+		p.LineWidth(2),
+		p.ColorMask(true, false, true, false),
+		p.UseProgram(nil),
+		p.Disable(gfx.Dither),
+	)
+	want := p.Describe(s)
+
+	data, err := MarshalContextState(p, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantJSON := `{"BlendColor":[0.5,0.25,1,1],"BlendEquation":"FuncReverseSubtract","UseProgram":null,"Viewport":[0,0,640,480],"LineWidth":2,"ColorMask":[true,false,true,false],"CullFaceMode":"Front","Blend":true,"CullFace":true,"Dither":false}`
+	if string(data) != wantJSON {
+		t.Errorf("got JSON %s\nwant %s", data, wantJSON)
+	}
+
+	// Decode into a different provider, as if it were another driver.
+	other := newProvider()
+	got, err := UnmarshalContextState(other, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(other.Describe(got), want) {
+		t.Errorf("JSON: got state %v, want %v", other.Describe(got), want)
+	}
+
+	data, err = EncodeContextState(p, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = DecodeContextState(other, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(other.Describe(got), want) {
+		t.Errorf("binary: got state %v, want %v", other.Describe(got), want)
+	}
+}
+
+func TestFramebufferState(t *testing.T) {
+	p := newProvider()
+	s := p.fb.NewState(p.fb.ClearColor(1, 0, 0, 1), p.fb.ClearDepth(0.5), p.fb.ClearStencil(-3))
+	want := p.fb.Describe(s)
+
+	data, err := MarshalFramebufferState(&p.fb, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := UnmarshalFramebufferState(&p.fb, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(p.fb.Describe(got), want) {
+		t.Errorf("JSON: got state %v, want %v", p.fb.Describe(got), want)
+	}
+
+	data, err = EncodeFramebufferState(&p.fb, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = DecodeFramebufferState(&p.fb, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(p.fb.Describe(got), want) {
+		t.Errorf("binary: got state %v, want %v", p.fb.Describe(got), want)
+	}
+}
+
+func TestUnencodable(t *testing.T) {
+	p := newProvider()
+	s := p.NewState(p.EnableVertexAttribArray(0))
+	if _, err := MarshalContextState(p, s); err == nil {
+		t.Error("MarshalContextState: expected error for vertex attribute array")
+	}
+	if _, err := EncodeContextState(p, s); err == nil {
+		t.Error("EncodeContextState: expected error for vertex attribute array")
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	p := newProvider()
+	for _, data := range []string{
+		`{"Unknown":true}`,
+		`{"Blend":1}`,
+		`{"CullFaceMode":"Sideways"}`,
+		`{"UseProgram":1}`,
+	} {
+		if _, err := UnmarshalContextState(p, []byte(data)); err == nil {
+			t.Errorf("UnmarshalContextState(%s): expected error", data)
+		}
+	}
+	for _, data := range [][]byte{
+		nil,
+		{2, 0},
+		{binaryVersion, 1},
+		{binaryVersion, 1, 200},
+		{binaryVersion, 1, 1, 9},
+		{binaryVersion, 0, 0},
+	} {
+		if _, err := DecodeContextState(p, data); err == nil {
+			t.Errorf("DecodeContextState(%v): expected error", data)
+		}
+	}
+}
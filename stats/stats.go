@@ -0,0 +1,264 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package stats implements per-frame rendering statistics.
+//
+// A context wrapped by New counts the draw calls, vertices and primitives,
+// bytes uploaded, object creations and deletions and Check calls made on it,
+// along with the state values applied or elided and the bind calls elided by
+// the caches of the driver. Call Frame at the end of each frame to get its
+// counters:
+//
+//	ctx := stats.New(ctx)
+//	ctx.Publish("gfx") // Optionally, via expvar.
+//	for {
+//	    renderFrame(ctx)
+//	    f := ctx.Frame()
+//	    drawOverlay(f.Draws, f.Vertices[gfx.Triangles])
+//	}
+package stats
+
+import (
+	"expvar"
+	"sync"
+
+	"github.com/slimsag/gfx"
+	"github.com/slimsag/gfx/intercept"
+)
+
+// Frame holds the counters of a frame.
+type Frame struct {
+	// Draws is the number of draw calls.
+	Draws int
+
+	// Vertices and Primitives are the number of vertices drawn, and of
+	// primitives they form, by primitive type.
+	Vertices, Primitives map[gfx.Primitive]int
+
+	// StateApplied is the number of context and framebuffer state values
+	// applied using GL calls, and StateElided the number of those that were
+	// not because they were already current.
+	StateApplied, StateElided int
+
+	// BindsElided is the number of GL bind calls that were not made because
+	// the object was already bound.
+	BindsElided int
+
+	// BytesUploaded is the number of bytes uploaded by the Data* and
+	// SubData* methods of buffers.
+	BytesUploaded int
+
+	// Created and Deleted are the number of objects created (e.g. by
+	// NewBuffer) and deleted.
+	Created, Deleted int
+
+	// Checks is the number of calls to the Check method of the context.
+	Checks int
+}
+
+// counter is implemented by drivers which count the work done, and avoided,
+// by their caches.
+type counter interface {
+	Counters() (applied, elided, bindsElided int)
+}
+
+// Context is a graphics context which counts the calls made on it, or on any
+// object gotten from it, by frame.
+type Context struct {
+	gfx.Context
+
+	driver  counter
+	current Frame
+
+	// base holds the counters of the driver at the start of the frame.
+	base Frame
+
+	// mu guards last, which may be read by expvar from other goroutines.
+	mu   sync.Mutex
+	last Frame
+}
+
+// New wraps the given graphics context such that the calls made on it (or on
+// any object gotten from it) are counted.
+//
+// The counters of the driver's caches are only available if it implements
+// them, as all the drivers of this repository do, and if ctx is the context of
+// a driver or one wrapped using the intercept package (e.g. by debug.Checker).
+func New(ctx gfx.Context) *Context {
+	c := &Context{}
+	for d := interface{}(ctx); ; {
+		if v, ok := d.(counter); ok {
+			c.driver = v
+			break
+		}
+		u := intercept.Unwrap(d)
+		if u == d {
+			break
+		}
+		d = u
+	}
+	c.reset()
+	c.Context = intercept.Context(ctx, intercept.Hooks{
+		Before: c.before,
+		After:  c.after,
+	})
+	return c
+}
+
+// reset starts a new frame.
+func (c *Context) reset() {
+	c.current = Frame{
+		Vertices:   make(map[gfx.Primitive]int),
+		Primitives: make(map[gfx.Primitive]int),
+	}
+	if c.driver != nil {
+		c.base.StateApplied, c.base.StateElided, c.base.BindsElided = c.driver.Counters()
+	}
+}
+
+// Frame ends the current frame and returns its counters.
+func (c *Context) Frame() Frame {
+	f := c.current
+	if c.driver != nil {
+		applied, elided, binds := c.driver.Counters()
+		f.StateApplied = applied - c.base.StateApplied
+		f.StateElided = elided - c.base.StateElided
+		f.BindsElided = binds - c.base.BindsElided
+	}
+	c.mu.Lock()
+	c.last = f
+	c.mu.Unlock()
+	c.reset()
+	return f
+}
+
+// Last returns the counters of the last frame ended by Frame. It may be
+// called from any goroutine.
+func (c *Context) Last() Frame {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.last
+}
+
+// Publish publishes the counters of the last frame ended by Frame as an
+// expvar variable with the given name, e.g. for dashboards reading
+// /debug/vars. Like expvar.Publish, it panics if the name is already in use.
+func (c *Context) Publish(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return c.Last().vars()
+	}))
+}
+
+// primitiveNames are the names of the primitive types, as published by
+// expvar.
+var primitiveNames = map[gfx.Primitive]string{
+	gfx.Points:        "Points",
+	gfx.Lines:         "Lines",
+	gfx.LineStrip:     "LineStrip",
+	gfx.LineLoop:      "LineLoop",
+	gfx.Triangles:     "Triangles",
+	gfx.TriangleStrip: "TriangleStrip",
+	gfx.TriangleFan:   "TriangleFan",
+}
+
+// vars returns the counters of the frame in a form marshaled to JSON by
+// expvar.
+func (f Frame) vars() map[string]interface{} {
+	byPrimitive := func(m map[gfx.Primitive]int) map[string]int {
+		named := make(map[string]int, len(m))
+		for p, n := range m {
+			named[primitiveNames[p]] = n
+		}
+		return named
+	}
+	return map[string]interface{}{
+		"Draws":         f.Draws,
+		"Vertices":      byPrimitive(f.Vertices),
+		"Primitives":    byPrimitive(f.Primitives),
+		"StateApplied":  f.StateApplied,
+		"StateElided":   f.StateElided,
+		"BindsElided":   f.BindsElided,
+		"BytesUploaded": f.BytesUploaded,
+		"Created":       f.Created,
+		"Deleted":       f.Deleted,
+		"Checks":        f.Checks,
+	}
+}
+
+// before counts the Check calls, even those which panic.
+func (c *Context) before(call *intercept.Call) {
+	if call.Interface == "Context" && call.Method == "Check" {
+		c.current.Checks++
+	}
+}
+
+// after counts the calls which returned.
+func (c *Context) after(call *intercept.Call) {
+	f := &c.current
+	switch call.Method {
+	case "NewFramebuffer", "NewRenderbuffer", "NewShader", "NewTexture", "NewBuffer", "NewProgram":
+		f.Created++
+	case "Delete":
+		f.Deleted++
+	case "Draw":
+		p, count := call.Args[0].(gfx.Primitive), call.Args[2].(int)
+		f.Draws++
+		f.Vertices[p] += count
+		f.Primitives[p] += primitives(p, count)
+	case "DataInt8", "DataUint8", "DataInt16", "DataUint16", "DataInt32", "DataUint32", "DataFloat32", "DataFloat64":
+		f.BytesUploaded += size(call.Args[0])
+	case "SubDataInt8", "SubDataUint8", "SubDataInt16", "SubDataUint16", "SubDataInt32", "SubDataUint32", "SubDataFloat32", "SubDataFloat64":
+		f.BytesUploaded += size(call.Args[1])
+	}
+}
+
+// primitives returns the number of primitives of the given type formed by
+// count vertices.
+func primitives(p gfx.Primitive, count int) int {
+	switch p {
+	case gfx.Points:
+		return count
+	case gfx.Lines:
+		return count / 2
+	case gfx.LineStrip:
+		if count >= 2 {
+			return count - 1
+		}
+	case gfx.LineLoop:
+		if count >= 2 {
+			return count
+		}
+	case gfx.Triangles:
+		return count / 3
+	case gfx.TriangleStrip, gfx.TriangleFan:
+		if count >= 3 {
+			return count - 2
+		}
+	}
+	return 0
+}
+
+// size returns the size in bytes of the data slice given to a Data* or
+// SubData* method.
+func size(data interface{}) int {
+	switch d := data.(type) {
+	case []int8:
+		return len(d)
+	case []uint8:
+		return len(d)
+	case []int16:
+		return 2 * len(d)
+	case []uint16:
+		return 2 * len(d)
+	case []int32:
+		return 4 * len(d)
+	case []uint32:
+		return 4 * len(d)
+	case []float32:
+		return 4 * len(d)
+	case []float64:
+		return 8 * len(d)
+	}
+	return 0
+}
@@ -0,0 +1,111 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stats
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/slimsag/gfx"
+	"github.com/slimsag/gfx/debug"
+	"github.com/slimsag/gfx/driver/null"
+)
+
+func TestFrame(t *testing.T) {
+	ctx := New(debug.Checker(null.New(4, 4)))
+	vs, fs := ctx.NewShader(gfx.VertexShader), ctx.NewShader(gfx.FragmentShader)
+	vs.Compile("void main() {}")
+	fs.Compile("void main() {}")
+	p := ctx.NewProgram()
+	p.Link(vs, fs)
+	b := ctx.NewBuffer(gfx.ArrayBuffer)
+	b.DataFloat32(make([]float32, 6), gfx.StaticDraw)
+	b.SubDataUint16(0, make([]uint16, 2))
+
+	s := ctx.NewState(ctx.UseProgram(p), ctx.Enable(gfx.Blend))
+	ctx.Load(s)
+	b.Draw(gfx.Triangles, 0, 6)
+	ctx.Load(s)
+	b.Draw(gfx.TriangleStrip, 0, 4)
+	ctx.Check()
+
+	want := Frame{
+		Draws:         2,
+		Vertices:      map[gfx.Primitive]int{gfx.Triangles: 6, gfx.TriangleStrip: 4},
+		Primitives:    map[gfx.Primitive]int{gfx.Triangles: 2, gfx.TriangleStrip: 2},
+		StateApplied:  2,
+		StateElided:   2,
+		BindsElided:   3,
+		BytesUploaded: 28,
+		Created:       4,
+		Checks:        1,
+	}
+	if f := ctx.Frame(); !reflect.DeepEqual(f, want) {
+		t.Errorf("frame 1 = %+v, want %+v", f, want)
+	}
+
+	b.Delete()
+	want = Frame{
+		Vertices:   map[gfx.Primitive]int{},
+		Primitives: map[gfx.Primitive]int{},
+		Deleted:    1,
+	}
+	if f := ctx.Frame(); !reflect.DeepEqual(f, want) {
+		t.Errorf("frame 2 = %+v, want %+v", f, want)
+	}
+	if f := ctx.Last(); f.Deleted != 1 {
+		t.Errorf("Last() = %+v", f)
+	}
+}
+
+func TestBindsElided(t *testing.T) {
+	ctx := New(null.New(4, 4))
+	b := ctx.NewBuffer(gfx.ArrayBuffer)
+	b.DataSize(8, gfx.StaticDraw)
+	b.SubDataFloat32(0, []float32{1})
+	if f := ctx.Frame(); f.BindsElided != 1 {
+		t.Errorf("frame 1: BindsElided = %d, want 1", f.BindsElided)
+	}
+
+	// Each frame only counts the binds elided during it.
+	b.SubDataFloat32(1, []float32{2})
+	b.SubDataFloat32(0, []float32{3})
+	if f := ctx.Frame(); f.BindsElided != 2 {
+		t.Errorf("frame 2: BindsElided = %d, want 2", f.BindsElided)
+	}
+	ctx.NewBuffer(gfx.ArrayBuffer).DataSize(4, gfx.StaticDraw)
+	if f := ctx.Frame(); f.BindsElided != 0 {
+		t.Errorf("frame 3: BindsElided = %d, want 0", f.BindsElided)
+	}
+}
+
+// published is the number of variables published by TestPublish, whose names
+// must be unique.
+var published int
+
+func TestPublish(t *testing.T) {
+	published++
+	name := fmt.Sprintf("stats_test%d", published)
+	ctx := New(null.New(4, 4))
+	ctx.Publish(name)
+	b := ctx.NewBuffer(gfx.ArrayBuffer)
+	b.Draw(gfx.Lines, 0, 4)
+	ctx.Frame()
+
+	var vars struct {
+		Draws    int
+		Vertices map[string]int
+		Created  int
+	}
+	if err := json.Unmarshal([]byte(expvar.Get(name).String()), &vars); err != nil {
+		t.Fatal(err)
+	}
+	if vars.Draws != 1 || vars.Vertices["Lines"] != 4 || vars.Created != 1 {
+		t.Errorf("published %+v", vars)
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trace
+
+import "github.com/slimsag/gfx"
+
+// bufferRecorder is like the Recorder type, but for a gfx.Buffer.
+type bufferRecorder struct {
+	b   gfx.Buffer
+	id  uint32
+	ctx *Recorder
+}
+
+func (b *bufferRecorder) traceID() uint32 { return b.id }
+
+// data records a call specifying the data of the buffer.
+func (b *bufferRecorder) data(op Op, data []byte, usage gfx.BufferUsage) {
+	b.ctx.record(op, b.id, Value{}, bytesValue(data), bufferUsage.encode(int(usage)))
+}
+
+// subData records a call updating the data of the buffer.
+func (b *bufferRecorder) subData(op Op, offset int, data []byte) {
+	b.ctx.record(op, b.id, Value{}, intValue(offset), bytesValue(data))
+}
+
+// DataSize implements the gfx.Buffer interface.
+func (b *bufferRecorder) DataSize(size int, usage gfx.BufferUsage) {
+	b.b.DataSize(size, usage)
+	b.ctx.record(BufferDataSize, b.id, Value{}, intValue(size), bufferUsage.encode(int(usage)))
+}
+
+// DataInt8 implements the gfx.Buffer interface.
+func (b *bufferRecorder) DataInt8(data []int8, usage gfx.BufferUsage) {
+	b.b.DataInt8(data, usage)
+	b.data(BufferDataInt8, bytesInt8(data), usage)
+}
+
+// DataUint8 implements the gfx.Buffer interface.
+func (b *bufferRecorder) DataUint8(data []uint8, usage gfx.BufferUsage) {
+	b.b.DataUint8(data, usage)
+	b.data(BufferDataUint8, data, usage)
+}
+
+// DataInt16 implements the gfx.Buffer interface.
+func (b *bufferRecorder) DataInt16(data []int16, usage gfx.BufferUsage) {
+	b.b.DataInt16(data, usage)
+	b.data(BufferDataInt16, bytesInt16(data), usage)
+}
+
+// DataUint16 implements the gfx.Buffer interface.
+func (b *bufferRecorder) DataUint16(data []uint16, usage gfx.BufferUsage) {
+	b.b.DataUint16(data, usage)
+	b.data(BufferDataUint16, bytesUint16(data), usage)
+}
+
+// DataInt32 implements the gfx.Buffer interface.
+func (b *bufferRecorder) DataInt32(data []int32, usage gfx.BufferUsage) {
+	b.b.DataInt32(data, usage)
+	b.data(BufferDataInt32, bytesInt32(data), usage)
+}
+
+// DataUint32 implements the gfx.Buffer interface.
+func (b *bufferRecorder) DataUint32(data []uint32, usage gfx.BufferUsage) {
+	b.b.DataUint32(data, usage)
+	b.data(BufferDataUint32, bytesUint32(data), usage)
+}
+
+// DataFloat32 implements the gfx.Buffer interface.
+func (b *bufferRecorder) DataFloat32(data []float32, usage gfx.BufferUsage) {
+	b.b.DataFloat32(data, usage)
+	b.data(BufferDataFloat32, bytesFloat32(data), usage)
+}
+
+// DataFloat64 implements the gfx.Buffer interface.
+func (b *bufferRecorder) DataFloat64(data []float64, usage gfx.BufferUsage) {
+	b.b.DataFloat64(data, usage)
+	b.data(BufferDataFloat64, bytesFloat64(data), usage)
+}
+
+// SubDataInt8 implements the gfx.Buffer interface.
+func (b *bufferRecorder) SubDataInt8(offset int, data []int8) {
+	b.b.SubDataInt8(offset, data)
+	b.subData(BufferSubDataInt8, offset, bytesInt8(data))
+}
+
+// SubDataUint8 implements the gfx.Buffer interface.
+func (b *bufferRecorder) SubDataUint8(offset int, data []uint8) {
+	b.b.SubDataUint8(offset, data)
+	b.subData(BufferSubDataUint8, offset, data)
+}
+
+// SubDataInt16 implements the gfx.Buffer interface.
+func (b *bufferRecorder) SubDataInt16(offset int, data []int16) {
+	b.b.SubDataInt16(offset, data)
+	b.subData(BufferSubDataInt16, offset, bytesInt16(data))
+}
+
+// SubDataUint16 implements the gfx.Buffer interface.
+func (b *bufferRecorder) SubDataUint16(offset int, data []uint16) {
+	b.b.SubDataUint16(offset, data)
+	b.subData(BufferSubDataUint16, offset, bytesUint16(data))
+}
+
+// SubDataInt32 implements the gfx.Buffer interface.
+func (b *bufferRecorder) SubDataInt32(offset int, data []int32) {
+	b.b.SubDataInt32(offset, data)
+	b.subData(BufferSubDataInt32, offset, bytesInt32(data))
+}
+
+// SubDataUint32 implements the gfx.Buffer interface.
+func (b *bufferRecorder) SubDataUint32(offset int, data []uint32) {
+	b.b.SubDataUint32(offset, data)
+	b.subData(BufferSubDataUint32, offset, bytesUint32(data))
+}
+
+// SubDataFloat32 implements the gfx.Buffer interface.
+func (b *bufferRecorder) SubDataFloat32(offset int, data []float32) {
+	b.b.SubDataFloat32(offset, data)
+	b.subData(BufferSubDataFloat32, offset, bytesFloat32(data))
+}
+
+// SubDataFloat64 implements the gfx.Buffer interface.
+func (b *bufferRecorder) SubDataFloat64(offset int, data []float64) {
+	b.b.SubDataFloat64(offset, data)
+	b.subData(BufferSubDataFloat64, offset, bytesFloat64(data))
+}
+
+// Draw implements the gfx.Buffer interface.
+func (b *bufferRecorder) Draw(p gfx.Primitive, first, count int) {
+	b.b.Draw(p, first, count)
+	b.ctx.record(BufferDraw, b.id, Value{}, primitive.encode(int(p)), intValue(first), intValue(count))
+}
+
+// VertexAttribPointer implements the gfx.Buffer interface.
+func (b *bufferRecorder) VertexAttribPointer(l gfx.AttribLocation, size int, normalized bool, stride, offset int) {
+	inner, id := location(l)
+	b.b.VertexAttribPointer(inner, size, normalized, stride, offset)
+	b.ctx.record(BufferVertexAttribPointer, b.id, Value{}, id, intValue(size), boolValue(normalized), intValue(stride), intValue(offset))
+}
+
+// Delete implements the gfx.Object interface.
+func (b *bufferRecorder) Delete() {
+	b.b.Delete()
+	b.ctx.record(BufferDelete, b.id, Value{})
+}
+
+// Object implements the gfx.Object interface.
+func (b *bufferRecorder) Object() interface{} {
+	return b.b.Object()
+}
@@ -0,0 +1,279 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trace
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// ErrFormat is returned when reading data that is not a valid trace.
+var ErrFormat = errors.New("trace: invalid trace data")
+
+// Writer writes the records of a trace session to an io.Writer.
+type Writer struct {
+	w   io.Writer
+	buf []byte
+	err error
+}
+
+// NewWriter returns a new writer for a trace session, which writes the Header
+// record to w. The data may be appended to an existing trace.
+func NewWriter(w io.Writer) *Writer {
+	tw := &Writer{w: w}
+	tw.Write(&Call{
+		Op:   Header,
+		Args: []Value{{Kind: String, String: Magic}, {Kind: Int, Int: Version}},
+	})
+	return tw
+}
+
+// Write writes the given record. Each record is written using a single call
+// to the Write method of the underlying io.Writer, so that a trace being
+// written can be read concurrently. After an error, no more records are
+// written and the error is returned again.
+func (w *Writer) Write(c *Call) error {
+	if w.err != nil {
+		return w.err
+	}
+	body := appendBody(nil, c)
+	w.buf = appendUvarint(w.buf[:0], uint64(c.Op))
+	w.buf = appendUvarint(w.buf, uint64(len(body)))
+	w.buf = append(w.buf, body...)
+	_, w.err = w.w.Write(w.buf)
+	return w.err
+}
+
+// Err returns the first error that occurred while writing, if any.
+func (w *Writer) Err() error {
+	return w.err
+}
+
+func appendUvarint(b []byte, x uint64) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	return append(b, tmp[:binary.PutUvarint(tmp[:], x)]...)
+}
+
+func appendBody(b []byte, c *Call) []byte {
+	b = appendUvarint(b, uint64(c.Object))
+	b = appendUvarint(b, uint64(len(c.Args)))
+	for _, a := range c.Args {
+		b = appendValue(b, a)
+	}
+	return appendValue(b, c.Result)
+}
+
+func appendValue(b []byte, v Value) []byte {
+	b = append(b, byte(v.Kind))
+	switch v.Kind {
+	case Bool:
+		if v.Bool {
+			return append(b, 1)
+		}
+		return append(b, 0)
+	case Int:
+		var tmp [binary.MaxVarintLen64]byte
+		return append(b, tmp[:binary.PutVarint(tmp[:], v.Int)]...)
+	case Float32:
+		var tmp [4]byte
+		binary.LittleEndian.PutUint32(tmp[:], math.Float32bits(float32(v.Float)))
+		return append(b, tmp[:]...)
+	case Float64:
+		var tmp [8]byte
+		binary.LittleEndian.PutUint64(tmp[:], math.Float64bits(v.Float))
+		return append(b, tmp[:]...)
+	case String:
+		b = appendUvarint(b, uint64(len(v.String)))
+		return append(b, v.String...)
+	case Bytes:
+		b = appendUvarint(b, uint64(len(v.Bytes)))
+		return append(b, v.Bytes...)
+	case ID:
+		return appendUvarint(b, uint64(v.ID))
+	case StateValue:
+		b = appendUvarint(b, uint64(v.Call.Op))
+		return appendBody(b, v.Call)
+	}
+	return b
+}
+
+// Reader reads the records of a trace.
+type Reader struct {
+	r   *bufio.Reader
+	buf bytes.Buffer
+
+	// Version is the version of the format of the current session.
+	Version int
+}
+
+// NewReader returns a new reader for the trace read from r, after reading
+// its first Header record.
+func NewReader(r io.Reader) (*Reader, error) {
+	tr := &Reader{r: bufio.NewReader(r)}
+	c, err := tr.Next()
+	if err == io.EOF {
+		return nil, ErrFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+	if c.Op != Header {
+		return nil, ErrFormat
+	}
+	return tr, nil
+}
+
+// Next returns the next record of the trace, or io.EOF at its end. Header
+// records of the sessions following the first one are returned like other
+// records. An incomplete last record (e.g. of a trace still being written)
+// results in io.ErrUnexpectedEOF.
+func (r *Reader) Next() (*Call, error) {
+	op, err := binary.ReadUvarint(r.r)
+	if err != nil {
+		return nil, err
+	}
+	size, err := binary.ReadUvarint(r.r)
+	if err != nil {
+		return nil, unexpected(err)
+	}
+	r.buf.Reset()
+	if n, err := io.CopyN(&r.buf, r.r, int64(size)); n < int64(size) {
+		return nil, unexpected(err)
+	}
+	if op > math.MaxUint32 {
+		return nil, ErrFormat
+	}
+	d := decoder{b: r.buf.Bytes()}
+	c := d.body(Op(op), 0)
+	if d.err != nil {
+		return nil, d.err
+	}
+	if c.Op == Header {
+		if len(c.Args) != 2 || c.Args[0].Kind != String || c.Args[0].String != Magic || c.Args[1].Kind != Int {
+			return nil, ErrFormat
+		}
+		if v := c.Args[1].Int; v < 1 || v > Version {
+			return nil, fmt.Errorf("trace: unsupported version %d", v)
+		}
+		r.Version = int(c.Args[1].Int)
+	}
+	return c, nil
+}
+
+func unexpected(err error) error {
+	if err == io.EOF || err == nil {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// maxDepth is the maximum nesting of state values in a record.
+const maxDepth = 8
+
+// decoder decodes the body of a record.
+type decoder struct {
+	b   []byte
+	err error
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	x, n := binary.Uvarint(d.b)
+	if n <= 0 {
+		d.err = ErrFormat
+		return 0
+	}
+	d.b = d.b[n:]
+	return x
+}
+
+func (d *decoder) bytes(n uint64) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n > uint64(len(d.b)) {
+		d.err = ErrFormat
+		return nil
+	}
+	b := d.b[:n:n]
+	d.b = d.b[n:]
+	return b
+}
+
+func (d *decoder) body(op Op, depth int) *Call {
+	c := &Call{Op: op}
+	o := d.uvarint()
+	if o > math.MaxUint32 {
+		d.err = ErrFormat
+	}
+	c.Object = uint32(o)
+	n := d.uvarint()
+	if n > uint64(len(d.b)) {
+		// Each argument takes at least one byte.
+		d.err = ErrFormat
+	}
+	for i := uint64(0); i < n && d.err == nil; i++ {
+		c.Args = append(c.Args, d.value(depth))
+	}
+	c.Result = d.value(depth)
+	return c
+}
+
+func (d *decoder) value(depth int) Value {
+	kind := d.bytes(1)
+	if d.err != nil {
+		return Value{}
+	}
+	v := Value{Kind: Kind(kind[0])}
+	switch v.Kind {
+	case Nil:
+	case Bool:
+		b := d.bytes(1)
+		v.Bool = d.err == nil && b[0] != 0
+	case Int:
+		x, n := binary.Varint(d.b)
+		if n <= 0 {
+			d.err = ErrFormat
+			break
+		}
+		d.b = d.b[n:]
+		v.Int = x
+	case Float32:
+		if b := d.bytes(4); d.err == nil {
+			v.Float = float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+		}
+	case Float64:
+		if b := d.bytes(8); d.err == nil {
+			v.Float = math.Float64frombits(binary.LittleEndian.Uint64(b))
+		}
+	case String:
+		v.String = string(d.bytes(d.uvarint()))
+	case Bytes:
+		v.Bytes = append([]byte(nil), d.bytes(d.uvarint())...)
+	case ID:
+		id := d.uvarint()
+		if id > math.MaxUint32 {
+			d.err = ErrFormat
+		}
+		v.ID = uint32(id)
+	case StateValue:
+		op := d.uvarint()
+		if depth == maxDepth || op > math.MaxUint32 {
+			d.err = ErrFormat
+			break
+		}
+		v.Call = d.body(Op(op), depth+1)
+	default:
+		d.err = ErrFormat
+	}
+	return v
+}
@@ -0,0 +1,146 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trace
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/slimsag/gfx"
+)
+
+// enum is an enumeration of the gfx package. The constants of most
+// enumerations share a single iota sequence, so their values change whenever
+// a constant is inserted before them: traces instead encode each value as its
+// index within its enumeration, e.g. 0 for gfx.Points and 4 for
+// gfx.Triangles.
+type enum struct {
+	// typ is the name of the type, e.g. "Primitive".
+	typ string
+
+	// first is the value of the first constant.
+	first int
+
+	// names are the names of the constants, in order.
+	names []string
+
+	// mask is whether values are bitmasks of the constants, which are the
+	// bits 1, 2, 4 and so on, and are encoded as is.
+	mask bool
+}
+
+// encode returns the trace encoding of the gfx value x.
+func (e *enum) encode(x int) Value {
+	if e.mask {
+		return intValue(x)
+	}
+	return intValue(x - e.first)
+}
+
+// decode returns the gfx value encoded as x.
+func (e *enum) decode(x int) int {
+	if e.mask {
+		return x
+	}
+	return x + e.first
+}
+
+// valid reports whether x encodes a constant of the enumeration, or for
+// masks, a combination of them.
+func (e *enum) valid(x int) bool {
+	if e.mask {
+		return x&^(1<<uint(len(e.names))-1) == 0
+	}
+	return x >= 0 && x < len(e.names)
+}
+
+// name returns the name of the gfx constant encoded as x, see EnumName.
+func (e *enum) name(x int64) string {
+	if !e.mask {
+		if x >= 0 && x < int64(len(e.names)) {
+			return e.names[x]
+		}
+		return fmt.Sprintf("%s(%d)", e.typ, x+int64(e.first))
+	}
+	var names []string
+	for i, name := range e.names {
+		if b := int64(1) << uint(i); x&b != 0 {
+			names = append(names, name)
+			x &^= b
+		}
+	}
+	if x != 0 || len(names) == 0 {
+		names = append(names, fmt.Sprintf("%s(%d)", e.typ, x))
+	}
+	return strings.Join(names, "|")
+}
+
+// The enumerations of the gfx package, whose constants are named in order.
+// Constants added to an enumeration of the gfx package must come after the
+// existing ones, such that the indices recorded in traces remain valid.
+var (
+	textureTarget = &enum{typ: "TextureTarget", first: int(gfx.Texture2D), names: []string{
+		"Texture2D",
+		"TextureCubeMapPositiveX", "TextureCubeMapNegativeX",
+		"TextureCubeMapPositiveY", "TextureCubeMapNegativeY",
+		"TextureCubeMapPositiveZ", "TextureCubeMapNegativeZ",
+	}}
+	textureType           = &enum{typ: "TextureType", first: int(gfx.TextureType2D), names: []string{"TextureType2D", "TextureTypeCubeMap"}}
+	renderbufferFormat    = &enum{typ: "RenderbufferFormat", first: int(gfx.RGBA4), names: []string{"RGBA4", "RGB565", "RGB5A1", "DepthComponent16"}}
+	framebufferAttachment = &enum{typ: "FramebufferAttachment", first: int(gfx.ColorAttachment0), names: []string{"ColorAttachment0", "DepthAttachment", "StencilAttachment", "DepthStencilAttachment"}}
+	bufferUsage           = &enum{typ: "BufferUsage", first: int(gfx.StaticDraw), names: []string{"StaticDraw", "DynamicDraw", "StreamDraw"}}
+	bufferType            = &enum{typ: "BufferType", first: int(gfx.ArrayBuffer), names: []string{"ArrayBuffer", "ElementArrayBuffer"}}
+	feature               = &enum{typ: "Feature", first: int(gfx.Blend), names: []string{"Blend", "DepthTest", "CullFace", "PolygonOffsetFill", "ScissorTest", "Dither"}}
+	orientation           = &enum{typ: "Orientation", first: int(gfx.CCW), names: []string{"CCW", "CW"}}
+	facet                 = &enum{typ: "Facet", first: int(gfx.Front), names: []string{"Front", "Back", "FrontAndBack"}}
+	shaderType            = &enum{typ: "ShaderType", first: int(gfx.VertexShader), names: []string{"VertexShader", "FragmentShader"}}
+	blendEquation         = &enum{typ: "BlendEquation", first: int(gfx.FuncAdd), names: []string{"FuncAdd", "FuncSubtract", "FuncReverseSubtract"}}
+	primitive             = &enum{typ: "Primitive", first: int(gfx.Points), names: []string{"Points", "Lines", "LineStrip", "LineLoop", "Triangles", "TriangleStrip", "TriangleFan"}}
+	clearMask             = &enum{typ: "ClearMask", names: []string{"ColorBuffer", "DepthBuffer", "StencilBuffer"}, mask: true}
+)
+
+// enums maps operations to their enumeration arguments, by index, and to the
+// enumeration of their result, at index -1.
+var enums = map[Op]map[int]*enum{
+	ContextNewShader:        {0: shaderType},
+	ContextNewTexture:       {0: textureType},
+	ContextNewBuffer:        {0: bufferType},
+	ContextBlendEquation:    {0: blendEquation},
+	ContextEnable:           {0: feature},
+	ContextDisable:          {0: feature},
+	ContextCullFace:         {0: facet},
+	ContextFrontFace:        {0: orientation},
+	FramebufferClear:        {0: clearMask},
+	FramebufferTexture2D:    {0: framebufferAttachment, 1: textureTarget},
+	FramebufferRenderbuffer: {0: framebufferAttachment},
+	RenderbufferStorage:     {0: renderbufferFormat},
+	TextureType:             {-1: textureType},
+	BufferDataSize:          {1: bufferUsage},
+	BufferDataInt8:          {1: bufferUsage},
+	BufferDataUint8:         {1: bufferUsage},
+	BufferDataInt16:         {1: bufferUsage},
+	BufferDataUint16:        {1: bufferUsage},
+	BufferDataInt32:         {1: bufferUsage},
+	BufferDataUint32:        {1: bufferUsage},
+	BufferDataFloat32:       {1: bufferUsage},
+	BufferDataFloat64:       {1: bufferUsage},
+	BufferDraw:              {0: primitive},
+}
+
+// EnumName returns the name of the gfx constant encoded by an Int argument of
+// a call with the given operation, e.g. "Triangles" for the argument at index
+// 0 of BufferDraw, and false if the argument is not an enumeration. The index
+// -1 stands for the result of the call.
+//
+// Values that have no name are formatted as conversions of their gfx value,
+// e.g. "Primitive(99)", and clear masks as names joined by "|", e.g.
+// "ColorBuffer|DepthBuffer".
+func EnumName(op Op, arg int, x int64) (string, bool) {
+	e := enums[op][arg]
+	if e == nil {
+		return "", false
+	}
+	return e.name(x), true
+}
@@ -0,0 +1,398 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trace
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Magic is the first argument of the header record of a trace.
+const Magic = "gfxtrace"
+
+// Version is the version of the trace format written by this package. It is
+// incremented whenever the meaning of existing records changes; new
+// operations may be added without changing it.
+const Version = 1
+
+// Op identifies the method of a call, or a record that is not a call (Header
+// and Frame). The values of existing operations never change.
+type Op uint32
+
+const (
+	// Header begins each trace session. Its arguments are the String Magic
+	// and the Int version of the format. A trace may hold multiple sessions
+	// (e.g. when recording appends to an existing file); the identities of
+	// each session are independent of the others.
+	Header Op = iota
+
+	// Frame marks the end of a frame. It has no arguments.
+	Frame
+
+	ContextNewFramebuffer
+	ContextNewRenderbuffer
+	ContextNewShader
+	ContextNewTexture
+	ContextNewBuffer
+	ContextNewProgram
+	ContextNewState
+	ContextLoad
+	ContextBake
+	ContextCurrent
+	ContextDescribe
+	ContextPushState
+	ContextPopState
+	ContextBlendColor
+	ContextBlendEquation
+	ContextDepthMask
+	ContextEnable
+	ContextDisable
+	ContextUseProgram
+	ContextViewport
+	ContextScissor
+	ContextLineWidth
+	ContextColorMask
+	ContextCullFace
+	ContextFrontFace
+	ContextEnableVertexAttribArray
+	ContextCheck
+	ContextFlush
+	ContextFinish
+	ContextInvalidate
+	ContextResync
+
+	FramebufferNewState
+	FramebufferLoad
+	FramebufferCurrent
+	FramebufferDescribe
+	FramebufferPushState
+	FramebufferPopState
+	FramebufferClearColor
+	FramebufferClearDepth
+	FramebufferClearStencil
+	FramebufferClear
+	FramebufferReadPixelsUint8
+	FramebufferTexture2D
+	FramebufferRenderbuffer
+	FramebufferStatus
+	FramebufferDelete
+
+	RenderbufferStorage
+	RenderbufferDelete
+
+	ShaderCompile
+	ShaderInfoLog
+	ShaderDelete
+
+	TextureType
+	TextureDelete
+
+	BufferDataSize
+	BufferDataInt8
+	BufferDataUint8
+	BufferDataInt16
+	BufferDataUint16
+	BufferDataInt32
+	BufferDataUint32
+	BufferDataFloat32
+	BufferDataFloat64
+	BufferSubDataInt8
+	BufferSubDataUint8
+	BufferSubDataInt16
+	BufferSubDataUint16
+	BufferSubDataInt32
+	BufferSubDataUint32
+	BufferSubDataFloat32
+	BufferSubDataFloat64
+	BufferDraw
+	BufferVertexAttribPointer
+	BufferDelete
+
+	ProgramLink
+	ProgramInfoLog
+	ProgramAttribLocation
+	ProgramUniformLocation
+	ProgramUniform1fv
+	ProgramUniform1iv
+	ProgramUniform2fv
+	ProgramUniform2iv
+	ProgramUniform3fv
+	ProgramUniform3iv
+	ProgramUniform4fv
+	ProgramUniform4iv
+	ProgramUniformMatrix2fv
+	ProgramUniformMatrix3fv
+	ProgramUniformMatrix4fv
+	ProgramDelete
+
+	numOps
+)
+
+// opNames are the method names of the operations, qualified by the name of
+// their receiver's type.
+var opNames = [numOps]string{
+	"Header",
+	"Frame",
+
+	"Context.NewFramebuffer",
+	"Context.NewRenderbuffer",
+	"Context.NewShader",
+	"Context.NewTexture",
+	"Context.NewBuffer",
+	"Context.NewProgram",
+	"Context.NewState",
+	"Context.Load",
+	"Context.Bake",
+	"Context.Current",
+	"Context.Describe",
+	"Context.PushState",
+	"Context.PopState",
+	"Context.BlendColor",
+	"Context.BlendEquation",
+	"Context.DepthMask",
+	"Context.Enable",
+	"Context.Disable",
+	"Context.UseProgram",
+	"Context.Viewport",
+	"Context.Scissor",
+	"Context.LineWidth",
+	"Context.ColorMask",
+	"Context.CullFace",
+	"Context.FrontFace",
+	"Context.EnableVertexAttribArray",
+	"Context.Check",
+	"Context.Flush",
+	"Context.Finish",
+	"Context.Invalidate",
+	"Context.Resync",
+
+	"Framebuffer.NewState",
+	"Framebuffer.Load",
+	"Framebuffer.Current",
+	"Framebuffer.Describe",
+	"Framebuffer.PushState",
+	"Framebuffer.PopState",
+	"Framebuffer.ClearColor",
+	"Framebuffer.ClearDepth",
+	"Framebuffer.ClearStencil",
+	"Framebuffer.Clear",
+	"Framebuffer.ReadPixelsUint8",
+	"Framebuffer.Texture2D",
+	"Framebuffer.Renderbuffer",
+	"Framebuffer.Status",
+	"Framebuffer.Delete",
+
+	"Renderbuffer.Storage",
+	"Renderbuffer.Delete",
+
+	"Shader.Compile",
+	"Shader.InfoLog",
+	"Shader.Delete",
+
+	"Texture.Type",
+	"Texture.Delete",
+
+	"Buffer.DataSize",
+	"Buffer.DataInt8",
+	"Buffer.DataUint8",
+	"Buffer.DataInt16",
+	"Buffer.DataUint16",
+	"Buffer.DataInt32",
+	"Buffer.DataUint32",
+	"Buffer.DataFloat32",
+	"Buffer.DataFloat64",
+	"Buffer.SubDataInt8",
+	"Buffer.SubDataUint8",
+	"Buffer.SubDataInt16",
+	"Buffer.SubDataUint16",
+	"Buffer.SubDataInt32",
+	"Buffer.SubDataUint32",
+	"Buffer.SubDataFloat32",
+	"Buffer.SubDataFloat64",
+	"Buffer.Draw",
+	"Buffer.VertexAttribPointer",
+	"Buffer.Delete",
+
+	"Program.Link",
+	"Program.InfoLog",
+	"Program.AttribLocation",
+	"Program.UniformLocation",
+	"Program.Uniform1fv",
+	"Program.Uniform1iv",
+	"Program.Uniform2fv",
+	"Program.Uniform2iv",
+	"Program.Uniform3fv",
+	"Program.Uniform3iv",
+	"Program.Uniform4fv",
+	"Program.Uniform4iv",
+	"Program.UniformMatrix2fv",
+	"Program.UniformMatrix3fv",
+	"Program.UniformMatrix4fv",
+	"Program.Delete",
+}
+
+// String returns the name of the operation, e.g. "Buffer.DataFloat32", or
+// e.g. "Op(123)" for an operation unknown to this version of the package.
+func (o Op) String() string {
+	if o < numOps {
+		return opNames[o]
+	}
+	return "Op(" + strconv.FormatUint(uint64(o), 10) + ")"
+}
+
+// Receiver returns the name of the type of the receiver of the operation,
+// e.g. "Buffer", or "" for Header, Frame and unknown operations.
+func (o Op) Receiver() string {
+	name := o.String()
+	if i := strings.IndexByte(name, '.'); i >= 0 {
+		return name[:i]
+	}
+	return ""
+}
+
+// ParseOp returns the operation with the given name (see Op.String).
+func ParseOp(name string) (Op, bool) {
+	for o, n := range opNames {
+		if n == name {
+			return Op(o), true
+		}
+	}
+	return 0, false
+}
+
+// Kind is the kind of a Value.
+type Kind uint8
+
+const (
+	// Nil is a nil object, location, state or error.
+	Nil Kind = iota
+
+	// Bool is a bool.
+	Bool
+
+	// Int is an integer, including the gfx enumerations (e.g. a BufferUsage
+	// or a Feature) which are stored as their numeric value.
+	Int
+
+	// Float32 and Float64 are floating-point numbers of the given size.
+	Float32
+	Float64
+
+	// String is a string, e.g. shader source code or an info log.
+	String
+
+	// Bytes is a slice, e.g. buffer data or uniform values, stored as the
+	// little-endian encoding of its elements. Its element type is implied by
+	// the operation, e.g. float32 for Buffer.DataFloat32.
+	Bytes
+
+	// ID is the identity of an object, attribute or uniform location, or
+	// state, assigned when it was returned by a call. Identities are unique
+	// within a session and never zero.
+	ID
+
+	// StateValue is a context or framebuffer state value, stored as the call
+	// that created it (e.g. Context.BlendColor).
+	StateValue
+)
+
+var kindNames = [...]string{"Nil", "Bool", "Int", "Float32", "Float64", "String", "Bytes", "ID", "StateValue"}
+
+// String returns the name of the kind, e.g. "Bytes".
+func (k Kind) String() string {
+	if int(k) < len(kindNames) {
+		return kindNames[k]
+	}
+	return "Kind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// Value is an argument or result of a call. Only the field corresponding to
+// its kind is used.
+type Value struct {
+	Kind   Kind
+	Bool   bool
+	Int    int64
+	Float  float64 // Float32 and Float64.
+	String string
+	Bytes  []byte
+	ID     uint32
+	Call   *Call // StateValue.
+}
+
+// Format returns a textual representation of the value, e.g. "true", "1.5",
+// "#3" for an identity or "[48 bytes]".
+func (v Value) Format() string {
+	switch v.Kind {
+	case Nil:
+		return "nil"
+	case Bool:
+		return strconv.FormatBool(v.Bool)
+	case Int:
+		return strconv.FormatInt(v.Int, 10)
+	case Float32:
+		return strconv.FormatFloat(v.Float, 'g', -1, 32)
+	case Float64:
+		return strconv.FormatFloat(v.Float, 'g', -1, 64)
+	case String:
+		return strconv.Quote(v.String)
+	case Bytes:
+		return fmt.Sprintf("[%d bytes]", len(v.Bytes))
+	case ID:
+		return "#" + strconv.FormatUint(uint64(v.ID), 10)
+	case StateValue:
+		if v.Call == nil {
+			return "nil"
+		}
+		return v.Call.Op.String()[len(v.Call.Op.Receiver())+1:] + formatArgs(v.Call.Args)
+	}
+	return v.Kind.String()
+}
+
+// Call is a record of a trace: a method call made on a context or one of its
+// objects, or a Header or Frame record.
+type Call struct {
+	Op Op
+
+	// Object is the identity of the object whose method was called, or zero
+	// for the context and its default framebuffer.
+	Object uint32
+
+	// Args are the arguments of the method, in order (the variadic values of
+	// NewState are individual arguments). Objects, locations and states are
+	// given by their identity or as Nil, slices as Bytes and state values as
+	// StateValue.
+	Args []Value
+
+	// Result is the value returned by the method, or Nil if it returns
+	// nothing. New objects, locations and states are given the identity by
+	// which later calls refer to them. Errors (of Framebuffer.Status and the
+	// panic of Context.Check) are given as their String message, the info
+	// of Describe as its String, and the pixels read by ReadPixelsUint8 as
+	// Bytes.
+	Result Value
+}
+
+// String returns e.g. "Buffer(3).DataFloat32([48 bytes], 35)" or
+// "Context(0).NewBuffer(1) = #3".
+func (c *Call) String() string {
+	recv := c.Op.Receiver()
+	if recv == "" {
+		return c.Op.String() + formatArgs(c.Args)
+	}
+	s := fmt.Sprintf("%s(%d)%s%s", recv, c.Object, c.Op.String()[len(recv):], formatArgs(c.Args))
+	if c.Result.Kind != Nil {
+		s += " = " + c.Result.Format()
+	}
+	return s
+}
+
+func formatArgs(args []Value) string {
+	parts := make([]string, len(args))
+	for i, a := range args {
+		parts[i] = a.Format()
+	}
+	return "(" + strings.Join(parts, ", ") + ")"
+}
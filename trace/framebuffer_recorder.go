@@ -0,0 +1,134 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trace
+
+import "github.com/slimsag/gfx"
+
+// fbRecorder is like the Recorder type, but for a gfx.Framebuffer. Its
+// identity is zero for the default framebuffer.
+type fbRecorder struct {
+	fb  gfx.Framebuffer
+	id  uint32
+	ctx *Recorder
+
+	// current is the currently loaded state, and stack the states pushed by
+	// PushState.
+	current gfx.FramebufferState
+	stack   []gfx.FramebufferState
+}
+
+func (f *fbRecorder) traceID() uint32 { return f.id }
+
+// NewState implements the gfx.FramebufferStateProvider interface.
+func (f *fbRecorder) NewState(values ...gfx.FramebufferStateValue) gfx.FramebufferState {
+	inner := make([]gfx.FramebufferStateValue, len(values))
+	args := make([]Value, len(values))
+	for i, v := range values {
+		inner[i], args[i] = stateValue(v)
+	}
+	s := &stateRef{s: f.fb.NewState(inner...), id: f.ctx.newID()}
+	f.ctx.record(FramebufferNewState, f.id, idValue(s.id), args...)
+	return s
+}
+
+// Load implements the gfx.FramebufferStateProvider interface.
+func (f *fbRecorder) Load(s gfx.FramebufferState) {
+	inner, id := state(s)
+	f.fb.Load(inner)
+	f.current = s
+	f.ctx.record(FramebufferLoad, f.id, Value{}, id)
+}
+
+// Current implements the gfx.FramebufferStateProvider interface.
+func (f *fbRecorder) Current() gfx.FramebufferState {
+	f.fb.Current()
+	_, id := state(f.current)
+	f.ctx.record(FramebufferCurrent, f.id, id)
+	return f.current
+}
+
+// Describe implements the gfx.FramebufferStateProvider interface.
+func (f *fbRecorder) Describe(s gfx.FramebufferState) gfx.StateInfo {
+	inner, id := state(s)
+	info := f.fb.Describe(inner)
+	f.ctx.record(FramebufferDescribe, f.id, stringValue(info.String()), id)
+	return info
+}
+
+// PushState implements the gfx.FramebufferStateProvider interface.
+func (f *fbRecorder) PushState() {
+	f.fb.PushState()
+	f.stack = append(f.stack, f.current)
+	f.ctx.record(FramebufferPushState, f.id, Value{})
+}
+
+// PopState implements the gfx.FramebufferStateProvider interface.
+func (f *fbRecorder) PopState() {
+	f.fb.PopState()
+	f.current = nil
+	if n := len(f.stack); n > 0 {
+		f.current = f.stack[n-1]
+		f.stack = f.stack[:n-1]
+	}
+	f.ctx.record(FramebufferPopState, f.id, Value{})
+}
+
+// ClearColor implements the gfx.FramebufferStateProvider interface.
+func (f *fbRecorder) ClearColor(r, g, b, a float32) gfx.FramebufferStateValue {
+	return f.ctx.value(f.fb.ClearColor(r, g, b, a), FramebufferClearColor, f.id, float32Value(r), float32Value(g), float32Value(b), float32Value(a))
+}
+
+// ClearDepth implements the gfx.FramebufferStateProvider interface.
+func (f *fbRecorder) ClearDepth(depth float64) gfx.FramebufferStateValue {
+	return f.ctx.value(f.fb.ClearDepth(depth), FramebufferClearDepth, f.id, Value{Kind: Float64, Float: depth})
+}
+
+// ClearStencil implements the gfx.FramebufferStateProvider interface.
+func (f *fbRecorder) ClearStencil(stencil int) gfx.FramebufferStateValue {
+	return f.ctx.value(f.fb.ClearStencil(stencil), FramebufferClearStencil, f.id, intValue(stencil))
+}
+
+// Clear implements the gfx.Framebuffer interface.
+func (f *fbRecorder) Clear(m gfx.ClearMask) {
+	f.fb.Clear(m)
+	f.ctx.record(FramebufferClear, f.id, Value{}, clearMask.encode(int(m)))
+}
+
+// ReadPixelsUint8 implements the gfx.Framebuffer interface. The pixels read
+// are recorded, along with the length of dst.
+func (f *fbRecorder) ReadPixelsUint8(x, y, width, height int, dst []uint8) {
+	f.fb.ReadPixelsUint8(x, y, width, height, dst)
+	f.ctx.record(FramebufferReadPixelsUint8, f.id, bytesValue(dst), intValue(x), intValue(y), intValue(width), intValue(height), intValue(len(dst)))
+}
+
+// Texture2D implements the gfx.Framebuffer interface.
+func (f *fbRecorder) Texture2D(attachment gfx.FramebufferAttachment, target gfx.TextureTarget, tex gfx.Texture) {
+	f.fb.Texture2D(attachment, target, tex)
+	f.ctx.record(FramebufferTexture2D, f.id, Value{}, framebufferAttachment.encode(int(attachment)), textureTarget.encode(int(target)), object(tex))
+}
+
+// Renderbuffer implements the gfx.Framebuffer interface.
+func (f *fbRecorder) Renderbuffer(attachment gfx.FramebufferAttachment, buf gfx.Renderbuffer) {
+	f.fb.Renderbuffer(attachment, buf)
+	f.ctx.record(FramebufferRenderbuffer, f.id, Value{}, framebufferAttachment.encode(int(attachment)), object(buf))
+}
+
+// Status implements the gfx.Framebuffer interface.
+func (f *fbRecorder) Status() error {
+	err := f.fb.Status()
+	f.ctx.record(FramebufferStatus, f.id, errorValue(err))
+	return err
+}
+
+// Delete implements the gfx.Object interface.
+func (f *fbRecorder) Delete() {
+	f.fb.Delete()
+	f.ctx.record(FramebufferDelete, f.id, Value{})
+}
+
+// Object implements the gfx.Object interface.
+func (f *fbRecorder) Object() interface{} {
+	return f.fb.Object()
+}
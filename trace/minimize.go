@@ -0,0 +1,270 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trace
+
+import "github.com/slimsag/gfx"
+
+// Minimize returns a minimal subsequence of the given calls for which the
+// predicate holds, e.g. one of those returned by Panics and PixelIs, so that
+// a trace of thousands of calls reproducing a problem becomes a handful of
+// calls that can be read. If the predicate does not hold for all the calls,
+// they are returned as-is.
+//
+// Calls are removed by delta debugging: whole frames first, then all the
+// calls involving each object, location or state that can be removed, then
+// individual calls, until no single call can be removed. Header records are
+// never removed. The predicate is called with sequences in which a call may
+// refer to an identity whose creation was removed; Player reports such calls
+// as invalid, and the predicates of this package then do not hold.
+func Minimize(calls []*Call, holds func(calls []*Call) bool) []*Call {
+	m := &minimizer{calls: calls, kept: make([]bool, len(calls)), holds: holds}
+	for i := range m.kept {
+		m.kept[i] = true
+	}
+	if !holds(calls) {
+		return calls
+	}
+
+	// Remove whole frames.
+	var frames [][]int
+	var frame []int
+	for i, c := range calls {
+		if c.Op != Header {
+			frame = append(frame, i)
+		}
+		if c.Op == Frame {
+			frames = append(frames, frame)
+			frame = nil
+		}
+	}
+	if frame != nil {
+		frames = append(frames, frame)
+	}
+	m.ddmin(frames)
+
+	for {
+		n := m.count()
+		m.removeObjects()
+		var units [][]int
+		for i, c := range calls {
+			if m.kept[i] && c.Op != Header {
+				units = append(units, []int{i})
+			}
+		}
+		m.ddmin(units)
+		if m.count() == n {
+			return m.result(nil)
+		}
+	}
+}
+
+// minimizer holds the state of Minimize.
+type minimizer struct {
+	calls []*Call
+	kept  []bool
+	holds func([]*Call) bool
+}
+
+// count returns the number of kept calls.
+func (m *minimizer) count() int {
+	n := 0
+	for _, k := range m.kept {
+		if k {
+			n++
+		}
+	}
+	return n
+}
+
+// result returns the kept calls, excluding those of the given units.
+func (m *minimizer) result(removed [][]int) []*Call {
+	skip := make(map[int]bool)
+	for _, u := range removed {
+		for _, i := range u {
+			skip[i] = true
+		}
+	}
+	var calls []*Call
+	for i, c := range m.calls {
+		if m.kept[i] && !skip[i] {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+// remove tries to remove the calls of the given units, and tells if the
+// predicate still holds without them.
+func (m *minimizer) remove(units [][]int) bool {
+	if !m.holds(m.result(units)) {
+		return false
+	}
+	for _, u := range units {
+		for _, i := range u {
+			m.kept[i] = false
+		}
+	}
+	return true
+}
+
+// ddmin removes as many of the given units of kept calls as possible, by
+// removing chunks of them of decreasing size.
+func (m *minimizer) ddmin(units [][]int) {
+	n := 2
+	for len(units) >= 2 {
+		size := (len(units) + n - 1) / n
+		reduced := false
+		for start := 0; start < len(units); {
+			end := start + size
+			if end > len(units) {
+				end = len(units)
+			}
+			if m.remove(units[start:end]) {
+				units = append(units[:start], units[end:]...)
+				reduced = true
+				continue
+			}
+			start = end
+		}
+		switch {
+		case reduced:
+			if n > 2 {
+				n--
+			}
+		case n >= len(units):
+			return
+		default:
+			n *= 2
+			if n > len(units) {
+				n = len(units)
+			}
+		}
+	}
+}
+
+// identity is an identity of a session of a trace.
+type identity struct {
+	session int
+	id      uint32
+}
+
+// removeObjects tries to remove, for each object, location and state, all the
+// kept calls involving it: the call that returned it, the calls of its
+// methods and those given it as an argument.
+func (m *minimizer) removeObjects() {
+	var (
+		order   []identity
+		uses    = make(map[identity][]int)
+		session int
+	)
+	use := func(i int, id uint32) {
+		if id == 0 {
+			return
+		}
+		key := identity{session, id}
+		if _, ok := uses[key]; !ok {
+			order = append(order, key)
+		}
+		if u := uses[key]; len(u) == 0 || u[len(u)-1] != i {
+			uses[key] = append(u, i)
+		}
+	}
+	var visit func(i int, c *Call)
+	visit = func(i int, c *Call) {
+		use(i, c.Object)
+		for _, a := range c.Args {
+			switch {
+			case a.Kind == ID:
+				use(i, a.ID)
+			case a.Kind == StateValue && a.Call != nil:
+				visit(i, a.Call)
+			}
+		}
+		if c.Result.Kind == ID {
+			use(i, c.Result.ID)
+		}
+	}
+	for i, c := range m.calls {
+		if c.Op == Header {
+			session++
+		}
+		if m.kept[i] && c.Op != Header {
+			visit(i, c)
+		}
+	}
+
+	for _, key := range order {
+		var u []int
+		for _, i := range uses[key] {
+			if m.kept[i] {
+				u = append(u, i)
+			}
+		}
+		if len(u) > 0 {
+			m.remove([][]int{u})
+		}
+	}
+}
+
+// play replays calls onto ctx using a Player, and tells if all of them were
+// valid. The Context.Check calls are made directly, so that they panic with
+// the errors of the context if strict is true, and are ignored otherwise.
+func play(ctx gfx.Context, calls []*Call, strict bool) bool {
+	p := NewPlayer(ctx)
+	for _, c := range calls {
+		if c.Op == ContextCheck {
+			if strict {
+				ctx.Check()
+			} else {
+				check(ctx)
+			}
+			continue
+		}
+		if p.Play(c) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Panics returns a predicate for Minimize, which replays calls onto a new
+// context returned by newContext (e.g. using driver/soft, or driver/gl2 with
+// headless Mesa) and holds if a call panics with a value for which match
+// returns true, or with any value if match is nil. For example:
+//
+//	trace.Panics(newContext, func(v interface{}) bool {
+//	    return v == gfx.InvalidOperation
+//	})
+//
+// holds if a Context.Check call panics with gfx.InvalidOperation.
+func Panics(newContext func() gfx.Context, match func(v interface{}) bool) func(calls []*Call) bool {
+	return func(calls []*Call) (holds bool) {
+		defer func() {
+			if v := recover(); v != nil {
+				holds = match == nil || match(v)
+			}
+		}()
+		play(newContext(), calls, true)
+		return false
+	}
+}
+
+// PixelIs returns a predicate for Minimize, which replays calls onto a new
+// context returned by newContext and holds if the pixel of its default
+// framebuffer at x, y (as read by ReadPixelsUint8) then has the given RGBA
+// color, e.g. the wrong color of a rendering bug. The errors of the context
+// are ignored, and the predicate does not hold if a call panics.
+func PixelIs(newContext func() gfx.Context, x, y int, color [4]uint8) func(calls []*Call) bool {
+	return func(calls []*Call) bool {
+		defer func() { recover() }()
+		ctx := newContext()
+		if !play(ctx, calls, false) {
+			return false
+		}
+		pixel := make([]uint8, 4)
+		ctx.Framebuffer().ReadPixelsUint8(x, y, 1, 1, pixel)
+		return [4]uint8{pixel[0], pixel[1], pixel[2], pixel[3]} == color
+	}
+}
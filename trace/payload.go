@@ -0,0 +1,128 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trace
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// The functions below encode slices as the data of Bytes values, i.e. as the
+// little-endian encoding of their elements.
+
+func bytesInt8(data []int8) []byte {
+	buf := make([]byte, len(data))
+	for i, x := range data {
+		buf[i] = byte(x)
+	}
+	return buf
+}
+
+func bytesInt16(data []int16) []byte {
+	buf := make([]byte, len(data)*2)
+	for i, x := range data {
+		binary.LittleEndian.PutUint16(buf[i*2:], uint16(x))
+	}
+	return buf
+}
+
+func bytesUint16(data []uint16) []byte {
+	buf := make([]byte, len(data)*2)
+	for i, x := range data {
+		binary.LittleEndian.PutUint16(buf[i*2:], x)
+	}
+	return buf
+}
+
+func bytesInt32(data []int32) []byte {
+	buf := make([]byte, len(data)*4)
+	for i, x := range data {
+		binary.LittleEndian.PutUint32(buf[i*4:], uint32(x))
+	}
+	return buf
+}
+
+func bytesUint32(data []uint32) []byte {
+	buf := make([]byte, len(data)*4)
+	for i, x := range data {
+		binary.LittleEndian.PutUint32(buf[i*4:], x)
+	}
+	return buf
+}
+
+func bytesFloat32(data []float32) []byte {
+	buf := make([]byte, len(data)*4)
+	for i, x := range data {
+		binary.LittleEndian.PutUint32(buf[i*4:], math.Float32bits(x))
+	}
+	return buf
+}
+
+func bytesFloat64(data []float64) []byte {
+	buf := make([]byte, len(data)*8)
+	for i, x := range data {
+		binary.LittleEndian.PutUint64(buf[i*8:], math.Float64bits(x))
+	}
+	return buf
+}
+
+// The functions below decode the data of Bytes values, whose length must be a
+// multiple of the size of the elements.
+
+func int8s(b []byte) []int8 {
+	data := make([]int8, len(b))
+	for i := range data {
+		data[i] = int8(b[i])
+	}
+	return data
+}
+
+func int16s(b []byte) []int16 {
+	data := make([]int16, len(b)/2)
+	for i := range data {
+		data[i] = int16(binary.LittleEndian.Uint16(b[i*2:]))
+	}
+	return data
+}
+
+func uint16s(b []byte) []uint16 {
+	data := make([]uint16, len(b)/2)
+	for i := range data {
+		data[i] = binary.LittleEndian.Uint16(b[i*2:])
+	}
+	return data
+}
+
+func int32s(b []byte) []int32 {
+	data := make([]int32, len(b)/4)
+	for i := range data {
+		data[i] = int32(binary.LittleEndian.Uint32(b[i*4:]))
+	}
+	return data
+}
+
+func uint32s(b []byte) []uint32 {
+	data := make([]uint32, len(b)/4)
+	for i := range data {
+		data[i] = binary.LittleEndian.Uint32(b[i*4:])
+	}
+	return data
+}
+
+func float32s(b []byte) []float32 {
+	data := make([]float32, len(b)/4)
+	for i := range data {
+		data[i] = math.Float32frombits(binary.LittleEndian.Uint32(b[i*4:]))
+	}
+	return data
+}
+
+func float64s(b []byte) []float64 {
+	data := make([]float64, len(b)/8)
+	for i := range data {
+		data[i] = math.Float64frombits(binary.LittleEndian.Uint64(b[i*8:]))
+	}
+	return data
+}
@@ -0,0 +1,714 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trace
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/slimsag/gfx"
+)
+
+// Player replays the calls of a trace onto a graphics context. Objects,
+// locations and states are recreated by the calls that returned them, and the
+// identities given to them by the trace are remapped to the new ones.
+type Player struct {
+	ctx gfx.Context
+
+	// objects maps the identities of the current session to the objects,
+	// locations and states recreated for them.
+	objects map[uint32]interface{}
+}
+
+// NewPlayer returns a new player replaying calls onto the given context.
+func NewPlayer(ctx gfx.Context) *Player {
+	return &Player{
+		ctx:     ctx,
+		objects: make(map[uint32]interface{}),
+	}
+}
+
+// Play replays the given call. Header records begin a new session, which
+// forgets the identities of the previous one. Frame records call the EndFrame
+// method of the context if it has one (e.g. if it is a Recorder), and do
+// nothing otherwise.
+//
+// An error is returned if the call is invalid, e.g. if it refers to an
+// unknown identity or its operation is unknown to this version of the
+// package. If the Context.Check call panics with an error where the recorded
+// one did not, the error is returned.
+func (p *Player) Play(c *Call) error {
+	a := &args{p: p, v: c.Args}
+	result := p.call(c, a)
+	if a.err == nil && len(a.v) > 0 {
+		a.err = errors.New("too many arguments")
+	}
+	if a.err != nil {
+		return a.err
+	}
+	if c.Result.Kind == ID {
+		p.objects[c.Result.ID] = result
+	}
+	return nil
+}
+
+// Options are the options of Replay.
+type Options struct {
+	// StopFrame, if positive, stops the replay before the first call of the
+	// frame with the given index (i.e. after that many Frame records).
+	StopFrame int
+
+	// StopCall, if positive, stops the replay before the call with the given
+	// index. Calls are indexed from zero, in the order they are read by a
+	// Reader (excluding the Header record that begins the trace).
+	StopCall int
+}
+
+// Replay replays the trace read from r onto the given graphics context, using
+// a Player. If o is nil, the whole trace is replayed.
+//
+// The context need not use the same driver as the recorded one, allowing e.g.
+// a trace recorded using WebGL to be replayed using driver/gl2 with headless
+// Mesa, or using driver/soft.
+func Replay(r io.Reader, ctx gfx.Context, o *Options) error {
+	if o == nil {
+		o = &Options{}
+	}
+	tr, err := NewReader(r)
+	if err != nil {
+		return err
+	}
+	p := NewPlayer(ctx)
+	frame := 0
+	for i := 0; o.StopCall <= 0 || i < o.StopCall; i++ {
+		if o.StopFrame > 0 && frame >= o.StopFrame {
+			return nil
+		}
+		c, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := p.Play(c); err != nil {
+			return fmt.Errorf("trace: call %d (%s): %v", i, c, err)
+		}
+		if c.Op == Frame {
+			frame++
+		}
+	}
+	return nil
+}
+
+// frameEnder is implemented by contexts that can mark the end of a frame.
+type frameEnder interface {
+	EndFrame()
+}
+
+// args reads the arguments of a call, recording the first error.
+type args struct {
+	p   *Player
+	v   []Value
+	err error
+}
+
+// ok tells if no error occurred.
+func (a *args) ok() bool {
+	return a.err == nil
+}
+
+func (a *args) next(kinds ...Kind) Value {
+	if a.err != nil {
+		return Value{}
+	}
+	if len(a.v) == 0 {
+		a.err = errors.New("too few arguments")
+		return Value{}
+	}
+	v := a.v[0]
+	a.v = a.v[1:]
+	for _, k := range kinds {
+		if v.Kind == k {
+			return v
+		}
+	}
+	a.err = fmt.Errorf("unexpected %s argument", v.Kind)
+	return Value{}
+}
+
+func (a *args) int() int {
+	return int(a.next(Int).Int)
+}
+
+// enum returns the gfx value of the next argument, an enumeration encoded as
+// an Int, and records an error if it encodes no constant of the enumeration.
+func (a *args) enum(e *enum) int {
+	x := a.int()
+	if a.err == nil && !e.valid(x) {
+		a.err = fmt.Errorf("invalid %s %d", e.typ, x)
+	}
+	return e.decode(x)
+}
+
+func (a *args) bool() bool {
+	return a.next(Bool).Bool
+}
+
+func (a *args) float32() float32 {
+	return float32(a.next(Float32).Float)
+}
+
+func (a *args) float64() float64 {
+	return a.next(Float64).Float
+}
+
+func (a *args) string() string {
+	return a.next(String).String
+}
+
+// bytes returns a Bytes argument, whose length must be a multiple of size.
+func (a *args) bytes(size int) []byte {
+	b := a.next(Bytes).Bytes
+	if a.err == nil && len(b)%size != 0 {
+		a.err = fmt.Errorf("%d bytes is not a multiple of the element size %d", len(b), size)
+	}
+	return b
+}
+
+// lookup returns the object, location or state with the given identity.
+func (a *args) lookup(id uint32) interface{} {
+	if a.err != nil {
+		return nil
+	}
+	o, ok := a.p.objects[id]
+	if !ok {
+		a.err = fmt.Errorf("unknown identity #%d", id)
+	}
+	return o
+}
+
+// ref returns the object, location or state given by an ID or Nil argument.
+func (a *args) ref() interface{} {
+	v := a.next(ID, Nil)
+	if v.Kind != ID {
+		return nil
+	}
+	return a.lookup(v.ID)
+}
+
+// stateValue returns the state value given by a StateValue argument, which is
+// recreated by replaying the call that created it.
+func (a *args) stateValue() interface{} {
+	v := a.next(StateValue)
+	if a.err != nil {
+		return nil
+	}
+	if v.Call == nil {
+		a.err = errors.New("missing state value")
+		return nil
+	}
+	va := &args{p: a.p, v: v.Call.Args}
+	value := a.p.call(v.Call, va)
+	if va.err == nil && len(va.v) > 0 {
+		va.err = errors.New("too many arguments")
+	}
+	if va.err != nil {
+		a.err = fmt.Errorf("state value %s: %v", v.Format(), va.err)
+	}
+	return value
+}
+
+// typeError records an error for an object that does not have the expected
+// type.
+func (a *args) typeError(id uint32, want string) {
+	if a.err == nil {
+		a.err = fmt.Errorf("#%d is not a %s", id, want)
+	}
+}
+
+func (a *args) framebuffer(id uint32) gfx.Framebuffer {
+	if id == 0 {
+		return a.p.ctx.Framebuffer()
+	}
+	fb, ok := a.lookup(id).(gfx.Framebuffer)
+	if !ok {
+		a.typeError(id, "framebuffer")
+	}
+	return fb
+}
+
+func (a *args) renderbuffer(id uint32) gfx.Renderbuffer {
+	rb, ok := a.lookup(id).(gfx.Renderbuffer)
+	if !ok {
+		a.typeError(id, "renderbuffer")
+	}
+	return rb
+}
+
+func (a *args) shader(id uint32) gfx.Shader {
+	s, ok := a.lookup(id).(gfx.Shader)
+	if !ok {
+		a.typeError(id, "shader")
+	}
+	return s
+}
+
+func (a *args) texture(id uint32) gfx.Texture {
+	t, ok := a.lookup(id).(gfx.Texture)
+	if !ok {
+		a.typeError(id, "texture")
+	}
+	return t
+}
+
+func (a *args) buffer(id uint32) gfx.Buffer {
+	b, ok := a.lookup(id).(gfx.Buffer)
+	if !ok {
+		a.typeError(id, "buffer")
+	}
+	return b
+}
+
+func (a *args) program(id uint32) gfx.Program {
+	p, ok := a.lookup(id).(gfx.Program)
+	if !ok {
+		a.typeError(id, "program")
+	}
+	return p
+}
+
+// call replays the given call, reading its arguments from a, and returns its
+// result. Nothing is called if an argument is invalid.
+func (p *Player) call(c *Call, a *args) interface{} {
+	ctx := p.ctx
+	switch c.Op {
+	case Header:
+		p.objects = make(map[uint32]interface{})
+		a.v = nil
+	case Frame:
+		if f, ok := ctx.(frameEnder); ok {
+			f.EndFrame()
+		}
+	case ContextNewFramebuffer:
+		return ctx.NewFramebuffer()
+	case ContextNewRenderbuffer:
+		return ctx.NewRenderbuffer()
+	case ContextNewShader:
+		if t := gfx.ShaderType(a.enum(shaderType)); a.ok() {
+			return ctx.NewShader(t)
+		}
+	case ContextNewTexture:
+		if t := gfx.TextureType(a.enum(textureType)); a.ok() {
+			return ctx.NewTexture(t)
+		}
+	case ContextNewBuffer:
+		if t := gfx.BufferType(a.enum(bufferType)); a.ok() {
+			return ctx.NewBuffer(t)
+		}
+	case ContextNewProgram:
+		return ctx.NewProgram()
+	case ContextNewState:
+		values := make([]gfx.ContextStateValue, len(a.v))
+		for i := range values {
+			values[i] = a.stateValue()
+		}
+		if a.ok() {
+			return ctx.NewState(values...)
+		}
+	case ContextLoad:
+		if s := a.ref(); a.ok() {
+			ctx.Load(s)
+		}
+	case ContextBake:
+		if s := a.ref(); a.ok() {
+			return ctx.Bake(s)
+		}
+	case ContextCurrent:
+		return ctx.Current()
+	case ContextDescribe:
+		if s := a.ref(); a.ok() {
+			ctx.Describe(s)
+		}
+	case ContextPushState:
+		ctx.PushState()
+	case ContextPopState:
+		ctx.PopState()
+	case ContextBlendColor:
+		if r, g, b, alpha := a.float32(), a.float32(), a.float32(), a.float32(); a.ok() {
+			return ctx.BlendColor(r, g, b, alpha)
+		}
+	case ContextBlendEquation:
+		if eq := gfx.BlendEquation(a.enum(blendEquation)); a.ok() {
+			return ctx.BlendEquation(eq)
+		}
+	case ContextDepthMask:
+		if m := a.bool(); a.ok() {
+			return ctx.DepthMask(m)
+		}
+	case ContextEnable:
+		if f := gfx.Feature(a.enum(feature)); a.ok() {
+			return ctx.Enable(f)
+		}
+	case ContextDisable:
+		if f := gfx.Feature(a.enum(feature)); a.ok() {
+			return ctx.Disable(f)
+		}
+	case ContextUseProgram:
+		var prog gfx.Program
+		if v := a.next(ID, Nil); v.Kind == ID {
+			prog = a.program(v.ID)
+		}
+		if a.ok() {
+			return ctx.UseProgram(prog)
+		}
+	case ContextViewport:
+		if x, y, w, h := a.int(), a.int(), a.int(), a.int(); a.ok() {
+			return ctx.Viewport(x, y, w, h)
+		}
+	case ContextScissor:
+		if x, y, w, h := a.int(), a.int(), a.int(), a.int(); a.ok() {
+			return ctx.Scissor(x, y, w, h)
+		}
+	case ContextLineWidth:
+		if w := a.float32(); a.ok() {
+			return ctx.LineWidth(w)
+		}
+	case ContextColorMask:
+		if r, g, b, alpha := a.bool(), a.bool(), a.bool(), a.bool(); a.ok() {
+			return ctx.ColorMask(r, g, b, alpha)
+		}
+	case ContextCullFace:
+		if f := gfx.Facet(a.enum(facet)); a.ok() {
+			return ctx.CullFace(f)
+		}
+	case ContextFrontFace:
+		if o := gfx.Orientation(a.enum(orientation)); a.ok() {
+			return ctx.FrontFace(o)
+		}
+	case ContextEnableVertexAttribArray:
+		if l := a.ref(); a.ok() {
+			return ctx.EnableVertexAttribArray(l)
+		}
+	case ContextCheck:
+		if err := check(ctx); err != nil && c.Result.Kind == Nil {
+			a.err = err
+		}
+	case ContextFlush:
+		ctx.Flush()
+	case ContextFinish:
+		ctx.Finish()
+	case ContextInvalidate:
+		ctx.Invalidate()
+	case ContextResync:
+		ctx.Resync()
+
+	case FramebufferNewState, FramebufferLoad, FramebufferCurrent, FramebufferDescribe,
+		FramebufferPushState, FramebufferPopState, FramebufferClearColor, FramebufferClearDepth,
+		FramebufferClearStencil, FramebufferClear, FramebufferReadPixelsUint8,
+		FramebufferTexture2D, FramebufferRenderbuffer, FramebufferStatus, FramebufferDelete:
+		if fb := a.framebuffer(c.Object); a.ok() {
+			return p.framebufferCall(c, a, fb)
+		}
+	case RenderbufferStorage:
+		rb := a.renderbuffer(c.Object)
+		format, w, h := gfx.RenderbufferFormat(a.enum(renderbufferFormat)), a.int(), a.int()
+		if a.ok() {
+			rb.Storage(format, w, h)
+		}
+	case RenderbufferDelete:
+		if rb := a.renderbuffer(c.Object); a.ok() {
+			rb.Delete()
+			delete(p.objects, c.Object)
+		}
+	case ShaderCompile:
+		s, src := a.shader(c.Object), a.string()
+		if a.ok() {
+			s.Compile(src)
+		}
+	case ShaderInfoLog:
+		if s := a.shader(c.Object); a.ok() {
+			s.InfoLog()
+		}
+	case ShaderDelete:
+		if s := a.shader(c.Object); a.ok() {
+			s.Delete()
+			delete(p.objects, c.Object)
+		}
+	case TextureType:
+		if t := a.texture(c.Object); a.ok() {
+			t.Type()
+		}
+	case TextureDelete:
+		if t := a.texture(c.Object); a.ok() {
+			t.Delete()
+			delete(p.objects, c.Object)
+		}
+	default:
+		switch {
+		case c.Op >= BufferDataSize && c.Op <= BufferDelete:
+			if b := a.buffer(c.Object); a.ok() {
+				p.bufferCall(c, a, b)
+			}
+		case c.Op >= ProgramLink && c.Op <= ProgramDelete:
+			if prog := a.program(c.Object); a.ok() {
+				return p.programCall(c, a, prog)
+			}
+		default:
+			a.err = fmt.Errorf("unknown operation %s", c.Op)
+		}
+	}
+	return nil
+}
+
+// check calls ctx.Check, and returns the error it panics with.
+func check(ctx gfx.Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if e, ok := r.(error); ok {
+				err = e
+				return
+			}
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	ctx.Check()
+	return nil
+}
+
+func (p *Player) framebufferCall(c *Call, a *args, fb gfx.Framebuffer) interface{} {
+	switch c.Op {
+	case FramebufferNewState:
+		values := make([]gfx.FramebufferStateValue, len(a.v))
+		for i := range values {
+			values[i] = a.stateValue()
+		}
+		if a.ok() {
+			return fb.NewState(values...)
+		}
+	case FramebufferLoad:
+		if s := a.ref(); a.ok() {
+			fb.Load(s)
+		}
+	case FramebufferCurrent:
+		return fb.Current()
+	case FramebufferDescribe:
+		if s := a.ref(); a.ok() {
+			fb.Describe(s)
+		}
+	case FramebufferPushState:
+		fb.PushState()
+	case FramebufferPopState:
+		fb.PopState()
+	case FramebufferClearColor:
+		if r, g, b, alpha := a.float32(), a.float32(), a.float32(), a.float32(); a.ok() {
+			return fb.ClearColor(r, g, b, alpha)
+		}
+	case FramebufferClearDepth:
+		if depth := a.float64(); a.ok() {
+			return fb.ClearDepth(depth)
+		}
+	case FramebufferClearStencil:
+		if stencil := a.int(); a.ok() {
+			return fb.ClearStencil(stencil)
+		}
+	case FramebufferClear:
+		if m := gfx.ClearMask(a.enum(clearMask)); a.ok() {
+			fb.Clear(m)
+		}
+	case FramebufferReadPixelsUint8:
+		x, y, w, h, n := a.int(), a.int(), a.int(), a.int(), a.int()
+		if a.ok() && n < 0 {
+			a.err = fmt.Errorf("invalid length %d", n)
+		}
+		if a.ok() {
+			fb.ReadPixelsUint8(x, y, w, h, make([]uint8, n))
+		}
+	case FramebufferTexture2D:
+		attachment, target := gfx.FramebufferAttachment(a.enum(framebufferAttachment)), gfx.TextureTarget(a.enum(textureTarget))
+		var tex gfx.Texture
+		if v := a.next(ID, Nil); v.Kind == ID {
+			tex = a.texture(v.ID)
+		}
+		if a.ok() {
+			fb.Texture2D(attachment, target, tex)
+		}
+	case FramebufferRenderbuffer:
+		attachment := gfx.FramebufferAttachment(a.enum(framebufferAttachment))
+		var rb gfx.Renderbuffer
+		if v := a.next(ID, Nil); v.Kind == ID {
+			rb = a.renderbuffer(v.ID)
+		}
+		if a.ok() {
+			fb.Renderbuffer(attachment, rb)
+		}
+	case FramebufferStatus:
+		fb.Status()
+	case FramebufferDelete:
+		fb.Delete()
+		delete(p.objects, c.Object)
+	}
+	return nil
+}
+
+func (p *Player) bufferCall(c *Call, a *args, b gfx.Buffer) {
+	switch c.Op {
+	case BufferDataSize:
+		size, usage := a.int(), gfx.BufferUsage(a.enum(bufferUsage))
+		if a.ok() {
+			b.DataSize(size, usage)
+		}
+	case BufferDataInt8, BufferDataUint8, BufferDataInt16, BufferDataUint16,
+		BufferDataInt32, BufferDataUint32, BufferDataFloat32, BufferDataFloat64:
+		data, usage := a.bytes(elementSize(c.Op)), gfx.BufferUsage(a.enum(bufferUsage))
+		if !a.ok() {
+			return
+		}
+		switch c.Op {
+		case BufferDataInt8:
+			b.DataInt8(int8s(data), usage)
+		case BufferDataUint8:
+			b.DataUint8(data, usage)
+		case BufferDataInt16:
+			b.DataInt16(int16s(data), usage)
+		case BufferDataUint16:
+			b.DataUint16(uint16s(data), usage)
+		case BufferDataInt32:
+			b.DataInt32(int32s(data), usage)
+		case BufferDataUint32:
+			b.DataUint32(uint32s(data), usage)
+		case BufferDataFloat32:
+			b.DataFloat32(float32s(data), usage)
+		case BufferDataFloat64:
+			b.DataFloat64(float64s(data), usage)
+		}
+	case BufferSubDataInt8, BufferSubDataUint8, BufferSubDataInt16, BufferSubDataUint16,
+		BufferSubDataInt32, BufferSubDataUint32, BufferSubDataFloat32, BufferSubDataFloat64:
+		offset, data := a.int(), a.bytes(elementSize(c.Op))
+		if !a.ok() {
+			return
+		}
+		switch c.Op {
+		case BufferSubDataInt8:
+			b.SubDataInt8(offset, int8s(data))
+		case BufferSubDataUint8:
+			b.SubDataUint8(offset, data)
+		case BufferSubDataInt16:
+			b.SubDataInt16(offset, int16s(data))
+		case BufferSubDataUint16:
+			b.SubDataUint16(offset, uint16s(data))
+		case BufferSubDataInt32:
+			b.SubDataInt32(offset, int32s(data))
+		case BufferSubDataUint32:
+			b.SubDataUint32(offset, uint32s(data))
+		case BufferSubDataFloat32:
+			b.SubDataFloat32(offset, float32s(data))
+		case BufferSubDataFloat64:
+			b.SubDataFloat64(offset, float64s(data))
+		}
+	case BufferDraw:
+		prim, first, count := gfx.Primitive(a.enum(primitive)), a.int(), a.int()
+		if a.ok() {
+			b.Draw(prim, first, count)
+		}
+	case BufferVertexAttribPointer:
+		l, size, normalized, stride, offset := a.ref(), a.int(), a.bool(), a.int(), a.int()
+		if a.ok() {
+			b.VertexAttribPointer(l, size, normalized, stride, offset)
+		}
+	case BufferDelete:
+		b.Delete()
+		delete(p.objects, c.Object)
+	}
+}
+
+func (p *Player) programCall(c *Call, a *args, prog gfx.Program) interface{} {
+	switch c.Op {
+	case ProgramLink:
+		var vert, frag gfx.Shader
+		if v := a.next(ID, Nil); v.Kind == ID {
+			vert = a.shader(v.ID)
+		}
+		if v := a.next(ID, Nil); v.Kind == ID {
+			frag = a.shader(v.ID)
+		}
+		if a.ok() {
+			prog.Link(vert, frag)
+		}
+	case ProgramInfoLog:
+		prog.InfoLog()
+	case ProgramAttribLocation:
+		if name := a.string(); a.ok() {
+			return prog.AttribLocation(name)
+		}
+	case ProgramUniformLocation:
+		if name := a.string(); a.ok() {
+			return prog.UniformLocation(name)
+		}
+	case ProgramUniform1iv, ProgramUniform2iv, ProgramUniform3iv, ProgramUniform4iv:
+		l, data := a.ref(), a.bytes(4)
+		if !a.ok() {
+			return nil
+		}
+		switch c.Op {
+		case ProgramUniform1iv:
+			prog.Uniform1iv(l, int32s(data))
+		case ProgramUniform2iv:
+			prog.Uniform2iv(l, int32s(data))
+		case ProgramUniform3iv:
+			prog.Uniform3iv(l, int32s(data))
+		case ProgramUniform4iv:
+			prog.Uniform4iv(l, int32s(data))
+		}
+	case ProgramUniform1fv, ProgramUniform2fv, ProgramUniform3fv, ProgramUniform4fv:
+		l, data := a.ref(), a.bytes(4)
+		if !a.ok() {
+			return nil
+		}
+		switch c.Op {
+		case ProgramUniform1fv:
+			prog.Uniform1fv(l, float32s(data))
+		case ProgramUniform2fv:
+			prog.Uniform2fv(l, float32s(data))
+		case ProgramUniform3fv:
+			prog.Uniform3fv(l, float32s(data))
+		case ProgramUniform4fv:
+			prog.Uniform4fv(l, float32s(data))
+		}
+	case ProgramUniformMatrix2fv, ProgramUniformMatrix3fv, ProgramUniformMatrix4fv:
+		l, transpose, data := a.ref(), a.bool(), a.bytes(4)
+		if !a.ok() {
+			return nil
+		}
+		switch c.Op {
+		case ProgramUniformMatrix2fv:
+			prog.UniformMatrix2fv(l, transpose, float32s(data))
+		case ProgramUniformMatrix3fv:
+			prog.UniformMatrix3fv(l, transpose, float32s(data))
+		case ProgramUniformMatrix4fv:
+			prog.UniformMatrix4fv(l, transpose, float32s(data))
+		}
+	case ProgramDelete:
+		prog.Delete()
+		delete(p.objects, c.Object)
+	}
+	return nil
+}
+
+// elementSize returns the size in bytes of the elements of the Bytes data of
+// the given buffer operation.
+func elementSize(op Op) int {
+	switch op {
+	case BufferDataInt16, BufferDataUint16, BufferSubDataInt16, BufferSubDataUint16:
+		return 2
+	case BufferDataInt32, BufferDataUint32, BufferDataFloat32,
+		BufferSubDataInt32, BufferSubDataUint32, BufferSubDataFloat32:
+		return 4
+	case BufferDataFloat64, BufferSubDataFloat64:
+		return 8
+	}
+	return 1
+}
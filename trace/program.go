@@ -0,0 +1,522 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trace
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ProgramOptions are the options of WriteProgram.
+type ProgramOptions struct {
+	// Driver is the name of the driver used by the program: "soft" (the
+	// default), "null", "gl2", "gles2" or "webgl".
+	Driver string
+
+	// Width and Height are the size of the default framebuffer, i.e. of the
+	// window or canvas for the gl2, gles2 and webgl drivers. They default to
+	// 640x480.
+	Width, Height int
+}
+
+// programDriver describes how a program creates a context using a driver.
+type programDriver struct {
+	// imports are the import paths used by main, besides gfx.
+	imports []string
+
+	// main is the source of the main function (and its helpers), formatted
+	// with the width and height of the default framebuffer. It plays the
+	// functions of the frames variable in order.
+	main string
+}
+
+var programDrivers = map[string]programDriver{
+	"soft": {
+		imports: []string{"github.com/slimsag/gfx/driver/soft"},
+		main: `func main() {
+	ctx := soft.New(%[1]d, %[2]d)
+	for _, frame := range frames {
+		frame(ctx)
+	}
+}
+`,
+	},
+	"null": {
+		imports: []string{"github.com/slimsag/gfx/driver/null"},
+		main: `func main() {
+	ctx := null.New(%[1]d, %[2]d)
+	for _, frame := range frames {
+		frame(ctx)
+	}
+}
+`,
+	},
+	"gl2":   glfwDriver("gl2"),
+	"gles2": glfwDriver("gles2"),
+	"webgl": {
+		imports: []string{"github.com/gopherjs/gopherjs/js", "github.com/slimsag/gfx/driver/webgl"},
+		main: `func main() {
+	document := js.Global.Get("document")
+	body := document.Call("createElement", "body")
+	document.Set("body", body)
+	canvas := document.Call("createElement", "canvas")
+	canvas.Set("width", %[1]d)
+	canvas.Set("height", %[2]d)
+	body.Call("appendChild", canvas)
+
+	ctx, err := webgl.New(canvas, nil)
+	if err != nil {
+		panic(err)
+	}
+	for _, frame := range frames {
+		frame(ctx)
+	}
+}
+`,
+	},
+}
+
+// glfwDriver returns the description of a driver whose context is created
+// for a GLFW window.
+func glfwDriver(name string) programDriver {
+	return programDriver{
+		imports: []string{"runtime", "github.com/go-gl/glfw/v3.1/glfw", "github.com/slimsag/gfx/driver/" + name},
+		main: `func init() {
+	// GLFW must be used from the main thread.
+	runtime.LockOSThread()
+}
+
+func main() {
+	if err := glfw.Init(); err != nil {
+		panic(err)
+	}
+	defer glfw.Terminate()
+
+	window, err := glfw.CreateWindow(%[1]d, %[2]d, "Trace", nil, nil)
+	if err != nil {
+		panic(err)
+	}
+	window.MakeContextCurrent()
+	ctx, err := ` + name + `.New()
+	if err != nil {
+		panic(err)
+	}
+	for _, frame := range frames {
+		if window.ShouldClose() {
+			return
+		}
+		frame(ctx)
+		window.SwapBuffers()
+		glfw.PollEvents()
+	}
+
+	// Show the last frame until the window is closed.
+	for !window.ShouldClose() {
+		glfw.WaitEvents()
+	}
+}
+`,
+	}
+}
+
+// programVar describes the variables holding the objects, locations and
+// states returned by an operation.
+type programVar struct {
+	prefix, typ string
+}
+
+var programVars = map[Op]programVar{
+	ContextNewFramebuffer:  {"framebuffer", "gfx.Framebuffer"},
+	ContextNewRenderbuffer: {"renderbuffer", "gfx.Renderbuffer"},
+	ContextNewShader:       {"shader", "gfx.Shader"},
+	ContextNewTexture:      {"texture", "gfx.Texture"},
+	ContextNewBuffer:       {"buffer", "gfx.Buffer"},
+	ContextNewProgram:      {"program", "gfx.Program"},
+	ContextNewState:        {"state", "gfx.ContextState"},
+	ContextCurrent:         {"state", "gfx.ContextState"},
+	ContextBake:            {"pipeline", "gfx.PipelineState"},
+	FramebufferNewState:    {"fbState", "gfx.FramebufferState"},
+	FramebufferCurrent:     {"fbState", "gfx.FramebufferState"},
+	ProgramAttribLocation:  {"attrib", "gfx.AttribLocation"},
+	ProgramUniformLocation: {"uniform", "gfx.UniformLocation"},
+}
+
+// WriteProgram writes the source code of a standalone Go program (a main
+// package) which issues the calls of the trace read from r, in order, onto a
+// context created using the driver given by the options. If o is nil, the
+// default options are used.
+//
+// The program only uses the public API of gfx and of the driver, so that it
+// can be built and run without the trace package, e.g. to attach a bug report
+// or to bisect a driver: objects, locations and states are held by package
+// variables named after their identity (e.g. buffer6 for #6), payloads are
+// embedded as slice literals, and the calls of each frame are issued by a
+// function, e.g. frame0. The gl2 and gles2 programs swap the buffers of their
+// window after each frame and show the last one until the window is closed;
+// the others exit once all frames were issued.
+//
+// Results are not checked, except that the program panics where a
+// Context.Check call was recorded to panic.
+func WriteProgram(w io.Writer, r io.Reader, o *ProgramOptions) error {
+	if o == nil {
+		o = &ProgramOptions{}
+	}
+	name := o.Driver
+	if name == "" {
+		name = "soft"
+	}
+	driver, ok := programDrivers[name]
+	if !ok {
+		return fmt.Errorf("trace: unknown driver %q", name)
+	}
+	width, height := o.Width, o.Height
+	if width <= 0 || height <= 0 {
+		width, height = 640, 480
+	}
+
+	tr, err := NewReader(r)
+	if err != nil {
+		return err
+	}
+	p := &program{names: make(map[uint32]string)}
+	n := 0
+	for ; ; n++ {
+		c, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if err := p.call(n, c); err != nil {
+			return fmt.Errorf("trace: call %d (%s): %v", n, c, err)
+		}
+	}
+	if n > p.first {
+		p.endFrame(n - 1)
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated from a trace by trace.WriteProgram. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&b, "// This program issues the %d calls of a trace (%d frames) using the %s driver.\n", n, p.frame, name)
+	fmt.Fprintf(&b, "package main\n\nimport (\n")
+	var std, other []string
+	for _, path := range append(driver.imports, "github.com/slimsag/gfx") {
+		if strings.Contains(path, ".") {
+			other = append(other, path)
+		} else {
+			std = append(std, path)
+		}
+	}
+	if p.usesMath {
+		std = append(std, "math")
+	}
+	sort.Strings(std)
+	sort.Strings(other)
+	for _, path := range std {
+		fmt.Fprintf(&b, "%q\n", path)
+	}
+	b.WriteString("\n")
+	for _, path := range other {
+		fmt.Fprintf(&b, "%q\n", path)
+	}
+	b.WriteString(")\n\n")
+	if len(p.vars) > 0 {
+		fmt.Fprintf(&b, "// The objects, locations and states of the trace.\nvar (\n%s)\n\n", strings.Join(p.vars, ""))
+	}
+	b.Write(p.data.Bytes())
+	b.WriteString("// frames are the functions issuing the calls of each frame.\nvar frames = []func(ctx gfx.Context){")
+	for i := 0; i < p.frame; i++ {
+		fmt.Fprintf(&b, "frame%d, ", i)
+	}
+	b.WriteString("}\n\n")
+	b.Write(p.frames.Bytes())
+	fmt.Fprintf(&b, driver.main, width, height)
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return fmt.Errorf("trace: formatting program: %v", err)
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+// program accumulates the source of a program issuing the calls of a trace.
+type program struct {
+	// session is the index of the current session of the trace, and names
+	// maps its identities to the names of their variables.
+	session int
+	names   map[uint32]string
+
+	// vars are the declarations of the variables, and data the declarations
+	// of the payloads too large to be given inline.
+	vars []string
+	data bytes.Buffer
+
+	// frames are the functions of the completed frames, and body the body
+	// of the current one, whose first call has index first. usesFB tells if
+	// the body uses the default framebuffer.
+	frames bytes.Buffer
+	body   bytes.Buffer
+	frame  int
+	first  int
+	usesFB bool
+
+	// usesMath tells if the math package is used.
+	usesMath bool
+}
+
+// endFrame completes the function of the current frame, whose last call has
+// index last.
+func (p *program) endFrame(last int) {
+	fmt.Fprintf(&p.frames, "// frame%d issues calls %d-%d of the trace.\nfunc frame%d(ctx gfx.Context) {\n", p.frame, p.first, last, p.frame)
+	if p.usesFB {
+		p.frames.WriteString("fb := ctx.Framebuffer()\n")
+	}
+	p.frames.Write(p.body.Bytes())
+	p.frames.WriteString("}\n\n")
+	p.body.Reset()
+	p.frame++
+	p.first = last + 1
+	p.usesFB = false
+}
+
+// call appends the statement issuing the call with index i.
+func (p *program) call(i int, c *Call) error {
+	switch c.Op {
+	case Header:
+		p.session++
+		p.names = make(map[uint32]string)
+		return nil
+	case Frame:
+		p.endFrame(i)
+		return nil
+	case ContextCheck:
+		if c.Result.Kind == String {
+			msg := strings.Replace(c.Result.String, "\n", " ", -1)
+			fmt.Fprintf(&p.body, "// Check panicked when the trace was recorded: %s\n", msg)
+		}
+	}
+	expr, err := p.expr(i, c)
+	if err != nil {
+		return err
+	}
+	if c.Result.Kind == ID {
+		if _, ok := p.names[c.Result.ID]; !ok {
+			v, ok := programVars[c.Op]
+			if !ok {
+				return fmt.Errorf("unexpected identity #%d", c.Result.ID)
+			}
+			name := v.prefix + strconv.FormatUint(uint64(c.Result.ID), 10)
+			if p.session > 0 {
+				name += "_" + strconv.Itoa(p.session)
+			}
+			p.names[c.Result.ID] = name
+			p.vars = append(p.vars, name+" "+v.typ+"\n")
+			expr = name + " = " + expr
+		}
+	}
+	p.body.WriteString(expr + "\n")
+	return nil
+}
+
+// expr returns the expression of the call with index i, or of a state value
+// given as one of its arguments.
+func (p *program) expr(i int, c *Call) (string, error) {
+	var recv string
+	switch c.Op.Receiver() {
+	case "":
+		return "", fmt.Errorf("unknown operation %s", c.Op)
+	case "Context":
+		recv = "ctx"
+	default:
+		if c.Op.Receiver() == "Framebuffer" && c.Object == 0 {
+			recv = "fb"
+			p.usesFB = true
+			break
+		}
+		var err error
+		if recv, err = p.name(c.Object); err != nil {
+			return "", err
+		}
+	}
+	args := make([]string, len(c.Args))
+	for k, a := range c.Args {
+		var err error
+		if args[k], err = p.value(i, c.Op, k, a); err != nil {
+			return "", err
+		}
+	}
+	if c.Op == FramebufferReadPixelsUint8 && len(args) == 5 {
+		args[4] = "make([]uint8, " + args[4] + ")"
+	}
+	method := c.Op.String()[len(c.Op.Receiver())+1:]
+	return recv + "." + method + "(" + strings.Join(args, ", ") + ")", nil
+}
+
+// name returns the name of the variable holding the given identity.
+func (p *program) name(id uint32) (string, error) {
+	name, ok := p.names[id]
+	if !ok {
+		return "", fmt.Errorf("unknown identity #%d", id)
+	}
+	return name, nil
+}
+
+// value returns the expression of the argument at index k of a call with the
+// given operation, which has index i.
+func (p *program) value(i int, op Op, k int, a Value) (string, error) {
+	switch a.Kind {
+	case Nil:
+		return "nil", nil
+	case Bool:
+		return strconv.FormatBool(a.Bool), nil
+	case Int:
+		if name, ok := EnumName(op, k, a.Int); ok {
+			return "gfx." + strings.Replace(name, "|", "|gfx.", -1), nil
+		}
+		return strconv.FormatInt(a.Int, 10), nil
+	case Float32:
+		return p.float(a.Float, 32), nil
+	case Float64:
+		return p.float(a.Float, 64), nil
+	case String:
+		if strings.Contains(a.String, "\n") && canRawQuote(a.String) {
+			return "`" + a.String + "`", nil
+		}
+		return strconv.Quote(a.String), nil
+	case Bytes:
+		return p.payload(i, op, a.Bytes)
+	case ID:
+		return p.name(a.ID)
+	case StateValue:
+		if a.Call == nil {
+			return "", errors.New("missing state value")
+		}
+		return p.expr(i, a.Call)
+	}
+	return "", fmt.Errorf("unexpected %s argument", a.Kind)
+}
+
+// canRawQuote tells if a string, e.g. the source of a shader, can be given as
+// a raw string literal.
+func canRawQuote(s string) bool {
+	if !utf8.ValidString(s) {
+		return false
+	}
+	for _, r := range s {
+		if r == '`' || r == '\uFEFF' || (r < ' ' && r != '\n' && r != '\t') {
+			return false
+		}
+	}
+	return true
+}
+
+// float returns the expression of a floating-point number of the given size.
+// Numbers which have no literal (infinities, NaNs and negative zero) are
+// given by their bits.
+func (p *program) float(x float64, bits int) string {
+	if !math.IsInf(x, 0) && !math.IsNaN(x) && (x != 0 || !math.Signbit(x)) {
+		return strconv.FormatFloat(x, 'g', -1, bits)
+	}
+	p.usesMath = true
+	if bits == 32 {
+		return fmt.Sprintf("math.Float32frombits(0x%08x)", math.Float32bits(float32(x)))
+	}
+	return fmt.Sprintf("math.Float64frombits(0x%016x)", math.Float64bits(x))
+}
+
+// maxInline is the maximum number of elements of the payloads given inline;
+// larger ones are declared as package variables, named after the index of
+// their call (e.g. data12).
+const maxInline = 8
+
+// payloadSizes are the sizes in bytes of the elements of payloads, by type.
+var payloadSizes = map[string]int{
+	"int8": 1, "uint8": 1, "int16": 2, "uint16": 2,
+	"int32": 4, "uint32": 4, "float32": 4, "float64": 8,
+}
+
+// payload returns the expression of the Bytes argument of a call with the
+// given operation, which has index i.
+func (p *program) payload(i int, op Op, b []byte) (string, error) {
+	var (
+		typ   string
+		elems []string
+	)
+	switch {
+	case op >= ProgramUniform1fv && op <= ProgramUniformMatrix4fv:
+		typ = "float32"
+		if strings.HasSuffix(op.String(), "iv") {
+			typ = "int32"
+		}
+	case op >= BufferDataInt8 && op <= BufferSubDataFloat64:
+		name := op.String()
+		typ = strings.ToLower(name[strings.LastIndex(name, "Data")+len("Data"):])
+	default:
+		return "", errors.New("unexpected Bytes argument")
+	}
+	if size := payloadSizes[typ]; len(b)%size != 0 {
+		return "", fmt.Errorf("%d bytes is not a multiple of the element size %d", len(b), size)
+	}
+	switch typ {
+	case "int8":
+		for _, x := range int8s(b) {
+			elems = append(elems, strconv.FormatInt(int64(x), 10))
+		}
+	case "uint8":
+		for _, x := range b {
+			elems = append(elems, strconv.FormatUint(uint64(x), 10))
+		}
+	case "int16":
+		for _, x := range int16s(b) {
+			elems = append(elems, strconv.FormatInt(int64(x), 10))
+		}
+	case "uint16":
+		for _, x := range uint16s(b) {
+			elems = append(elems, strconv.FormatUint(uint64(x), 10))
+		}
+	case "int32":
+		for _, x := range int32s(b) {
+			elems = append(elems, strconv.FormatInt(int64(x), 10))
+		}
+	case "uint32":
+		for _, x := range uint32s(b) {
+			elems = append(elems, strconv.FormatUint(uint64(x), 10))
+		}
+	case "float32":
+		for _, x := range float32s(b) {
+			elems = append(elems, p.float(float64(x), 32))
+		}
+	case "float64":
+		for _, x := range float64s(b) {
+			elems = append(elems, p.float(x, 64))
+		}
+	}
+
+	if len(elems) <= maxInline {
+		return "[]" + typ + "{" + strings.Join(elems, ", ") + "}", nil
+	}
+	name := "data" + strconv.Itoa(i)
+	fmt.Fprintf(&p.data, "// %s is the payload of call %d.\nvar %s = []%s{\n", name, i, name, typ)
+	for len(elems) > 0 {
+		n := maxInline
+		if n > len(elems) {
+			n = len(elems)
+		}
+		p.data.WriteString(strings.Join(elems[:n], ", ") + ",\n")
+		elems = elems[n:]
+	}
+	p.data.WriteString("}\n\n")
+	return name, nil
+}
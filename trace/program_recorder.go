@@ -0,0 +1,161 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trace
+
+import "github.com/slimsag/gfx"
+
+// programRecorder is like the Recorder type, but for a gfx.Program.
+type programRecorder struct {
+	p   gfx.Program
+	id  uint32
+	ctx *Recorder
+}
+
+func (p *programRecorder) traceID() uint32 { return p.id }
+
+// newLocation returns the location returned to the caller for the location l
+// of the program, or nil if l is nil.
+func (p *programRecorder) newLocation(l interface{}) *locationRef {
+	if l == nil {
+		return nil
+	}
+	return &locationRef{l: l, id: p.ctx.newID()}
+}
+
+// uniform records a call specifying the values of a uniform.
+func (p *programRecorder) uniform(op Op, l gfx.UniformLocation, data []byte) {
+	_, id := location(l)
+	p.ctx.record(op, p.id, Value{}, id, bytesValue(data))
+}
+
+// uniformMatrix records a call specifying the values of a uniform matrix.
+func (p *programRecorder) uniformMatrix(op Op, l gfx.UniformLocation, transpose bool, data []float32) {
+	_, id := location(l)
+	p.ctx.record(op, p.id, Value{}, id, boolValue(transpose), bytesValue(bytesFloat32(data)))
+}
+
+// Link implements the gfx.Program interface.
+func (p *programRecorder) Link(vert, frag gfx.Shader) bool {
+	success := p.p.Link(vert, frag)
+	p.ctx.record(ProgramLink, p.id, boolValue(success), object(vert), object(frag))
+	return success
+}
+
+// InfoLog implements the gfx.Program interface.
+func (p *programRecorder) InfoLog() string {
+	infoLog := p.p.InfoLog()
+	p.ctx.record(ProgramInfoLog, p.id, stringValue(infoLog))
+	return infoLog
+}
+
+// AttribLocation implements the gfx.Program interface.
+func (p *programRecorder) AttribLocation(name string) gfx.AttribLocation {
+	l := p.newLocation(p.p.AttribLocation(name))
+	if l == nil {
+		p.ctx.record(ProgramAttribLocation, p.id, Value{}, stringValue(name))
+		return nil
+	}
+	p.ctx.record(ProgramAttribLocation, p.id, idValue(l.id), stringValue(name))
+	return l
+}
+
+// UniformLocation implements the gfx.Program interface.
+func (p *programRecorder) UniformLocation(name string) gfx.UniformLocation {
+	l := p.newLocation(p.p.UniformLocation(name))
+	if l == nil {
+		p.ctx.record(ProgramUniformLocation, p.id, Value{}, stringValue(name))
+		return nil
+	}
+	p.ctx.record(ProgramUniformLocation, p.id, idValue(l.id), stringValue(name))
+	return l
+}
+
+// Uniform1fv implements the gfx.Program interface.
+func (p *programRecorder) Uniform1fv(l gfx.UniformLocation, data []float32) {
+	inner, _ := location(l)
+	p.p.Uniform1fv(inner, data)
+	p.uniform(ProgramUniform1fv, l, bytesFloat32(data))
+}
+
+// Uniform1iv implements the gfx.Program interface.
+func (p *programRecorder) Uniform1iv(l gfx.UniformLocation, data []int32) {
+	inner, _ := location(l)
+	p.p.Uniform1iv(inner, data)
+	p.uniform(ProgramUniform1iv, l, bytesInt32(data))
+}
+
+// Uniform2fv implements the gfx.Program interface.
+func (p *programRecorder) Uniform2fv(l gfx.UniformLocation, data []float32) {
+	inner, _ := location(l)
+	p.p.Uniform2fv(inner, data)
+	p.uniform(ProgramUniform2fv, l, bytesFloat32(data))
+}
+
+// Uniform2iv implements the gfx.Program interface.
+func (p *programRecorder) Uniform2iv(l gfx.UniformLocation, data []int32) {
+	inner, _ := location(l)
+	p.p.Uniform2iv(inner, data)
+	p.uniform(ProgramUniform2iv, l, bytesInt32(data))
+}
+
+// Uniform3fv implements the gfx.Program interface.
+func (p *programRecorder) Uniform3fv(l gfx.UniformLocation, data []float32) {
+	inner, _ := location(l)
+	p.p.Uniform3fv(inner, data)
+	p.uniform(ProgramUniform3fv, l, bytesFloat32(data))
+}
+
+// Uniform3iv implements the gfx.Program interface.
+func (p *programRecorder) Uniform3iv(l gfx.UniformLocation, data []int32) {
+	inner, _ := location(l)
+	p.p.Uniform3iv(inner, data)
+	p.uniform(ProgramUniform3iv, l, bytesInt32(data))
+}
+
+// Uniform4fv implements the gfx.Program interface.
+func (p *programRecorder) Uniform4fv(l gfx.UniformLocation, data []float32) {
+	inner, _ := location(l)
+	p.p.Uniform4fv(inner, data)
+	p.uniform(ProgramUniform4fv, l, bytesFloat32(data))
+}
+
+// Uniform4iv implements the gfx.Program interface.
+func (p *programRecorder) Uniform4iv(l gfx.UniformLocation, data []int32) {
+	inner, _ := location(l)
+	p.p.Uniform4iv(inner, data)
+	p.uniform(ProgramUniform4iv, l, bytesInt32(data))
+}
+
+// UniformMatrix2fv implements the gfx.Program interface.
+func (p *programRecorder) UniformMatrix2fv(l gfx.UniformLocation, transpose bool, data []float32) {
+	inner, _ := location(l)
+	p.p.UniformMatrix2fv(inner, transpose, data)
+	p.uniformMatrix(ProgramUniformMatrix2fv, l, transpose, data)
+}
+
+// UniformMatrix3fv implements the gfx.Program interface.
+func (p *programRecorder) UniformMatrix3fv(l gfx.UniformLocation, transpose bool, data []float32) {
+	inner, _ := location(l)
+	p.p.UniformMatrix3fv(inner, transpose, data)
+	p.uniformMatrix(ProgramUniformMatrix3fv, l, transpose, data)
+}
+
+// UniformMatrix4fv implements the gfx.Program interface.
+func (p *programRecorder) UniformMatrix4fv(l gfx.UniformLocation, transpose bool, data []float32) {
+	inner, _ := location(l)
+	p.p.UniformMatrix4fv(inner, transpose, data)
+	p.uniformMatrix(ProgramUniformMatrix4fv, l, transpose, data)
+}
+
+// Delete implements the gfx.Object interface.
+func (p *programRecorder) Delete() {
+	p.p.Delete()
+	p.ctx.record(ProgramDelete, p.id, Value{})
+}
+
+// Object implements the gfx.Object interface.
+func (p *programRecorder) Object() interface{} {
+	return p.p.Object()
+}
@@ -0,0 +1,436 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trace
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/slimsag/gfx"
+)
+
+// Recorder is a graphics context that records each call made on it (or on any
+// object gotten from it) to a trace, after passing it through to the
+// underlying context.
+//
+// States, state values and locations must be those returned by the recorder
+// and its objects, rather than by the underlying context.
+type Recorder struct {
+	fb  *fbRecorder
+	ctx gfx.Context
+	w   *Writer
+
+	// lastID is the last identity given to an object, location or state.
+	lastID uint32
+
+	// current is the currently loaded state, and stack the states pushed by
+	// PushState.
+	current gfx.ContextState
+	stack   []gfx.ContextState
+}
+
+// identified is implemented by the objects, locations and states returned
+// by a recorder.
+type identified interface {
+	traceID() uint32
+}
+
+// stateRef is a context or framebuffer state returned by a recorder.
+type stateRef struct {
+	s  interface{}
+	id uint32
+}
+
+func (s *stateRef) traceID() uint32 { return s.id }
+
+// pipelineRef is a pipeline state returned by a recorder.
+type pipelineRef struct {
+	p  gfx.PipelineState
+	id uint32
+}
+
+func (p *pipelineRef) traceID() uint32 { return p.id }
+
+// Hash implements the gfx.PipelineState interface.
+func (p *pipelineRef) Hash() uint64 {
+	return p.p.Hash()
+}
+
+// locationRef is an attribute or uniform location returned by a recorder.
+type locationRef struct {
+	l  interface{}
+	id uint32
+}
+
+func (l *locationRef) traceID() uint32 { return l.id }
+
+// valueRef is a context or framebuffer state value returned by a recorder,
+// along with the call that created it.
+type valueRef struct {
+	v    interface{}
+	call *Call
+}
+
+// newID returns a new identity.
+func (r *Recorder) newID() uint32 {
+	r.lastID++
+	return r.lastID
+}
+
+// record writes a call to the trace.
+func (r *Recorder) record(op Op, o uint32, result Value, args ...Value) {
+	r.w.Write(&Call{Op: op, Object: o, Args: args, Result: result})
+}
+
+// value returns the state value returned to the caller for the value v
+// created by the given call.
+func (r *Recorder) value(v interface{}, op Op, o uint32, args ...Value) *valueRef {
+	return &valueRef{v: v, call: &Call{Op: op, Object: o, Args: args}}
+}
+
+// stateValue returns the underlying state value of v, and its recorded
+// argument.
+func stateValue(v interface{}) (interface{}, Value) {
+	if ref, ok := v.(*valueRef); ok {
+		return ref.v, Value{Kind: StateValue, Call: ref.call}
+	}
+	return v, Value{}
+}
+
+// state returns the underlying state of s, and its recorded identity.
+func state(s interface{}) (interface{}, Value) {
+	switch ref := s.(type) {
+	case *stateRef:
+		return ref.s, idValue(ref.id)
+	case *pipelineRef:
+		return ref.p, idValue(ref.id)
+	}
+	return s, Value{}
+}
+
+// location returns the underlying location of l, and its recorded identity.
+func location(l interface{}) (interface{}, Value) {
+	if ref, ok := l.(*locationRef); ok {
+		return ref.l, idValue(ref.id)
+	}
+	return l, Value{}
+}
+
+// object returns the recorded identity of the given object, which is Nil if it
+// is nil or was not returned by a recorder.
+func object(o gfx.Object) Value {
+	if ref, ok := o.(identified); ok {
+		return idValue(ref.traceID())
+	}
+	return Value{}
+}
+
+func idValue(id uint32) Value {
+	if id == 0 {
+		return Value{}
+	}
+	return Value{Kind: ID, ID: id}
+}
+
+func boolValue(b bool) Value {
+	return Value{Kind: Bool, Bool: b}
+}
+
+func intValue(i int) Value {
+	return Value{Kind: Int, Int: int64(i)}
+}
+
+func float32Value(f float32) Value {
+	return Value{Kind: Float32, Float: float64(f)}
+}
+
+func stringValue(s string) Value {
+	return Value{Kind: String, String: s}
+}
+
+func bytesValue(b []byte) Value {
+	return Value{Kind: Bytes, Bytes: b}
+}
+
+func errorValue(err error) Value {
+	if err == nil {
+		return Value{}
+	}
+	return stringValue(err.Error())
+}
+
+// EndFrame records the end of a frame.
+func (r *Recorder) EndFrame() {
+	r.record(Frame, 0, Value{})
+}
+
+// Err returns the first error that occurred while writing the trace, if any.
+// No calls are recorded after an error, but they are still passed through to
+// the underlying context.
+func (r *Recorder) Err() error {
+	return r.w.Err()
+}
+
+// Framebuffer implements the gfx.Context interface.
+func (r *Recorder) Framebuffer() gfx.Framebuffer {
+	return r.fb
+}
+
+// NewFramebuffer implements the gfx.Context interface.
+func (r *Recorder) NewFramebuffer() gfx.Framebuffer {
+	fb := &fbRecorder{
+		fb:  r.ctx.NewFramebuffer(),
+		id:  r.newID(),
+		ctx: r,
+	}
+	r.record(ContextNewFramebuffer, 0, idValue(fb.id))
+	return fb
+}
+
+// NewRenderbuffer implements the gfx.Context interface.
+func (r *Recorder) NewRenderbuffer() gfx.Renderbuffer {
+	rb := &rbRecorder{
+		rb:  r.ctx.NewRenderbuffer(),
+		id:  r.newID(),
+		ctx: r,
+	}
+	r.record(ContextNewRenderbuffer, 0, idValue(rb.id))
+	return rb
+}
+
+// NewShader implements the gfx.Context interface.
+func (r *Recorder) NewShader(t gfx.ShaderType) gfx.Shader {
+	s := &shaderRecorder{
+		s:   r.ctx.NewShader(t),
+		id:  r.newID(),
+		ctx: r,
+	}
+	r.record(ContextNewShader, 0, idValue(s.id), shaderType.encode(int(t)))
+	return s
+}
+
+// NewTexture implements the gfx.Context interface.
+func (r *Recorder) NewTexture(t gfx.TextureType) gfx.Texture {
+	tex := &textureRecorder{
+		t:   r.ctx.NewTexture(t),
+		id:  r.newID(),
+		ctx: r,
+	}
+	r.record(ContextNewTexture, 0, idValue(tex.id), textureType.encode(int(t)))
+	return tex
+}
+
+// NewBuffer implements the gfx.Context interface.
+func (r *Recorder) NewBuffer(t gfx.BufferType) gfx.Buffer {
+	b := &bufferRecorder{
+		b:   r.ctx.NewBuffer(t),
+		id:  r.newID(),
+		ctx: r,
+	}
+	r.record(ContextNewBuffer, 0, idValue(b.id), bufferType.encode(int(t)))
+	return b
+}
+
+// NewProgram implements the gfx.Context interface.
+func (r *Recorder) NewProgram() gfx.Program {
+	p := &programRecorder{
+		p:   r.ctx.NewProgram(),
+		id:  r.newID(),
+		ctx: r,
+	}
+	r.record(ContextNewProgram, 0, idValue(p.id))
+	return p
+}
+
+// NewState implements the gfx.Context interface.
+func (r *Recorder) NewState(values ...gfx.ContextStateValue) gfx.ContextState {
+	inner := make([]gfx.ContextStateValue, len(values))
+	args := make([]Value, len(values))
+	for i, v := range values {
+		inner[i], args[i] = stateValue(v)
+	}
+	s := &stateRef{s: r.ctx.NewState(inner...), id: r.newID()}
+	r.record(ContextNewState, 0, idValue(s.id), args...)
+	return s
+}
+
+// Load implements the gfx.Context interface.
+func (r *Recorder) Load(s gfx.ContextState) {
+	inner, id := state(s)
+	r.ctx.Load(inner)
+	r.current = s
+	r.record(ContextLoad, 0, Value{}, id)
+}
+
+// Bake implements the gfx.Context interface.
+func (r *Recorder) Bake(s gfx.ContextState) gfx.PipelineState {
+	inner, id := state(s)
+	p := r.ctx.Bake(inner)
+	ps, ok := s.(*pipelineRef)
+	if !ok || ps.p != p {
+		ps = &pipelineRef{p: p, id: r.newID()}
+	}
+	r.record(ContextBake, 0, idValue(ps.id), id)
+	return ps
+}
+
+// Current implements the gfx.Context interface.
+func (r *Recorder) Current() gfx.ContextState {
+	r.ctx.Current()
+	_, id := state(r.current)
+	r.record(ContextCurrent, 0, id)
+	return r.current
+}
+
+// Describe implements the gfx.Context interface.
+func (r *Recorder) Describe(s gfx.ContextState) gfx.StateInfo {
+	inner, id := state(s)
+	info := r.ctx.Describe(inner)
+	r.record(ContextDescribe, 0, stringValue(info.String()), id)
+	return info
+}
+
+// PushState implements the gfx.Context interface.
+func (r *Recorder) PushState() {
+	r.ctx.PushState()
+	r.stack = append(r.stack, r.current)
+	r.record(ContextPushState, 0, Value{})
+}
+
+// PopState implements the gfx.Context interface.
+func (r *Recorder) PopState() {
+	r.ctx.PopState()
+	r.current = nil
+	if n := len(r.stack); n > 0 {
+		r.current = r.stack[n-1]
+		r.stack = r.stack[:n-1]
+	}
+	r.record(ContextPopState, 0, Value{})
+}
+
+// BlendColor implements the gfx.Context interface.
+func (r *Recorder) BlendColor(red, g, b, a float32) gfx.ContextStateValue {
+	return r.value(r.ctx.BlendColor(red, g, b, a), ContextBlendColor, 0, float32Value(red), float32Value(g), float32Value(b), float32Value(a))
+}
+
+// BlendEquation implements the gfx.Context interface.
+func (r *Recorder) BlendEquation(eq gfx.BlendEquation) gfx.ContextStateValue {
+	return r.value(r.ctx.BlendEquation(eq), ContextBlendEquation, 0, blendEquation.encode(int(eq)))
+}
+
+// DepthMask implements the gfx.Context interface.
+func (r *Recorder) DepthMask(m bool) gfx.ContextStateValue {
+	return r.value(r.ctx.DepthMask(m), ContextDepthMask, 0, boolValue(m))
+}
+
+// Enable implements the gfx.Context interface.
+func (r *Recorder) Enable(f gfx.Feature) gfx.ContextStateValue {
+	return r.value(r.ctx.Enable(f), ContextEnable, 0, feature.encode(int(f)))
+}
+
+// Disable implements the gfx.Context interface.
+func (r *Recorder) Disable(f gfx.Feature) gfx.ContextStateValue {
+	return r.value(r.ctx.Disable(f), ContextDisable, 0, feature.encode(int(f)))
+}
+
+// UseProgram implements the gfx.Context interface.
+func (r *Recorder) UseProgram(p gfx.Program) gfx.ContextStateValue {
+	return r.value(r.ctx.UseProgram(p), ContextUseProgram, 0, object(p))
+}
+
+// Viewport implements the gfx.Context interface.
+func (r *Recorder) Viewport(x, y, width, height int) gfx.ContextStateValue {
+	return r.value(r.ctx.Viewport(x, y, width, height), ContextViewport, 0, intValue(x), intValue(y), intValue(width), intValue(height))
+}
+
+// Scissor implements the gfx.Context interface.
+func (r *Recorder) Scissor(x, y, width, height int) gfx.ContextStateValue {
+	return r.value(r.ctx.Scissor(x, y, width, height), ContextScissor, 0, intValue(x), intValue(y), intValue(width), intValue(height))
+}
+
+// LineWidth implements the gfx.Context interface.
+func (r *Recorder) LineWidth(w float32) gfx.ContextStateValue {
+	return r.value(r.ctx.LineWidth(w), ContextLineWidth, 0, float32Value(w))
+}
+
+// ColorMask implements the gfx.Context interface.
+func (r *Recorder) ColorMask(red, g, b, a bool) gfx.ContextStateValue {
+	return r.value(r.ctx.ColorMask(red, g, b, a), ContextColorMask, 0, boolValue(red), boolValue(g), boolValue(b), boolValue(a))
+}
+
+// CullFace implements the gfx.Context interface.
+func (r *Recorder) CullFace(f gfx.Facet) gfx.ContextStateValue {
+	return r.value(r.ctx.CullFace(f), ContextCullFace, 0, facet.encode(int(f)))
+}
+
+// FrontFace implements the gfx.Context interface.
+func (r *Recorder) FrontFace(o gfx.Orientation) gfx.ContextStateValue {
+	return r.value(r.ctx.FrontFace(o), ContextFrontFace, 0, orientation.encode(int(o)))
+}
+
+// EnableVertexAttribArray implements the gfx.Context interface.
+func (r *Recorder) EnableVertexAttribArray(l gfx.AttribLocation) gfx.ContextStateValue {
+	inner, id := location(l)
+	return r.value(r.ctx.EnableVertexAttribArray(inner), ContextEnableVertexAttribArray, 0, id)
+}
+
+// Check implements the gfx.Context interface. The error it panics with, if
+// any, is recorded.
+func (r *Recorder) Check() {
+	defer func() {
+		e := recover()
+		var result Value
+		if e != nil {
+			result = stringValue(fmt.Sprint(e))
+		}
+		r.record(ContextCheck, 0, result)
+		if e != nil {
+			panic(e)
+		}
+	}()
+	r.ctx.Check()
+}
+
+// Flush implements the gfx.Context interface.
+func (r *Recorder) Flush() {
+	r.ctx.Flush()
+	r.record(ContextFlush, 0, Value{})
+}
+
+// Finish implements the gfx.Context interface.
+func (r *Recorder) Finish() {
+	r.ctx.Finish()
+	r.record(ContextFinish, 0, Value{})
+}
+
+// Invalidate implements the gfx.Context interface.
+func (r *Recorder) Invalidate() {
+	r.ctx.Invalidate()
+	r.record(ContextInvalidate, 0, Value{})
+}
+
+// Resync implements the gfx.Context interface.
+func (r *Recorder) Resync() {
+	r.ctx.Resync()
+	r.record(ContextResync, 0, Value{})
+}
+
+// SetDebugHandler implements the gfx.Context interface. Handlers are not
+// recorded, since they cannot be replayed.
+func (r *Recorder) SetDebugHandler(h func(m gfx.DebugMessage)) {
+	r.ctx.SetDebugHandler(h)
+}
+
+// Record wraps the given graphics context such that each call made on it (or
+// on any object gotten from it) is recorded to a new trace session written to
+// w. The session may be appended to an existing trace.
+//
+// The calls are written as they are made, each using a single call to the
+// Write method of w, which should be buffered if it is slow.
+func Record(ctx gfx.Context, w io.Writer) *Recorder {
+	r := &Recorder{ctx: ctx, w: NewWriter(w)}
+	r.fb = &fbRecorder{fb: ctx.Framebuffer(), ctx: r}
+	return r
+}
@@ -0,0 +1,33 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trace
+
+import "github.com/slimsag/gfx"
+
+// rbRecorder is like the Recorder type, but for a gfx.Renderbuffer.
+type rbRecorder struct {
+	rb  gfx.Renderbuffer
+	id  uint32
+	ctx *Recorder
+}
+
+func (r *rbRecorder) traceID() uint32 { return r.id }
+
+// Storage implements the gfx.Renderbuffer interface.
+func (r *rbRecorder) Storage(internalFormat gfx.RenderbufferFormat, width, height int) {
+	r.rb.Storage(internalFormat, width, height)
+	r.ctx.record(RenderbufferStorage, r.id, Value{}, renderbufferFormat.encode(int(internalFormat)), intValue(width), intValue(height))
+}
+
+// Delete implements the gfx.Object interface.
+func (r *rbRecorder) Delete() {
+	r.rb.Delete()
+	r.ctx.record(RenderbufferDelete, r.id, Value{})
+}
+
+// Object implements the gfx.Object interface.
+func (r *rbRecorder) Object() interface{} {
+	return r.rb.Object()
+}
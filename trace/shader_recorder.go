@@ -0,0 +1,41 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trace
+
+import "github.com/slimsag/gfx"
+
+// shaderRecorder is like the Recorder type, but for a gfx.Shader.
+type shaderRecorder struct {
+	s   gfx.Shader
+	id  uint32
+	ctx *Recorder
+}
+
+func (s *shaderRecorder) traceID() uint32 { return s.id }
+
+// Compile implements the gfx.Shader interface.
+func (s *shaderRecorder) Compile(src string) bool {
+	success := s.s.Compile(src)
+	s.ctx.record(ShaderCompile, s.id, boolValue(success), stringValue(src))
+	return success
+}
+
+// InfoLog implements the gfx.Shader interface.
+func (s *shaderRecorder) InfoLog() string {
+	infoLog := s.s.InfoLog()
+	s.ctx.record(ShaderInfoLog, s.id, stringValue(infoLog))
+	return infoLog
+}
+
+// Delete implements the gfx.Object interface.
+func (s *shaderRecorder) Delete() {
+	s.s.Delete()
+	s.ctx.record(ShaderDelete, s.id, Value{})
+}
+
+// Object implements the gfx.Object interface.
+func (s *shaderRecorder) Object() interface{} {
+	return s.s.Object()
+}
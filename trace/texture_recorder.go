@@ -0,0 +1,34 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trace
+
+import "github.com/slimsag/gfx"
+
+// textureRecorder is like the Recorder type, but for a gfx.Texture.
+type textureRecorder struct {
+	t   gfx.Texture
+	id  uint32
+	ctx *Recorder
+}
+
+func (t *textureRecorder) traceID() uint32 { return t.id }
+
+// Type implements the gfx.Texture interface.
+func (t *textureRecorder) Type() gfx.TextureType {
+	typ := t.t.Type()
+	t.ctx.record(TextureType, t.id, textureType.encode(int(typ)))
+	return typ
+}
+
+// Delete implements the gfx.Object interface.
+func (t *textureRecorder) Delete() {
+	t.t.Delete()
+	t.ctx.record(TextureDelete, t.id, Value{})
+}
+
+// Object implements the gfx.Object interface.
+func (t *textureRecorder) Object() interface{} {
+	return t.t.Object()
+}
@@ -0,0 +1,78 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package trace implements recording of the calls made on graphics contexts
+// to trace files, and their replay.
+//
+// A context wrapped by Record writes each call made on it (or on any object
+// gotten from it) to a trace, with its arguments, the identities of the
+// objects involved, buffer and pixel payloads and state values, before
+// returning:
+//
+//	f, err := os.Create("app.trace")
+//	...
+//	r := trace.Record(ctx, f)
+//	ctx = r
+//	for {
+//	    ... render a frame using ctx ...
+//	    r.EndFrame()
+//	}
+//
+// Replay re-issues the calls of a trace onto another context, which may use
+// any driver, e.g. to reproduce a rendering bug using driver/gl2 with headless
+// Mesa on a CI machine:
+//
+//	err := trace.Replay(f, ctx, &trace.Options{StopFrame: 10})
+//
+// WriteProgram exports a trace as the source of a standalone Go program
+// issuing the same calls using a given driver, e.g. to attach to a bug report:
+//
+//	err := trace.WriteProgram(out, f, &trace.ProgramOptions{Driver: "gl2"})
+//
+// Minimize reduces the calls of a trace to a minimal subsequence which still
+// reproduces a problem, e.g. a Context.Check call panicking with
+// gfx.InvalidOperation when replayed using driver/soft:
+//
+//	newContext := func() gfx.Context { return soft.New(640, 480) }
+//	calls = trace.Minimize(calls, trace.Panics(newContext, func(v interface{}) bool {
+//	    return v == gfx.InvalidOperation
+//	}))
+//
+// # Format
+//
+// A trace is a stream of records, described by the Call type, with no index
+// or trailer: it can be read while being written, and sessions can be
+// appended to it. Each session begins with a Header record. Records are
+// encoded as follows, where uvarint and varint are the variable-length
+// integer encodings of the encoding/binary package:
+//
+//	record = op:uvarint size:uvarint body[size]
+//	body   = object:uvarint nargs:uvarint value[nargs] result:value
+//	value  = kind:byte data
+//
+// The data of a value depends on its kind:
+//
+//	Nil         nothing
+//	Bool        byte, 0 or 1
+//	Int         varint
+//	Float32     4 bytes, little-endian IEEE 754
+//	Float64     8 bytes, little-endian IEEE 754
+//	String      length:uvarint bytes[length]
+//	Bytes       length:uvarint bytes[length]
+//	ID          uvarint
+//	StateValue  op:uvarint body
+//
+// The constants of most gfx enumerations share a single iota sequence, so
+// their values change whenever a constant is inserted in the gfx package.
+// Enumeration arguments are instead Int values holding the index of the
+// constant within its type, in the order of declaration, e.g. 0 for
+// gfx.Points and 4 for gfx.Triangles, or 1 for gfx.DynamicDraw. Clear masks
+// are bitmasks of gfx.ColorBuffer (1), gfx.DepthBuffer (2) and
+// gfx.StencilBuffer (4). See EnumName for the arguments which are
+// enumerations.
+//
+// Readers ignore any data following the result in a body, which future
+// versions may use, and can decode (or skip) the records of operations they
+// do not know.
+package trace
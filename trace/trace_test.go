@@ -0,0 +1,553 @@
+// Copyright 2015 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trace
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/slimsag/gfx"
+	"github.com/slimsag/gfx/driver/null"
+	"github.com/slimsag/gfx/driver/soft"
+)
+
+// Just for ensuring we meet the interface requirements.
+func init() {
+	_ = gfx.Context(&Recorder{})
+	_ = gfx.Buffer(&bufferRecorder{})
+	_ = gfx.Framebuffer(&fbRecorder{})
+	_ = gfx.Program(&programRecorder{})
+	_ = gfx.Renderbuffer(&rbRecorder{})
+	_ = gfx.Shader(&shaderRecorder{})
+	_ = gfx.Texture(&textureRecorder{})
+	_ = gfx.PipelineState(&pipelineRef{})
+}
+
+// readAll returns the records of a trace, excluding its first header.
+func readAll(t *testing.T, data []byte) []*Call {
+	r, err := NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var calls []*Call
+	for {
+		c, err := r.Next()
+		if err == io.EOF {
+			return calls
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		calls = append(calls, c)
+	}
+}
+
+func callStrings(calls []*Call) []string {
+	s := make([]string, len(calls))
+	for i, c := range calls {
+		s[i] = c.String()
+	}
+	return s
+}
+
+// Sources of the shaders used by render, which any driver can compile.
+const (
+	vertSrc = "attribute vec2 pos; uniform float scale; void main() { gl_Position = vec4(pos * scale, 0.0, 1.0); }"
+	fragSrc = "void main() { gl_FragColor = vec4(1.0); }"
+)
+
+// render records a frame drawing a triangle.
+func render(ctx gfx.Context) {
+	vert := ctx.NewShader(gfx.VertexShader)
+	vert.Compile(vertSrc)
+	frag := ctx.NewShader(gfx.FragmentShader)
+	frag.Compile(fragSrc)
+	p := ctx.NewProgram()
+	p.Link(vert, frag)
+	pos := p.AttribLocation("pos")
+	p.Uniform1fv(p.UniformLocation("scale"), []float32{2})
+
+	buf := ctx.NewBuffer(gfx.ArrayBuffer)
+	buf.DataFloat32([]float32{0, 0, 1, 0, 0, 1}, gfx.StaticDraw)
+	buf.VertexAttribPointer(pos, 2, false, 0, 0)
+	s := ctx.NewState(ctx.UseProgram(p), ctx.EnableVertexAttribArray(pos), ctx.Enable(gfx.Blend))
+	ctx.Load(ctx.Bake(s))
+
+	fb := ctx.Framebuffer()
+	fb.Load(fb.NewState(fb.ClearColor(0, 0, 0.5, 1), fb.ClearDepth(1)))
+	fb.Clear(gfx.ColorBuffer | gfx.DepthBuffer)
+	buf.Draw(gfx.Triangles, 0, 3)
+	ctx.Check()
+}
+
+func TestRecord(t *testing.T) {
+	var w bytes.Buffer
+	ctx := null.New(4, 4)
+	r := Record(ctx, &w)
+	render(r)
+	r.EndFrame()
+	if r.Err() != nil {
+		t.Fatal(r.Err())
+	}
+
+	want := []string{
+		"Context(0).NewShader(0) = #1",
+		fmt.Sprintf("Shader(1).Compile(%q) = true", vertSrc),
+		"Context(0).NewShader(1) = #2",
+		fmt.Sprintf("Shader(2).Compile(%q) = true", fragSrc),
+		"Context(0).NewProgram() = #3",
+		"Program(3).Link(#1, #2) = true",
+		`Program(3).AttribLocation("pos") = #4`,
+		`Program(3).UniformLocation("scale") = #5`,
+		"Program(3).Uniform1fv(#5, [4 bytes])",
+		"Context(0).NewBuffer(0) = #6",
+		"Buffer(6).DataFloat32([24 bytes], 0)",
+		"Buffer(6).VertexAttribPointer(#4, 2, false, 0, 0)",
+		"Context(0).NewState(UseProgram(#3), EnableVertexAttribArray(#4), Enable(0)) = #7",
+		"Context(0).Bake(#7) = #8",
+		"Context(0).Load(#8)",
+		"Framebuffer(0).NewState(ClearColor(0, 0, 0.5, 1), ClearDepth(1)) = #9",
+		"Framebuffer(0).Load(#9)",
+		"Framebuffer(0).Clear(3)",
+		"Buffer(6).Draw(4, 0, 3)",
+		"Context(0).Check()",
+		"Frame()",
+	}
+	got := callStrings(readAll(t, w.Bytes()))
+	if len(got) != len(want) {
+		t.Fatalf("got %d calls:\n%q\nwant %d", len(got), got, len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("call %d = %s, want %s", i, got[i], want[i])
+		}
+	}
+
+	// The calls were passed through to the context.
+	if g := ctx.(*null.Context).GL(); !g.Enabled(gfx.Blend) || g.ClearColor != [4]float32{0, 0, 0.5, 1} {
+		t.Errorf("GL state = %+v", g)
+	}
+}
+
+func TestRecordCurrent(t *testing.T) {
+	var w bytes.Buffer
+	r := Record(null.New(4, 4), &w)
+	s := r.NewState(r.DepthMask(false))
+	r.Load(s)
+	r.PushState()
+	r.Load(nil)
+	if r.Current() != nil {
+		t.Error("Current: want nil for the default state")
+	}
+	r.PopState()
+	if r.Current() != s {
+		t.Error("Current: want the state loaded before PushState")
+	}
+	if p := r.Bake(s); r.Bake(p) != p {
+		t.Error("Bake: want a pipeline state returned as-is")
+	}
+}
+
+func TestRecordCheck(t *testing.T) {
+	var w bytes.Buffer
+	ctx := null.New(4, 4)
+	r := Record(ctx, &w)
+	ctx.(*null.Context).SetError(gfx.InvalidValue)
+	func() {
+		defer func() {
+			if e := recover(); e != gfx.InvalidValue {
+				t.Errorf("Check panicked with %v, want %v", e, gfx.InvalidValue)
+			}
+		}()
+		r.Check()
+	}()
+	calls := readAll(t, w.Bytes())
+	if len(calls) != 1 || !reflect.DeepEqual(calls[0].Result, stringValue(gfx.InvalidValue.Error())) {
+		t.Errorf("calls = %q", callStrings(calls))
+	}
+}
+
+func TestRecordPayload(t *testing.T) {
+	var w bytes.Buffer
+	r := Record(null.New(2, 2), &w)
+	buf := r.NewBuffer(gfx.ElementArrayBuffer)
+	buf.DataUint16([]uint16{1, 0x0203}, gfx.StaticDraw)
+	buf.SubDataInt32(1, []int32{-2})
+	pixels := make([]uint8, 2*2*4)
+	r.Framebuffer().ReadPixelsUint8(0, 0, 2, 2, pixels)
+
+	calls := readAll(t, w.Bytes())
+	if got, want := calls[1].Args[0].Bytes, []byte{1, 0, 3, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("DataUint16 payload = %v, want %v", got, want)
+	}
+	if got, want := calls[2].Args, []Value{intValue(1), bytesValue([]byte{0xfe, 0xff, 0xff, 0xff})}; !reflect.DeepEqual(got, want) {
+		t.Errorf("SubDataInt32 args = %v, want %v", got, want)
+	}
+	if res := calls[3].Result; res.Kind != Bytes || len(res.Bytes) != len(pixels) {
+		t.Errorf("ReadPixelsUint8 result = %s", res.Format())
+	}
+}
+
+func TestAppend(t *testing.T) {
+	var w bytes.Buffer
+	r := Record(null.New(4, 4), &w)
+	r.NewBuffer(gfx.ArrayBuffer)
+	r.EndFrame()
+	r = Record(null.New(4, 4), &w)
+	r.NewBuffer(gfx.ArrayBuffer)
+
+	newBuffer := "Context(0).NewBuffer(0) = #1"
+	want := []string{newBuffer, "Frame()", `Header("gfxtrace", 1)`, newBuffer}
+	if got := callStrings(readAll(t, w.Bytes())); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestReaderErrors(t *testing.T) {
+	var w bytes.Buffer
+	r := Record(null.New(4, 4), &w)
+	r.NewBuffer(gfx.ArrayBuffer)
+	data := w.Bytes()
+
+	if _, err := NewReader(bytes.NewReader(nil)); err != ErrFormat {
+		t.Errorf("empty trace: err = %v, want %v", err, ErrFormat)
+	}
+	if _, err := NewReader(bytes.NewReader(data[len(data)/2:])); err == nil {
+		t.Error("no header: expected an error")
+	}
+
+	tr, err := NewReader(bytes.NewReader(data[:len(data)-1]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tr.Next(); err != io.ErrUnexpectedEOF {
+		t.Errorf("truncated trace: err = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+
+	var v bytes.Buffer
+	tw := NewWriter(&v)
+	tw.Write(&Call{Op: Header, Args: []Value{stringValue(Magic), intValue(Version + 1)}})
+	tr, err = NewReader(&v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tr.Next(); err == nil {
+		t.Error("newer version: expected an error")
+	}
+}
+
+func TestOp(t *testing.T) {
+	for o := Header; o < numOps; o++ {
+		if p, ok := ParseOp(o.String()); !ok || p != o {
+			t.Errorf("ParseOp(%q) = %v, %v", o.String(), p, ok)
+		}
+	}
+	if s := Op(numOps).String(); s != "Op(91)" {
+		t.Errorf("unknown op: String = %q", s)
+	}
+}
+
+func TestEnums(t *testing.T) {
+	// The last constant of each enumeration, which must be named last.
+	tests := []struct {
+		e    *enum
+		last interface{}
+	}{
+		{textureTarget, gfx.TextureCubeMapNegativeZ},
+		{textureType, gfx.TextureTypeCubeMap},
+		{renderbufferFormat, gfx.DepthComponent16},
+		{framebufferAttachment, gfx.DepthStencilAttachment},
+		{bufferUsage, gfx.StreamDraw},
+		{bufferType, gfx.ElementArrayBuffer},
+		{feature, gfx.Dither},
+		{orientation, gfx.CW},
+		{facet, gfx.FrontAndBack},
+		{shaderType, gfx.FragmentShader},
+		{blendEquation, gfx.FuncReverseSubtract},
+		{primitive, gfx.TriangleFan},
+	}
+	for _, tst := range tests {
+		typ := reflect.TypeOf(tst.last)
+		if tst.e.typ != typ.Name() {
+			t.Errorf("%s: named %s", typ, tst.e.typ)
+		}
+		last := int(reflect.ValueOf(tst.last).Int())
+		if i := tst.e.encode(last).Int; i != int64(len(tst.e.names)-1) {
+			t.Errorf("%s: last constant encoded as %d, want %d", typ, i, len(tst.e.names)-1)
+		}
+		for i, name := range tst.e.names {
+			x := tst.e.decode(i)
+			if s, ok := reflect.ValueOf(x).Convert(typ).Interface().(fmt.Stringer); ok && s.String() != name {
+				t.Errorf("%s: constant %d named %s, want %s", typ, i, name, s)
+			}
+			if e := tst.e.encode(x).Int; e != int64(i) {
+				t.Errorf("%s: %d encoded as %d, want %d", typ, x, e, i)
+			}
+		}
+	}
+
+	names := []struct {
+		op   Op
+		x    int64
+		want string
+	}{
+		{BufferDraw, 4, "Triangles"},
+		{BufferDraw, 99, fmt.Sprintf("Primitive(%d)", int(gfx.Points)+99)},
+		{FramebufferClear, int64(gfx.ColorBuffer | gfx.StencilBuffer), "ColorBuffer|StencilBuffer"},
+		{FramebufferClear, 8, "ClearMask(8)"},
+	}
+	for _, n := range names {
+		if got, _ := EnumName(n.op, 0, n.x); got != n.want {
+			t.Errorf("EnumName(%v, 0, %d) = %s, want %s", n.op, n.x, got, n.want)
+		}
+	}
+}
+
+func TestReplay(t *testing.T) {
+	var w bytes.Buffer
+	r := Record(null.New(4, 4), &w)
+	render(r)
+	r.EndFrame()
+	r.NewBuffer(gfx.ArrayBuffer).DataSize(4, gfx.DynamicDraw)
+	want := callStrings(readAll(t, w.Bytes()))
+
+	// Recording the replay must yield the same trace.
+	var w2 bytes.Buffer
+	ctx := null.New(4, 4)
+	if err := Replay(bytes.NewReader(w.Bytes()), Record(ctx, &w2), nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := callStrings(readAll(t, w2.Bytes())); !reflect.DeepEqual(got, want) {
+		t.Errorf("replayed trace:\n%q\nwant\n%q", got, want)
+	}
+	if g := ctx.(*null.Context).GL(); !g.Enabled(gfx.Blend) || g.Program == nil {
+		t.Errorf("GL state = %+v", g)
+	}
+
+	tests := []struct {
+		opts *Options
+		n    int
+	}{
+		{&Options{StopCall: 5}, 5},
+		{&Options{StopFrame: 1}, len(want) - 2},
+		{&Options{StopFrame: 1, StopCall: 3}, 3},
+		{&Options{StopFrame: 2}, len(want)},
+	}
+	for _, tst := range tests {
+		w2.Reset()
+		if err := Replay(bytes.NewReader(w.Bytes()), Record(null.New(4, 4), &w2), tst.opts); err != nil {
+			t.Fatal(err)
+		}
+		if got := readAll(t, w2.Bytes()); len(got) != tst.n {
+			t.Errorf("%+v: replayed %d calls, want %d", *tst.opts, len(got), tst.n)
+		}
+	}
+}
+
+func TestReplayErrors(t *testing.T) {
+	tests := []struct {
+		call *Call
+		want string
+	}{
+		{&Call{Op: BufferDraw, Object: 7}, "unknown identity #7"},
+		{&Call{Op: ContextNewBuffer}, "too few arguments"},
+		{&Call{Op: ContextNewProgram, Args: []Value{intValue(1)}}, "too many arguments"},
+		{&Call{Op: ContextNewBuffer, Args: []Value{boolValue(true)}}, "unexpected Bool argument"},
+		{&Call{Op: ContextNewBuffer, Args: []Value{intValue(2)}}, "invalid BufferType 2"},
+		{&Call{Op: ContextNewBuffer, Args: []Value{intValue(-1)}}, "invalid BufferType -1"},
+		{&Call{Op: FramebufferClear, Object: 2, Args: []Value{intValue(8)}}, "invalid ClearMask 8"},
+		{&Call{Op: ContextNewState, Args: []Value{{Kind: StateValue, Call: &Call{Op: ContextEnable, Args: []Value{intValue(40)}}}}}, "state value Enable(40): invalid Feature 40"},
+		{&Call{Op: BufferDataFloat32, Object: 1, Args: []Value{bytesValue(make([]byte, 3)), intValue(0)}}, "3 bytes is not a multiple of the element size 4"},
+		{&Call{Op: ShaderCompile, Object: 1, Args: []Value{stringValue("")}}, "#1 is not a shader"},
+		{&Call{Op: numOps}, "unknown operation Op(91)"},
+		{&Call{Op: ContextCheck}, gfx.InvalidOperation.Error()},
+	}
+	for _, tst := range tests {
+		ctx := null.New(4, 4)
+		p := NewPlayer(ctx)
+		p.Play(&Call{Op: ContextNewBuffer, Args: []Value{intValue(0)}, Result: idValue(1)})
+		p.Play(&Call{Op: ContextNewFramebuffer, Result: idValue(2)})
+		ctx.(*null.Context).SetError(gfx.InvalidOperation)
+		err := p.Play(tst.call)
+		if err == nil || err.Error() != tst.want {
+			t.Errorf("%s: err = %v, want %s", tst.call, err, tst.want)
+		}
+	}
+}
+
+// runnable are the drivers of the programs written by WriteProgram which the
+// tests type-check and run. The others need cgo and GLFW, or GopherJS.
+var runnable = map[string]bool{"soft": true, "null": true}
+
+// typeCheck type-checks the source of a program, importing its packages from
+// source.
+func typeCheck(src []byte) error {
+	// The file is named as if in the current directory, so that packages are
+	// imported like the tests import them.
+	name, err := filepath.Abs("main.go")
+	if err != nil {
+		return err
+	}
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, name, src, 0)
+	if err != nil {
+		return err
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	_, err = conf.Check("main", fset, []*ast.File{f}, nil)
+	return err
+}
+
+// runProgram runs the source of a program using go run, and fails the test
+// if it exits with a non-zero status, e.g. if one of the calls panics.
+func runProgram(t *testing.T, driver string, src []byte) {
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go tool not found")
+	}
+	name := filepath.Join(t.TempDir(), "main.go")
+	if err := os.WriteFile(name, src, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := exec.Command(goTool, "run", name).CombinedOutput(); err != nil {
+		t.Errorf("%s: go run: %v\n%s", driver, err, out)
+	}
+}
+
+func TestWriteProgram(t *testing.T) {
+	var w bytes.Buffer
+	r := Record(null.New(4, 4), &w)
+	render(r)
+	r.EndFrame()
+	buf := r.NewBuffer(gfx.ElementArrayBuffer)
+	buf.DataUint16([]uint16{0, 1, 2, 3, 4, 5, 6, 7, 8}, gfx.StaticDraw)
+	buf.SubDataFloat64(0, []float64{math.Inf(-1), math.Copysign(0, -1)})
+	r.Framebuffer().ReadPixelsUint8(0, 0, 1, 1, make([]uint8, 4))
+
+	for _, driver := range []string{"soft", "null", "gl2", "gles2", "webgl"} {
+		var src bytes.Buffer
+		if err := WriteProgram(&src, bytes.NewReader(w.Bytes()), &ProgramOptions{Driver: driver, Width: 320, Height: 200}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := parser.ParseFile(token.NewFileSet(), "main.go", src.Bytes(), 0); err != nil {
+			t.Errorf("%s: %v\n%s", driver, err, src.Bytes())
+		}
+		if runnable[driver] {
+			if err := typeCheck(src.Bytes()); err != nil {
+				t.Errorf("%s: %v\n%s", driver, err, src.Bytes())
+			} else if !testing.Short() {
+				runProgram(t, driver, src.Bytes())
+			}
+		}
+		for _, want := range []string{
+			"\"github.com/slimsag/gfx/driver/" + driver + "\"",
+			"320", "200",
+			"program3  gfx.Program",
+			fmt.Sprintf("\tshader1.Compile(%q)\n", vertSrc),
+			"\tuniform5 = program3.UniformLocation(\"scale\")\n",
+			"\tprogram3.Uniform1fv(uniform5, []float32{2})\n",
+			"\tstate7 = ctx.NewState(ctx.UseProgram(program3), ctx.EnableVertexAttribArray(attrib4), ctx.Enable(gfx.Blend))\n",
+			"\tfbState9 = fb.NewState(fb.ClearColor(0, 0, 0.5, 1), fb.ClearDepth(1))\n",
+			"\tfb.Clear(gfx.ColorBuffer | gfx.DepthBuffer)\n",
+			"// frame1 issues calls 21-24 of the trace.\n",
+			"var data22 = []uint16{\n\t0, 1, 2, 3, 4, 5, 6, 7,\n\t8,\n}\n",
+			"\tbuffer10.DataUint16(data22, gfx.StaticDraw)\n",
+			"\tbuffer10.SubDataFloat64(0, []float64{math.Float64frombits(0xfff0000000000000), math.Float64frombits(0x8000000000000000)})\n",
+			"\tfb.ReadPixelsUint8(0, 0, 1, 1, make([]uint8, 4))\n",
+		} {
+			if !strings.Contains(src.String(), want) {
+				t.Errorf("%s: program does not contain %q:\n%s", driver, want, src.Bytes())
+			}
+		}
+	}
+
+	if err := WriteProgram(io.Discard, bytes.NewReader(w.Bytes()), &ProgramOptions{Driver: "vulkan"}); err == nil {
+		t.Error("unknown driver: want an error")
+	}
+	var bad bytes.Buffer
+	tw := NewWriter(&bad)
+	tw.Write(&Call{Op: BufferDraw, Object: 7})
+	if err := WriteProgram(io.Discard, &bad, nil); err == nil || !strings.Contains(err.Error(), "unknown identity #7") {
+		t.Errorf("err = %v, want unknown identity", err)
+	}
+}
+
+func TestMinimize(t *testing.T) {
+	var w bytes.Buffer
+	r := Record(null.New(4, 4), &w)
+	for i := 0; i < 3; i++ {
+		render(r)
+		r.EndFrame()
+	}
+	r.Load(nil)
+	buf := r.NewBuffer(gfx.ArrayBuffer)
+	buf.DataSize(12, gfx.StaticDraw)
+	r.Framebuffer().Clear(gfx.ColorBuffer)
+	buf.Draw(gfx.Points, 0, 1)
+	func() {
+		defer func() { recover() }()
+		r.Check()
+	}()
+	r.EndFrame()
+	calls := readAll(t, w.Bytes())
+
+	holds := Panics(func() gfx.Context { return null.New(4, 4) }, func(v interface{}) bool {
+		return v == gfx.InvalidOperation
+	})
+	want := []Op{ContextNewBuffer, BufferDraw, ContextCheck}
+	got := Minimize(calls, holds)
+	if len(got) != len(want) {
+		t.Fatalf("got %d calls:\n%q\nwant %v", len(got), callStrings(got), want)
+	}
+	for i := range want {
+		if got[i].Op != want[i] {
+			t.Errorf("call %d = %s, want %s", i, got[i], want[i])
+		}
+	}
+
+	// The predicate does not hold without the failing frame.
+	if got := Minimize(calls[:len(calls)-7], holds); len(got) != len(calls)-7 {
+		t.Errorf("got %d calls, want them all", len(got))
+	}
+}
+
+func TestMinimizePixel(t *testing.T) {
+	var w bytes.Buffer
+	r := Record(null.New(4, 4), &w)
+	fb := r.Framebuffer()
+	for i := 0; i < 4; i++ {
+		buf := r.NewBuffer(gfx.ArrayBuffer)
+		buf.DataFloat32([]float32{0, 1, 2}, gfx.StaticDraw)
+		fb.Load(fb.NewState(fb.ClearColor(float32(i%2), 0, 0, 1)))
+		fb.Clear(gfx.ColorBuffer)
+		buf.Delete()
+		r.EndFrame()
+	}
+	calls := readAll(t, w.Bytes())
+
+	holds := PixelIs(func() gfx.Context { return soft.New(4, 4) }, 1, 1, [4]uint8{255, 0, 0, 255})
+	got := callStrings(Minimize(calls, holds))
+	want := []string{
+		"Framebuffer(0).NewState(ClearColor(1, 0, 0, 1)) = #8",
+		"Framebuffer(0).Load(#8)",
+		"Framebuffer(0).Clear(1)",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got\n%q\nwant\n%q", got, want)
+	}
+}